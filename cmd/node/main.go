@@ -1,23 +1,45 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/xnok/btree-server-msg/pkg/factory"
+	"github.com/xnok/btree-server-msg/pkg/transport/tcp"
 )
 
 func main() {
+	// Subcommands are dispatched before node flags are parsed
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		if err := runReplay(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "send" {
+		if err := runSend(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: logLevel(os.Getenv("LOG_LEVEL")),
+	})))
+
 	// Parse configuration from command line
 	config, err := factory.ParseNodeConfig()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	fmt.Printf("Starting node on port %s with config: %+v\n", config.Port, config)
+	fmt.Printf("Starting node with config: %s\n", config)
 
 	// Create and start the btree node with TCP transport
 	node, err := factory.NewBTreeNodeWithTCP(config)
@@ -36,8 +58,34 @@ func main() {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	<-sigChan
 
-	// Graceful shutdown
-	if err := node.Stop(); err != nil {
+	// Graceful shutdown, bounded so that a hung child or listener cannot
+	// keep the process alive until the orchestrator kills it
+	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	summary, err := node.StopContext(ctx)
+	if errors.Is(err, factory.ErrStopTimeout) {
+		log.Printf("Forcing exit after %v: %d inbound and %d pending messages abandoned", config.ShutdownTimeout, summary.Inbound, summary.Pending)
+		os.Exit(1)
+	}
+	if err != nil {
 		log.Printf("Error during shutdown: %v", err)
 	}
+	log.Printf("Node stopped: %d messages drained, %d discarded", summary.Drained, summary.Discarded)
+}
+
+// logLevel parses a LOG_LEVEL value such as "debug" or "warn", defaulting to info
+func logLevel(value string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// newTCPTransport creates the transport of the send and replay subcommands,
+// presenting the auth token from BTREE_AUTH_TOKEN when it is set
+func newTCPTransport() *tcp.TCPTransport {
+	t := tcp.NewTCPTransport()
+	t.SetAuthToken(os.Getenv(factory.AuthTokenEnv))
+	return t
 }
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/replay"
+	"github.com/xnok/btree-server-msg/pkg/transport"
+)
+
+// runReplay implements the "replay" subcommand, which reads a journal of
+// newline-delimited JSON messages and injects them into a running node
+func runReplay(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	file := flags.String("file", "", "Journal file of newline-delimited JSON messages")
+	addr := flags.String("addr", "", "Address of the target node (host:port or port)")
+	rate := flags.Int("rate", 10, "Messages per second (0 for unlimited)")
+	flags.Parse(args)
+
+	if *file == "" {
+		return fmt.Errorf("file is required")
+	}
+	if *addr == "" {
+		return fmt.Errorf("addr is required")
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %v", err)
+	}
+	defer f.Close()
+
+	messages, err := replay.ReadJournal(f)
+	if err != nil {
+		return err
+	}
+
+	client := transport.NewClient(newTCPTransport(), *addr)
+	ctx := context.Background()
+	if err := client.Connect(ctx); err != nil {
+		return err
+	}
+	defer client.Close()
+
+	sent, err := replay.Replay(ctx, messages, client.GetOutboundChannel(), *rate)
+	if err != nil {
+		return fmt.Errorf("replay stopped after %d messages: %v", sent, err)
+	}
+
+	drainCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := replay.WaitForDrain(drainCtx, client.GetOutboundChannel()); err != nil {
+		return fmt.Errorf("failed to deliver all replayed messages: %v", err)
+	}
+
+	log.Printf("Replayed %d messages from %s to %s", sent, *file, *addr)
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+	"github.com/xnok/btree-server-msg/pkg/replay"
+	"github.com/xnok/btree-server-msg/pkg/transport"
+)
+
+// runSend implements the "send" subcommand, which injects a single message
+// into a running node and optionally waits for the node to acknowledge it
+func runSend(args []string) error {
+	flags := flag.NewFlagSet("send", flag.ExitOnError)
+	addr := flags.String("addr", "", "Address of the target node (host:port or port)")
+	content := flags.String("content", "", "Content of the message")
+	msgType := flags.String("type", "", "Type of the message, empty for a plain broadcast")
+	ack := flags.Bool("ack", false, "Wait for the node to acknowledge the message")
+	timeout := flags.Duration("timeout", 5*time.Second, "How long to wait for delivery or the ack")
+	flags.Parse(args)
+
+	if *addr == "" {
+		return fmt.Errorf("addr is required")
+	}
+	if *content == "" {
+		return fmt.Errorf("content is required")
+	}
+
+	msg := btree.AssignID(btree.NewMessage(*content, ""), nil)
+	msg.Type = *msgType
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err := sendOne(ctx, transport.NewClient(newTCPTransport(), *addr), msg, *ack); err != nil {
+		return err
+	}
+
+	if *ack {
+		log.Printf("Sent message %s to %s and received its ack", msg.ID, *addr)
+	} else {
+		log.Printf("Sent message %s to %s", msg.ID, *addr)
+	}
+	return nil
+}
+
+// sendOne connects client, sends msg and, with waitAck, waits for the node's
+// ack. The client is closed before returning.
+func sendOne(ctx context.Context, client *transport.Client, msg btree.Message, waitAck bool) error {
+	if err := client.Connect(ctx); err != nil {
+		return err
+	}
+	defer client.Close()
+
+	msg.AckRequested = waitAck
+	if err := client.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send message: %v", err)
+	}
+
+	if !waitAck {
+		if err := replay.WaitForDrain(ctx, client.GetOutboundChannel()); err != nil {
+			return fmt.Errorf("failed to deliver message: %v", err)
+		}
+		return nil
+	}
+
+	// The node acks upstream, to every connection it accepted; other upstream
+	// traffic arriving meanwhile is ignored
+	for {
+		select {
+		case reply, ok := <-client.GetInboundChannel():
+			if !ok {
+				return fmt.Errorf("connection closed before message %s was acknowledged", msg.ID)
+			}
+			if reply.GetType() == btree.TypeAck && reply.CorrelationID == msg.ID {
+				return nil
+			}
+		case <-ctx.Done():
+			return fmt.Errorf("no ack for message %s: %w", msg.ID, ctx.Err())
+		}
+	}
+}
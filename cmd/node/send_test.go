@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+	"github.com/xnok/btree-server-msg/pkg/factory"
+	"github.com/xnok/btree-server-msg/pkg/transport"
+	"github.com/xnok/btree-server-msg/pkg/transport/tcp"
+)
+
+// startNode starts a leaf node on port that reports every message it handles
+func startNode(t *testing.T, port string) <-chan btree.Message {
+	t.Helper()
+
+	node, err := factory.NewBTreeNodeWithTCP(factory.NewNodeConfigWithChildren(port, nil))
+	if err != nil {
+		t.Fatalf("Failed to create node: %v", err)
+	}
+	received := make(chan btree.Message, 1)
+	node.Node.OnReceive(func(msg btree.Message) { received <- msg })
+	if err := node.Start(); err != nil {
+		t.Fatalf("Failed to start node: %v", err)
+	}
+	t.Cleanup(func() { node.Stop() })
+
+	deadline := time.Now().Add(2 * time.Second)
+	for node.IsHealthy().State != factory.HealthHealthy {
+		if time.Now().After(deadline) {
+			t.Fatal("Node did not start listening")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return received
+}
+
+func TestRunSend(t *testing.T) {
+	received := startNode(t, "39141")
+
+	if err := runSend([]string{"-addr", "39141", "-content", "one-off"}); err != nil {
+		t.Fatalf("runSend failed: %v", err)
+	}
+
+	select {
+	case msg := <-received:
+		if msg.Content != "one-off" || msg.ID == "" {
+			t.Errorf("Expected content one-off with a generated ID, got %+v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("The node did not receive the message")
+	}
+}
+
+func TestRunSendWaitsForAck(t *testing.T) {
+	startNode(t, "39142")
+
+	if err := runSend([]string{"-addr", "39142", "-content", "acked", "-ack", "-timeout", "2s"}); err != nil {
+		t.Fatalf("runSend failed: %v", err)
+	}
+}
+
+func TestSendOneTimesOutWithoutAck(t *testing.T) {
+	// A bare transport never acknowledges anything
+	server := tcp.NewTCPTransport()
+	if err := server.Listen(context.Background(), "127.0.0.1:39143"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	client := transport.NewClient(tcp.NewTCPTransport(), "127.0.0.1:39143")
+	if err := sendOne(ctx, client, btree.NewMessage("ignored", "s-1"), true); err == nil {
+		t.Fatal("Expected an error when no ack arrives")
+	}
+}
+
+func TestRunSendRequiresFlags(t *testing.T) {
+	if err := runSend([]string{"-content", "x"}); err == nil {
+		t.Error("Expected an error without -addr")
+	}
+	if err := runSend([]string{"-addr", "39141"}); err == nil {
+		t.Error("Expected an error without -content")
+	}
+}
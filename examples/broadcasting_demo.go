@@ -11,7 +11,8 @@ import (
 
 // Example demonstrating message broadcasting in a tree
 func main() {
-	fmt.Println("=== Message Broadcasting Example ===\n")
+	fmt.Println("=== Message Broadcasting Example ===")
+	fmt.Println()
 
 	// Create a 3-level tree
 	root := btree.NewBinaryNode("ROOT")
@@ -28,45 +29,21 @@ func main() {
 	}
 
 	// Wire the tree: root -> left, right -> grandchildren
-	go func() {
-		for msg := range root.GetLeftChannel() {
-			select {
-			case leftChild.GetInboundChannel() <- msg:
-			case <-time.After(100 * time.Millisecond):
-				log.Println("Timeout sending to left child")
-			}
-		}
-	}()
-
-	go func() {
-		for msg := range root.GetRightChannel() {
-			select {
-			case rightChild.GetInboundChannel() <- msg:
-			case <-time.After(100 * time.Millisecond):
-				log.Println("Timeout sending to right child")
-			}
-		}
-	}()
-
-	go func() {
-		for msg := range leftChild.GetLeftChannel() {
-			select {
-			case leftGrandchild.GetInboundChannel() <- msg:
-			case <-time.After(100 * time.Millisecond):
-				log.Println("Timeout sending to left grandchild")
-			}
-		}
-	}()
-
-	go func() {
-		for msg := range rightChild.GetRightChannel() {
-			select {
-			case rightGrandchild.GetInboundChannel() <- msg:
-			case <-time.After(100 * time.Millisecond):
-				log.Println("Timeout sending to right grandchild")
-			}
+	links := []struct {
+		parent *btree.Node
+		index  int
+		child  *btree.Node
+	}{
+		{root, 0, leftChild},
+		{root, 1, rightChild},
+		{leftChild, 0, leftGrandchild},
+		{rightChild, 1, rightGrandchild},
+	}
+	for _, link := range links {
+		if err := btree.PipeChild(link.parent, link.index, link.child); err != nil {
+			log.Fatalf("Failed to wire %s: %v", link.child.Name(), err)
 		}
-	}()
+	}
 
 	fmt.Println("Tree structure:")
 	fmt.Println("       ROOT")
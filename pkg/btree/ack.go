@@ -0,0 +1,90 @@
+package btree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrAckTimeout is returned by SendReliable when no ack arrives in time
+var ErrAckTimeout = errors.New("ack timeout")
+
+// SendReliable sends a message to the child at index and waits until the
+// child acknowledges receiving it. Acks are per hop: they confirm the child
+// node handled the message, not that it reached the child's own subtree. A
+// message without an ID is given one.
+func (n *Node) SendReliable(ctx context.Context, index int, msg Message, timeout time.Duration) error {
+	msg = n.assignID(msg)
+	msg.AckRequested = true
+
+	// Register before sending so a fast ack is not lost
+	acked := make(chan struct{}, 1)
+	n.ackMu.Lock()
+	if n.acks == nil {
+		n.acks = make(map[string]chan struct{})
+	}
+	if _, exists := n.acks[msg.ID]; exists {
+		n.ackMu.Unlock()
+		return fmt.Errorf("message %s is already awaiting an ack", msg.ID)
+	}
+	n.acks[msg.ID] = acked
+	n.ackMu.Unlock()
+
+	defer func() {
+		n.ackMu.Lock()
+		delete(n.acks, msg.ID)
+		n.ackMu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := n.SendToChild(ctx, index, msg); err != nil {
+		return err
+	}
+
+	select {
+	case <-acked:
+		return nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w: message %s to child %d after %v", ErrAckTimeout, msg.ID, index, timeout)
+		}
+		return ctx.Err()
+	}
+}
+
+// sendAck acknowledges a message upstream to the node that sent it
+func (n *Node) sendAck(msg Message) {
+	if msg.ID == "" {
+		n.log().Warn("Cannot acknowledge a message without an ID")
+		return
+	}
+
+	n.log().Debug("Acknowledging message", "id", msg.ID)
+	n.forwardUpstream(Message{
+		Type:          TypeAck,
+		CorrelationID: msg.ID,
+		Source:        n.name,
+		Timestamp:     time.Now(),
+	})
+}
+
+// handleAck completes the SendReliable waiting for an ack. Acks are never
+// relayed further upstream.
+func (n *Node) handleAck(msg Message) {
+	n.ackMu.Lock()
+	acked, ok := n.acks[msg.CorrelationID]
+	n.ackMu.Unlock()
+
+	if !ok {
+		n.log().Debug("Ignoring ack for unknown message", "correlation", msg.CorrelationID)
+		return
+	}
+
+	select {
+	case acked <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,71 @@
+package btree
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// link delivers the parent's messages for child index to child, and the
+// child's upstream messages back to the parent, as the factory wiring does
+func link(t *testing.T, parent *Node, index int, child *Node) {
+	t.Helper()
+
+	childChannel, err := parent.GetChildChannel(index)
+	if err != nil {
+		t.Fatalf("Failed to get child channel: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go func() {
+		for {
+			select {
+			case msg := <-childChannel:
+				child.HandleMessage(ctx, msg)
+			case msg := <-child.GetUpstreamChannel():
+				parent.HandleMessage(ctx, msg)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func TestSendReliableAcknowledged(t *testing.T) {
+	parent := NewNode("parent", 1)
+	child := NewNode("child", 1)
+	link(t, parent, 0, child)
+
+	err := parent.SendReliable(context.Background(), 0, NewMessage("important", "ack-1"), time.Second)
+	if err != nil {
+		t.Fatalf("Expected the child to acknowledge, got %v", err)
+	}
+
+	// The flag is per hop and is not passed on to the child's children
+	grandchildChannel, _ := child.GetChildChannel(0)
+	select {
+	case msg := <-grandchildChannel:
+		if msg.AckRequested {
+			t.Error("Expected the ack request to be cleared before forwarding")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the child to forward the message")
+	}
+}
+
+func TestSendReliableTimesOut(t *testing.T) {
+	// Nobody drains the child channel, so no ack ever comes back
+	parent := NewNode("parent", 1)
+
+	start := time.Now()
+	err := parent.SendReliable(context.Background(), 0, NewMessage("lost", "ack-2"), 50*time.Millisecond)
+	if !errors.Is(err, ErrAckTimeout) {
+		t.Fatalf("Expected ErrAckTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected to give up after the timeout, took %v", elapsed)
+	}
+}
@@ -0,0 +1,127 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// AggregatorNode is the fan-in counterpart of Node: it merges the messages
+// of many sources into a single output stream, for aggregation pipelines
+// where many producers feed one consumer. Messages are forwarded unchanged,
+// in the order they arrive; messages from one source keep their order.
+type AggregatorNode struct {
+	name    string
+	sources []chan Message
+	output  chan Message
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	// startOnce makes Start idempotent
+	startOnce sync.Once
+
+	// forwarded counts messages passed to the output
+	forwarded atomic.Uint64
+
+	// logger receives the aggregator's structured log records
+	logger *slog.Logger
+}
+
+// NewAggregatorNode creates an aggregator merging numSources sources. Of the
+// NodeOptions only the buffer size and logger apply.
+func NewAggregatorNode(name string, numSources int, opts ...NodeOption) *AggregatorNode {
+	options := defaultNodeOptions()
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sources := make([]chan Message, numSources)
+	for i := range sources {
+		sources[i] = make(chan Message, options.bufferSize)
+	}
+
+	logger := options.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &AggregatorNode{
+		name:    name,
+		sources: sources,
+		output:  make(chan Message, options.bufferSize),
+		ctx:     ctx,
+		cancel:  cancel,
+		logger:  logger.With("node", name),
+	}
+}
+
+// Name returns the aggregator's name
+func (a *AggregatorNode) Name() string {
+	return a.name
+}
+
+// GetNumSources returns the number of sources the aggregator merges
+func (a *AggregatorNode) GetNumSources() int {
+	return len(a.sources)
+}
+
+// GetSourceChannel returns the channel a source sends its messages on
+func (a *AggregatorNode) GetSourceChannel(index int) (chan<- Message, error) {
+	if index < 0 || index >= len(a.sources) {
+		return nil, fmt.Errorf("source index %d out of range [0, %d)", index, len(a.sources))
+	}
+	return a.sources[index], nil
+}
+
+// GetOutputChannel returns the merged stream of every source's messages
+func (a *AggregatorNode) GetOutputChannel() <-chan Message {
+	return a.output
+}
+
+// Forwarded returns how many messages have been passed to the output
+func (a *AggregatorNode) Forwarded() uint64 {
+	return a.forwarded.Load()
+}
+
+// Start begins merging the sources into the output
+func (a *AggregatorNode) Start() {
+	a.startOnce.Do(func() {
+		for i, source := range a.sources {
+			a.wg.Add(1)
+			go a.forward(i, source)
+		}
+	})
+}
+
+// Stop stops merging and waits for in-flight messages to be dropped or
+// forwarded. Messages still queued on the sources are discarded.
+func (a *AggregatorNode) Stop() {
+	a.cancel()
+	a.wg.Wait()
+	a.logger.Info("Aggregator stopped")
+}
+
+// forward moves messages from one source to the output until stopped
+func (a *AggregatorNode) forward(index int, source <-chan Message) {
+	defer a.wg.Done()
+
+	for {
+		select {
+		case msg := <-source:
+			select {
+			case a.output <- msg:
+				a.forwarded.Add(1)
+				a.logger.Debug("Aggregated message", "source", index, "id", msg.ID)
+			case <-a.ctx.Done():
+				return
+			}
+		case <-a.ctx.Done():
+			return
+		}
+	}
+}
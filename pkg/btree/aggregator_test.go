@@ -0,0 +1,79 @@
+package btree
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAggregatorMergesSources(t *testing.T) {
+	aggregator := NewAggregatorNode("sink", 3)
+	aggregator.Start()
+	defer aggregator.Stop()
+
+	const perSource = 10
+	for i := 0; i < aggregator.GetNumSources(); i++ {
+		source, err := aggregator.GetSourceChannel(i)
+		if err != nil {
+			t.Fatalf("GetSourceChannel(%d) failed: %v", i, err)
+		}
+		go func(i int) {
+			for j := 0; j < perSource; j++ {
+				source <- NewMessage("reading", fmt.Sprintf("s%d-%d", i, j))
+			}
+		}(i)
+	}
+
+	// Everything arrives once, and each source's messages keep their order
+	next := make([]int, 3)
+	for received := 0; received < 3*perSource; received++ {
+		select {
+		case msg := <-aggregator.GetOutputChannel():
+			var source, seq int
+			if _, err := fmt.Sscanf(msg.ID, "s%d-%d", &source, &seq); err != nil {
+				t.Fatalf("Unexpected message %q", msg.ID)
+			}
+			if seq != next[source] {
+				t.Errorf("Expected s%d-%d, got %s", source, next[source], msg.ID)
+			}
+			next[source] = seq + 1
+		case <-time.After(time.Second):
+			t.Fatalf("Timeout after %d messages", received)
+		}
+	}
+
+	if forwarded := aggregator.Forwarded(); forwarded != 3*perSource {
+		t.Errorf("Expected %d forwarded messages, got %d", 3*perSource, forwarded)
+	}
+}
+
+func TestAggregatorSourceOutOfRange(t *testing.T) {
+	aggregator := NewAggregatorNode("sink", 2)
+	if _, err := aggregator.GetSourceChannel(2); err == nil {
+		t.Error("Expected an error for a nonexistent source")
+	}
+	if _, err := aggregator.GetSourceChannel(-1); err == nil {
+		t.Error("Expected an error for a negative source index")
+	}
+}
+
+func TestAggregatorStop(t *testing.T) {
+	aggregator := NewAggregatorNode("sink", 1, WithBufferSize(0))
+	aggregator.Start()
+
+	source, _ := aggregator.GetSourceChannel(0)
+	source <- NewMessage("blocked", "b-1")
+
+	// The forwarder is blocked on the unbuffered, unread output; Stop must
+	// still return
+	done := make(chan struct{})
+	go func() {
+		aggregator.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Stop did not return")
+	}
+}
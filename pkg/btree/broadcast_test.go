@@ -202,3 +202,31 @@ func TestMessageSourceTracking(t *testing.T) {
 	}
 	mu.Unlock()
 }
+
+func TestBroadcastFiltered(t *testing.T) {
+	node := NewBinaryNode("router")
+	ctx := context.Background()
+
+	// Even-keyed messages go to child 0, odd-keyed ones to child 1
+	byKey := func(childIndex int, msg Message) bool {
+		return len(msg.Content)%2 == childIndex
+	}
+
+	for _, content := range []string{"ab", "abc", "abcd"} {
+		if err := node.BroadcastFiltered(ctx, NewMessage(content, content), byKey); err != nil {
+			t.Fatalf("Failed to broadcast: %v", err)
+		}
+	}
+
+	left, _ := node.GetChildChannel(0)
+	right, _ := node.GetChildChannel(1)
+	if len(left) != 2 || len(right) != 1 {
+		t.Fatalf("Expected 2 messages on child 0 and 1 on child 1, got %d and %d", len(left), len(right))
+	}
+	if msg := <-right; msg.Content != "abc" {
+		t.Errorf("Expected the odd-keyed message on child 1, got %q", msg.Content)
+	}
+	if stats := node.Stats(); stats.Broadcast != 3 || stats.Dropped != 0 {
+		t.Errorf("Expected 3 deliveries and no drops, got %+v", stats)
+	}
+}
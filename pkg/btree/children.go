@@ -0,0 +1,72 @@
+package btree
+
+import (
+	"fmt"
+)
+
+// AddChild attaches a new child while the node is running and returns its
+// index; the child's channel is available from GetChildChannel. The slot of
+// a removed child is reused before the node grows.
+func (n *Node) AddChild() (int, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := n.stopped(); err != nil {
+		return 0, err
+	}
+
+	childOut := make(chan Message, n.bufferSize)
+
+	for i, existing := range n.childrenOut {
+		if existing == nil {
+			n.childrenOut[i] = childOut
+			n.log().Info("Attached child", "child", i)
+			return i, nil
+		}
+	}
+
+	n.childrenOut = append(n.childrenOut, childOut)
+	index := len(n.childrenOut) - 1
+	n.log().Info("Attached child", "child", index)
+	return index, nil
+}
+
+// RemoveChild detaches the child at index and closes its channel. The
+// indices of the remaining children do not change.
+func (n *Node) RemoveChild(index int) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if index < 0 || index >= len(n.childrenOut) {
+		return n.indexOutOfRange(index)
+	}
+	if n.childrenOut[index] == nil {
+		return fmt.Errorf("%w: %d", ErrChildRemoved, index)
+	}
+
+	// Senders hold the read lock, so nothing can be sending while we close
+	close(n.childrenOut[index])
+	n.childrenOut[index] = nil
+
+	// Trailing empty slots can go; earlier ones keep later indices stable
+	last := len(n.childrenOut)
+	for last > 0 && n.childrenOut[last-1] == nil {
+		last--
+	}
+	n.childrenOut = n.childrenOut[:last]
+
+	n.log().Info("Detached child", "child", index)
+	return nil
+}
+
+// activeChildren counts the children that have not been removed. Callers
+// must hold n.mu.
+func (n *Node) activeChildren() int {
+	count := 0
+	for _, childOut := range n.childrenOut {
+		if childOut != nil {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,167 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestAddChildReceivesSubsequentBroadcasts(t *testing.T) {
+	node := NewNode("elastic", 1)
+	node.Start()
+	defer node.Stop()
+
+	ctx := context.Background()
+	node.HandleMessage(ctx, NewMessage("before", "add-1"))
+
+	index, err := node.AddChild()
+	if err != nil {
+		t.Fatalf("Failed to add child: %v", err)
+	}
+	if index != 1 {
+		t.Fatalf("Expected new child at index 1, got %d", index)
+	}
+
+	node.HandleMessage(ctx, NewMessage("after", "add-2"))
+
+	childChannel, err := node.GetChildChannel(index)
+	if err != nil {
+		t.Fatalf("Failed to get new child channel: %v", err)
+	}
+	if len(childChannel) != 1 {
+		t.Fatalf("Expected only the later broadcast, got %d messages", len(childChannel))
+	}
+	if msg := <-childChannel; msg.ID != "add-2" {
+		t.Errorf("Expected add-2, got %s", msg.ID)
+	}
+}
+
+func TestRemoveChildKeepsOtherIndices(t *testing.T) {
+	node := NewNode("elastic", 3)
+
+	if err := node.RemoveChild(1); err != nil {
+		t.Fatalf("Failed to remove child: %v", err)
+	}
+	if err := node.RemoveChild(1); err == nil {
+		t.Error("Expected error removing the same child twice")
+	}
+
+	node.HandleMessage(context.Background(), NewMessage("after removal", "rm-1"))
+
+	for _, i := range []int{0, 2} {
+		childChannel, _ := node.GetChildChannel(i)
+		if len(childChannel) != 1 {
+			t.Errorf("Expected child %d to receive the broadcast", i)
+		}
+	}
+	if _, err := node.GetChildChannel(1); err == nil {
+		t.Error("Expected error getting a removed child's channel")
+	}
+
+	// The freed slot is reused
+	if index, _ := node.AddChild(); index != 1 {
+		t.Errorf("Expected the removed slot to be reused, got index %d", index)
+	}
+}
+
+func TestRemoveLastChildMakesLeaf(t *testing.T) {
+	node := NewNode("elastic", 1)
+	childChannel, _ := node.GetChildChannel(0)
+
+	if err := node.RemoveChild(0); err != nil {
+		t.Fatalf("Failed to remove child: %v", err)
+	}
+	if !node.IsLeaf() {
+		t.Error("Expected node without children to be a leaf")
+	}
+	if _, ok := <-childChannel; ok {
+		t.Error("Expected the removed child's channel to be closed")
+	}
+}
+
+func TestBroadcastDuringTopologyChanges(t *testing.T) {
+	node := NewNode("elastic", 2)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			node.HandleMessage(ctx, NewMessage("concurrent", fmt.Sprintf("c-%d", i)))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			index, err := node.AddChild()
+			if err != nil {
+				t.Errorf("Failed to add child: %v", err)
+				return
+			}
+			if err := node.RemoveChild(index); err != nil {
+				t.Errorf("Failed to remove child: %v", err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	if children := node.GetNumChildren(); children != 2 {
+		t.Errorf("Expected 2 children after balanced changes, got %d", children)
+	}
+}
+
+func TestBinaryChannelsDuringTopologyChanges(t *testing.T) {
+	node := NewNode("elastic", 1)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			index, err := node.AddChild()
+			if err != nil {
+				t.Errorf("AddChild failed: %v", err)
+				return
+			}
+			if err := node.RemoveChild(index); err != nil {
+				t.Errorf("RemoveChild failed: %v", err)
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		node.GetLeftChannel()
+		node.GetRightChannel()
+	}
+	wg.Wait()
+}
+
+func TestStatsCountOnlyActiveChildren(t *testing.T) {
+	node := NewNode("parent", 3)
+	if err := node.RemoveChild(1); err != nil {
+		t.Fatalf("Failed to remove child: %v", err)
+	}
+
+	if got := node.Stats().Children; got != 2 {
+		t.Errorf("Expected 2 children, got %d", got)
+	}
+	if got := node.ActiveChildren(); got != 2 {
+		t.Errorf("Expected 2 active children, got %d", got)
+	}
+	if got := node.GetNumChildren(); got != 3 {
+		t.Errorf("Expected 3 child slots, got %d", got)
+	}
+	if err := node.RemoveChild(0); err != nil {
+		t.Fatalf("Failed to remove child: %v", err)
+	}
+	if err := node.RemoveChild(2); err != nil {
+		t.Fatalf("Failed to remove child: %v", err)
+	}
+	if got := node.Stats().Children; got != 0 || !node.IsLeaf() {
+		t.Errorf("Expected a leaf with 0 children, got %d children", got)
+	}
+}
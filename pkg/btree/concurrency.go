@@ -0,0 +1,19 @@
+package btree
+
+// WithConcurrency runs n workers taking messages from the inbound channel,
+// so a slow HandleMessage, such as one with heavy middleware, does not hold
+// up the messages queued behind it. Values below one are ignored; the
+// default is a single worker.
+//
+// With more than one worker, messages are handled and broadcast in no
+// particular order: children may receive a later message before an earlier
+// one, and sequence checks report the resulting out-of-order numbers as
+// gaps. Hooks, type handlers, filters and middleware run concurrently and
+// must be safe for concurrent use. Keep a single worker where order matters.
+func WithConcurrency(n int) NodeOption {
+	return func(o *nodeOptions) {
+		if n >= 1 {
+			o.concurrency = n
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowMiddleware simulates expensive processing and records the most
+// messages it was ever handling at once in peak
+func slowMiddleware(delay time.Duration, peak *atomic.Int32) Middleware {
+	var inFlight atomic.Int32
+	return func(ctx context.Context, msg Message) (Message, error) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			previous := peak.Load()
+			if current <= previous || peak.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+		time.Sleep(delay)
+		return msg, nil
+	}
+}
+
+func TestConcurrentWorkersHandleEveryMessage(t *testing.T) {
+	const workers, total = 8, 200
+
+	node := NewNodeWithOptions("pool", 2, WithConcurrency(workers), WithBufferSize(total))
+	var peak atomic.Int32
+	node.Use(slowMiddleware(time.Millisecond, &peak))
+	node.Start()
+	defer node.Stop()
+
+	for i := 0; i < total; i++ {
+		node.GetInboundChannel() <- NewMessage("work", fmt.Sprintf("w-%d", i))
+	}
+
+	for child := 0; child < 2; child++ {
+		ch, _ := node.GetChildChannel(child)
+		seen := make(map[string]bool)
+		for len(seen) < total {
+			select {
+			case msg := <-ch:
+				if seen[msg.ID] {
+					t.Fatalf("Child %d received %s twice", child, msg.ID)
+				}
+				seen[msg.ID] = true
+			case <-time.After(2 * time.Second):
+				t.Fatalf("Child %d received %d of %d messages", child, len(seen), total)
+			}
+		}
+	}
+
+	if peak.Load() < 2 {
+		t.Errorf("Expected messages to be handled concurrently, peak was %d", peak.Load())
+	}
+	if peak.Load() > workers {
+		t.Errorf("Expected at most %d messages in flight, peak was %d", workers, peak.Load())
+	}
+	if received := node.Stats().Received; received != total {
+		t.Errorf("Expected %d received messages, got %d", total, received)
+	}
+}
+
+func TestConcurrentWorkersDrainOnShutdown(t *testing.T) {
+	const total = 50
+
+	node := NewNodeWithOptions("pool", 1, WithConcurrency(4), WithBufferSize(total))
+	var peak atomic.Int32
+	node.Use(slowMiddleware(time.Millisecond, &peak))
+	for i := 0; i < total; i++ {
+		node.GetInboundChannel() <- NewMessage("queued", fmt.Sprintf("q-%d", i))
+	}
+	node.Start()
+
+	child, _ := node.GetChildChannel(0)
+	received := make(chan int)
+	go func() {
+		count := 0
+		for range child {
+			count++
+			if count == total {
+				break
+			}
+		}
+		received <- count
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := node.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	select {
+	case count := <-received:
+		if count != total {
+			t.Errorf("Expected %d messages, got %d", total, count)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the drained messages")
+	}
+}
+
+func TestWithConcurrencyIgnoresInvalidValues(t *testing.T) {
+	for _, n := range []int{0, -3} {
+		if node := NewNodeWithOptions("pool", 1, WithConcurrency(n)); node.concurrency != 1 {
+			t.Errorf("WithConcurrency(%d): expected 1 worker, got %d", n, node.concurrency)
+		}
+	}
+}
+
+func BenchmarkConcurrency(b *testing.B) {
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("workers-%d", workers), func(b *testing.B) {
+			node := NewNodeWithOptions("bench", 2, WithConcurrency(workers), WithLogger(slog.New(slog.DiscardHandler)))
+			node.SetDeliveryMode(DeliveryBlocking)
+			var peak atomic.Int32
+			node.Use(slowMiddleware(100*time.Microsecond, &peak))
+
+			channels := make([]<-chan Message, 2)
+			for i := range channels {
+				channels[i], _ = node.GetChildChannel(i)
+			}
+			var delivered atomic.Int64
+			done := make(chan struct{})
+			for _, ch := range channels {
+				go func() {
+					for range ch {
+						if delivered.Add(1) == int64(2*b.N) {
+							close(done)
+						}
+					}
+				}()
+			}
+
+			messages := make([]Message, b.N)
+			for i := range messages {
+				messages[i] = NewMessage(benchmarkContent, fmt.Sprintf("bench-%d", i))
+			}
+
+			node.Start()
+			b.ResetTimer()
+			for _, msg := range messages {
+				node.GetInboundChannel() <- msg
+			}
+			<-done
+			b.StopTimer()
+
+			node.Stop()
+			for i := range channels {
+				node.RemoveChild(i)
+			}
+		})
+	}
+}
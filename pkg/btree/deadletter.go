@@ -0,0 +1,64 @@
+package btree
+
+// Reasons recorded on dead-lettered messages
+const (
+	// ReasonNoSuchChild marks a message routed to a child index that does not exist
+	ReasonNoSuchChild = "no-such-child"
+
+	// ReasonChannelFull marks a broadcast skipped because the child channel was full
+	ReasonChannelFull = "channel-full"
+)
+
+// DroppedMessage is a message that could not be delivered to a child
+type DroppedMessage struct {
+	Message    Message
+	ChildIndex int
+	Reason     string
+}
+
+// OutOfRangePolicy controls how sends to a nonexistent child index are handled
+type OutOfRangePolicy int
+
+const (
+	// OutOfRangeError returns an error to the caller (the default)
+	OutOfRangeError OutOfRangePolicy = iota
+
+	// OutOfRangeDeadLetter routes the message to the dead-letter channel
+	// and reports success, so a bad route cannot break the caller
+	OutOfRangeDeadLetter
+)
+
+// SetOutOfRangePolicy sets how sends to a nonexistent child index are handled
+func (n *Node) SetOutOfRangePolicy(policy OutOfRangePolicy) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.outOfRangePolicy = policy
+}
+
+// DeadLetters returns the channel of messages that could not be delivered,
+// including best-effort broadcasts skipped because a child channel was full.
+// The channel is buffered; dead letters are discarded when it is full, so a
+// slow consumer never blocks a broadcast.
+func (n *Node) DeadLetters() <-chan DroppedMessage {
+	return n.deadLetters
+}
+
+// OutOfRangeCount returns how many messages targeted a nonexistent child
+func (n *Node) OutOfRangeCount() uint64 {
+	return n.outOfRange.Load()
+}
+
+// DeadLetter records a message that could not be delivered to a child by a
+// component delivering on the node's behalf, such as a network client
+func (n *Node) DeadLetter(msg Message, childIndex int, reason string) {
+	n.deadLetter(msg, childIndex, reason)
+}
+
+// deadLetter queues a dropped message without ever blocking the caller
+func (n *Node) deadLetter(msg Message, childIndex int, reason string) {
+	select {
+	case n.deadLetters <- DroppedMessage{Message: msg, ChildIndex: childIndex, Reason: reason}:
+	default:
+		n.log().Warn("Dead-letter channel full, discarding message", "id", msg.ID, "reason", reason)
+	}
+}
@@ -0,0 +1,98 @@
+package btree
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOutOfRangeRouting(t *testing.T) {
+	// A routing function that disagrees with the configured number of children
+	route := func(msg Message) int { return 7 }
+
+	tests := []struct {
+		name       string
+		policy     OutOfRangePolicy
+		wantErr    bool
+		deadLetter bool
+	}{
+		{"error policy returns error", OutOfRangeError, true, false},
+		{"dead-letter policy routes to dead letters", OutOfRangeDeadLetter, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := NewBinaryNode("router")
+			node.SetOutOfRangePolicy(tt.policy)
+
+			msg := NewMessage("misrouted", "route-1")
+			err := node.SendToChild(context.Background(), route(msg), msg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error: %v, got %v", tt.wantErr, err)
+			}
+
+			if node.OutOfRangeCount() != 1 {
+				t.Errorf("Expected out-of-range count 1, got %d", node.OutOfRangeCount())
+			}
+
+			select {
+			case dropped := <-node.DeadLetters():
+				if !tt.deadLetter {
+					t.Fatalf("Unexpected dead letter: %+v", dropped)
+				}
+				if dropped.Reason != ReasonNoSuchChild {
+					t.Errorf("Expected reason %q, got %q", ReasonNoSuchChild, dropped.Reason)
+				}
+				if dropped.ChildIndex != 7 || dropped.Message.ID != msg.ID {
+					t.Errorf("Unexpected dead letter: %+v", dropped)
+				}
+			default:
+				if tt.deadLetter {
+					t.Error("Expected message on the dead-letter channel")
+				}
+			}
+		})
+	}
+}
+
+func TestBroadcastDropGoesToDeadLetters(t *testing.T) {
+	parent := NewNode("parent", 2)
+	fillChannel(t, parent, 1)
+
+	if err := parent.BroadcastToChildren(context.Background(), NewMessage("partial", "dl-1")); err != nil {
+		t.Fatalf("Best-effort broadcast should not fail: %v", err)
+	}
+
+	select {
+	case dropped := <-parent.DeadLetters():
+		if dropped.Message.ID != "dl-1" || dropped.ChildIndex != 1 || dropped.Reason != ReasonChannelFull {
+			t.Errorf("Expected dl-1 dropped for child 1 (channel full), got %+v", dropped)
+		}
+	default:
+		t.Fatal("Expected the skipped delivery on the dead-letter channel")
+	}
+
+	// Child 0 still received it
+	left, _ := parent.GetChildChannel(0)
+	if msg := <-left; msg.ID != "dl-1" {
+		t.Errorf("Expected child 0 to receive dl-1, got %q", msg.ID)
+	}
+}
+
+func TestFullDeadLetterChannelDoesNotBlockBroadcast(t *testing.T) {
+	parent := NewNodeWithOptions("parent", 1, WithBufferSize(1))
+	fillChannel(t, parent, 0)
+
+	// Nobody reads the dead letters; once their buffer fills, drops are discarded
+	for i := 0; i < 5; i++ {
+		if err := parent.BroadcastToChildren(context.Background(), NewMessage("overflow", "")); err != nil {
+			t.Fatalf("Broadcast %d failed: %v", i, err)
+		}
+	}
+
+	if queued := len(parent.DeadLetters()); queued != 1 {
+		t.Errorf("Expected the dead-letter buffer of 1 to be full, got %d", queued)
+	}
+	if dropped := parent.Stats().Dropped; dropped != 5 {
+		t.Errorf("Expected 5 dropped deliveries, got %d", dropped)
+	}
+}
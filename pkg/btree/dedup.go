@@ -0,0 +1,69 @@
+package btree
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultDedupCacheSize is the number of recently seen message IDs a node
+// remembers by default
+const DefaultDedupCacheSize = 1024
+
+// idCache is a bounded set of message IDs that evicts the oldest entry
+type idCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	ids   map[string]*list.Element
+}
+
+// newIDCache creates a cache holding up to size IDs; size 0 disables it
+func newIDCache(size int) *idCache {
+	return &idCache{
+		size:  size,
+		order: list.New(),
+		ids:   make(map[string]*list.Element),
+	}
+}
+
+// seen records id and reports whether it was already present
+func (c *idCache) seen(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.size <= 0 {
+		return false
+	}
+
+	if elem, ok := c.ids[id]; ok {
+		c.order.MoveToFront(elem)
+		return true
+	}
+
+	c.ids[id] = c.order.PushFront(id)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.ids, oldest.Value.(string))
+	}
+	return false
+}
+
+// resize changes the capacity, evicting the oldest IDs if needed
+func (c *idCache) resize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.size = size
+	for c.order.Len() > 0 && c.order.Len() > size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.ids, oldest.Value.(string))
+	}
+}
+
+// SetDedupCacheSize sets how many recently seen message IDs the node
+// remembers to drop duplicates; 0 disables deduplication
+func (n *Node) SetDedupCacheSize(size int) {
+	n.dedup.resize(size)
+}
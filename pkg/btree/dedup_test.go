@@ -0,0 +1,62 @@
+package btree
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDuplicateMessageIsBroadcastOnce(t *testing.T) {
+	parent := NewBinaryNode("parent")
+	ctx := context.Background()
+
+	msg := NewMessage("only once", "dup-1")
+	for i := 0; i < 2; i++ {
+		if err := parent.HandleMessage(ctx, msg); err != nil {
+			t.Fatalf("Failed to handle message: %v", err)
+		}
+	}
+
+	for i := 0; i < parent.GetNumChildren(); i++ {
+		childChannel, _ := parent.GetChildChannel(i)
+		if len(childChannel) != 1 {
+			t.Errorf("Child %d should have received the message once, got %d", i, len(childChannel))
+		}
+	}
+
+	if stats := parent.Stats(); stats.Duplicates != 1 {
+		t.Errorf("Expected 1 duplicate, got %d", stats.Duplicates)
+	}
+}
+
+func TestDedupCacheEvictsOldest(t *testing.T) {
+	cache := newIDCache(2)
+
+	for _, id := range []string{"a", "b", "c"} {
+		if cache.seen(id) {
+			t.Errorf("%s should not have been seen yet", id)
+		}
+	}
+
+	// "a" was evicted to make room for "c"; "b" and "c" are remembered
+	if cache.seen("b") != true || cache.seen("c") != true {
+		t.Error("Most recent IDs should be remembered")
+	}
+	if cache.seen("a") {
+		t.Error("Oldest ID should have been evicted")
+	}
+}
+
+func TestDedupDisabled(t *testing.T) {
+	parent := NewNode("parent", 1)
+	parent.SetDedupCacheSize(0)
+
+	msg := NewMessage("twice", "dup-2")
+	for i := 0; i < 2; i++ {
+		parent.HandleMessage(context.Background(), msg)
+	}
+
+	childChannel, _ := parent.GetChildChannel(0)
+	if len(childChannel) != 2 {
+		t.Errorf("Expected 2 deliveries with deduplication disabled, got %d", len(childChannel))
+	}
+}
@@ -0,0 +1,118 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DeliveryMode controls what a broadcast does when a child channel is full
+type DeliveryMode int
+
+const (
+	// DeliveryBestEffort skips children whose channel is full (the default)
+	DeliveryBestEffort DeliveryMode = iota
+
+	// DeliveryBlocking waits until every child channel accepts the message
+	// or the context is cancelled
+	DeliveryBlocking
+
+	// DeliveryDeadline waits like DeliveryBlocking, but the whole broadcast
+	// must finish before the context's deadline and the node's broadcast
+	// timeout. Otherwise it stops with a *PartialDeliveryError wrapping
+	// context.DeadlineExceeded, and the children it did not reach get the
+	// message as a dead letter.
+	DeliveryDeadline
+)
+
+// ReasonDeadlineExceeded marks a message not delivered to a child before a
+// DeliveryDeadline broadcast ran out of time
+const ReasonDeadlineExceeded = "deadline-exceeded"
+
+// String returns the name of the delivery mode
+func (m DeliveryMode) String() string {
+	switch m {
+	case DeliveryBestEffort:
+		return "best-effort"
+	case DeliveryBlocking:
+		return "blocking"
+	case DeliveryDeadline:
+		return "deadline"
+	default:
+		return "unknown"
+	}
+}
+
+// SetDeliveryMode sets how broadcasts handle full child channels
+func (n *Node) SetDeliveryMode(mode DeliveryMode) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.deliveryMode = mode
+}
+
+// GetDeliveryMode returns the current delivery mode
+func (n *Node) GetDeliveryMode() DeliveryMode {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.deliveryMode
+}
+
+// SetBroadcastTimeout bounds each broadcast in DeliveryDeadline mode, which
+// matters when the caller's context has no deadline of its own, as in the
+// message loop. Zero (the default) relies on the context alone.
+func (n *Node) SetBroadcastTimeout(timeout time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.broadcastTimeout = timeout
+}
+
+// PartialDeliveryError reports a DeliveryDeadline broadcast that ran out of
+// time before every child accepted the message
+type PartialDeliveryError struct {
+	Delivered   int   // Children that accepted the message
+	Undelivered []int // Indices of the children that did not
+	Err         error // Why the broadcast stopped, e.g. context.DeadlineExceeded
+}
+
+// Error describes how far the broadcast got
+func (e *PartialDeliveryError) Error() string {
+	return fmt.Sprintf("broadcast reached %d of %d children: %v", e.Delivered, e.Delivered+len(e.Undelivered), e.Err)
+}
+
+// Unwrap returns the context error that stopped the broadcast
+func (e *PartialDeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// deliverWithDeadline sends msg to one child in DeliveryDeadline mode and
+// reports whether it was accepted. An expired context fails the send even if
+// the channel has room, so a deadline is honored however fast children are.
+func deliverWithDeadline(ctx context.Context, childOut chan Message, msg Message) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	select {
+	case childOut <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// partialDelivery dead-letters msg for every child from index from on that
+// the broadcast would have reached, and returns the error reporting them.
+// Callers must hold n.mu.
+func (n *Node) partialDelivery(ctx context.Context, msg Message, from, delivered int, predicate func(int, Message) bool) error {
+	var undelivered []int
+	for i := from; i < len(n.childrenOut); i++ {
+		if n.childrenOut[i] == nil || (predicate != nil && !predicate(i, msg)) {
+			continue
+		}
+		undelivered = append(undelivered, i)
+		n.counters.dropped.Add(1)
+		n.deadLetter(msg, i, ReasonDeadlineExceeded)
+	}
+
+	n.log().Warn("Broadcast deadline exceeded", "id", msg.ID, "reached", delivered, "missed", len(undelivered))
+	return &PartialDeliveryError{Delivered: delivered, Undelivered: undelivered, Err: ctx.Err()}
+}
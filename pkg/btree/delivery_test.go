@@ -0,0 +1,209 @@
+package btree
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fillChannel queues messages on a child channel until its buffer is full
+func fillChannel(t *testing.T, n *Node, index int) int {
+	t.Helper()
+
+	queued := 0
+	for {
+		err := n.SendToChild(context.Background(), index, Message{Content: "filler"})
+		if err != nil {
+			t.Fatalf("Failed to fill child channel: %v", err)
+		}
+		queued++
+
+		n.mu.RLock()
+		full := len(n.childrenOut[index]) == cap(n.childrenOut[index])
+		n.mu.RUnlock()
+		if full {
+			return queued
+		}
+	}
+}
+
+func TestBestEffortDropsOnFullChannel(t *testing.T) {
+	parent := NewNode("parent", 1)
+	queued := fillChannel(t, parent, 0)
+
+	err := parent.BroadcastToChildren(context.Background(), NewMessage("dropped", "drop-1"))
+	if err != nil {
+		t.Fatalf("Best-effort broadcast should not fail: %v", err)
+	}
+
+	childChannel, _ := parent.GetChildChannel(0)
+	if len(childChannel) != queued {
+		t.Errorf("Expected %d queued messages, got %d", queued, len(childChannel))
+	}
+	for i := 0; i < queued; i++ {
+		if msg := <-childChannel; msg.ID == "drop-1" {
+			t.Error("Best-effort broadcast should have dropped the message")
+		}
+	}
+}
+
+func TestBlockingDeliveryWithSlowConsumer(t *testing.T) {
+	parent := NewNode("parent", 1)
+	parent.SetDeliveryMode(DeliveryBlocking)
+	queued := fillChannel(t, parent, 0)
+
+	// Slow consumer drains one message every millisecond
+	childChannel, _ := parent.GetChildChannel(0)
+	received := make(chan Message, queued+10)
+	go func() {
+		for msg := range childChannel {
+			time.Sleep(time.Millisecond)
+			received <- msg
+		}
+	}()
+
+	const extra = 10
+	for i := 0; i < extra; i++ {
+		err := parent.BroadcastToChildren(context.Background(), Message{Content: "must arrive", ID: "blocking"})
+		if err != nil {
+			t.Fatalf("Blocking broadcast failed: %v", err)
+		}
+	}
+
+	delivered := 0
+	timeout := time.After(2 * time.Second)
+	for i := 0; i < queued+extra; i++ {
+		select {
+		case msg := <-received:
+			if msg.ID == "blocking" {
+				delivered++
+			}
+		case <-timeout:
+			t.Fatalf("Timeout after receiving %d messages", i)
+		}
+	}
+
+	if delivered != extra {
+		t.Errorf("Expected %d blocked messages delivered, got %d", extra, delivered)
+	}
+}
+
+func TestBlockingDeliveryRespectsContext(t *testing.T) {
+	parent := NewNode("parent", 1)
+	parent.SetDeliveryMode(DeliveryBlocking)
+	fillChannel(t, parent, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := parent.BroadcastToChildren(ctx, NewMessage("stuck", "stuck-1"))
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestTrySendToChildAccepts(t *testing.T) {
+	parent := NewNode("parent", 1)
+
+	ok, err := parent.TrySendToChild(0, NewMessage("accepted", "try-1"))
+	if err != nil || !ok {
+		t.Fatalf("Expected (true, nil), got (%v, %v)", ok, err)
+	}
+
+	childChannel, _ := parent.GetChildChannel(0)
+	if msg := <-childChannel; msg.ID != "try-1" {
+		t.Errorf("Expected try-1, got %q", msg.ID)
+	}
+}
+
+func TestTrySendToChildFullChannel(t *testing.T) {
+	parent := NewNode("parent", 1)
+	queued := fillChannel(t, parent, 0)
+
+	ok, err := parent.TrySendToChild(0, NewMessage("rejected", "try-2"))
+	if err != nil || ok {
+		t.Fatalf("Expected (false, nil) on a full channel, got (%v, %v)", ok, err)
+	}
+
+	childChannel, _ := parent.GetChildChannel(0)
+	if len(childChannel) != queued {
+		t.Errorf("Expected %d queued messages, got %d", queued, len(childChannel))
+	}
+
+	if _, err := parent.TrySendToChild(5, NewMessage("nowhere", "try-3")); err == nil {
+		t.Error("Expected an error for an out-of-range index")
+	}
+}
+
+func TestDeadlineDeliveryReportsPartialDelivery(t *testing.T) {
+	node := NewNodeWithOptions("deadline", 3, WithBufferSize(2))
+	node.SetDeliveryMode(DeliveryDeadline)
+
+	// The middle child is a slow consumer that never drains its channel
+	fillChannel(t, node, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := node.BroadcastToChildren(ctx, NewMessage("late", "d-1"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the broadcast to stop at the deadline, took %v", elapsed)
+	}
+
+	var partial *PartialDeliveryError
+	if !errors.As(err, &partial) {
+		t.Fatalf("Expected a *PartialDeliveryError, got %T", err)
+	}
+	if partial.Delivered != 1 || !reflect.DeepEqual(partial.Undelivered, []int{1, 2}) {
+		t.Errorf("Expected 1 delivery and children [1 2] missed, got %+v", partial)
+	}
+
+	for _, want := range []int{1, 2} {
+		select {
+		case dropped := <-node.DeadLetters():
+			if dropped.ChildIndex != want || dropped.Reason != ReasonDeadlineExceeded {
+				t.Errorf("Expected child %d dead-lettered for the deadline, got %+v", want, dropped)
+			}
+		default:
+			t.Fatalf("Expected a dead letter for child %d", want)
+		}
+	}
+}
+
+func TestDeadlineDeliveryHonorsExpiredContext(t *testing.T) {
+	node := NewNode("deadline", 1)
+	node.SetDeliveryMode(DeliveryDeadline)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	// The channel has room, but the deadline has already passed
+	if err := node.BroadcastToChildren(ctx, NewMessage("expired", "d-2")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	child, _ := node.GetChildChannel(0)
+	if len(child) != 0 {
+		t.Errorf("Expected nothing delivered after the deadline, got %d messages", len(child))
+	}
+}
+
+func TestBroadcastTimeoutBoundsHandleMessage(t *testing.T) {
+	node := NewNodeWithOptions("deadline", 1, WithBufferSize(1))
+	node.SetDeliveryMode(DeliveryDeadline)
+	node.SetBroadcastTimeout(30 * time.Millisecond)
+	fillChannel(t, node, 0)
+
+	// The message loop's context has no deadline; the broadcast timeout
+	// bounds the broadcast instead
+	err := node.HandleMessage(context.Background(), NewMessage("bounded", "d-3"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package btree
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ToDOT renders the tree in Graphviz DOT format, one statement per node and
+// one edge from each parent to each of its children labelled with the child
+// index, for example to check the topology with `dot -Tsvg`
+func (t *Tree) ToDOT() string {
+	var b strings.Builder
+	b.WriteString("digraph tree {\n")
+	for _, node := range t.nodes {
+		fmt.Fprintf(&b, "\t%q;\n", node.name)
+	}
+	for _, node := range t.nodes {
+		for index, child := range t.children[node.name] {
+			fmt.Fprintf(&b, "\t%q -> %q [label=\"%d\"];\n", node.name, child, index)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,55 @@
+package btree
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTreeToDOT(t *testing.T) {
+	tree, err := BuildTree(3, 2)
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	defer tree.Stop()
+
+	dot := tree.ToDOT()
+	if !strings.HasPrefix(dot, "digraph tree {\n") || !strings.HasSuffix(dot, "}\n") {
+		t.Fatalf("Expected a digraph, got:\n%s", dot)
+	}
+
+	for _, node := range tree.Nodes() {
+		if !strings.Contains(dot, "\t\""+node.Name()+"\";\n") {
+			t.Errorf("Expected node %s in:\n%s", node.Name(), dot)
+		}
+	}
+
+	edges := []string{
+		`"root" -> "root.0" [label="0"];`,
+		`"root" -> "root.1" [label="1"];`,
+		`"root.0" -> "root.0.0" [label="0"];`,
+		`"root.0" -> "root.0.1" [label="1"];`,
+		`"root.1" -> "root.1.0" [label="0"];`,
+		`"root.1" -> "root.1.1" [label="1"];`,
+	}
+	for _, edge := range edges {
+		if !strings.Contains(dot, edge) {
+			t.Errorf("Expected edge %s in:\n%s", edge, dot)
+		}
+	}
+	if count := strings.Count(dot, "->"); count != len(edges) {
+		t.Errorf("Expected %d edges, got %d", len(edges), count)
+	}
+}
+
+func TestSingleNodeTreeToDOT(t *testing.T) {
+	tree, err := BuildTree(1, 0)
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	defer tree.Stop()
+
+	want := "digraph tree {\n\t\"root\";\n}\n"
+	if dot := tree.ToDOT(); dot != want {
+		t.Errorf("Expected %q, got %q", want, dot)
+	}
+}
@@ -0,0 +1,25 @@
+package btree
+
+import "time"
+
+// ReasonExpired marks a message dropped because its expiry had passed; it is
+// recorded with ChildIndex -1 since no child was involved
+const ReasonExpired = "expired"
+
+// WithTTL returns a copy of the message that expires ttl from now, such as
+// NewMessage("restart", "cmd-1").WithTTL(30 * time.Second)
+func (m Message) WithTTL(ttl time.Duration) Message {
+	m.ExpiresAt = time.Now().Add(ttl)
+	return m
+}
+
+// WithExpiry returns a copy of the message that expires at t
+func (m Message) WithExpiry(t time.Time) Message {
+	m.ExpiresAt = t
+	return m
+}
+
+// Expired reports whether the message has an expiry that is not after now
+func (m Message) Expired(now time.Time) bool {
+	return !m.ExpiresAt.IsZero() && !now.Before(m.ExpiresAt)
+}
@@ -0,0 +1,107 @@
+package btree
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpiredMessageIsDropped(t *testing.T) {
+	tree, err := BuildTree(2, 1)
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	defer tree.Stop()
+
+	leaf := tree.Leaves()[0]
+	received := make(chan Message, 2)
+	leaf.OnReceive(func(msg Message) { received <- msg })
+
+	tree.Root().GetInboundChannel() <- NewMessage("stale command", "old").WithExpiry(time.Now().Add(-time.Second))
+	tree.Root().GetInboundChannel() <- NewMessage("fresh command", "new").WithTTL(time.Minute)
+
+	select {
+	case msg := <-received:
+		if msg.ID != "new" {
+			t.Errorf("Expected only the fresh message to reach the leaf, got %s", msg.ID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the fresh message")
+	}
+
+	select {
+	case dropped := <-tree.Root().DeadLetters():
+		if dropped.Message.ID != "old" || dropped.Reason != ReasonExpired || dropped.ChildIndex != -1 {
+			t.Errorf("Expected old to be dead-lettered as expired, got %+v", dropped)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the expired message to be dead-lettered")
+	}
+	if expired := tree.Root().Stats().Expired; expired != 1 {
+		t.Errorf("Expected 1 expired message, got %d", expired)
+	}
+}
+
+func TestMessageExpiresInTransit(t *testing.T) {
+	node := NewNode("slow", 1)
+	child, _ := node.GetChildChannel(0)
+
+	msg := NewMessage("short-lived", "ttl-1").WithTTL(20 * time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	if err := node.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+
+	select {
+	case got := <-child:
+		t.Errorf("Expected the message to expire before broadcast, got %s", got.ID)
+	default:
+	}
+}
+
+func TestMessageExpired(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tests := []struct {
+		name    string
+		expires time.Time
+		want    bool
+	}{
+		{"no expiry", time.Time{}, false},
+		{"future", now.Add(time.Second), false},
+		{"exactly now", now, true},
+		{"past", now.Add(-time.Second), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := NewMessage("x", "e").WithExpiry(tt.expires)
+			if got := msg.Expired(now); got != tt.want {
+				t.Errorf("Expected Expired=%v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExpiresAtJSON(t *testing.T) {
+	data, err := json.Marshal(NewMessage("x", "j-1"))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if strings.Contains(string(data), "expires_at") {
+		t.Errorf("Expected no expiry in %s", data)
+	}
+
+	original := NewMessage("x", "j-2").WithExpiry(time.Unix(1700000000, 0))
+	data, err = json.Marshal(original)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded Message
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !decoded.ExpiresAt.Equal(original.ExpiresAt) {
+		t.Errorf("Expected expiry %v, got %v", original.ExpiresAt, decoded.ExpiresAt)
+	}
+}
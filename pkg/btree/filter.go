@@ -0,0 +1,35 @@
+package btree
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ReasonFiltered marks a message the node's filter rejected; it is recorded
+// with ChildIndex -1 since no child was involved
+const ReasonFiltered = "filtered"
+
+// SetFilter makes the node forward only messages for which fn returns true;
+// the rest are dead-lettered instead of broadcast. Messages consumed by a type
+// handler, and those relayed for another Target, are not filtered. A nil fn
+// removes the filter. The filter may be replaced while the node is running.
+func (n *Node) SetFilter(fn func(Message) bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.filter = fn
+}
+
+// PrefixFilter returns a filter accepting messages whose content starts with
+// prefix
+func PrefixFilter(prefix string) func(Message) bool {
+	return func(msg Message) bool {
+		return strings.HasPrefix(msg.Content, prefix)
+	}
+}
+
+// RegexFilter returns a filter accepting messages whose content matches re
+func RegexFilter(re *regexp.Regexp) func(Message) bool {
+	return func(msg Message) bool {
+		return re.MatchString(msg.Content)
+	}
+}
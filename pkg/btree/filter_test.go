@@ -0,0 +1,83 @@
+package btree
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestPrefixFilter(t *testing.T) {
+	node := NewNode("filter", 1)
+	node.SetFilter(PrefixFilter("alert:"))
+	child, _ := node.GetChildChannel(0)
+
+	for _, msg := range []Message{
+		NewMessage("alert: disk full", "f-1"),
+		NewMessage("info: all good", "f-2"),
+		NewMessage("alert: cpu hot", "f-3"),
+	} {
+		if err := node.HandleMessage(context.Background(), msg); err != nil {
+			t.Fatalf("HandleMessage failed: %v", err)
+		}
+	}
+
+	for _, want := range []string{"f-1", "f-3"} {
+		select {
+		case msg := <-child:
+			if msg.ID != want {
+				t.Errorf("Expected %s, got %s", want, msg.ID)
+			}
+		default:
+			t.Fatalf("Expected %s to be broadcast", want)
+		}
+	}
+	select {
+	case msg := <-child:
+		t.Errorf("Expected nothing else to be broadcast, got %s", msg.ID)
+	default:
+	}
+
+	select {
+	case dropped := <-node.DeadLetters():
+		if dropped.Message.ID != "f-2" || dropped.Reason != ReasonFiltered || dropped.ChildIndex != -1 {
+			t.Errorf("Expected f-2 to be dead-lettered as filtered, got %+v", dropped)
+		}
+	default:
+		t.Error("Expected the rejected message to be dead-lettered")
+	}
+	if filtered := node.Stats().Filtered; filtered != 1 {
+		t.Errorf("Expected 1 filtered message, got %d", filtered)
+	}
+}
+
+func TestFilterChangedAtRuntime(t *testing.T) {
+	node := NewNode("filter", 1)
+	node.Start()
+	defer node.Stop()
+	child, _ := node.GetChildChannel(0)
+
+	node.SetFilter(RegexFilter(regexp.MustCompile(`^\d+$`)))
+	node.GetInboundChannel() <- NewMessage("abc", "r-1")
+	node.GetInboundChannel() <- NewMessage("123", "r-2")
+	select {
+	case msg := <-child:
+		if msg.ID != "r-2" {
+			t.Errorf("Expected r-2, got %s", msg.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for the matching message")
+	}
+
+	// Removing the filter lets everything through again
+	node.SetFilter(nil)
+	node.GetInboundChannel() <- NewMessage("abc", "r-3")
+	select {
+	case msg := <-child:
+		if msg.ID != "r-3" {
+			t.Errorf("Expected r-3, got %s", msg.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for the unfiltered message")
+	}
+}
@@ -0,0 +1,90 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// NewResponse creates the upstream reply to a query sent with Gather
+func NewResponse(query Message, content string) Message {
+	return Message{
+		Content:       content,
+		Timestamp:     time.Now(),
+		Upstream:      true,
+		CorrelationID: query.ID,
+	}
+}
+
+// Gather broadcasts a query to all children and collects one response per
+// child that received it. Children answer by sending NewResponse(query, ...)
+// upstream. If the timeout elapses or ctx ends first, the responses gathered
+// so far are returned together with an error. A query without an ID is
+// given one.
+func (n *Node) Gather(ctx context.Context, msg Message, timeout time.Duration) ([]Message, error) {
+	msg = n.assignID(msg)
+	msg.Source = n.name
+
+	// Register before broadcasting so fast responses are not lost
+	responses := make(chan Message, n.GetNumChildren())
+	n.gatherMu.Lock()
+	if n.gathers == nil {
+		n.gathers = make(map[string]chan Message)
+	}
+	if _, exists := n.gathers[msg.ID]; exists {
+		n.gatherMu.Unlock()
+		return nil, fmt.Errorf("gather for message %s already in progress", msg.ID)
+	}
+	n.gathers[msg.ID] = responses
+	n.gatherMu.Unlock()
+
+	defer func() {
+		n.gatherMu.Lock()
+		delete(n.gathers, msg.ID)
+		n.gatherMu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	expected, err := n.broadcast(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	collected := make([]Message, 0, expected)
+	for len(collected) < expected {
+		select {
+		case response := <-responses:
+			collected = append(collected, response)
+		case <-ctx.Done():
+			n.log().Warn("Gather stopped before all responses arrived", "id", msg.ID, "responses", len(collected), "expected", expected)
+			return collected, fmt.Errorf("gathered %d of %d responses: %w", len(collected), expected, ctx.Err())
+		}
+	}
+
+	return collected, nil
+}
+
+// deliverGatherResponse hands an upstream message to the Gather waiting for
+// it and reports whether there was one
+func (n *Node) deliverGatherResponse(msg Message) bool {
+	if msg.CorrelationID == "" {
+		return false
+	}
+
+	n.gatherMu.Lock()
+	defer n.gatherMu.Unlock()
+
+	responses, ok := n.gathers[msg.CorrelationID]
+	if !ok {
+		return false
+	}
+
+	select {
+	case responses <- msg:
+	default:
+		n.log().Warn("Dropping extra response", "correlation", msg.CorrelationID)
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package btree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+// respondFrom answers every query reaching the child at index by handing a
+// response to the parent, as the wiring between nodes would
+func respondFrom(t *testing.T, parent *Node, index int) {
+	t.Helper()
+
+	childChannel, err := parent.GetChildChannel(index)
+	if err != nil {
+		t.Fatalf("Failed to get child channel: %v", err)
+	}
+
+	go func() {
+		for query := range childChannel {
+			response := NewResponse(query, fmt.Sprintf("child-%d", index))
+			parent.HandleMessage(context.Background(), response)
+		}
+	}()
+}
+
+func TestGatherCollectsOneResponsePerChild(t *testing.T) {
+	parent := NewNode("parent", 3)
+	defer parent.Stop()
+	for i := 0; i < 3; i++ {
+		respondFrom(t, parent, i)
+	}
+
+	responses, err := parent.Gather(context.Background(), NewMessage("status?", "query-1"), time.Second)
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+
+	var contents []string
+	for _, response := range responses {
+		if response.CorrelationID != "query-1" {
+			t.Errorf("Expected response to query-1, got %s", response.CorrelationID)
+		}
+		contents = append(contents, response.Content)
+	}
+	sort.Strings(contents)
+
+	expected := []string{"child-0", "child-1", "child-2"}
+	if fmt.Sprint(contents) != fmt.Sprint(expected) {
+		t.Errorf("Expected responses %v, got %v", expected, contents)
+	}
+}
+
+func TestGatherReturnsPartialResultsOnTimeout(t *testing.T) {
+	parent := NewNode("parent", 3)
+	defer parent.Stop()
+
+	// Child 2 never answers
+	respondFrom(t, parent, 0)
+	respondFrom(t, parent, 1)
+
+	start := time.Now()
+	responses, err := parent.Gather(context.Background(), NewMessage("status?", "query-2"), 100*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected a deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Gather should give up after its timeout, took %v", elapsed)
+	}
+	if len(responses) != 2 {
+		t.Errorf("Expected 2 partial responses, got %d", len(responses))
+	}
+}
+
+func TestUncorrelatedUpstreamMessagesStillRelay(t *testing.T) {
+	node := NewNode("relay", 0)
+
+	msg := NewResponse(NewMessage("old", "query-unknown"), "late")
+	if err := node.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("Failed to handle upstream message: %v", err)
+	}
+
+	select {
+	case relayed := <-node.GetUpstreamChannel():
+		if relayed.CorrelationID != "query-unknown" {
+			t.Errorf("Expected the late response to be relayed, got %+v", relayed)
+		}
+	default:
+		t.Error("Expected a response with no waiting Gather to be relayed upstream")
+	}
+}
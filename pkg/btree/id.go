@@ -0,0 +1,104 @@
+package btree
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// IDGenerator produces IDs for messages that arrive without one
+type IDGenerator interface {
+	Next() string
+}
+
+// IDGeneratorFunc adapts a function to the IDGenerator interface
+type IDGeneratorFunc func() string
+
+// Next calls f
+func (f IDGeneratorFunc) Next() string {
+	return f()
+}
+
+// SequentialIDGenerator produces IDs of the form prefix-1, prefix-2, ...
+type SequentialIDGenerator struct {
+	prefix string
+	next   atomic.Uint64
+}
+
+// NewSequentialIDGenerator creates a generator numbering IDs after prefix
+func NewSequentialIDGenerator(prefix string) *SequentialIDGenerator {
+	return &SequentialIDGenerator{prefix: prefix}
+}
+
+// Next returns the next ID in the sequence
+func (g *SequentialIDGenerator) Next() string {
+	return fmt.Sprintf("%s-%d", g.prefix, g.next.Add(1))
+}
+
+// UUIDGenerator produces random (version 4) UUIDs such as
+// "f47ac10b-58cc-4372-a567-0e02b2c3d479"
+type UUIDGenerator struct{}
+
+// NewUUIDGenerator creates a generator of random UUIDs
+func NewUUIDGenerator() UUIDGenerator {
+	return UUIDGenerator{}
+}
+
+// Next returns a new random UUID
+func (UUIDGenerator) Next() string {
+	var uuid [16]byte
+	rand.Read(uuid[:])
+	uuid[6] = uuid[6]&0x0f | 0x40 // version 4
+	uuid[8] = uuid[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
+
+var (
+	defaultIDMu        sync.RWMutex
+	defaultIDGenerator IDGenerator = NewUUIDGenerator()
+)
+
+// DefaultIDGenerator returns the generator used by ingestion points that
+// were not given one
+func DefaultIDGenerator() IDGenerator {
+	defaultIDMu.RLock()
+	defer defaultIDMu.RUnlock()
+	return defaultIDGenerator
+}
+
+// SetDefaultIDGenerator replaces the process-wide default generator
+func SetDefaultIDGenerator(gen IDGenerator) {
+	defaultIDMu.Lock()
+	defer defaultIDMu.Unlock()
+	defaultIDGenerator = gen
+}
+
+// WithIDGenerator sets the generator the node uses for the messages it
+// assigns IDs to, such as SendReliable and Gather messages without one; nil
+// uses the default generator. Tests can inject a SequentialIDGenerator to get
+// predictable IDs.
+func WithIDGenerator(gen IDGenerator) NodeOption {
+	return func(o *nodeOptions) {
+		o.idGenerator = gen
+	}
+}
+
+// assignID gives msg an ID from the node's generator if it has none
+func (n *Node) assignID(msg Message) Message {
+	return AssignID(msg, n.idGenerator)
+}
+
+// AssignID gives msg an ID from gen if it has none. Every ingestion point
+// routes messages through here so IDs are assigned uniformly; a nil gen uses
+// the default generator.
+func AssignID(msg Message, gen IDGenerator) Message {
+	if msg.ID != "" {
+		return msg
+	}
+	if gen == nil {
+		gen = DefaultIDGenerator()
+	}
+	msg.ID = gen.Next()
+	return msg
+}
@@ -0,0 +1,78 @@
+package btree
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestAssignIDKeepsExistingID(t *testing.T) {
+	msg := AssignID(Message{ID: "given"}, NewSequentialIDGenerator("gen"))
+	if msg.ID != "given" {
+		t.Errorf("Expected existing ID to be kept, got %s", msg.ID)
+	}
+}
+
+func TestAssignIDUsesGenerator(t *testing.T) {
+	gen := NewSequentialIDGenerator("gen")
+	first := AssignID(Message{Content: "a"}, gen)
+	second := AssignID(Message{Content: "b"}, gen)
+
+	if first.ID != "gen-1" || second.ID != "gen-2" {
+		t.Errorf("Expected gen-1 and gen-2, got %s and %s", first.ID, second.ID)
+	}
+}
+
+func TestAssignIDFallsBackToDefault(t *testing.T) {
+	previous := DefaultIDGenerator()
+	defer SetDefaultIDGenerator(previous)
+
+	SetDefaultIDGenerator(IDGeneratorFunc(func() string { return "from-default" }))
+	if msg := AssignID(Message{}, nil); msg.ID != "from-default" {
+		t.Errorf("Expected default generator ID, got %s", msg.ID)
+	}
+}
+
+func TestUUIDGenerator(t *testing.T) {
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	gen := NewUUIDGenerator()
+
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id := gen.Next()
+		if !uuidPattern.MatchString(id) {
+			t.Fatalf("Expected a version 4 UUID, got %q", id)
+		}
+		if seen[id] {
+			t.Fatalf("Generated %s twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestDefaultIDGeneratorIsUUID(t *testing.T) {
+	if _, ok := DefaultIDGenerator().(UUIDGenerator); !ok {
+		t.Errorf("Expected UUIDs by default, got %T", DefaultIDGenerator())
+	}
+}
+
+func TestNodeUsesInjectedIDGenerator(t *testing.T) {
+	node := NewNodeWithOptions("ids", 1, WithIDGenerator(NewSequentialIDGenerator("test")))
+	child, _ := node.GetChildChannel(0)
+
+	for _, want := range []string{"test-1", "test-2"} {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		node.Gather(ctx, Message{Content: "query"}, time.Second)
+		cancel()
+
+		select {
+		case msg := <-child:
+			if msg.ID != want {
+				t.Errorf("Expected query ID %s, got %s", want, msg.ID)
+			}
+		default:
+			t.Fatal("Expected the query to be broadcast")
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package btree
+
+import (
+	"sync"
+	"time"
+)
+
+// HopTimestampPrefix prefixes the metadata keys that record when each node
+// handled a message; the rest of the key is the node's name
+const HopTimestampPrefix = "hop."
+
+// WithHopTimestamps makes the node stamp the time it handles each message
+// into the message's metadata under HopTimestampPrefix plus its name, so
+// latency can be read per level of the tree
+func WithHopTimestamps() NodeOption {
+	return func(o *nodeOptions) {
+		o.hopTimestamps = true
+	}
+}
+
+// HopTimestamp returns the time the named node handled msg, if it stamped it
+func HopTimestamp(msg Message, node string) (time.Time, bool) {
+	value := msg.GetMetadata(HopTimestampPrefix + node)
+	if value == "" {
+		return time.Time{}, false
+	}
+	stamp, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return stamp, true
+}
+
+// latencyTracker accumulates the age of handled messages
+type latencyTracker struct {
+	mu   sync.Mutex
+	hist histogram
+}
+
+// observe records the age of a message created at timestamp
+func (l *latencyTracker) observe(timestamp, now time.Time) {
+	age := now.Sub(timestamp)
+	if age < 0 {
+		// The sender's clock is ahead of ours
+		age = 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hist.observe(age)
+}
+
+// snapshot copies the latency histogram
+func (l *latencyTracker) snapshot() HistogramSnapshot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.hist.snapshot()
+}
+
+// trackLatency records the age of msg and, when enabled, stamps the hop
+// time into its metadata
+func (n *Node) trackLatency(msg Message) Message {
+	now := time.Now()
+	if !msg.Timestamp.IsZero() {
+		n.latency.observe(msg.Timestamp, now)
+	}
+	if n.hopTimestamps {
+		msg = msg.WithMetadata(HopTimestampPrefix+n.name, now.Format(time.RFC3339Nano))
+	}
+	return msg
+}
@@ -0,0 +1,69 @@
+package btree
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLatencyOfPastTimestamp(t *testing.T) {
+	node := NewNode("leaf", 0)
+
+	const age = 250 * time.Millisecond
+	msg := Message{Content: "old", ID: "lat-1", Timestamp: time.Now().Add(-age)}
+	if err := node.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+
+	// Messages without a timestamp are not measured
+	if err := node.HandleMessage(context.Background(), Message{Content: "untimed", ID: "lat-2"}); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+
+	latency := node.Stats().Latency
+	if latency.Count != 1 {
+		t.Fatalf("Expected 1 latency observation, got %d", latency.Count)
+	}
+	if mean := latency.Mean(); mean < age || mean > age+100*time.Millisecond {
+		t.Errorf("Expected latency within 100ms of %v, got %v", age, mean)
+	}
+}
+
+func TestHopTimestamps(t *testing.T) {
+	root := NewNodeWithOptions("root", 1, WithHopTimestamps())
+	leaf := NewNodeWithOptions("leaf", 1, WithHopTimestamps())
+	ctx := context.Background()
+
+	before := time.Now()
+	if err := root.HandleMessage(ctx, NewMessage("hop", "hop-1")); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+	rootOut, _ := root.GetChildChannel(0)
+	if err := leaf.HandleMessage(ctx, <-rootOut); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+	leafOut, _ := leaf.GetChildChannel(0)
+	msg := <-leafOut
+
+	rootStamp, ok := HopTimestamp(msg, "root")
+	if !ok {
+		t.Fatalf("Expected a root hop timestamp, got metadata %v", msg.Metadata)
+	}
+	leafStamp, ok := HopTimestamp(msg, "leaf")
+	if !ok {
+		t.Fatalf("Expected a leaf hop timestamp, got metadata %v", msg.Metadata)
+	}
+	if rootStamp.Before(before) || leafStamp.Before(rootStamp) {
+		t.Errorf("Expected %v <= root %v <= leaf %v", before, rootStamp, leafStamp)
+	}
+
+	// Without the option nothing is stamped
+	plain := NewNode("plain", 1)
+	if err := plain.HandleMessage(ctx, NewMessage("hop", "hop-2")); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+	plainOut, _ := plain.GetChildChannel(0)
+	if _, ok := HopTimestamp(<-plainOut, "plain"); ok {
+		t.Error("Expected no hop timestamp without WithHopTimestamps")
+	}
+}
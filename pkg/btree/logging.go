@@ -0,0 +1,26 @@
+package btree
+
+import "log/slog"
+
+// SetLogger sets the structured logger the node writes to; records carry a
+// "node" attribute with the node name. A nil logger restores the default,
+// slog.Default(). Per-message records are logged at debug level, lifecycle
+// events at info and dropped messages at warn.
+func (n *Node) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	n.logger.Store(logger.With("node", n.name))
+}
+
+// log returns the node's logger
+func (n *Node) log() *slog.Logger {
+	return n.logger.Load()
+}
+
+// WithLogger sets the logger of a node created with NewNodeWithOptions
+func WithLogger(logger *slog.Logger) NodeOption {
+	return func(o *nodeOptions) {
+		o.logger = logger
+	}
+}
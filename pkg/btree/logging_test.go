@@ -0,0 +1,94 @@
+package btree
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+// capturedRecord is a log record kept by captureHandler
+type capturedRecord struct {
+	level   slog.Level
+	message string
+	attrs   map[string]any
+}
+
+// captureHandler is a slog.Handler that keeps every record in memory
+type captureHandler struct {
+	mu      *sync.Mutex
+	records *[]capturedRecord
+	attrs   []slog.Attr
+}
+
+func newCaptureHandler() *captureHandler {
+	return &captureHandler{mu: &sync.Mutex{}, records: &[]capturedRecord{}}
+}
+
+func (h *captureHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *captureHandler) Handle(_ context.Context, r slog.Record) error {
+	attrs := make(map[string]any)
+	for _, a := range h.attrs {
+		attrs[a.Key] = a.Value.Any()
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value.Any()
+		return true
+	})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.records = append(*h.records, capturedRecord{level: r.Level, message: r.Message, attrs: attrs})
+	return nil
+}
+
+func (h *captureHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &captureHandler{mu: h.mu, records: h.records, attrs: append(append([]slog.Attr{}, h.attrs...), attrs...)}
+}
+
+func (h *captureHandler) WithGroup(string) slog.Handler { return h }
+
+// find returns the first record with the given message
+func (h *captureHandler) find(message string) (capturedRecord, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, r := range *h.records {
+		if r.message == message {
+			return r, true
+		}
+	}
+	return capturedRecord{}, false
+}
+
+func TestNodeLogsAtExpectedLevels(t *testing.T) {
+	handler := newCaptureHandler()
+	node := NewNodeWithOptions("logged", 1, WithBufferSize(1), WithLogger(slog.New(handler)))
+
+	ctx := context.Background()
+	node.HandleMessage(ctx, NewMessage("fits", "log-1"))
+	node.HandleMessage(ctx, NewMessage("overflows", "log-2"))
+
+	tests := []struct {
+		message string
+		level   slog.Level
+	}{
+		{"Received message", slog.LevelDebug},
+		{"Broadcast to child successful", slog.LevelDebug},
+		{"Child channel full, skipping broadcast", slog.LevelWarn},
+	}
+
+	for _, tt := range tests {
+		record, ok := handler.find(tt.message)
+		if !ok {
+			t.Errorf("Expected a %q record", tt.message)
+			continue
+		}
+		if record.level != tt.level {
+			t.Errorf("Expected %q at %v, got %v", tt.message, tt.level, record.level)
+		}
+		if record.attrs["node"] != "logged" {
+			t.Errorf("Expected %q to carry the node name, got %v", tt.message, record.attrs["node"])
+		}
+	}
+}
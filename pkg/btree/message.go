@@ -5,14 +5,44 @@ import (
 	"time"
 )
 
-// Message represents a message that flows through the tree
+// Message represents a message that flows through the tree.
+//
+// A message with a Target is consumed only by the node of that name. Other
+// nodes relay it untouched: they run the OnReceive hook, path tracking and
+// reach tracking, but neither the filter nor middleware, so a relay cannot
+// drop or change another node's message, and they skip the local consumers,
+// i.e. type handlers, subscribers and the sink. The target runs every stage
+// but does not broadcast the message, so it takes no sequence number and is
+// not retained for replay.
 type Message struct {
-	Content   string
-	ID        string    // Optional message ID for tracking
-	Timestamp time.Time // When the message was created
-	Source    string    // Optional source node identifier
+	Content   string    `json:"content"`
+	Payload   []byte    `json:"payload,omitempty"`   // Raw binary content, carried byte-exact by every transport
+	ID        string    `json:"id,omitempty"`        // Optional message ID for tracking
+	Timestamp time.Time `json:"timestamp"`           // When the message was created
+	Source    string    `json:"source,omitempty"`    // Optional source node identifier; the last hop
+	Path      []string  `json:"path,omitempty"`      // Names of the nodes that forwarded the message, root first
+	Target    string    `json:"target,omitempty"`    // Name of the only node that consumes the message; empty means every node
+	Type      string    `json:"type,omitempty"`      // Message kind used for routing; empty means TypeData
+	Seq       uint64    `json:"seq,omitempty"`       // Per-source sequence number assigned by the sending node; 0 means unsequenced
+	ExpiresAt time.Time `json:"expires_at,omitzero"` // When nodes stop forwarding the message; zero means never
+
+	Metadata map[string]string `json:"metadata,omitempty"` // Arbitrary headers such as trace IDs or tenant
+
+	Upstream      bool   `json:"upstream,omitempty"`       // Message travels toward the root
+	TrackReach    bool   `json:"track_reach,omitempty"`    // Ask every node to report how many nodes the broadcast reached
+	CorrelationID string `json:"correlation_id,omitempty"` // ID of the message a report refers to
+	ReachCount    int    `json:"reach_count,omitempty"`    // Number of nodes reached; non-zero marks a reach report
+	AckRequested  bool   `json:"ack_requested,omitempty"`  // Ask the receiving node to acknowledge delivery
 }
 
+// Built-in message types
+const (
+	TypeData    = "data"    // Broadcast to children (the default)
+	TypeControl = "control" // Instructions for a node rather than payload
+	TypeAck     = "ack"     // Acknowledgement of a previous message
+	TypeNack    = "nack"    // Report that a node failed to process a previous message
+)
+
 // NewMessage creates a new message with timestamp
 func NewMessage(content, id string) Message {
 	return Message{
@@ -22,6 +52,41 @@ func NewMessage(content, id string) Message {
 	}
 }
 
+// GetType returns the message type, defaulting to TypeData
+func (m Message) GetType() string {
+	if m.Type == "" {
+		return TypeData
+	}
+	return m.Type
+}
+
+// NewBinaryMessage creates a new message carrying a binary payload
+func NewBinaryMessage(payload []byte, id string) Message {
+	return Message{
+		Payload:   payload,
+		ID:        id,
+		Timestamp: time.Now(),
+	}
+}
+
+// WithMetadata returns a copy of the message with key set to value. The
+// metadata map is copied, never modified in place, because broadcast copies
+// of a message share it.
+func (m Message) WithMetadata(key, value string) Message {
+	metadata := make(map[string]string, len(m.Metadata)+1)
+	for k, v := range m.Metadata {
+		metadata[k] = v
+	}
+	metadata[key] = value
+	m.Metadata = metadata
+	return m
+}
+
+// GetMetadata returns the metadata value for key, or "" if it is not set
+func (m Message) GetMetadata(key string) string {
+	return m.Metadata[key]
+}
+
 // MessageHandler defines the interface for handling messages in a tree node
 type MessageHandler interface {
 	HandleMessage(ctx context.Context, msg Message) error
@@ -35,6 +100,9 @@ type MessageSender interface {
 	// Convenience methods for binary trees
 	SendToLeft(ctx context.Context, msg Message) error
 	SendToRight(ctx context.Context, msg Message) error
+
+	// Send toward the root via the upstream channel
+	SendToParent(ctx context.Context, msg Message) error
 }
 
 // MessageBroadcaster defines the interface for broadcasting messages
@@ -42,7 +110,8 @@ type MessageBroadcaster interface {
 	// BroadcastToChildren sends a message to all children
 	BroadcastToChildren(ctx context.Context, msg Message) error
 
-	// GetNumChildren returns the number of children
+	// GetNumChildren returns the number of child slots, the range of valid
+	// child indices
 	GetNumChildren() int
 }
 
@@ -0,0 +1,48 @@
+package btree
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithMetadataCopiesMap(t *testing.T) {
+	var empty Message
+	if got := empty.GetMetadata("missing"); got != "" {
+		t.Errorf("Expected empty value from nil metadata, got %q", got)
+	}
+
+	original := Message{Content: "m"}.WithMetadata("trace-id", "abc")
+	updated := original.WithMetadata("trace-id", "def")
+
+	if got := original.GetMetadata("trace-id"); got != "abc" {
+		t.Errorf("Expected original to keep abc, got %q", got)
+	}
+	if got := updated.GetMetadata("trace-id"); got != "def" {
+		t.Errorf("Expected updated to have def, got %q", got)
+	}
+}
+
+func TestHandleMessagePreservesMetadata(t *testing.T) {
+	node := NewBinaryNode("parent")
+
+	msg := Message{Content: "hello", ID: "meta-1"}.WithMetadata("tenant", "acme")
+	if err := node.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+
+	for i := 0; i < node.GetNumChildren(); i++ {
+		ch, err := node.GetChildChannel(i)
+		if err != nil {
+			t.Fatalf("GetChildChannel(%d): %v", i, err)
+		}
+		select {
+		case got := <-ch:
+			if got.GetMetadata("tenant") != "acme" {
+				t.Errorf("Child %d: expected tenant acme, got %q", i, got.GetMetadata("tenant"))
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Child %d: timeout waiting for message", i)
+		}
+	}
+}
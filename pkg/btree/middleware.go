@@ -0,0 +1,42 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+)
+
+// Middleware inspects a message before it is broadcast. It returns the
+// message to forward, possibly modified, or an error to stop propagation.
+type Middleware func(ctx context.Context, msg Message) (Message, error)
+
+// OnReceive sets a hook that runs on every message the node receives for
+// broadcast, after duplicates are dropped and before middleware or any
+// routing decision, including messages it only relays because their Target
+// names another node. Unlike middleware it cannot change or stop the message.
+func (n *Node) OnReceive(handler func(Message)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.onReceive = handler
+}
+
+// Use appends middleware to the chain run on every message before it is
+// broadcast to children, except those relayed for another Target.
+// Middleware runs in the order it was registered.
+func (n *Node) Use(middleware ...Middleware) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.middleware = append(n.middleware, middleware...)
+}
+
+// runMiddleware passes msg through each middleware in turn, stopping at the
+// first error
+func runMiddleware(ctx context.Context, chain []Middleware, msg Message) (Message, error) {
+	for i, middleware := range chain {
+		next, err := middleware(ctx, msg)
+		if err != nil {
+			return msg, fmt.Errorf("middleware %d rejected message: %w", i, err)
+		}
+		msg = next
+	}
+	return msg, nil
+}
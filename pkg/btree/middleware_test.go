@@ -0,0 +1,103 @@
+package btree
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareTransformsMessage(t *testing.T) {
+	parent := NewNode("parent", 1)
+	parent.Use(
+		func(ctx context.Context, msg Message) (Message, error) {
+			msg.Content = strings.ToUpper(msg.Content)
+			return msg, nil
+		},
+		func(ctx context.Context, msg Message) (Message, error) {
+			msg.Content += "!"
+			return msg, nil
+		},
+	)
+
+	if err := parent.HandleMessage(context.Background(), NewMessage("hello", "mw-1")); err != nil {
+		t.Fatalf("Failed to handle message: %v", err)
+	}
+
+	childChannel, _ := parent.GetChildChannel(0)
+	if msg := <-childChannel; msg.Content != "HELLO!" {
+		t.Errorf("Expected middleware to run in order, got %q", msg.Content)
+	}
+}
+
+func TestMiddlewareAbortsPropagation(t *testing.T) {
+	parent := NewNode("parent", 1)
+	errRejected := errors.New("rejected")
+	parent.Use(func(ctx context.Context, msg Message) (Message, error) {
+		if msg.Content == "blocked" {
+			return msg, errRejected
+		}
+		return msg, nil
+	})
+
+	ctx := context.Background()
+	if err := parent.HandleMessage(ctx, NewMessage("blocked", "mw-2")); !errors.Is(err, errRejected) {
+		t.Fatalf("Expected rejection error, got %v", err)
+	}
+	if err := parent.HandleMessage(ctx, NewMessage("allowed", "mw-3")); err != nil {
+		t.Fatalf("Failed to handle message: %v", err)
+	}
+
+	childChannel, _ := parent.GetChildChannel(0)
+	if len(childChannel) != 1 {
+		t.Fatalf("Expected only the allowed message to be forwarded, got %d", len(childChannel))
+	}
+	if msg := <-childChannel; msg.ID != "mw-3" {
+		t.Errorf("Expected mw-3, got %s", msg.ID)
+	}
+}
+
+func TestOnReceiveFiresOncePerUniqueMessageAtEachNode(t *testing.T) {
+	root := NewBinaryNode("root")
+	children := []*Node{NewNode("left", 0), NewNode("right", 0)}
+
+	counts := make(map[string]int)
+	for _, node := range append([]*Node{root}, children...) {
+		name := node.name
+		node.OnReceive(func(msg Message) { counts[name]++ })
+	}
+
+	// Middleware that rejects everything must not stop the hook
+	root.Use(func(ctx context.Context, msg Message) (Message, error) {
+		if msg.ID == "rejected" {
+			return msg, errors.New("rejected")
+		}
+		return msg, nil
+	})
+
+	ctx := context.Background()
+	for _, msg := range []Message{
+		NewMessage("first", "m-1"),
+		NewMessage("first again", "m-1"),
+		NewMessage("second", "m-2"),
+		NewMessage("rejected", "rejected"),
+	} {
+		root.HandleMessage(ctx, msg)
+	}
+
+	for i, child := range children {
+		childChannel, _ := root.GetChildChannel(i)
+		for len(childChannel) > 0 {
+			child.HandleMessage(ctx, <-childChannel)
+		}
+	}
+
+	if counts["root"] != 3 {
+		t.Errorf("Expected root hook to fire 3 times, got %d", counts["root"])
+	}
+	for _, child := range children {
+		if counts[child.name] != 2 {
+			t.Errorf("Expected %s hook to fire 2 times, got %d", child.name, counts[child.name])
+		}
+	}
+}
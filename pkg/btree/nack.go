@@ -0,0 +1,58 @@
+package btree
+
+import "time"
+
+// MessageError reports that a node in the subtree failed to process a
+// message, as carried upstream by a TypeNack message
+type MessageError struct {
+	MessageID string    // ID of the message that failed
+	Node      string    // Name of the node where it failed
+	Error     string    // Why it failed
+	Timestamp time.Time // When the failure was reported
+}
+
+// Errors returns the channel of failures reported by nodes below this one.
+// A node that fails to handle a message from its inbound channel, e.g.
+// because middleware or a type handler returned an error, sends a NACK
+// upstream; every ancestor surfaces it here and relays it further, so the
+// root observes failures anywhere in the tree. The channel is buffered;
+// errors are discarded when it is full, so a slow consumer never blocks the
+// node.
+func (n *Node) Errors() <-chan MessageError {
+	return n.messageErrors
+}
+
+// sendNack reports upstream that msg failed with err
+func (n *Node) sendNack(msg Message, err error) {
+	if msg.ID == "" {
+		n.log().Debug("Cannot report a failure for a message without an ID", "error", err)
+		return
+	}
+
+	n.log().Debug("Reporting failed message", "id", msg.ID, "error", err)
+	n.forwardUpstream(Message{
+		Type:          TypeNack,
+		CorrelationID: msg.ID,
+		Content:       err.Error(),
+		Source:        n.name,
+		Timestamp:     time.Now(),
+	})
+}
+
+// handleNack surfaces a NACK from below on Errors and relays it upstream.
+// The reporting node is kept as Source while relaying.
+func (n *Node) handleNack(msg Message) {
+	report := MessageError{
+		MessageID: msg.CorrelationID,
+		Node:      msg.Source,
+		Error:     msg.Content,
+		Timestamp: msg.Timestamp,
+	}
+
+	select {
+	case n.messageErrors <- report:
+	default:
+		n.log().Warn("Error channel full, discarding failure report", "id", report.MessageID, "node", report.Node)
+	}
+	n.forwardUpstream(msg)
+}
@@ -0,0 +1,70 @@
+package btree
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// expectNack waits for the next failure report on node
+func expectNack(t *testing.T, node *Node) MessageError {
+	t.Helper()
+
+	select {
+	case report := <-node.Errors():
+		return report
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timeout waiting for a failure report on %s", node.Name())
+		return MessageError{}
+	}
+}
+
+func TestChildFailureReachesAncestors(t *testing.T) {
+	tree, err := BuildTree(3, 1)
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	defer tree.Stop()
+
+	leaf, _ := tree.Lookup("root.0.0")
+	leaf.HandleType("fragile", func(ctx context.Context, msg Message) error {
+		return errors.New("cannot process fragile message")
+	})
+
+	msg := NewMessage("breaks at the leaf", "nack-1")
+	msg.Type = "fragile"
+	tree.Root().GetInboundChannel() <- msg
+
+	middle, _ := tree.Lookup("root.0")
+	for _, node := range []*Node{middle, tree.Root()} {
+		report := expectNack(t, node)
+		if report.MessageID != "nack-1" {
+			t.Errorf("%s: expected a report for nack-1, got %s", node.Name(), report.MessageID)
+		}
+		if report.Node != "root.0.0" {
+			t.Errorf("%s: expected the failure at root.0.0, got %s", node.Name(), report.Node)
+		}
+		if report.Error != "cannot process fragile message" {
+			t.Errorf("%s: unexpected error %q", node.Name(), report.Error)
+		}
+	}
+
+	// The failing node reports to its ancestors, not to itself
+	select {
+	case report := <-leaf.Errors():
+		t.Errorf("Expected no report on the failing node, got %+v", report)
+	default:
+	}
+}
+
+func TestFailureWithoutIDIsNotReported(t *testing.T) {
+	node := NewNode("anonymous", 0)
+
+	node.sendNack(Message{Type: "fragile"}, errors.New("failed"))
+	select {
+	case msg := <-node.GetUpstreamChannel():
+		t.Errorf("Expected no NACK for a message without ID, got %+v", msg)
+	default:
+	}
+}
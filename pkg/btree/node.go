@@ -2,9 +2,32 @@ package btree
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand/v2"
 	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Errors returned by nodes; they are wrapped with details such as the child
+// index, so compare them with errors.Is
+var (
+	// ErrNodeStopped is returned by sends and broadcasts on a node that has
+	// been stopped; it wraps the cause of the node's context ending
+	ErrNodeStopped = errors.New("node is stopped")
+
+	// ErrChildIndexOutOfRange is returned for a child index the node has
+	// never had
+	ErrChildIndexOutOfRange = errors.New("child index out of range")
+
+	// ErrChildRemoved is returned for the index of a removed child
+	ErrChildRemoved = errors.New("child has been removed")
+
+	// ErrNoChildAvailable is returned by SendRoundRobin when no child channel
+	// can take the message
+	ErrNoChildAvailable = errors.New("no child available")
 )
 
 // Node represents a node in a tree structure
@@ -12,28 +35,200 @@ type Node struct {
 	name        string
 	inbound     chan Message
 	childrenOut []chan Message
+	upstreamOut chan Message
 	mu          sync.RWMutex
+	reachMu     sync.Mutex
+	reach       map[string]*reachState
 	ctx         context.Context
 	cancel      context.CancelFunc
+
+	// deliveryMode controls what broadcasts do with full child channels
+	deliveryMode DeliveryMode
+
+	// Undeliverable messages and the handling of nonexistent child indices
+	deadLetters      chan DroppedMessage
+	outOfRangePolicy OutOfRangePolicy
+
+	// messageErrors surfaces the NACKs of failures in the subtree
+	messageErrors chan MessageError
+	outOfRange    atomic.Uint64
+
+	// profiler samples per-stage handling durations when set
+	profiler *Profiler
+
+	counters nodeCounters
+
+	// dedup remembers recently seen message IDs to drop duplicates
+	dedup *idCache
+
+	// replay retains handled messages for late-joining children when set
+	replay *ReplayBuffer
+
+	// middleware runs in order on every message before it is broadcast
+	middleware []Middleware
+
+	// onReceive runs on every non-duplicate downstream message when set
+	onReceive func(Message)
+
+	// bufferSize is the capacity of child channels created by AddChild
+	bufferSize int
+
+	// typeHandlers consume messages of a type instead of broadcasting them
+	typeHandlers map[string]HandlerFunc
+
+	// gathers holds the response channel of each in-flight Gather by query ID
+	gatherMu sync.Mutex
+	gathers  map[string]chan Message
+
+	// acks holds the channel of each SendReliable awaiting an ack by message ID
+	ackMu sync.Mutex
+	acks  map[string]chan struct{}
+
+	// logger receives the node's structured log records
+	logger atomic.Pointer[slog.Logger]
+
+	// Graceful shutdown state; draining is closed by Shutdown and drained is
+	// closed once the message loop has handled the queued inbound messages
+	started   atomic.Bool
+	draining  chan struct{}
+	drainOnce sync.Once
+	drained   chan struct{}
+
+	// loopDone is closed when the message loop returns
+	loopDone chan struct{}
+
+	// rateLimiter caps inbound processing in the message loop when set
+	rateLimiter     *tokenBucket
+	rateLimitPolicy RateLimitPolicy
+
+	// Sequence numbers: nextSeq numbers the messages this node broadcasts and
+	// sequences checks the numbers of messages it receives
+	nextSeq       atomic.Uint64
+	sequences     *sequenceTracker
+	onSequenceGap func(SequenceGap)
+
+	// latency tracks the age of handled messages; hopTimestamps stamps the
+	// handling time into their metadata
+	latency       latencyTracker
+	hopTimestamps bool
+
+	// resumed is non-nil while the node is paused and closed by Resume
+	pauseMu sync.Mutex
+	resumed chan struct{}
+
+	// filter decides which messages are broadcast when set
+	filter func(Message) bool
+
+	// broadcastTimeout bounds broadcasts in DeliveryDeadline mode when set
+	broadcastTimeout time.Duration
+
+	// Per-child forwarding probabilities of BroadcastWeighted and the seeded
+	// random source drawing against them
+	childWeights []float64
+	weightMu     sync.Mutex
+	weightRand   *rand.Rand
+
+	// wal logs inbound messages until they are handled when set
+	wal *WAL
+
+	// noPath stops the node appending its name to the Path of the messages
+	// it forwards
+	noPath bool
+
+	// concurrency is the number of workers handling inbound messages
+	concurrency int
+
+	// sink consumes the messages the node handles, by default only while
+	// it has no children
+	sink     HandlerFunc
+	sinkMode SinkMode
+
+	// idGenerator assigns IDs to the messages the node sends without one;
+	// nil uses the default generator
+	idGenerator IDGenerator
+
+	// validator checks inbound downstream messages before they are handled
+	// when set; invalidPolicy decides what happens to the ones it rejects
+	validator     Validator
+	invalidPolicy InvalidPolicy
+
+	// overflowPolicy decides what best-effort broadcasts do with full child
+	// channels
+	overflowPolicy OverflowPolicy
+
+	// subscribers receive a copy of every handled message; subscriberID
+	// numbers them so unsubscribe removes the right one
+	subMu        sync.Mutex
+	subscribers  map[int]chan Message
+	subscriberID int
+
+	// roundRobin is the child index SendRoundRobin tries first for the next
+	// message
+	roundRobinMu sync.Mutex
+	roundRobin   int
 }
 
 // NewNode creates a new tree node with the specified number of children
 func NewNode(name string, numChildren int) *Node {
-	ctx, cancel := context.WithCancel(context.Background())
+	return NewNodeWithOptions(name, numChildren)
+}
+
+// NewNodeWithOptions creates a new tree node with the specified number of
+// children, configured by opts
+func NewNodeWithOptions(name string, numChildren int, opts ...NodeOption) *Node {
+	return newNode(context.Background(), name, numChildren, opts...)
+}
+
+// NewNodeWithContext creates a new tree node whose lifetime is tied to ctx:
+// cancelling ctx stops the node as Stop does. It accepts the same options as
+// NewNodeWithOptions.
+func NewNodeWithContext(ctx context.Context, name string, numChildren int, opts ...NodeOption) *Node {
+	return newNode(ctx, name, numChildren, opts...)
+}
+
+// newNode creates a node whose context derives from parent
+func newNode(parent context.Context, name string, numChildren int, opts ...NodeOption) *Node {
+	options := defaultNodeOptions()
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	ctx, cancel := context.WithCancel(parent)
 
 	// Create channels for each child
 	childrenOut := make([]chan Message, numChildren)
 	for i := range childrenOut {
-		childrenOut[i] = make(chan Message, 100)
+		childrenOut[i] = make(chan Message, options.bufferSize)
 	}
 
-	return &Node{
-		name:        name,
-		inbound:     make(chan Message, 100),
-		childrenOut: childrenOut,
-		ctx:         ctx,
-		cancel:      cancel,
+	n := &Node{
+		name:          name,
+		inbound:       make(chan Message, options.bufferSize),
+		childrenOut:   childrenOut,
+		upstreamOut:   make(chan Message, options.bufferSize),
+		reach:         make(map[string]*reachState),
+		ctx:           ctx,
+		cancel:        cancel,
+		deadLetters:   make(chan DroppedMessage, options.bufferSize),
+		messageErrors: make(chan MessageError, options.bufferSize),
+		dedup:         newIDCache(DefaultDedupCacheSize),
+		sequences:     newSequenceTracker(),
+		draining:      make(chan struct{}),
+		drained:       make(chan struct{}),
+		loopDone:      make(chan struct{}),
+		bufferSize:    options.bufferSize,
+	}
+	n.hopTimestamps = options.hopTimestamps
+	n.wal = options.wal
+	n.concurrency = options.concurrency
+	n.idGenerator = options.idGenerator
+	n.overflowPolicy = options.overflowPolicy
+	if options.rateLimit > 0 {
+		n.rateLimiter = newTokenBucket(options.rateLimit)
+		n.rateLimitPolicy = options.rateLimitPolicy
 	}
+	n.SetLogger(options.logger)
+	return n
 }
 
 // NewBinaryNode creates a new binary tree node (convenience function)
@@ -43,12 +238,30 @@ func NewBinaryNode(name string) *Node {
 
 // Start begins message processing for this node
 func (n *Node) Start() {
+	n.started.Store(true)
 	go n.messageLoop()
 }
 
-// Stop stops the node
-func (n *Node) Stop() {
+// Stop stops the node immediately, abandoning queued messages; use Shutdown
+// to drain them first. The returned summary reports the messages left in the
+// node's channels, and those a preceding Shutdown drained.
+func (n *Node) Stop() StopSummary {
 	n.cancel()
+	return n.stopSummary()
+}
+
+// stopped returns ErrNodeStopped, wrapping the context error, once the node
+// has been stopped
+func (n *Node) stopped() error {
+	if err := n.ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrNodeStopped, err)
+	}
+	return nil
+}
+
+// Name returns the node's name, which it sets as Source on the messages it broadcasts
+func (n *Node) Name() string {
+	return n.name
 }
 
 // GetInboundChannel returns the channel for receiving messages
@@ -56,13 +269,21 @@ func (n *Node) GetInboundChannel() chan<- Message {
 	return n.inbound
 }
 
+// GetUpstreamChannel returns the channel for messages travelling toward the root
+func (n *Node) GetUpstreamChannel() <-chan Message {
+	return n.upstreamOut
+}
+
 // GetChildChannel returns the channel for the specified child index
 func (n *Node) GetChildChannel(index int) (<-chan Message, error) {
 	n.mu.RLock()
 	defer n.mu.RUnlock()
 
 	if index < 0 || index >= len(n.childrenOut) {
-		return nil, fmt.Errorf("child index %d out of range [0, %d)", index, len(n.childrenOut))
+		return nil, n.indexOutOfRange(index)
+	}
+	if n.childrenOut[index] == nil {
+		return nil, fmt.Errorf("%w: %d", ErrChildRemoved, index)
 	}
 
 	return n.childrenOut[index], nil
@@ -70,6 +291,9 @@ func (n *Node) GetChildChannel(index int) (<-chan Message, error) {
 
 // GetLeftChannel returns the channel for left child (index 0) - convenience for binary trees
 func (n *Node) GetLeftChannel() <-chan Message {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
 	if len(n.childrenOut) > 0 {
 		return n.childrenOut[0]
 	}
@@ -78,65 +302,276 @@ func (n *Node) GetLeftChannel() <-chan Message {
 
 // GetRightChannel returns the channel for right child (index 1) - convenience for binary trees
 func (n *Node) GetRightChannel() <-chan Message {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
 	if len(n.childrenOut) > 1 {
 		return n.childrenOut[1]
 	}
 	return nil
 }
 
-// GetNumChildren returns the number of children this node supports
+// GetNumChildren returns the number of child slots, i.e. the range of valid
+// child indices. Slots of removed children are included until they are
+// trimmed; use ActiveChildren to count the children messages reach.
 func (n *Node) GetNumChildren() int {
 	n.mu.RLock()
 	defer n.mu.RUnlock()
 	return len(n.childrenOut)
 }
 
-// HandleMessage processes an incoming message and broadcasts to all children
+// ActiveChildren returns the number of children that have not been removed,
+// which broadcasts reach and Stats reports
+func (n *Node) ActiveChildren() int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.activeChildren()
+}
+
+// IsLeaf reports whether the node has no children that have not been removed
+func (n *Node) IsLeaf() bool {
+	return n.ActiveChildren() == 0
+}
+
+// HandleMessage processes an incoming message and broadcasts to all
+// children. A message whose Target names another node is relayed without
+// being consumed, and one whose Target names this node is consumed but not
+// broadcast; see Message.Target.
 func (n *Node) HandleMessage(ctx context.Context, msg Message) error {
-	log.Printf("[%s] Received message: %s (ID: %s)", n.name, msg.Content, msg.ID)
+	// Messages from children flow upward and are never broadcast
+	if msg.Upstream {
+		return n.handleUpstream(msg)
+	}
+
+	// A paused node holds this message, leaving the rest queued behind it
+	if err := n.waitWhilePaused(ctx); err != nil {
+		return err
+	}
+
+	// Acknowledge delivery to the sender before anything else; duplicates are
+	// acknowledged too, since the sender may be retrying a lost ack
+	if msg.AckRequested {
+		n.sendAck(msg)
+		msg.AckRequested = false
+	}
+
+	// Drop messages already seen, e.g. delivered twice in a diamond topology
+	if msg.ID != "" && n.dedup.seen(msg.ID) {
+		n.log().Debug("Dropping duplicate message", "id", msg.ID)
+		n.counters.duplicates.Add(1)
+		return nil
+	}
+
+	// Stale messages are dropped rather than propagated through a slow tree
+	if msg.Expired(time.Now()) {
+		n.log().Debug("Dropping expired message", "id", msg.ID, "expires_at", msg.ExpiresAt)
+		n.counters.expired.Add(1)
+		n.deadLetter(msg, -1, ReasonExpired)
+		return nil
+	}
+
+	n.log().Debug("Received message", "content", msg.Content, "id", msg.ID)
+	n.counters.received.Add(1)
+	n.checkSequence(msg)
+	msg = n.trackLatency(msg)
+
+	// A message with a Target is consumed only by the node of that name;
+	// the others relay it, and the target does not forward it further
+	consume := msg.Target == "" || msg.Target == n.name
+	forward := msg.Target != n.name
+
+	n.mu.RLock()
+	onReceive := n.onReceive
+	typeHandler := n.typeHandlers[msg.GetType()]
+	filter := n.filter
+	trackPath := !n.noPath
+	n.mu.RUnlock()
+	if onReceive != nil {
+		onReceive(msg)
+	}
+
+	// Types with a registered handler are consumed here instead of broadcast
+	if typeHandler != nil && consume {
+		n.log().Debug("Dispatching message to its type handler", "type", msg.GetType(), "id", msg.ID)
+		return typeHandler(ctx, msg)
+	}
+
+	// A filtering node only forwards the messages its filter accepts;
+	// relays pass on messages for another node untouched
+	if consume && filter != nil && !filter(msg) {
+		n.log().Debug("Filter rejected message", "id", msg.ID)
+		n.counters.filtered.Add(1)
+		n.deadLetter(msg, -1, ReasonFiltered)
+		return nil
+	}
+
+	// Record this node as the last hop, and on the path unless disabled
+	if trackPath {
+		msg = msg.withHop(n.name)
+	} else {
+		msg.Source = n.name
+	}
+
+	n.mu.RLock()
+	timer := stageTimerFor(ctx, n.profiler, msg)
+	replay := n.replay
+	middleware := n.middleware
+	n.mu.RUnlock()
+
+	// Let registered middleware transform or reject the message, unless
+	// the node only relays it
+	var err error
+	if consume {
+		stopMiddleware := timer.Start(StageMiddleware)
+		msg, err = runMiddleware(ctx, middleware, msg)
+		stopMiddleware()
+		if err != nil {
+			n.log().Info("Middleware aborted message", "id", msg.ID, "error", err)
+			return err
+		}
+	}
+
+	tracked := msg.TrackReach && msg.ID != ""
+	if tracked {
+		n.startReach(msg.ID)
+	}
+
+	// Broadcast to all children, numbering the message for their ordering
+	// checks and retaining it for late joiners
+	delivered := 0
+	if forward {
+		msg.Seq = n.nextSeq.Add(1)
+		if replay != nil {
+			replay.Add(msg)
+		}
+		stopBroadcast := timer.Start(StageBroadcast)
+		delivered, err = n.broadcast(ctx, msg)
+		stopBroadcast()
+	}
+	if tracked {
+		n.setReachExpected(msg.ID, delivered)
+	}
+
+	if !consume {
+		n.log().Debug("Relayed message for another node", "id", msg.ID, "target", msg.Target)
+		return err
+	}
 
-	// Update message source for tracking
-	msg.Source = n.name
+	n.publish(msg)
 
-	// Broadcast to all children
-	return n.BroadcastToChildren(ctx, msg)
+	// Hand the message to the sink, which is where a leaf's messages end up
+	return errors.Join(err, n.runSink(ctx, msg))
 }
 
-// BroadcastToChildren sends a message to all children
+// BroadcastToChildren sends a message to all children; once the node has
+// been stopped it returns ErrNodeStopped without sending
 func (n *Node) BroadcastToChildren(ctx context.Context, msg Message) error {
+	_, err := n.broadcast(ctx, msg)
+	return err
+}
+
+// BroadcastFiltered sends a message only to the children for which
+// predicate returns true. The predicate is called with the node's read lock
+// held, like a normal broadcast, so it must not call back into the node.
+func (n *Node) BroadcastFiltered(ctx context.Context, msg Message, predicate func(childIndex int, msg Message) bool) error {
+	_, err := n.broadcastFiltered(ctx, msg, predicate)
+	return err
+}
+
+// broadcast sends a message to all children and returns how many accepted it
+func (n *Node) broadcast(ctx context.Context, msg Message) (int, error) {
+	return n.broadcastFiltered(ctx, msg, nil)
+}
+
+// broadcastFiltered sends a message to the children selected by predicate,
+// or to all children when predicate is nil, and returns how many accepted it
+func (n *Node) broadcastFiltered(ctx context.Context, msg Message, predicate func(int, Message) bool) (int, error) {
+	if err := n.stopped(); err != nil {
+		return 0, err
+	}
+
 	n.mu.RLock()
 	defer n.mu.RUnlock()
 
-	if len(n.childrenOut) == 0 {
-		log.Printf("[%s] No children to broadcast to (leaf node)", n.name)
-		return nil
+	if n.activeChildren() == 0 {
+		n.log().Debug("No children to broadcast to (leaf node)")
+		return 0, nil
+	}
+
+	if n.deliveryMode == DeliveryDeadline && n.broadcastTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.broadcastTimeout)
+		defer cancel()
 	}
 
 	successCount := 0
 	for i, childOut := range n.childrenOut {
+		// Skip slots of removed children and children the predicate rejects
+		if childOut == nil || (predicate != nil && !predicate(i, msg)) {
+			continue
+		}
+
+		if n.deliveryMode == DeliveryDeadline {
+			if !deliverWithDeadline(ctx, childOut, msg) {
+				return successCount, n.partialDelivery(ctx, msg, i, successCount, predicate)
+			}
+			n.log().Debug("Broadcast to child successful", "child", i)
+			n.counters.broadcast.Add(1)
+			successCount++
+			continue
+		}
+
+		if n.deliveryMode == DeliveryBlocking {
+			// Wait for the child to accept, applying backpressure upstream
+			select {
+			case childOut <- msg:
+				n.log().Debug("Broadcast to child successful", "child", i)
+				n.counters.broadcast.Add(1)
+				successCount++
+			case <-ctx.Done():
+				return successCount, ctx.Err()
+			}
+			continue
+		}
+
 		select {
 		case childOut <- msg:
-			log.Printf("[%s] Broadcast to child %d successful", n.name, i)
+			n.log().Debug("Broadcast to child successful", "child", i)
+			n.counters.broadcast.Add(1)
 			successCount++
 		case <-ctx.Done():
-			return ctx.Err()
+			return successCount, ctx.Err()
 		default:
-			// Child channel is full or not being read, continue
-			log.Printf("[%s] Child %d channel full, skipping broadcast", n.name, i)
+			// Child channel is full or not being read
+			delivered, err := n.overflow(ctx, i, childOut, msg)
+			if err != nil {
+				return successCount, err
+			}
+			if delivered {
+				n.log().Debug("Broadcast to child successful", "child", i)
+				n.counters.broadcast.Add(1)
+				successCount++
+			}
 		}
 	}
 
-	log.Printf("[%s] Broadcast complete: %d/%d children reached", n.name, successCount, len(n.childrenOut))
-	return nil
+	n.log().Debug("Broadcast complete", "reached", successCount, "children", n.activeChildren())
+	return successCount, nil
 }
 
-// SendToChild sends a message to the specified child index
+// SendToChild sends a message to the specified child index, blocking until
+// the child channel accepts it or ctx is cancelled. Once the node has been
+// stopped it returns ErrNodeStopped without sending.
 func (n *Node) SendToChild(ctx context.Context, index int, msg Message) error {
+	if err := n.stopped(); err != nil {
+		return err
+	}
+
 	n.mu.RLock()
 	defer n.mu.RUnlock()
 
-	if index < 0 || index >= len(n.childrenOut) {
-		return fmt.Errorf("child index %d out of range [0, %d)", index, len(n.childrenOut))
+	if index < 0 || index >= len(n.childrenOut) || n.childrenOut[index] == nil {
+		return n.handleOutOfRange(index, msg)
 	}
 
 	select {
@@ -147,6 +582,48 @@ func (n *Node) SendToChild(ctx context.Context, index int, msg Message) error {
 	}
 }
 
+// TrySendToChild sends a message to the specified child index without
+// blocking. It returns false if the child channel is full, or if the index
+// does not exist and the out-of-range policy dead-lettered the message.
+func (n *Node) TrySendToChild(index int, msg Message) (bool, error) {
+	if err := n.stopped(); err != nil {
+		return false, err
+	}
+
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if index < 0 || index >= len(n.childrenOut) || n.childrenOut[index] == nil {
+		return false, n.handleOutOfRange(index, msg)
+	}
+
+	select {
+	case n.childrenOut[index] <- msg:
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// handleOutOfRange applies the out-of-range policy to a message addressed to
+// a nonexistent child. Callers must hold n.mu.
+func (n *Node) handleOutOfRange(index int, msg Message) error {
+	n.outOfRange.Add(1)
+
+	if n.outOfRangePolicy == OutOfRangeDeadLetter {
+		n.log().Warn("Child index out of range, dead-lettering message", "child", index, "id", msg.ID)
+		n.deadLetter(msg, index, ReasonNoSuchChild)
+		return nil
+	}
+
+	return n.indexOutOfRange(index)
+}
+
+// indexOutOfRange returns ErrChildIndexOutOfRange for index. Callers must hold n.mu.
+func (n *Node) indexOutOfRange(index int) error {
+	return fmt.Errorf("%w: %d not in [0, %d)", ErrChildIndexOutOfRange, index, len(n.childrenOut))
+}
+
 // SendToLeft sends a message to the left child (index 0) - convenience for binary trees
 func (n *Node) SendToLeft(ctx context.Context, msg Message) error {
 	return n.SendToChild(ctx, 0, msg)
@@ -157,21 +634,138 @@ func (n *Node) SendToRight(ctx context.Context, msg Message) error {
 	return n.SendToChild(ctx, 1, msg)
 }
 
+// SendToParent sends a message toward the root via the upstream channel
+func (n *Node) SendToParent(ctx context.Context, msg Message) error {
+	msg.Upstream = true
+
+	select {
+	case n.upstreamOut <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// handleUpstream aggregates reach reports and relays other messages from
+// children toward the root
+func (n *Node) handleUpstream(msg Message) error {
+	if msg.ReachCount > 0 {
+		n.handleReachReport(msg)
+		return nil
+	}
+
+	if msg.Type == TypeAck {
+		n.handleAck(msg)
+		return nil
+	}
+
+	if msg.Type == TypeNack {
+		n.handleNack(msg)
+		return nil
+	}
+
+	if n.deliverGatherResponse(msg) {
+		return nil
+	}
+
+	n.log().Debug("Relaying upstream message", "content", msg.Content, "id", msg.ID)
+	n.forwardUpstream(msg)
+	return nil
+}
+
+// forwardUpstream sends a message upstream without blocking the node, so an
+// undrained upstream channel at the root cannot stall message processing
+func (n *Node) forwardUpstream(msg Message) {
+	msg.Upstream = true
+
+	select {
+	case n.upstreamOut <- msg:
+	default:
+		n.log().Warn("Upstream channel full, dropping message", "id", msg.ID, "correlation", msg.CorrelationID)
+	}
+}
+
 // Receive returns the channel to receive messages
 func (n *Node) Receive(ctx context.Context) <-chan Message {
 	return n.inbound
 }
 
-// messageLoop processes incoming messages
+// handleInbound handles a message taken from the inbound channel once it has
+// passed the validator, logging it to the WAL first and confirming it once
+// handled. Failures are reported upstream as NACKs. Messages that failed
+// because the node was stopping, or ran out of time, stay unconfirmed so the
+// next run handles them again.
+func (n *Node) handleInbound(msg Message) {
+	// Sample once so that every stage of a profiled message is recorded
+	n.mu.RLock()
+	timer := n.profiler.SampleMessage(msg)
+	n.mu.RUnlock()
+
+	if !msg.Upstream && !n.validate(msg, timer) {
+		return
+	}
+
+	var lsn uint64
+	logged := false
+	if n.wal != nil && !msg.Upstream {
+		var err error
+		if lsn, err = n.wal.Append(msg); err != nil {
+			n.log().Error("Failed to log message to WAL", "id", msg.ID, "error", err)
+		} else {
+			logged = true
+		}
+	}
+
+	err := n.HandleMessage(withStageTimer(n.ctx, timer), msg)
+	if err != nil {
+		n.log().Error("Error handling message", "error", err)
+		if !msg.Upstream && n.ctx.Err() == nil {
+			n.sendNack(msg, err)
+		}
+	}
+	if logged && !isContextError(err) {
+		n.confirmWAL(lsn)
+	}
+}
+
+// messageLoop processes incoming messages with the node's workers
 func (n *Node) messageLoop() {
+	defer close(n.loopDone)
+
+	n.replayWAL()
+
+	var wg sync.WaitGroup
+	for i := 0; i < n.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n.processInbound()
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case <-n.draining:
+		close(n.drained)
+	default:
+		n.log().Info("Node stopped")
+	}
+}
+
+// processInbound handles inbound messages until the node stops, or until
+// Shutdown begins, at which point it handles those still queued and returns
+func (n *Node) processInbound() {
 	for {
 		select {
 		case msg := <-n.inbound:
-			if err := n.HandleMessage(n.ctx, msg); err != nil {
-				log.Printf("[%s] Error handling message: %v", n.name, err)
+			if !n.admit(msg) {
+				continue
 			}
+			n.handleInbound(msg)
+		case <-n.draining:
+			n.drainInbound()
+			return
 		case <-n.ctx.Done():
-			log.Printf("[%s] Node stopped", n.name)
 			return
 		}
 	}
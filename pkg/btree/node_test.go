@@ -2,6 +2,7 @@ package btree
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
@@ -269,3 +270,149 @@ func TestFlexibleChildren(t *testing.T) {
 		t.Error("Expected error for out of bounds child send")
 	}
 }
+
+func TestLeafMessageReachesRoot(t *testing.T) {
+	// Create a chain: root -> middle -> leaf
+	root := NewNode("root", 1)
+	middle := NewNode("middle", 1)
+	leaf := NewNode("leaf", 0)
+
+	for _, node := range []*Node{root, middle, leaf} {
+		node.Start()
+		defer node.Stop()
+	}
+
+	// Wire upstream channels to each parent's inbound channel
+	go func() {
+		for msg := range leaf.GetUpstreamChannel() {
+			middle.GetInboundChannel() <- msg
+		}
+	}()
+
+	go func() {
+		for msg := range middle.GetUpstreamChannel() {
+			root.GetInboundChannel() <- msg
+		}
+	}()
+
+	ack := NewMessage("ack from leaf", "ack-1")
+	if err := leaf.SendToParent(context.Background(), ack); err != nil {
+		t.Fatalf("Failed to send to parent: %v", err)
+	}
+
+	select {
+	case msg := <-root.GetUpstreamChannel():
+		if msg.ID != ack.ID || msg.Content != ack.Content {
+			t.Errorf("Expected %+v at root, got %+v", ack, msg)
+		}
+		if !msg.Upstream {
+			t.Error("Upstream message should be flagged as upstream")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for leaf message at root")
+	}
+
+	// The upstream message must not be broadcast back down
+	childChannel, _ := middle.GetChildChannel(0)
+	select {
+	case msg := <-childChannel:
+		t.Errorf("Upstream message was broadcast to children: %+v", msg)
+	default:
+	}
+}
+
+func TestIsLeaf(t *testing.T) {
+	if !NewNode("leaf", 0).IsLeaf() {
+		t.Error("Expected node without children to be a leaf")
+	}
+	if NewBinaryNode("parent").IsLeaf() {
+		t.Error("Expected node with children not to be a leaf")
+	}
+}
+
+func TestNodeStopsWithParentContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	node := NewNodeWithContext(ctx, "scoped", 1)
+	node.Start()
+
+	cancel()
+
+	select {
+	case <-node.loopDone:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the message loop to exit when the parent context is cancelled")
+	}
+}
+
+func TestNewNodeWithContextAppliesOptions(t *testing.T) {
+	node := NewNodeWithContext(context.Background(), "scoped", 1, WithBufferSize(3))
+	defer node.Stop()
+
+	if got := node.Snapshot().BufferSize; got != 3 {
+		t.Errorf("Expected buffer size 3, got %d", got)
+	}
+}
+
+func TestSendsAfterStopReturnErrNodeStopped(t *testing.T) {
+	node := NewNode("stopped", 2)
+	node.Stop()
+	ctx := context.Background()
+	msg := NewMessage("too late", "late-1")
+
+	if err := node.SendToChild(ctx, 0, msg); !errors.Is(err, ErrNodeStopped) {
+		t.Errorf("SendToChild: expected ErrNodeStopped, got %v", err)
+	}
+	if _, err := node.TrySendToChild(0, msg); !errors.Is(err, ErrNodeStopped) {
+		t.Errorf("TrySendToChild: expected ErrNodeStopped, got %v", err)
+	}
+	if err := node.BroadcastToChildren(ctx, msg); !errors.Is(err, ErrNodeStopped) {
+		t.Errorf("BroadcastToChildren: expected ErrNodeStopped, got %v", err)
+	}
+	if err := node.BroadcastToChildren(ctx, msg); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the error to wrap the context error, got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		child, _ := node.GetChildChannel(i)
+		if len(child) != 0 {
+			t.Errorf("Expected nothing queued for child %d, got %d messages", i, len(child))
+		}
+	}
+}
+
+func TestStoppedLeafBroadcastReturnsErrNodeStopped(t *testing.T) {
+	leaf := NewNode("leaf", 0)
+	leaf.Stop()
+	if err := leaf.BroadcastToChildren(context.Background(), NewMessage("x", "leaf-1")); !errors.Is(err, ErrNodeStopped) {
+		t.Errorf("Expected ErrNodeStopped from a stopped leaf, got %v", err)
+	}
+}
+
+func TestChildErrorsMatchSentinels(t *testing.T) {
+	node := NewNode("sentinels", 2)
+
+	if _, err := node.GetChildChannel(5); !errors.Is(err, ErrChildIndexOutOfRange) {
+		t.Errorf("GetChildChannel: expected ErrChildIndexOutOfRange, got %v", err)
+	}
+	if err := node.SendToChild(context.Background(), -1, NewMessage("x", "s-1")); !errors.Is(err, ErrChildIndexOutOfRange) {
+		t.Errorf("SendToChild: expected ErrChildIndexOutOfRange, got %v", err)
+	}
+	if err := node.RemoveChild(2); !errors.Is(err, ErrChildIndexOutOfRange) {
+		t.Errorf("RemoveChild: expected ErrChildIndexOutOfRange, got %v", err)
+	}
+
+	if err := node.RemoveChild(0); err != nil {
+		t.Fatalf("RemoveChild failed: %v", err)
+	}
+	if err := node.RemoveChild(0); !errors.Is(err, ErrChildRemoved) {
+		t.Errorf("RemoveChild: expected ErrChildRemoved, got %v", err)
+	}
+	if _, err := node.GetChildChannel(0); !errors.Is(err, ErrChildRemoved) {
+		t.Errorf("GetChildChannel: expected ErrChildRemoved, got %v", err)
+	}
+
+	node.Stop()
+	if _, err := node.AddChild(); !errors.Is(err, ErrNodeStopped) {
+		t.Errorf("AddChild: expected ErrNodeStopped, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package btree
+
+import "log/slog"
+
+// DefaultBufferSize is the capacity of a node's channels unless configured
+// with WithBufferSize
+const DefaultBufferSize = 100
+
+// NodeOption configures a node created with NewNodeWithOptions
+type NodeOption func(*nodeOptions)
+
+// nodeOptions collects the settings applied by NodeOptions
+type nodeOptions struct {
+	bufferSize      int
+	logger          *slog.Logger
+	rateLimit       int
+	rateLimitPolicy RateLimitPolicy
+	hopTimestamps   bool
+	wal             *WAL
+	concurrency     int
+	idGenerator     IDGenerator
+	overflowPolicy  OverflowPolicy
+}
+
+// defaultNodeOptions returns the settings used by NewNode
+func defaultNodeOptions() nodeOptions {
+	return nodeOptions{
+		bufferSize:  DefaultBufferSize,
+		concurrency: 1,
+	}
+}
+
+// WithBufferSize sets the capacity of the node's inbound, child, upstream
+// and dead-letter channels. Sizes below zero are ignored.
+func WithBufferSize(size int) NodeOption {
+	return func(o *nodeOptions) {
+		if size >= 0 {
+			o.bufferSize = size
+		}
+	}
+}
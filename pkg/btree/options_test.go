@@ -0,0 +1,29 @@
+package btree
+
+import "testing"
+
+func TestWithBufferSize(t *testing.T) {
+	node := NewNodeWithOptions("sized", 2, WithBufferSize(7))
+
+	if capacity := cap(node.inbound); capacity != 7 {
+		t.Errorf("Expected inbound capacity 7, got %d", capacity)
+	}
+	if capacity := cap(node.upstreamOut); capacity != 7 {
+		t.Errorf("Expected upstream capacity 7, got %d", capacity)
+	}
+	for i := 0; i < 2; i++ {
+		ch, _ := node.GetChildChannel(i)
+		if capacity := cap(ch); capacity != 7 {
+			t.Errorf("Expected child %d capacity 7, got %d", i, capacity)
+		}
+	}
+}
+
+func TestNewNodeUsesDefaultBufferSize(t *testing.T) {
+	node := NewNode("default", 1)
+
+	ch, _ := node.GetChildChannel(0)
+	if capacity := cap(ch); capacity != DefaultBufferSize {
+		t.Errorf("Expected child capacity %d, got %d", DefaultBufferSize, capacity)
+	}
+}
@@ -0,0 +1,101 @@
+package btree
+
+import "context"
+
+// OverflowPolicy controls what a best-effort broadcast does when a child
+// channel is full
+type OverflowPolicy int
+
+const (
+	// OverflowDropNewest skips the child for the new message, keeping what
+	// is already queued (the default)
+	OverflowDropNewest OverflowPolicy = iota
+
+	// OverflowDropOldest evicts the oldest message queued for the child to
+	// make room for the new one, so a lagging child sees the latest messages
+	OverflowDropOldest
+
+	// OverflowBlock waits for the child to make room, as DeliveryBlocking
+	// does, or until the context is cancelled
+	OverflowBlock
+)
+
+// ReasonEvicted marks a queued message evicted from a full child channel by
+// OverflowDropOldest
+const ReasonEvicted = "evicted"
+
+// String returns the name of the overflow policy
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowDropNewest:
+		return "drop-newest"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowBlock:
+		return "block"
+	default:
+		return "unknown"
+	}
+}
+
+// WithOverflowPolicy sets what broadcasts in DeliveryBestEffort mode do with
+// full child channels. Evicted and skipped messages are counted as dropped
+// and sent to the dead-letter channel. The blocking and deadline delivery
+// modes always wait and ignore the policy.
+func WithOverflowPolicy(policy OverflowPolicy) NodeOption {
+	return func(o *nodeOptions) {
+		o.overflowPolicy = policy
+	}
+}
+
+// evictAttempts bounds how often OverflowDropOldest evicts and retries
+// before giving up on a child that keeps refilling its channel
+const evictAttempts = 3
+
+// overflow handles a message for child i whose channel was full, according
+// to the node's overflow policy, and reports whether the child took it.
+// Callers must hold n.mu.
+func (n *Node) overflow(ctx context.Context, i int, childOut chan Message, msg Message) (bool, error) {
+	switch n.overflowPolicy {
+	case OverflowBlock:
+		select {
+		case childOut <- msg:
+			return true, nil
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	case OverflowDropOldest:
+		// An unbuffered channel holds nothing to evict, so it falls back to
+		// dropping the new message like a channel that stays full
+		if cap(childOut) > 0 && n.evictOldest(i, childOut, msg) {
+			return true, nil
+		}
+	}
+
+	n.log().Warn("Child channel full, skipping broadcast", "child", i, "id", msg.ID)
+	n.counters.dropped.Add(1)
+	n.deadLetter(msg, i, ReasonChannelFull)
+	return false, nil
+}
+
+// evictOldest makes room for msg in the full channel of child i by evicting
+// its oldest queued message, and reports whether msg was then queued
+func (n *Node) evictOldest(i int, childOut chan Message, msg Message) bool {
+	for attempt := 0; attempt < evictAttempts; attempt++ {
+		// The child may drain the channel meanwhile, leaving nothing to evict
+		select {
+		case evicted := <-childOut:
+			n.log().Warn("Child channel full, evicting oldest message", "child", i, "id", evicted.ID)
+			n.counters.dropped.Add(1)
+			n.deadLetter(evicted, i, ReasonEvicted)
+		default:
+		}
+
+		select {
+		case childOut <- msg:
+			return true
+		default:
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package btree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// saturate handles messages prefix-0 to prefix-(count-1) on a node whose
+// single child never reads
+func saturate(t *testing.T, node *Node, prefix string, count int) {
+	t.Helper()
+
+	for i := 0; i < count; i++ {
+		if err := node.HandleMessage(context.Background(), NewMessage("x", fmt.Sprintf("%s-%d", prefix, i))); err != nil {
+			t.Fatalf("HandleMessage failed: %v", err)
+		}
+	}
+}
+
+// queuedIDs drains a child channel and returns the IDs it held
+func queuedIDs(child <-chan Message) []string {
+	var ids []string
+	for {
+		select {
+		case msg := <-child:
+			ids = append(ids, msg.ID)
+		default:
+			return ids
+		}
+	}
+}
+
+func TestOverflowDropNewest(t *testing.T) {
+	node := NewNodeWithOptions("overflow", 1, WithBufferSize(2))
+	child, _ := node.GetChildChannel(0)
+
+	saturate(t, node, "of", 3)
+
+	if got := fmt.Sprint(queuedIDs(child)); got != "[of-0 of-1]" {
+		t.Errorf("Expected the first two messages to stay queued, got %s", got)
+	}
+	dropped := <-node.DeadLetters()
+	if dropped.Message.ID != "of-2" || dropped.Reason != ReasonChannelFull {
+		t.Errorf("Expected of-2 dropped as %s, got %+v", ReasonChannelFull, dropped)
+	}
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	node := NewNodeWithOptions("overflow", 1, WithBufferSize(2), WithOverflowPolicy(OverflowDropOldest))
+	child, _ := node.GetChildChannel(0)
+
+	saturate(t, node, "of", 4)
+
+	if got := fmt.Sprint(queuedIDs(child)); got != "[of-2 of-3]" {
+		t.Errorf("Expected the latest two messages to be queued, got %s", got)
+	}
+	for _, want := range []string{"of-0", "of-1"} {
+		dropped := <-node.DeadLetters()
+		if dropped.Message.ID != want || dropped.Reason != ReasonEvicted || dropped.ChildIndex != 0 {
+			t.Errorf("Expected %s evicted from child 0, got %+v", want, dropped)
+		}
+	}
+	if stats := node.Stats(); stats.Dropped != 2 || stats.Broadcast != 4 {
+		t.Errorf("Expected 4 broadcast and 2 dropped, got %+v", stats)
+	}
+}
+
+func TestOverflowDropOldestUnbufferedChild(t *testing.T) {
+	node := NewNodeWithOptions("overflow", 1, WithBufferSize(0), WithOverflowPolicy(OverflowDropOldest))
+
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		for i := 0; i < 2 && err == nil; i++ {
+			err = node.HandleMessage(context.Background(), NewMessage("x", fmt.Sprintf("unbuffered-%d", i)))
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("HandleMessage failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Broadcast to an unbuffered child never returned")
+	}
+
+	// Nothing can be evicted, so both messages are dropped instead
+	if stats := node.Stats(); stats.Dropped != 2 || stats.Broadcast != 0 {
+		t.Errorf("Expected 2 dropped and none broadcast, got %+v", stats)
+	}
+}
+
+func TestOverflowBlock(t *testing.T) {
+	node := NewNodeWithOptions("overflow", 1, WithBufferSize(2), WithOverflowPolicy(OverflowBlock))
+	child, _ := node.GetChildChannel(0)
+
+	saturate(t, node, "of", 2)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- node.HandleMessage(context.Background(), NewMessage("x", "of-2"))
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Expected the broadcast to wait for room, returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	<-child
+	if err := <-done; err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+	if got := fmt.Sprint(queuedIDs(child)); got != "[of-1 of-2]" {
+		t.Errorf("Expected of-1 and of-2 queued, got %s", got)
+	}
+
+	// A cancelled context stops the wait
+	saturate(t, node, "refill", 2)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := node.HandleMessage(ctx, NewMessage("x", "of-late")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestOverflowPolicyString(t *testing.T) {
+	tests := map[OverflowPolicy]string{
+		OverflowDropNewest: "drop-newest",
+		OverflowDropOldest: "drop-oldest",
+		OverflowBlock:      "block",
+		OverflowPolicy(9):  "unknown",
+	}
+	for policy, want := range tests {
+		if got := policy.String(); got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	}
+}
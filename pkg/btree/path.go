@@ -0,0 +1,31 @@
+package btree
+
+// SetPathTracking sets whether the node appends its name to the Path of the
+// messages it forwards. Tracking is on by default; Source is set either way.
+func (n *Node) SetPathTracking(enabled bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.noPath = !enabled
+}
+
+// HasVisited reports whether the named node already forwarded the message,
+// which means the message is looping
+func (m Message) HasVisited(node string) bool {
+	for _, hop := range m.Path {
+		if hop == node {
+			return true
+		}
+	}
+	return false
+}
+
+// withHop returns a copy of the message with node appended to its path. The
+// path is copied, never appended to in place, because broadcast copies of a
+// message share it.
+func (m Message) withHop(node string) Message {
+	path := make([]string, len(m.Path), len(m.Path)+1)
+	copy(path, m.Path)
+	m.Path = append(path, node)
+	m.Source = node
+	return m
+}
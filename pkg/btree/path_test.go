@@ -0,0 +1,91 @@
+package btree
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLeafSeesRootToLeafPath(t *testing.T) {
+	tree, err := BuildTree(3, 2)
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	defer tree.Stop()
+
+	leaf, ok := tree.Lookup("root.1.0")
+	if !ok {
+		t.Fatal("Expected leaf root.1.0")
+	}
+	received := make(chan Message, 1)
+	leaf.OnReceive(func(msg Message) { received <- msg })
+
+	tree.Root().GetInboundChannel() <- NewMessage("traced", "path-1")
+
+	select {
+	case msg := <-received:
+		route := append(msg.Path, leaf.Name())
+		if want := []string{"root", "root.1", "root.1.0"}; !slices.Equal(route, want) {
+			t.Errorf("Expected path %v, got %v", want, route)
+		}
+		if msg.Source != "root.1" {
+			t.Errorf("Expected Source to stay the last hop root.1, got %s", msg.Source)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the leaf")
+	}
+}
+
+func TestPathIsNotSharedBetweenChildren(t *testing.T) {
+	node := NewNode("root", 2)
+
+	msg := NewMessage("fan out", "path-2")
+	msg.Path = make([]string, 1, 8)
+	msg.Path[0] = "upstream"
+	if err := node.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+
+	left, _ := node.GetChildChannel(0)
+	right, _ := node.GetChildChannel(1)
+	first, second := <-left, <-right
+
+	// Appending on one copy must not show up in the other or the original
+	first.Path = append(first.Path, "left-only")
+	if want := []string{"upstream", "root"}; !slices.Equal(second.Path, want) {
+		t.Errorf("Expected %v, got %v", want, second.Path)
+	}
+	// Nor may the node have written into the spare capacity of the sender's path
+	if spare := msg.Path[:2]; spare[1] != "" {
+		t.Errorf("Expected the sender's path to be untouched, got %v", spare)
+	}
+}
+
+func TestPathTrackingDisabled(t *testing.T) {
+	node := NewNode("quiet", 1)
+	node.SetPathTracking(false)
+
+	if err := node.HandleMessage(context.Background(), NewMessage("untraced", "path-3")); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+
+	child, _ := node.GetChildChannel(0)
+	msg := <-child
+	if len(msg.Path) != 0 {
+		t.Errorf("Expected no path, got %v", msg.Path)
+	}
+	if msg.Source != "quiet" {
+		t.Errorf("Expected Source quiet, got %s", msg.Source)
+	}
+}
+
+func TestHasVisited(t *testing.T) {
+	msg := Message{Path: []string{"root", "root.0"}}
+	if !msg.HasVisited("root.0") {
+		t.Error("Expected root.0 to be on the path")
+	}
+	if msg.HasVisited("root.1") {
+		t.Error("Expected root.1 not to be on the path")
+	}
+}
@@ -0,0 +1,61 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+)
+
+// Pause stops the node from handling downstream messages until Resume is
+// called. Inbound messages are still accepted and queue on the inbound
+// channel, to be handled in order on resume; messages from children keep
+// flowing to the parent. Pausing a paused node has no effect.
+func (n *Node) Pause() {
+	n.pauseMu.Lock()
+	defer n.pauseMu.Unlock()
+
+	if n.resumed == nil {
+		n.resumed = make(chan struct{})
+		n.log().Info("Node paused")
+	}
+}
+
+// Resume lets a paused node handle its queued messages and broadcast again.
+// Resuming a node that is not paused has no effect.
+func (n *Node) Resume() {
+	n.pauseMu.Lock()
+	defer n.pauseMu.Unlock()
+
+	if n.resumed != nil {
+		close(n.resumed)
+		n.resumed = nil
+		n.log().Info("Node resumed")
+	}
+}
+
+// IsPaused reports whether the node is paused
+func (n *Node) IsPaused() bool {
+	n.pauseMu.Lock()
+	defer n.pauseMu.Unlock()
+	return n.resumed != nil
+}
+
+// waitWhilePaused blocks while the node is paused. A graceful shutdown ends
+// the pause so queued messages are still flushed to children.
+func (n *Node) waitWhilePaused(ctx context.Context) error {
+	n.pauseMu.Lock()
+	resumed := n.resumed
+	n.pauseMu.Unlock()
+
+	if resumed == nil {
+		return nil
+	}
+
+	select {
+	case <-resumed:
+		return nil
+	case <-n.draining:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("node paused: %w", ctx.Err())
+	}
+}
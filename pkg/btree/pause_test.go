@@ -0,0 +1,99 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPauseHoldsMessagesUntilResume(t *testing.T) {
+	node := NewNode("pausable", 2)
+	node.Start()
+	defer node.Stop()
+
+	node.Pause()
+	if !node.IsPaused() {
+		t.Fatal("Expected the node to be paused")
+	}
+
+	const total = 5
+	for i := 0; i < total; i++ {
+		node.GetInboundChannel() <- NewMessage("held", fmt.Sprintf("p-%d", i))
+	}
+
+	left, _ := node.GetChildChannel(0)
+	right, _ := node.GetChildChannel(1)
+	select {
+	case msg := <-left:
+		t.Fatalf("Expected no broadcast while paused, got %+v", msg)
+	case msg := <-right:
+		t.Fatalf("Expected no broadcast while paused, got %+v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	node.Resume()
+	if node.IsPaused() {
+		t.Fatal("Expected the node to be resumed")
+	}
+
+	for _, ch := range []<-chan Message{left, right} {
+		for i := 0; i < total; i++ {
+			select {
+			case msg := <-ch:
+				if want := fmt.Sprintf("p-%d", i); msg.ID != want {
+					t.Errorf("Expected %s, got %s", want, msg.ID)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("Timeout waiting for message %d after resume", i)
+			}
+		}
+	}
+}
+
+func TestPausedNodeStillForwardsUpstream(t *testing.T) {
+	node := NewNode("pausable", 1)
+	node.Pause()
+	defer node.Resume()
+
+	report := NewMessage("report", "up-1")
+	report.Upstream = true
+	if err := node.HandleMessage(context.Background(), report); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+	select {
+	case msg := <-node.GetUpstreamChannel():
+		if msg.ID != "up-1" {
+			t.Errorf("Expected up-1, got %s", msg.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the upstream message to reach the parent while paused")
+	}
+}
+
+func TestShutdownFlushesPausedNode(t *testing.T) {
+	node := NewNode("pausable", 1)
+	node.Start()
+	node.Pause()
+	node.GetInboundChannel() <- NewMessage("held", "s-1")
+
+	child, _ := node.GetChildChannel(0)
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		done <- node.Shutdown(ctx)
+	}()
+
+	select {
+	case msg := <-child:
+		if msg.ID != "s-1" {
+			t.Errorf("Expected s-1, got %s", msg.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected shutdown to flush the held message")
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package btree
+
+import "context"
+
+// Pipe copies messages from src to dst until ctx is done or src is closed,
+// replacing the hand-written forwarding goroutine between two nodes. It
+// blocks, so run it in its own goroutine.
+func Pipe(ctx context.Context, src <-chan Message, dst chan<- Message) {
+	for {
+		select {
+		case msg, ok := <-src:
+			if !ok {
+				return
+			}
+			select {
+			case dst <- msg:
+			case <-ctx.Done():
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// PipeChild forwards the messages parent sends to child index into the
+// inbound channel of child, in the background, until either node is stopped
+func PipeChild(parent *Node, index int, child *Node) error {
+	src, err := parent.GetChildChannel(index)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(parent.ctx)
+	stop := context.AfterFunc(child.ctx, cancel)
+	go func() {
+		defer cancel()
+		defer stop()
+		Pipe(ctx, src, child.GetInboundChannel())
+	}()
+	return nil
+}
@@ -0,0 +1,106 @@
+package btree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPipeConnectsTwoNodes(t *testing.T) {
+	parent := NewNode("parent", 1)
+	child := NewNode("child", 1)
+	parent.Start()
+	child.Start()
+	defer parent.Stop()
+	defer child.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	src, err := parent.GetChildChannel(0)
+	if err != nil {
+		t.Fatalf("Failed to get child channel: %v", err)
+	}
+	go Pipe(ctx, src, child.GetInboundChannel())
+
+	leaf, err := child.GetChildChannel(0)
+	if err != nil {
+		t.Fatalf("Failed to get child channel: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		parent.GetInboundChannel() <- Message{ID: fmt.Sprintf("pipe-%d", i), Content: "through the pipe"}
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case msg := <-leaf:
+			if want := fmt.Sprintf("pipe-%d", i); msg.ID != want {
+				t.Errorf("Expected %s, got %s", want, msg.ID)
+			}
+			if msg.Source != "child" {
+				t.Errorf("Expected source child, got %s", msg.Source)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for message %d", i)
+		}
+	}
+}
+
+func TestPipeReturnsWhenSourceClosesOrContextEnds(t *testing.T) {
+	src := make(chan Message)
+	done := make(chan struct{})
+	go func() {
+		Pipe(context.Background(), src, make(chan Message))
+		close(done)
+	}()
+	close(src)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Pipe did not return after src was closed")
+	}
+
+	// A pending send to a full dst is abandoned when ctx ends
+	ctx, cancel := context.WithCancel(context.Background())
+	src = make(chan Message, 1)
+	src <- Message{ID: "stuck"}
+	done = make(chan struct{})
+	go func() {
+		Pipe(ctx, src, make(chan Message))
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Pipe did not return after ctx was cancelled")
+	}
+}
+
+func TestPipeChild(t *testing.T) {
+	parent := NewBinaryNode("parent")
+	left := NewBinaryNode("left")
+	nodes := []*Node{parent, left}
+	for _, node := range nodes {
+		node.Start()
+		defer node.Stop()
+	}
+
+	if err := PipeChild(parent, 0, left); err != nil {
+		t.Fatalf("Failed to pipe child: %v", err)
+	}
+	if err := PipeChild(parent, 2, left); !errors.Is(err, ErrChildIndexOutOfRange) {
+		t.Errorf("Expected ErrChildIndexOutOfRange, got %v", err)
+	}
+
+	parent.GetInboundChannel() <- Message{ID: "pipe-child", Content: "hello"}
+	select {
+	case msg := <-left.GetRightChannel():
+		if msg.ID != "pipe-child" {
+			t.Errorf("Expected pipe-child, got %s", msg.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for piped message")
+	}
+}
@@ -0,0 +1,251 @@
+package btree
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Stage identifies a step of message handling timed by the profiler
+type Stage string
+
+// Stages recorded by the profiler
+const (
+	StageDecode     Stage = "decode"
+	StageValidate   Stage = "validate"
+	StageMiddleware Stage = "middleware"
+	StageBroadcast  Stage = "broadcast"
+	StageEncode     Stage = "encode"
+)
+
+// histogramBounds are the upper bounds of the duration histogram buckets
+var histogramBounds = []time.Duration{
+	time.Microsecond,
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// HistogramBucket counts observations up to an upper bound; the last bucket
+// of a snapshot has a zero bound and counts everything above the others
+type HistogramBucket struct {
+	UpperBound time.Duration
+	Count      uint64
+}
+
+// HistogramSnapshot is a point-in-time copy of a stage histogram
+type HistogramSnapshot struct {
+	Count   uint64
+	Sum     time.Duration
+	Min     time.Duration
+	Max     time.Duration
+	Buckets []HistogramBucket
+}
+
+// Mean returns the average observed duration
+func (h HistogramSnapshot) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// histogram accumulates durations into fixed buckets
+type histogram struct {
+	count   uint64
+	sum     time.Duration
+	min     time.Duration
+	max     time.Duration
+	buckets []uint64
+}
+
+// observe records a duration
+func (h *histogram) observe(d time.Duration) {
+	if h.buckets == nil {
+		h.buckets = make([]uint64, len(histogramBounds)+1)
+	}
+
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.sum += d
+
+	for i, bound := range histogramBounds {
+		if d <= bound {
+			h.buckets[i]++
+			return
+		}
+	}
+	h.buckets[len(histogramBounds)]++
+}
+
+// snapshot copies the histogram
+func (h *histogram) snapshot() HistogramSnapshot {
+	snap := HistogramSnapshot{Count: h.count, Sum: h.sum, Min: h.min, Max: h.max}
+	if h.buckets == nil {
+		return snap
+	}
+
+	snap.Buckets = make([]HistogramBucket, len(h.buckets))
+	for i, count := range h.buckets {
+		snap.Buckets[i].Count = count
+		if i < len(histogramBounds) {
+			snap.Buckets[i].UpperBound = histogramBounds[i]
+		}
+	}
+	return snap
+}
+
+// Profiler records per-stage durations for a sampled fraction of messages
+type Profiler struct {
+	mu         sync.Mutex
+	sampleRate float64
+	rng        *rand.Rand
+	histograms map[Stage]*histogram
+}
+
+// NewProfiler creates a profiler sampling the given fraction of messages,
+// from 0 (never) to 1 (every message)
+func NewProfiler(sampleRate float64) *Profiler {
+	return &Profiler{
+		sampleRate: sampleRate,
+		rng:        rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+		histograms: make(map[Stage]*histogram),
+	}
+}
+
+// Sample decides at random whether the current message is profiled. It
+// returns nil when it is not; a nil StageTimer records nothing, so callers
+// need no checks.
+func (p *Profiler) Sample() *StageTimer {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.sampleAt(p.rng.Float64())
+}
+
+// SampleMessage decides whether msg is profiled. Messages with an ID are
+// decided by a hash of it, so every stage timing the same message, in a node
+// or a transport sharing the profiler, makes the same decision; messages
+// without one are sampled at random like Sample.
+func (p *Profiler) SampleMessage(msg Message) *StageTimer {
+	if p == nil {
+		return nil
+	}
+	if msg.ID == "" {
+		return p.Sample()
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(msg.ID))
+	draw := float64(h.Sum64()>>11) / (1 << 53)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.sampleAt(draw)
+}
+
+// sampleAt returns a timer if draw, in [0, 1), falls within the sample rate.
+// Callers must hold p.mu.
+func (p *Profiler) sampleAt(draw float64) *StageTimer {
+	if p.sampleRate <= 0 || (p.sampleRate < 1 && draw >= p.sampleRate) {
+		return nil
+	}
+	return &StageTimer{profiler: p}
+}
+
+// Histogram returns a snapshot of the durations recorded for a stage
+func (p *Profiler) Histogram(stage Stage) HistogramSnapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h, ok := p.histograms[stage]
+	if !ok {
+		return HistogramSnapshot{}
+	}
+	return h.snapshot()
+}
+
+// record adds a stage duration
+func (p *Profiler) record(stage Stage, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h, ok := p.histograms[stage]
+	if !ok {
+		h = &histogram{}
+		p.histograms[stage] = h
+	}
+	h.observe(d)
+}
+
+// StageTimer times the stages of one sampled message
+type StageTimer struct {
+	profiler *Profiler
+}
+
+// Start begins timing a stage and returns the function that ends it
+func (st *StageTimer) Start(stage Stage) func() {
+	if st == nil {
+		return func() {}
+	}
+
+	start := time.Now()
+	return func() {
+		st.profiler.record(stage, time.Since(start))
+	}
+}
+
+// Time runs fn and records its duration under stage
+func (st *StageTimer) Time(stage Stage, fn func()) {
+	stop := st.Start(stage)
+	fn()
+	stop()
+}
+
+// Observe records d under stage, for stages timed before the message they
+// belong to was known, such as decoding
+func (st *StageTimer) Observe(stage Stage, d time.Duration) {
+	if st == nil {
+		return
+	}
+	st.profiler.record(stage, d)
+}
+
+// stageTimerKey is the context key of the stage timer of the message being
+// handled
+type stageTimerKey struct{}
+
+// withStageTimer returns a context carrying the sampling decision for the
+// message being handled, so its stages share it; timer may be nil
+func withStageTimer(ctx context.Context, timer *StageTimer) context.Context {
+	return context.WithValue(ctx, stageTimerKey{}, timer)
+}
+
+// stageTimerFor returns the stage timer of msg: the one carried by ctx when
+// a decision was already made for it, or a new decision by profiler otherwise
+func stageTimerFor(ctx context.Context, profiler *Profiler, msg Message) *StageTimer {
+	if timer, ok := ctx.Value(stageTimerKey{}).(*StageTimer); ok {
+		return timer
+	}
+	return profiler.SampleMessage(msg)
+}
+
+// SetProfiler enables stage profiling for this node; nil disables it
+func (n *Node) SetProfiler(p *Profiler) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.profiler = p
+}
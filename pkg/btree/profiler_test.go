@@ -0,0 +1,107 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestProfilerCapturesAllStages(t *testing.T) {
+	profiler := NewProfiler(1.0)
+
+	delays := map[Stage]time.Duration{
+		StageDecode:     2 * time.Millisecond,
+		StageValidate:   4 * time.Millisecond,
+		StageMiddleware: 6 * time.Millisecond,
+		StageBroadcast:  8 * time.Millisecond,
+		StageEncode:     10 * time.Millisecond,
+	}
+
+	const messages = 3
+	for i := 0; i < messages; i++ {
+		timer := profiler.Sample()
+		if timer == nil {
+			t.Fatal("Sample rate 1.0 should sample every message")
+		}
+		for stage, delay := range delays {
+			timer.Time(stage, func() { time.Sleep(delay) })
+		}
+	}
+
+	for stage, delay := range delays {
+		h := profiler.Histogram(stage)
+		if h.Count != messages {
+			t.Errorf("%s: expected %d samples, got %d", stage, messages, h.Count)
+		}
+		if h.Min < delay {
+			t.Errorf("%s: minimum %v shorter than injected delay %v", stage, h.Min, delay)
+		}
+		if h.Mean() > delay+20*time.Millisecond {
+			t.Errorf("%s: mean %v far above injected delay %v", stage, h.Mean(), delay)
+		}
+	}
+}
+
+func TestProfilerZeroRateSamplesNothing(t *testing.T) {
+	profiler := NewProfiler(0)
+
+	timer := profiler.Sample()
+	if timer != nil {
+		t.Fatal("Sample rate 0 should never sample")
+	}
+
+	// A nil timer is safe to use and records nothing
+	timer.Time(StageDecode, func() {})
+	if h := profiler.Histogram(StageDecode); h.Count != 0 {
+		t.Errorf("Expected no samples, got %d", h.Count)
+	}
+}
+
+func TestProfilerSamplesEachMessageOnce(t *testing.T) {
+	profiler := NewProfiler(0.5)
+
+	const messages = 200
+	for i := 0; i < messages; i++ {
+		msg := Message{ID: fmt.Sprintf("msg-%d", i)}
+		// Every stage asks again, as the node and transport do
+		profiler.SampleMessage(msg).Observe(StageDecode, time.Millisecond)
+		profiler.SampleMessage(msg).Observe(StageValidate, time.Millisecond)
+		profiler.SampleMessage(msg).Observe(StageEncode, time.Millisecond)
+	}
+
+	sampled := profiler.Histogram(StageDecode).Count
+	if sampled == 0 || sampled == messages {
+		t.Fatalf("Expected a fraction of %d messages sampled, got %d", messages, sampled)
+	}
+	for _, stage := range []Stage{StageValidate, StageEncode} {
+		if got := profiler.Histogram(stage).Count; got != sampled {
+			t.Errorf("%s: expected the %d sampled messages, got %d", stage, sampled, got)
+		}
+	}
+}
+
+func TestNodeRecordsBroadcastStage(t *testing.T) {
+	profiler := NewProfiler(1.0)
+	node := NewNode("profiled", 1)
+	node.SetProfiler(profiler)
+
+	for i := 0; i < 5; i++ {
+		if err := node.HandleMessage(context.Background(), Message{Content: "timed"}); err != nil {
+			t.Fatalf("Failed to handle message: %v", err)
+		}
+	}
+
+	h := profiler.Histogram(StageBroadcast)
+	if h.Count != 5 {
+		t.Errorf("Expected 5 broadcast samples, got %d", h.Count)
+	}
+
+	var bucketed uint64
+	for _, bucket := range h.Buckets {
+		bucketed += bucket.Count
+	}
+	if bucketed != h.Count {
+		t.Errorf("Bucket counts %d do not add up to %d", bucketed, h.Count)
+	}
+}
@@ -0,0 +1,120 @@
+package btree
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimitPolicy controls what the message loop does with inbound messages
+// that arrive faster than the node's rate limit
+type RateLimitPolicy int
+
+const (
+	// RateLimitBlock waits for a token before handling the message (the
+	// default), pushing back on the inbound channel
+	RateLimitBlock RateLimitPolicy = iota
+
+	// RateLimitDrop discards messages that arrive when no token is available
+	RateLimitDrop
+)
+
+// WithRateLimit caps inbound processing at rps messages per second using a
+// token bucket that holds up to one second of tokens, so bursts of up to rps
+// messages are handled immediately. Zero or negative rates disable limiting.
+func WithRateLimit(rps int) NodeOption {
+	return func(o *nodeOptions) {
+		o.rateLimit = rps
+	}
+}
+
+// WithRateLimitPolicy sets what happens to messages above the rate limit
+func WithRateLimitPolicy(policy RateLimitPolicy) NodeOption {
+	return func(o *nodeOptions) {
+		o.rateLimitPolicy = policy
+	}
+}
+
+// tokenBucket is a token bucket refilled continuously at rate tokens per second
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// newTokenBucket returns a full bucket refilled at rps tokens per second
+func newTokenBucket(rps int) *tokenBucket {
+	return &tokenBucket{
+		rate:   float64(rps),
+		burst:  float64(rps),
+		tokens: float64(rps),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// reserve takes a token if one is available and otherwise reports how long
+// until the next one will be
+func (b *tokenBucket) reserve() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// allow takes a token without waiting
+func (b *tokenBucket) allow() bool {
+	ok, _ := b.reserve()
+	return ok
+}
+
+// wait blocks until a token is taken or ctx is done
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		ok, delay := b.reserve()
+		if ok {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// admit applies the rate limit to msg and reports whether the message loop
+// should handle it
+func (n *Node) admit(msg Message) bool {
+	if n.rateLimiter == nil {
+		return true
+	}
+
+	if n.rateLimitPolicy == RateLimitDrop {
+		if n.rateLimiter.allow() {
+			return true
+		}
+		n.counters.rateLimited.Add(1)
+		n.log().Warn("Rate limit exceeded, dropping message", "id", msg.ID)
+		return false
+	}
+
+	return n.rateLimiter.wait(n.ctx) == nil
+}
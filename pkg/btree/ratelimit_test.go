@@ -0,0 +1,82 @@
+package btree
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRateLimitCapsProcessingRate(t *testing.T) {
+	const rps = 50
+	node := NewNodeWithOptions("limited", 1, WithRateLimit(rps))
+	node.Start()
+	defer node.Stop()
+
+	// One second of burst plus half a second above the limit
+	total := rps + rps/2
+	start := time.Now()
+	for i := 0; i < total; i++ {
+		node.GetInboundChannel() <- Message{ID: fmt.Sprintf("burst-%d", i), Content: "x"}
+	}
+
+	ch, _ := node.GetChildChannel(0)
+	for i := 0; i < total; i++ {
+		select {
+		case <-ch:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout after %d of %d messages", i, total)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("Expected processing to be capped near %d/s, handled %d messages in %v", rps, total, elapsed)
+	}
+}
+
+func TestRateLimitDropPolicy(t *testing.T) {
+	const rps = 10
+	node := NewNodeWithOptions("dropping", 1, WithRateLimit(rps), WithRateLimitPolicy(RateLimitDrop))
+
+	// Queue the burst before starting so it arrives all at once
+	for i := 0; i < 3*rps; i++ {
+		node.GetInboundChannel() <- Message{ID: fmt.Sprintf("flood-%d", i), Content: "x"}
+	}
+	node.Start()
+	defer node.Stop()
+
+	var stats NodeStats
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		stats = node.Stats()
+		if stats.Received+stats.RateLimited == 3*rps {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if stats.Received > rps+1 {
+		t.Errorf("Expected at most %d messages handled, got %d", rps+1, stats.Received)
+	}
+	if stats.RateLimited < 2*rps-1 {
+		t.Errorf("Expected at least %d messages dropped, got %d", 2*rps-1, stats.RateLimited)
+	}
+}
+
+func TestTokenBucketRefills(t *testing.T) {
+	now := time.Unix(0, 0)
+	bucket := newTokenBucket(2)
+	bucket.now = func() time.Time { return now }
+	bucket.last = now
+
+	if !bucket.allow() || !bucket.allow() {
+		t.Fatal("Expected a full bucket to allow a burst of 2")
+	}
+	if bucket.allow() {
+		t.Fatal("Expected an empty bucket to refuse")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if !bucket.allow() {
+		t.Error("Expected one token after half a second at 2/s")
+	}
+}
@@ -0,0 +1,77 @@
+package btree
+
+import (
+	"time"
+)
+
+// reachState aggregates the reach reports of a single tracked broadcast
+type reachState struct {
+	expected int // children the message was delivered to, -1 until known
+	received int // reports received from children
+	total    int // sum of the children's reach counts
+}
+
+// startReach registers a tracked message before it is broadcast, so reports
+// from fast children are never lost
+func (n *Node) startReach(id string) {
+	n.reachMu.Lock()
+	defer n.reachMu.Unlock()
+
+	n.reach[id] = &reachState{expected: -1}
+}
+
+// setReachExpected records how many children a tracked message reached
+func (n *Node) setReachExpected(id string, delivered int) {
+	n.reachMu.Lock()
+	state, ok := n.reach[id]
+	if !ok {
+		n.reachMu.Unlock()
+		return
+	}
+	state.expected = delivered
+	n.reachMu.Unlock()
+
+	n.completeReach(id)
+}
+
+// handleReachReport adds a child's reach count to the pending aggregation
+func (n *Node) handleReachReport(msg Message) {
+	n.reachMu.Lock()
+	state, ok := n.reach[msg.CorrelationID]
+	if !ok {
+		n.reachMu.Unlock()
+		n.log().Debug("Ignoring reach report for unknown message", "correlation", msg.CorrelationID)
+		return
+	}
+	state.received++
+	state.total += msg.ReachCount
+	n.reachMu.Unlock()
+
+	n.completeReach(msg.CorrelationID)
+}
+
+// completeReach reports upstream once every reached child has reported
+func (n *Node) completeReach(id string) {
+	n.reachMu.Lock()
+	state, ok := n.reach[id]
+	if !ok || state.expected < 0 || state.received < state.expected {
+		n.reachMu.Unlock()
+		return
+	}
+	delete(n.reach, id)
+	count := 1 + state.total
+	n.reachMu.Unlock()
+
+	n.reportReach(id, count)
+}
+
+// reportReach sends a reach report upstream
+func (n *Node) reportReach(id string, count int) {
+	n.log().Debug("Reporting reach", "reach", count, "id", id)
+	n.forwardUpstream(Message{
+		CorrelationID: id,
+		ReachCount:    count,
+		Source:        n.name,
+		Timestamp:     time.Now(),
+	})
+}
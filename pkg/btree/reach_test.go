@@ -0,0 +1,76 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBroadcastReachCount(t *testing.T) {
+	// Build a complete binary tree of 7 nodes: root, 2 internal, 4 leaves
+	nodes := make([]*Node, 7)
+	for i := range nodes {
+		if i < 3 {
+			nodes[i] = NewBinaryNode(fmt.Sprintf("node-%d", i))
+		} else {
+			nodes[i] = NewNode(fmt.Sprintf("node-%d", i), 0)
+		}
+		nodes[i].Start()
+		defer nodes[i].Stop()
+	}
+
+	// Wire children downward and reach reports upward
+	for parent := 0; parent < 3; parent++ {
+		for c := 0; c < 2; c++ {
+			child := nodes[2*parent+1+c]
+			childChannel, err := nodes[parent].GetChildChannel(c)
+			if err != nil {
+				t.Fatalf("Failed to get child channel: %v", err)
+			}
+
+			go func(src <-chan Message, dst chan<- Message) {
+				for msg := range src {
+					dst <- msg
+				}
+			}(childChannel, child.GetInboundChannel())
+
+			go func(src <-chan Message, dst chan<- Message) {
+				for msg := range src {
+					dst <- msg
+				}
+			}(child.GetUpstreamChannel(), nodes[parent].GetInboundChannel())
+		}
+	}
+
+	msg := NewMessage("How far did I go?", "reach-1")
+	msg.TrackReach = true
+	nodes[0].GetInboundChannel() <- msg
+
+	select {
+	case report := <-nodes[0].GetUpstreamChannel():
+		if report.CorrelationID != msg.ID {
+			t.Errorf("Expected report for %s, got %s", msg.ID, report.CorrelationID)
+		}
+		if report.ReachCount != 7 {
+			t.Errorf("Expected reach count 7, got %d", report.ReachCount)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for reach report at root")
+	}
+}
+
+func TestUntrackedMessageHasNoReachReport(t *testing.T) {
+	leaf := NewNode("leaf", 0)
+
+	err := leaf.HandleMessage(context.Background(), NewMessage("quiet", "quiet-1"))
+	if err != nil {
+		t.Fatalf("Failed to handle message: %v", err)
+	}
+
+	select {
+	case report := <-leaf.upstreamOut:
+		t.Errorf("Unexpected reach report: %+v", report)
+	default:
+	}
+}
@@ -0,0 +1,79 @@
+package btree
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrDuplicateName is returned when registering a node whose name is taken
+var ErrDuplicateName = errors.New("node name already registered")
+
+// Registry is an in-process directory of nodes by name. Registering through
+// it guarantees names are unique, so Source values and traces are unambiguous.
+type Registry struct {
+	mu    sync.RWMutex
+	nodes map[string]*Node
+}
+
+// NewRegistry creates an empty node registry
+func NewRegistry() *Registry {
+	return &Registry{nodes: make(map[string]*Node)}
+}
+
+// NewNode creates a node as NewNodeWithOptions does and registers it,
+// failing with ErrDuplicateName if the name is already taken
+func (r *Registry) NewNode(name string, numChildren int, opts ...NodeOption) (*Node, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, taken := r.nodes[name]; taken {
+		return nil, fmt.Errorf("%w: %q", ErrDuplicateName, name)
+	}
+
+	node := NewNodeWithOptions(name, numChildren, opts...)
+	r.nodes[name] = node
+	return node, nil
+}
+
+// Register adds an existing node, failing with ErrDuplicateName if its name
+// is already taken
+func (r *Registry) Register(node *Node) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, taken := r.nodes[node.name]; taken {
+		return fmt.Errorf("%w: %q", ErrDuplicateName, node.name)
+	}
+	r.nodes[node.name] = node
+	return nil
+}
+
+// Unregister removes the node with the given name, freeing the name
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.nodes, name)
+}
+
+// Lookup returns the node registered under name
+func (r *Registry) Lookup(name string) (*Node, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	node, ok := r.nodes[name]
+	return node, ok
+}
+
+// Names returns the registered names in sorted order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.nodes))
+	for name := range r.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,38 @@
+package btree
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRegistryRejectsDuplicateNames(t *testing.T) {
+	registry := NewRegistry()
+
+	first, err := registry.NewNode("worker", 2)
+	if err != nil {
+		t.Fatalf("First registration failed: %v", err)
+	}
+
+	if _, err := registry.NewNode("worker", 2); !errors.Is(err, ErrDuplicateName) {
+		t.Errorf("Expected ErrDuplicateName, got %v", err)
+	}
+	if err := registry.Register(NewNode("worker", 0)); !errors.Is(err, ErrDuplicateName) {
+		t.Errorf("Expected ErrDuplicateName from Register, got %v", err)
+	}
+
+	if node, ok := registry.Lookup("worker"); !ok || node != first {
+		t.Errorf("Expected lookup to return the first node, got %v, %v", node, ok)
+	}
+
+	// Unregistering frees the name
+	registry.Unregister("worker")
+	if _, ok := registry.Lookup("worker"); ok {
+		t.Error("Expected worker to be gone after Unregister")
+	}
+	if err := registry.Register(NewNode("worker", 0)); err != nil {
+		t.Errorf("Expected the freed name to register, got %v", err)
+	}
+	if names := registry.Names(); len(names) != 1 || names[0] != "worker" {
+		t.Errorf("Expected [worker], got %v", names)
+	}
+}
@@ -0,0 +1,133 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// bufferedMessage is a message retained by a replay buffer with the time it
+// was added
+type bufferedMessage struct {
+	msg   Message
+	added time.Time
+}
+
+// ReplayBuffer retains recently handled messages so they can be replayed to
+// children that join late. It keeps at most size messages and, when a window
+// is set, only messages added within that window.
+type ReplayBuffer struct {
+	mu       sync.Mutex
+	size     int
+	window   time.Duration
+	now      func() time.Time
+	messages []bufferedMessage
+}
+
+// NewReplayBuffer creates a replay buffer holding up to size messages
+func NewReplayBuffer(size int) *ReplayBuffer {
+	return &ReplayBuffer{
+		size: size,
+		now:  time.Now,
+	}
+}
+
+// SetWindow limits retention to messages added within the last window;
+// zero keeps messages until they are pushed out by size
+func (b *ReplayBuffer) SetWindow(window time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.window = window
+}
+
+// SetClock replaces the clock used to timestamp and expire messages
+func (b *ReplayBuffer) SetClock(now func() time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.now = now
+}
+
+// Add retains a message, evicting the oldest one when the buffer is full
+func (b *ReplayBuffer) Add(msg Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.size <= 0 {
+		return
+	}
+
+	b.messages = append(b.messages, bufferedMessage{msg: msg, added: b.now()})
+	if len(b.messages) > b.size {
+		b.messages = b.messages[len(b.messages)-b.size:]
+	}
+	b.evictExpired()
+}
+
+// Messages returns the retained messages, oldest first
+func (b *ReplayBuffer) Messages() []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.evictExpired()
+
+	messages := make([]Message, len(b.messages))
+	for i, buffered := range b.messages {
+		messages[i] = buffered.msg
+	}
+	return messages
+}
+
+// Len returns the number of retained messages
+func (b *ReplayBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.evictExpired()
+	return len(b.messages)
+}
+
+// evictExpired drops messages older than the window. Callers must hold b.mu.
+func (b *ReplayBuffer) evictExpired() {
+	if b.window <= 0 {
+		return
+	}
+
+	cutoff := b.now().Add(-b.window)
+	expired := 0
+	for expired < len(b.messages) && b.messages[expired].added.Before(cutoff) {
+		expired++
+	}
+	b.messages = b.messages[expired:]
+}
+
+// SetReplayBuffer retains handled messages in buf for late-joining children;
+// nil disables retention
+func (n *Node) SetReplayBuffer(buf *ReplayBuffer) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.replay = buf
+}
+
+// ReplayToChild sends the retained history to the child at index, oldest
+// first, and returns how many messages were replayed
+func (n *Node) ReplayToChild(ctx context.Context, index int) (int, error) {
+	n.mu.RLock()
+	buf := n.replay
+	n.mu.RUnlock()
+
+	if buf == nil {
+		return 0, fmt.Errorf("replay buffer not enabled")
+	}
+
+	replayed := 0
+	for _, msg := range buf.Messages() {
+		if err := n.SendToChild(ctx, index, msg); err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+
+	n.log().Info("Replayed buffered messages", "messages", replayed, "child", index)
+	return replayed, nil
+}
@@ -0,0 +1,82 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock for tests
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestReplayBufferSizeBound(t *testing.T) {
+	buf := NewReplayBuffer(3)
+	for i := 0; i < 5; i++ {
+		buf.Add(Message{ID: fmt.Sprintf("m-%d", i)})
+	}
+
+	messages := buf.Messages()
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 retained messages, got %d", len(messages))
+	}
+	for i, msg := range messages {
+		if expected := fmt.Sprintf("m-%d", i+2); msg.ID != expected {
+			t.Errorf("Expected %s, got %s", expected, msg.ID)
+		}
+	}
+}
+
+func TestLateJoinerGetsOnlyInWindowMessages(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	buf := NewReplayBuffer(100)
+	buf.SetWindow(10 * time.Second)
+	buf.SetClock(clock.Now)
+
+	parent := NewNode("parent", 1)
+	parent.SetReplayBuffer(buf)
+	ctx := context.Background()
+
+	// Two old messages, then the clock moves past the window for them
+	parent.HandleMessage(ctx, NewMessage("old", "old-1"))
+	clock.Advance(4 * time.Second)
+	parent.HandleMessage(ctx, NewMessage("old", "old-2"))
+	clock.Advance(5 * time.Second)
+	parent.HandleMessage(ctx, NewMessage("recent", "new-1"))
+	clock.Advance(3 * time.Second)
+	parent.HandleMessage(ctx, NewMessage("recent", "new-2"))
+	clock.Advance(6 * time.Second)
+
+	// Drain live deliveries, then attach the late child
+	childChannel, _ := parent.GetChildChannel(0)
+	for len(childChannel) > 0 {
+		<-childChannel
+	}
+
+	replayed, err := parent.ReplayToChild(ctx, 0)
+	if err != nil {
+		t.Fatalf("Failed to replay: %v", err)
+	}
+	if replayed != 2 {
+		t.Fatalf("Expected 2 replayed messages, got %d", replayed)
+	}
+
+	for _, expected := range []string{"new-1", "new-2"} {
+		if msg := <-childChannel; msg.ID != expected {
+			t.Errorf("Expected %s, got %s", expected, msg.ID)
+		}
+	}
+}
+
+func TestReplayWithoutBuffer(t *testing.T) {
+	parent := NewNode("parent", 1)
+	if _, err := parent.ReplayToChild(context.Background(), 0); err == nil {
+		t.Error("Expected error when no replay buffer is set")
+	}
+}
@@ -0,0 +1,51 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+)
+
+// SendRoundRobin sends msg to exactly one child, taking turns so that
+// successive messages are spread across the children, for distributing work
+// instead of broadcasting it. Removed children and children whose channel is
+// full are skipped in favour of the next one; when none can take the message
+// it returns ErrNoChildAvailable. Once the node has been stopped it returns
+// ErrNodeStopped without sending.
+func (n *Node) SendRoundRobin(ctx context.Context, msg Message) error {
+	if err := n.stopped(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	n.roundRobinMu.Lock()
+	defer n.roundRobinMu.Unlock()
+
+	count := len(n.childrenOut)
+	for k := 0; k < count; k++ {
+		i := (n.roundRobin + k) % count
+		childOut := n.childrenOut[i]
+		if childOut == nil {
+			continue
+		}
+
+		select {
+		case childOut <- msg:
+			n.roundRobin = (i + 1) % count
+			n.log().Debug("Round-robin send to child successful", "child", i)
+			n.counters.broadcast.Add(1)
+			return nil
+		default:
+			n.log().Debug("Skipping full child for round-robin send", "child", i)
+		}
+	}
+
+	if n.activeChildren() == 0 {
+		return fmt.Errorf("%w: node has no children", ErrNoChildAvailable)
+	}
+	return fmt.Errorf("%w: all %d children are full", ErrNoChildAvailable, n.activeChildren())
+}
@@ -0,0 +1,90 @@
+package btree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSendRoundRobinRotates(t *testing.T) {
+	node := NewNodeWithOptions("balancer", 3, WithBufferSize(20))
+	ctx := context.Background()
+
+	const count = 12
+	for i := 0; i < count; i++ {
+		if err := node.SendRoundRobin(ctx, Message{ID: fmt.Sprintf("job-%d", i)}); err != nil {
+			t.Fatalf("Failed to send job %d: %v", i, err)
+		}
+	}
+
+	for child := 0; child < 3; child++ {
+		got := queuedIDs(childChannel(t, node, child))
+		if len(got) != count/3 {
+			t.Fatalf("Child %d: expected %d jobs, got %v", child, count/3, got)
+		}
+		for k, id := range got {
+			if want := fmt.Sprintf("job-%d", child+3*k); id != want {
+				t.Errorf("Child %d: expected %s at position %d, got %s", child, want, k, id)
+			}
+		}
+	}
+}
+
+func TestSendRoundRobinSkipsFullAndRemovedChildren(t *testing.T) {
+	node := NewNodeWithOptions("balancer", 3, WithBufferSize(1))
+	ctx := context.Background()
+
+	if err := node.RemoveChild(1); err != nil {
+		t.Fatalf("Failed to remove child: %v", err)
+	}
+
+	// Child 0 takes the first job and is then full, so child 2 takes the rest
+	for i := 0; i < 2; i++ {
+		if err := node.SendRoundRobin(ctx, Message{ID: fmt.Sprintf("job-%d", i)}); err != nil {
+			t.Fatalf("Failed to send job %d: %v", i, err)
+		}
+	}
+	if got := queuedIDs(childChannel(t, node, 0)); len(got) != 1 || got[0] != "job-0" {
+		t.Errorf("Expected child 0 to hold job-0, got %v", got)
+	}
+	if got := queuedIDs(childChannel(t, node, 2)); len(got) != 1 || got[0] != "job-1" {
+		t.Errorf("Expected child 2 to hold job-1, got %v", got)
+	}
+}
+
+func TestSendRoundRobinWithoutAvailableChild(t *testing.T) {
+	ctx := context.Background()
+
+	leaf := NewNode("leaf", 0)
+	if err := leaf.SendRoundRobin(ctx, Message{ID: "job"}); !errors.Is(err, ErrNoChildAvailable) {
+		t.Errorf("Expected ErrNoChildAvailable for a leaf, got %v", err)
+	}
+
+	node := NewNodeWithOptions("balancer", 2, WithBufferSize(1))
+	for i := 0; i < 2; i++ {
+		if err := node.SendRoundRobin(ctx, Message{ID: fmt.Sprintf("job-%d", i)}); err != nil {
+			t.Fatalf("Failed to send job %d: %v", i, err)
+		}
+	}
+	if err := node.SendRoundRobin(ctx, Message{ID: "job-2"}); !errors.Is(err, ErrNoChildAvailable) {
+		t.Errorf("Expected ErrNoChildAvailable with full children, got %v", err)
+	}
+
+	node.Stop()
+	if err := node.SendRoundRobin(ctx, Message{ID: "job-3"}); !errors.Is(err, ErrNodeStopped) {
+		t.Errorf("Expected ErrNodeStopped, got %v", err)
+	}
+}
+
+// childChannel returns the channel of child index, failing the test if it
+// does not exist
+func childChannel(t *testing.T, node *Node, index int) <-chan Message {
+	t.Helper()
+
+	child, err := node.GetChildChannel(index)
+	if err != nil {
+		t.Fatalf("Failed to get child channel %d: %v", index, err)
+	}
+	return child
+}
@@ -0,0 +1,24 @@
+package btree
+
+import "context"
+
+// HandlerFunc handles a message of a registered type
+type HandlerFunc func(ctx context.Context, msg Message) error
+
+// HandleType registers fn to handle messages of type t. Such messages are
+// passed to fn instead of being broadcast; fn may forward them itself with
+// BroadcastToChildren or SendToChild. Messages of unregistered types are
+// broadcast. A nil fn removes the handler.
+func (n *Node) HandleType(t string, fn HandlerFunc) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if fn == nil {
+		delete(n.typeHandlers, t)
+		return
+	}
+	if n.typeHandlers == nil {
+		n.typeHandlers = make(map[string]HandlerFunc)
+	}
+	n.typeHandlers[t] = fn
+}
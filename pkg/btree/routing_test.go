@@ -0,0 +1,53 @@
+package btree
+
+import (
+	"context"
+	"testing"
+)
+
+func TestControlMessageConsumedLocally(t *testing.T) {
+	node := NewNode("router", 1)
+
+	var consumed []Message
+	node.HandleType(TypeControl, func(ctx context.Context, msg Message) error {
+		consumed = append(consumed, msg)
+		return nil
+	})
+
+	ctx := context.Background()
+	control := NewMessage("pause", "ctl-1")
+	control.Type = TypeControl
+	if err := node.HandleMessage(ctx, control); err != nil {
+		t.Fatalf("Failed to handle control message: %v", err)
+	}
+	if err := node.HandleMessage(ctx, NewMessage("payload", "data-1")); err != nil {
+		t.Fatalf("Failed to handle data message: %v", err)
+	}
+
+	if len(consumed) != 1 || consumed[0].ID != "ctl-1" {
+		t.Fatalf("Expected the control message to be consumed, got %v", consumed)
+	}
+
+	childChannel, _ := node.GetChildChannel(0)
+	if len(childChannel) != 1 {
+		t.Fatalf("Expected only the data message to be forwarded, got %d", len(childChannel))
+	}
+	if msg := <-childChannel; msg.ID != "data-1" {
+		t.Errorf("Expected data-1 to be forwarded, got %s", msg.ID)
+	}
+}
+
+func TestUnregisteredTypeIsBroadcast(t *testing.T) {
+	node := NewNode("router", 1)
+	node.HandleType(TypeControl, func(ctx context.Context, msg Message) error { return nil })
+	node.HandleType(TypeControl, nil)
+
+	msg := NewMessage("no longer handled", "ctl-2")
+	msg.Type = TypeControl
+	node.HandleMessage(context.Background(), msg)
+
+	childChannel, _ := node.GetChildChannel(0)
+	if len(childChannel) != 1 {
+		t.Errorf("Expected message of an unregistered type to be broadcast")
+	}
+}
@@ -0,0 +1,88 @@
+package btree
+
+import "sync"
+
+// SequenceGap describes a message whose sequence number did not follow the
+// previous one from the same source. Received above Expected means messages
+// were lost in between; below Expected means it arrived out of order.
+type SequenceGap struct {
+	Source   string
+	Expected uint64
+	Received uint64
+}
+
+// OutOfOrder reports whether the message arrived after a later one
+func (g SequenceGap) OutOfOrder() bool {
+	return g.Received < g.Expected
+}
+
+// Missing returns how many messages were skipped before this one
+func (g SequenceGap) Missing() uint64 {
+	if g.OutOfOrder() {
+		return 0
+	}
+	return g.Received - g.Expected
+}
+
+// OnSequenceGap registers fn to be called whenever a downstream message
+// arrives with a sequence number that does not follow the last one from its
+// source. fn runs on the message loop and must not block; nil removes it.
+func (n *Node) OnSequenceGap(fn func(SequenceGap)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.onSequenceGap = fn
+}
+
+// sequenceTracker remembers the highest sequence number seen per source
+type sequenceTracker struct {
+	mu   sync.Mutex
+	last map[string]uint64
+}
+
+// newSequenceTracker returns an empty tracker
+func newSequenceTracker() *sequenceTracker {
+	return &sequenceTracker{last: make(map[string]uint64)}
+}
+
+// observe records seq from source and returns the gap it reveals, if any.
+// The first message from a source only sets the baseline.
+func (s *sequenceTracker) observe(source string, seq uint64) (SequenceGap, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last, known := s.last[source]
+	if seq > last {
+		s.last[source] = seq
+	}
+	if !known || seq == last+1 {
+		return SequenceGap{}, false
+	}
+	return SequenceGap{Source: source, Expected: last + 1, Received: seq}, true
+}
+
+// checkSequence flags msg if it does not follow the previous message from
+// its source; unsequenced messages are not checked
+func (n *Node) checkSequence(msg Message) {
+	if msg.Seq == 0 {
+		return
+	}
+
+	gap, found := n.sequences.observe(msg.Source, msg.Seq)
+	if !found {
+		return
+	}
+
+	n.counters.sequenceGaps.Add(1)
+	if gap.OutOfOrder() {
+		n.log().Warn("Out-of-order message", "source", gap.Source, "expected", gap.Expected, "seq", gap.Received, "id", msg.ID)
+	} else {
+		n.log().Warn("Sequence gap detected", "source", gap.Source, "expected", gap.Expected, "seq", gap.Received, "missing", gap.Missing(), "id", msg.ID)
+	}
+
+	n.mu.RLock()
+	onGap := n.onSequenceGap
+	n.mu.RUnlock()
+	if onGap != nil {
+		onGap(gap)
+	}
+}
@@ -0,0 +1,72 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestHandleMessageAssignsSequence(t *testing.T) {
+	node := NewNode("parent", 1)
+	child, _ := node.GetChildChannel(0)
+
+	for i := 1; i <= 3; i++ {
+		if err := node.HandleMessage(context.Background(), Message{Content: "m", ID: fmt.Sprintf("s-%d", i)}); err != nil {
+			t.Fatalf("HandleMessage failed: %v", err)
+		}
+		if msg := <-child; msg.Seq != uint64(i) || msg.Source != "parent" {
+			t.Errorf("Expected seq %d from parent, got seq %d from %q", i, msg.Seq, msg.Source)
+		}
+	}
+}
+
+func TestSequenceGapsDetected(t *testing.T) {
+	node := NewNode("child", 0)
+
+	var mu sync.Mutex
+	var gaps []SequenceGap
+	node.OnSequenceGap(func(gap SequenceGap) {
+		mu.Lock()
+		gaps = append(gaps, gap)
+		mu.Unlock()
+	})
+
+	// 3 is lost and 5 arrives after 6; "other" is tracked independently
+	deliveries := []struct {
+		source string
+		seq    uint64
+	}{
+		{"parent", 1}, {"parent", 2}, {"parent", 4}, {"parent", 6}, {"parent", 5},
+		{"other", 10}, {"other", 11},
+	}
+	for i, d := range deliveries {
+		msg := Message{Content: "m", ID: fmt.Sprintf("g-%d", i), Source: d.source, Seq: d.seq}
+		if err := node.HandleMessage(context.Background(), msg); err != nil {
+			t.Fatalf("HandleMessage failed: %v", err)
+		}
+	}
+
+	expected := []SequenceGap{
+		{Source: "parent", Expected: 3, Received: 4},
+		{Source: "parent", Expected: 5, Received: 6},
+		{Source: "parent", Expected: 7, Received: 5},
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(gaps) != len(expected) {
+		t.Fatalf("Expected %d gaps, got %+v", len(expected), gaps)
+	}
+	for i, gap := range gaps {
+		if gap != expected[i] {
+			t.Errorf("Gap %d: expected %+v, got %+v", i, expected[i], gap)
+		}
+	}
+	if !gaps[2].OutOfOrder() || gaps[0].OutOfOrder() || gaps[0].Missing() != 1 {
+		t.Errorf("Unexpected classification: %+v", gaps)
+	}
+	if stats := node.Stats(); stats.SequenceGaps != 3 {
+		t.Errorf("Expected 3 sequence gaps in stats, got %d", stats.SequenceGaps)
+	}
+}
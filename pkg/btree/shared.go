@@ -0,0 +1,145 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// SharedNode is a zero-copy counterpart of Node's broadcast for
+// high-throughput trees: every child receives the same *Message instead of
+// its own copy of the Message value, so a broadcast costs one pointer per
+// child whatever the size of the message.
+//
+// A broadcast message is shared by every child and must be treated as
+// immutable from the moment it is passed to Broadcast: neither the sender
+// nor any handler may modify it, its Payload or its Metadata. A handler that
+// needs to change a message must copy it first, e.g. with WithMetadata,
+// which returns a new Message.
+type SharedNode struct {
+	name     string
+	children []chan *Message
+	mu       sync.RWMutex
+	mode     DeliveryMode
+	closed   bool
+
+	// Delivery counters, as in NodeStats
+	broadcast atomic.Uint64
+	dropped   atomic.Uint64
+
+	// logger receives the node's structured log records
+	logger *slog.Logger
+}
+
+// NewSharedNode creates a shared broadcaster with numChildren children. Of
+// the NodeOptions only the buffer size and logger apply.
+func NewSharedNode(name string, numChildren int, opts ...NodeOption) *SharedNode {
+	options := defaultNodeOptions()
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	children := make([]chan *Message, numChildren)
+	for i := range children {
+		children[i] = make(chan *Message, options.bufferSize)
+	}
+
+	logger := options.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &SharedNode{
+		name:     name,
+		children: children,
+		logger:   logger.With("node", name),
+	}
+}
+
+// Name returns the node's name
+func (s *SharedNode) Name() string {
+	return s.name
+}
+
+// GetNumChildren returns the number of children the node broadcasts to
+func (s *SharedNode) GetNumChildren() int {
+	return len(s.children)
+}
+
+// GetChildChannel returns the channel of shared messages for the child at index
+func (s *SharedNode) GetChildChannel(index int) (<-chan *Message, error) {
+	if index < 0 || index >= len(s.children) {
+		return nil, fmt.Errorf("%w: %d not in [0, %d)", ErrChildIndexOutOfRange, index, len(s.children))
+	}
+	return s.children[index], nil
+}
+
+// SetDeliveryMode sets how broadcasts handle full child channels.
+// DeliveryDeadline behaves like DeliveryBlocking bounded by the context.
+func (s *SharedNode) SetDeliveryMode(mode DeliveryMode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mode = mode
+}
+
+// Broadcast hands msg to every child. The caller gives up ownership of msg:
+// it must not be modified afterwards.
+func (s *SharedNode) Broadcast(ctx context.Context, msg *Message) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return fmt.Errorf("node %s is closed", s.name)
+	}
+
+	for i, childOut := range s.children {
+		if s.mode != DeliveryBestEffort {
+			select {
+			case childOut <- msg:
+				s.broadcast.Add(1)
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			continue
+		}
+
+		select {
+		case childOut <- msg:
+			s.broadcast.Add(1)
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			s.logger.Warn("Child channel full, skipping broadcast", "child", i, "id", msg.ID)
+			s.dropped.Add(1)
+		}
+	}
+	return nil
+}
+
+// Broadcasts returns how many deliveries to child channels succeeded
+func (s *SharedNode) Broadcasts() uint64 {
+	return s.broadcast.Load()
+}
+
+// Dropped returns how many deliveries were skipped because a child channel
+// was full
+func (s *SharedNode) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close closes every child channel once buffered messages are drained by
+// their readers; later broadcasts fail. It is safe to call more than once.
+func (s *SharedNode) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	for _, childOut := range s.children {
+		close(childOut)
+	}
+}
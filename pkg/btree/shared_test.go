@@ -0,0 +1,178 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSharedNodeHandsOutSamePointer(t *testing.T) {
+	node := NewSharedNode("shared", 3)
+	defer node.Close()
+
+	msg := NewMessage("payload", "s-1")
+	if err := node.Broadcast(context.Background(), &msg); err != nil {
+		t.Fatalf("Broadcast failed: %v", err)
+	}
+
+	for i := 0; i < node.GetNumChildren(); i++ {
+		ch, err := node.GetChildChannel(i)
+		if err != nil {
+			t.Fatalf("Failed to get child %d: %v", i, err)
+		}
+		if got := <-ch; got != &msg {
+			t.Errorf("Child %d: expected the broadcast pointer, got a different message", i)
+		}
+	}
+	if got := node.Broadcasts(); got != 3 {
+		t.Errorf("Expected 3 deliveries, got %d", got)
+	}
+}
+
+func TestSharedNodeBestEffortDropsWhenFull(t *testing.T) {
+	node := NewSharedNode("shared", 1, WithBufferSize(1))
+	defer node.Close()
+
+	for i := 0; i < 3; i++ {
+		msg := NewMessage("payload", fmt.Sprintf("s-%d", i))
+		if err := node.Broadcast(context.Background(), &msg); err != nil {
+			t.Fatalf("Broadcast failed: %v", err)
+		}
+	}
+	if node.Broadcasts() != 1 || node.Dropped() != 2 {
+		t.Errorf("Expected 1 delivery and 2 drops, got %d and %d", node.Broadcasts(), node.Dropped())
+	}
+}
+
+func TestSharedNodeCloseEndsChildren(t *testing.T) {
+	node := NewSharedNode("shared", 1)
+	node.Close()
+	node.Close()
+
+	ch, _ := node.GetChildChannel(0)
+	if _, ok := <-ch; ok {
+		t.Error("Expected the child channel to be closed")
+	}
+	msg := NewMessage("late", "s-late")
+	if err := node.Broadcast(context.Background(), &msg); err == nil {
+		t.Error("Expected broadcasting on a closed node to fail")
+	}
+	if _, err := node.GetChildChannel(1); err == nil {
+		t.Error("Expected an out-of-range child to fail")
+	}
+}
+
+// TestSharedNodeConcurrentReaders has every child read the shared messages
+// concurrently; run with -race to check that sharing read-only messages is
+// free of data races
+func TestSharedNodeConcurrentReaders(t *testing.T) {
+	const children, total = 8, 200
+	node := NewSharedNode("shared", children)
+	node.SetDeliveryMode(DeliveryBlocking)
+
+	var wg sync.WaitGroup
+	sums := make([]int, children)
+	for i := 0; i < children; i++ {
+		ch, _ := node.GetChildChannel(i)
+		wg.Add(1)
+		go func(i int, ch <-chan *Message) {
+			defer wg.Done()
+			for msg := range ch {
+				sums[i] += len(msg.Content) + len(msg.GetMetadata("trace"))
+			}
+		}(i, ch)
+	}
+
+	for i := 0; i < total; i++ {
+		msg := NewMessage(strings.Repeat("x", i), fmt.Sprintf("s-%d", i)).WithMetadata("trace", "t")
+		if err := node.Broadcast(context.Background(), &msg); err != nil {
+			t.Fatalf("Broadcast failed: %v", err)
+		}
+	}
+	node.Close()
+	wg.Wait()
+
+	want := total*(total-1)/2 + total
+	for i, sum := range sums {
+		if sum != want {
+			t.Errorf("Child %d read %d bytes, want %d", i, sum, want)
+		}
+	}
+}
+
+// benchmarkContent is large enough that copying messages would show
+var benchmarkContent = strings.Repeat("x", 64<<10)
+
+// drainChildren reads every child channel until it is closed
+func drainChildren[T any](channels []<-chan T) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	for _, ch := range channels {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range ch {
+			}
+		}()
+	}
+	return &wg
+}
+
+func BenchmarkBroadcastValue(b *testing.B) {
+	for _, fanout := range []int{2, 4, 16} {
+		b.Run(fmt.Sprintf("fanout-%d", fanout), func(b *testing.B) {
+			node := NewNode("bench", fanout)
+			node.SetDeliveryMode(DeliveryBlocking)
+
+			channels := make([]<-chan Message, fanout)
+			for i := range channels {
+				channels[i], _ = node.GetChildChannel(i)
+			}
+			drained := drainChildren(channels)
+
+			msg := NewMessage(benchmarkContent, "bench")
+			ctx := context.Background()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := node.BroadcastToChildren(ctx, msg); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+
+			for i := 0; i < fanout; i++ {
+				node.RemoveChild(i)
+			}
+			drained.Wait()
+		})
+	}
+}
+
+func BenchmarkBroadcastPointer(b *testing.B) {
+	for _, fanout := range []int{2, 4, 16} {
+		b.Run(fmt.Sprintf("fanout-%d", fanout), func(b *testing.B) {
+			node := NewSharedNode("bench", fanout)
+			node.SetDeliveryMode(DeliveryBlocking)
+
+			channels := make([]<-chan *Message, fanout)
+			for i := range channels {
+				channels[i], _ = node.GetChildChannel(i)
+			}
+			drained := drainChildren(channels)
+
+			msg := NewMessage(benchmarkContent, "bench")
+			ctx := context.Background()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := node.Broadcast(ctx, &msg); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+
+			node.Close()
+			drained.Wait()
+		})
+	}
+}
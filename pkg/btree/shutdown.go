@@ -0,0 +1,93 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// drainPollInterval is how often Shutdown checks whether children have
+// consumed their queued messages
+const drainPollInterval = 10 * time.Millisecond
+
+// StopSummary reports the messages in flight when a node stopped
+type StopSummary struct {
+	Inbound   int // Messages still queued on the inbound channel
+	Pending   int // Messages queued for children but not yet consumed
+	Drained   int // Queued inbound messages handled by Shutdown before stopping
+	Discarded int // Messages abandoned by the stop, Inbound plus Pending
+}
+
+// Shutdown stops the node gracefully: it stops reading new inbound messages,
+// handles those already queued, and waits for children to consume their
+// pending messages before tearing down. If ctx ends first the node is
+// stopped anyway and an error reports how many messages were left behind.
+func (n *Node) Shutdown(ctx context.Context) error {
+	defer n.cancel()
+
+	n.drainOnce.Do(func() { close(n.draining) })
+
+	if n.started.Load() {
+		select {
+		case <-n.drained:
+		case <-ctx.Done():
+			return fmt.Errorf("shutdown interrupted while draining inbound messages: %w", ctx.Err())
+		}
+	} else {
+		n.drainInbound()
+	}
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		pending := n.pendingChildMessages()
+		if pending == 0 {
+			n.log().Info("Node drained and stopped")
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("shutdown interrupted with %d messages pending for children: %w", pending, ctx.Err())
+		}
+	}
+}
+
+// drainInbound handles every message already queued on the inbound channel
+func (n *Node) drainInbound() {
+	for {
+		select {
+		case msg := <-n.inbound:
+			n.handleInbound(msg)
+			n.counters.drained.Add(1)
+		default:
+			return
+		}
+	}
+}
+
+// pendingChildMessages counts messages queued but not yet consumed by children
+func (n *Node) pendingChildMessages() int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	pending := 0
+	for _, ch := range n.childrenOut {
+		pending += len(ch)
+	}
+	return pending
+}
+
+// stopSummary counts the messages left in the node's channels. Producers may
+// still be sending, so the counts are a snapshot taken as the node stopped.
+func (n *Node) stopSummary() StopSummary {
+	summary := StopSummary{
+		Inbound: len(n.inbound),
+		Pending: n.pendingChildMessages(),
+		Drained: int(n.counters.drained.Load()),
+	}
+	summary.Discarded = summary.Inbound + summary.Pending
+	return summary
+}
@@ -0,0 +1,114 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShutdownDrainsQueuedMessages(t *testing.T) {
+	node := NewNode("draining", 2)
+
+	// Queue messages before the loop starts so they are all pending
+	const total = 20
+	for i := 0; i < total; i++ {
+		node.GetInboundChannel() <- NewMessage("queued", fmt.Sprintf("q-%d", i))
+	}
+	node.Start()
+
+	// Slow children consume their channels while shutdown is in progress
+	var wg sync.WaitGroup
+	counts := make([]int, 2)
+	for i := 0; i < 2; i++ {
+		ch, _ := node.GetChildChannel(i)
+		wg.Add(1)
+		go func(i int, ch <-chan Message) {
+			defer wg.Done()
+			for {
+				select {
+				case <-ch:
+					counts[i]++
+					time.Sleep(time.Millisecond)
+				case <-time.After(200 * time.Millisecond):
+					return
+				}
+			}
+		}(i, ch)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := node.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	if pending := node.pendingChildMessages(); pending != 0 {
+		t.Errorf("Expected no pending child messages after shutdown, got %d", pending)
+	}
+
+	wg.Wait()
+	for i, count := range counts {
+		if count != total {
+			t.Errorf("Expected child %d to receive %d messages, got %d", i, total, count)
+		}
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	node := NewNode("stuck", 1)
+	node.Start()
+
+	// Nobody consumes the child channel, so the drain cannot finish
+	node.GetInboundChannel() <- NewMessage("stuck", "s-1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := node.Shutdown(ctx); err == nil {
+		t.Error("Expected shutdown to report pending messages at the deadline")
+	}
+}
+
+func TestStopReportsBufferedMessages(t *testing.T) {
+	node := NewNode("stopping", 2)
+
+	// Three messages wait in each child channel and five on the inbound
+	// channel of a node whose loop never ran
+	for i := 0; i < 3; i++ {
+		if err := node.HandleMessage(context.Background(), NewMessage("queued", fmt.Sprintf("c-%d", i))); err != nil {
+			t.Fatalf("HandleMessage failed: %v", err)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		node.GetInboundChannel() <- NewMessage("queued", fmt.Sprintf("in-%d", i))
+	}
+
+	summary := node.Stop()
+	want := StopSummary{Inbound: 5, Pending: 6, Drained: 0, Discarded: 11}
+	if summary != want {
+		t.Errorf("Expected %+v, got %+v", want, summary)
+	}
+}
+
+func TestStopAfterShutdownReportsDrained(t *testing.T) {
+	node := NewNode("drained", 1)
+
+	const total = 4
+	for i := 0; i < total; i++ {
+		node.GetInboundChannel() <- NewMessage("queued", fmt.Sprintf("d-%d", i))
+	}
+
+	// The child never reads, so the drained messages stay pending
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := node.Shutdown(ctx); err == nil {
+		t.Fatal("Expected shutdown to time out with messages pending for the child")
+	}
+
+	summary := node.Stop()
+	want := StopSummary{Inbound: 0, Pending: total, Drained: total, Discarded: total}
+	if summary != want {
+		t.Errorf("Expected %+v, got %+v", want, summary)
+	}
+}
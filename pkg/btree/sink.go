@@ -0,0 +1,54 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+)
+
+// SinkMode controls which messages a node passes to its sink
+type SinkMode int
+
+const (
+	// SinkLeaf passes messages to the sink only while the node has no
+	// children (the default)
+	SinkLeaf SinkMode = iota
+
+	// SinkAlways passes every message to the sink after broadcasting it
+	SinkAlways
+)
+
+// SetSink sets the function that consumes the messages a leaf would
+// otherwise discard, such as writing them to a database, a file or stdout.
+// It receives each message after middleware, once it has been broadcast.
+// A sink error is returned by HandleMessage, so it is logged and reported
+// upstream as a NACK. A nil fn removes the sink.
+func (n *Node) SetSink(fn HandlerFunc) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sink = fn
+}
+
+// SetSinkMode sets which messages are passed to the sink
+func (n *Node) SetSinkMode(mode SinkMode) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sinkMode = mode
+}
+
+// runSink passes msg to the node's sink if its mode selects the message
+func (n *Node) runSink(ctx context.Context, msg Message) error {
+	n.mu.RLock()
+	sink := n.sink
+	selected := n.sinkMode == SinkAlways || n.activeChildren() == 0
+	n.mu.RUnlock()
+
+	if sink == nil || !selected {
+		return nil
+	}
+
+	if err := sink(ctx, msg); err != nil {
+		n.counters.sinkErrors.Add(1)
+		return fmt.Errorf("sink failed on message %s: %w", msg.ID, err)
+	}
+	return nil
+}
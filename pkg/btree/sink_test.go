@@ -0,0 +1,117 @@
+package btree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLeafSinkReceivesEveryMessage(t *testing.T) {
+	tree, err := BuildTree(2, 2)
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	defer tree.Stop()
+
+	var mu sync.Mutex
+	sunk := make(map[string][]string)
+	for _, node := range tree.Nodes() {
+		name := node.Name()
+		node.SetSink(func(ctx context.Context, msg Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			sunk[name] = append(sunk[name], msg.ID)
+			return nil
+		})
+	}
+
+	const total = 3
+	for i := 0; i < total; i++ {
+		tree.Root().GetInboundChannel() <- NewMessage("to the sinks", fmt.Sprintf("s-%d", i))
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		done := len(sunk["root.0"]) == total && len(sunk["root.1"]) == total
+		mu.Unlock()
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timeout waiting for the leaf sinks, got %v", sunk)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, leaf := range []string{"root.0", "root.1"} {
+		for i, id := range sunk[leaf] {
+			if want := fmt.Sprintf("s-%d", i); id != want {
+				t.Errorf("%s: expected %s at position %d, got %s", leaf, want, i, id)
+			}
+		}
+	}
+	if len(sunk["root"]) != 0 {
+		t.Errorf("Expected the root's leaf-only sink not to run, got %v", sunk["root"])
+	}
+}
+
+func TestSinkAlwaysRunsWithChildren(t *testing.T) {
+	node := NewNode("tee", 1)
+	child, _ := node.GetChildChannel(0)
+
+	var sunk []string
+	node.SetSink(func(ctx context.Context, msg Message) error {
+		sunk = append(sunk, msg.ID)
+		return nil
+	})
+	node.SetSinkMode(SinkAlways)
+
+	if err := node.HandleMessage(context.Background(), NewMessage("both", "tee-1")); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+
+	select {
+	case msg := <-child:
+		if msg.ID != "tee-1" {
+			t.Errorf("Expected tee-1 to be broadcast, got %s", msg.ID)
+		}
+	default:
+		t.Error("Expected the message to be broadcast as well")
+	}
+	if len(sunk) != 1 || sunk[0] != "tee-1" {
+		t.Errorf("Expected the sink to receive tee-1, got %v", sunk)
+	}
+}
+
+func TestSinkErrorIsSurfaced(t *testing.T) {
+	tree, err := BuildTree(2, 1)
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	defer tree.Stop()
+
+	errDiskFull := errors.New("disk full")
+	leaf := tree.Leaves()[0]
+	leaf.SetSink(func(ctx context.Context, msg Message) error {
+		return errDiskFull
+	})
+
+	if err := leaf.HandleMessage(context.Background(), NewMessage("lost", "direct")); !errors.Is(err, errDiskFull) {
+		t.Errorf("Expected HandleMessage to return the sink error, got %v", err)
+	}
+
+	tree.Root().GetInboundChannel() <- NewMessage("lost", "sink-1")
+	report := expectNack(t, tree.Root())
+	if report.MessageID != "sink-1" || report.Node != leaf.Name() {
+		t.Errorf("Expected a NACK for sink-1 from %s, got %+v", leaf.Name(), report)
+	}
+	if sinkErrors := leaf.Stats().SinkErrors; sinkErrors != 2 {
+		t.Errorf("Expected 2 sink errors, got %d", sinkErrors)
+	}
+}
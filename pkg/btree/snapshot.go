@@ -0,0 +1,50 @@
+package btree
+
+// NodeSnapshot is a read-only view of a node's state at one point in time
+type NodeSnapshot struct {
+	Name        string          // Name of the node
+	Running     bool            // Started and not yet stopped
+	Stopped     bool            // Stopped, whether or not it was started
+	Paused      bool            // Paused by Pause
+	BufferSize  int             // Capacity of each of the node's channels
+	Inbound     int             // Messages queued on the inbound channel
+	Upstream    int             // Messages queued on the upstream channel
+	DeadLetters int             // Dropped messages queued on the dead-letter channel
+	Children    []ChildSnapshot // Children that have not been removed, by index
+	Stats       NodeStats       // Message counters
+}
+
+// ChildSnapshot is the state of one of a node's child channels
+type ChildSnapshot struct {
+	Index    int // Index of the child
+	Buffered int // Messages queued for the child
+}
+
+// Snapshot returns the node's current state for debugging, health checks and
+// topology reports. It changes nothing and is safe to call concurrently with
+// the node handling messages; the queue lengths may be stale as soon as it
+// returns.
+func (n *Node) Snapshot() NodeSnapshot {
+	n.mu.RLock()
+	children := make([]ChildSnapshot, 0, len(n.childrenOut))
+	for i, childOut := range n.childrenOut {
+		if childOut != nil {
+			children = append(children, ChildSnapshot{Index: i, Buffered: len(childOut)})
+		}
+	}
+	n.mu.RUnlock()
+
+	stopped := n.ctx.Err() != nil
+	return NodeSnapshot{
+		Name:        n.name,
+		Running:     n.started.Load() && !stopped,
+		Stopped:     stopped,
+		Paused:      n.IsPaused(),
+		BufferSize:  n.bufferSize,
+		Inbound:     len(n.inbound),
+		Upstream:    len(n.upstreamOut),
+		DeadLetters: len(n.deadLetters),
+		Children:    children,
+		Stats:       n.Stats(),
+	}
+}
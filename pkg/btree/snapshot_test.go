@@ -0,0 +1,67 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestSnapshotReflectsChildrenAndBuffers(t *testing.T) {
+	node := NewNodeWithOptions("snap", 3, WithBufferSize(10))
+
+	// Queue two messages for every child and three on the inbound channel,
+	// which is not read because the node is not started
+	for i := 0; i < 2; i++ {
+		if err := node.HandleMessage(context.Background(), NewMessage("x", fmt.Sprintf("snap-%d", i))); err != nil {
+			t.Fatalf("HandleMessage failed: %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		node.GetInboundChannel() <- NewMessage("queued", fmt.Sprintf("in-%d", i))
+	}
+	if err := node.SendToParent(context.Background(), NewMessage("up", "up-1")); err != nil {
+		t.Fatalf("SendToParent failed: %v", err)
+	}
+	if err := node.RemoveChild(1); err != nil {
+		t.Fatalf("RemoveChild failed: %v", err)
+	}
+
+	snapshot := node.Snapshot()
+	if snapshot.Name != "snap" || snapshot.BufferSize != 10 {
+		t.Errorf("Expected name snap and buffer size 10, got %+v", snapshot)
+	}
+	if snapshot.Inbound != 3 || snapshot.Upstream != 1 {
+		t.Errorf("Expected 3 inbound and 1 upstream, got %d and %d", snapshot.Inbound, snapshot.Upstream)
+	}
+	want := []ChildSnapshot{{Index: 0, Buffered: 2}, {Index: 2, Buffered: 2}}
+	if fmt.Sprint(snapshot.Children) != fmt.Sprint(want) {
+		t.Errorf("Expected children %v, got %v", want, snapshot.Children)
+	}
+	if snapshot.Stats.Received != 2 {
+		t.Errorf("Expected 2 received, got %d", snapshot.Stats.Received)
+	}
+
+	// Taking a snapshot consumes nothing
+	if again := node.Snapshot(); again.Inbound != 3 || fmt.Sprint(again.Children) != fmt.Sprint(want) {
+		t.Errorf("Expected an identical second snapshot, got %+v", again)
+	}
+}
+
+func TestSnapshotLifecycle(t *testing.T) {
+	node := NewNode("snap", 0)
+	if s := node.Snapshot(); s.Running || s.Stopped || s.Paused {
+		t.Errorf("Expected a new node to be idle, got %+v", s)
+	}
+
+	node.Start()
+	node.Pause()
+	if s := node.Snapshot(); !s.Running || !s.Paused {
+		t.Errorf("Expected a running, paused node, got %+v", s)
+	}
+
+	node.Resume()
+	node.Stop()
+	if s := node.Snapshot(); s.Running || !s.Stopped || s.Paused {
+		t.Errorf("Expected a stopped node, got %+v", s)
+	}
+}
@@ -0,0 +1,58 @@
+package btree
+
+import "sync/atomic"
+
+// NodeStats is a snapshot of a node's message counters
+type NodeStats struct {
+	Received     uint64 // Messages handled for broadcast
+	Broadcast    uint64 // Successful deliveries to child channels
+	Dropped      uint64 // Deliveries skipped because a child channel was full
+	OutOfRange   uint64 // Sends addressed to a nonexistent child
+	Duplicates   uint64 // Messages dropped because their ID was already seen
+	RateLimited  uint64 // Inbound messages dropped by the rate limit
+	SequenceGaps uint64 // Received messages that skipped or repeated a sequence number
+	Filtered     uint64 // Messages rejected by the node's filter
+	Invalid      uint64 // Inbound messages rejected by the node's validator
+	Expired      uint64 // Messages dropped because their expiry had passed
+	SinkErrors   uint64 // Messages the node's sink failed on
+	Children     int    // Number of children that have not been removed
+
+	// Latency is the distribution of message age (handling time minus
+	// Timestamp) over messages that carried a timestamp
+	Latency HistogramSnapshot
+}
+
+// nodeCounters holds the counters behind NodeStats; all fields are updated
+// atomically so they can be read while the node processes messages
+type nodeCounters struct {
+	received     atomic.Uint64
+	broadcast    atomic.Uint64
+	dropped      atomic.Uint64
+	duplicates   atomic.Uint64
+	rateLimited  atomic.Uint64
+	sequenceGaps atomic.Uint64
+	filtered     atomic.Uint64
+	invalid      atomic.Uint64
+	expired      atomic.Uint64
+	sinkErrors   atomic.Uint64
+	drained      atomic.Uint64
+}
+
+// Stats returns a snapshot of the node's message counters
+func (n *Node) Stats() NodeStats {
+	return NodeStats{
+		Received:     n.counters.received.Load(),
+		Broadcast:    n.counters.broadcast.Load(),
+		Dropped:      n.counters.dropped.Load(),
+		OutOfRange:   n.outOfRange.Load(),
+		Duplicates:   n.counters.duplicates.Load(),
+		RateLimited:  n.counters.rateLimited.Load(),
+		SequenceGaps: n.counters.sequenceGaps.Load(),
+		Filtered:     n.counters.filtered.Load(),
+		Invalid:      n.counters.invalid.Load(),
+		Expired:      n.counters.expired.Load(),
+		SinkErrors:   n.counters.sinkErrors.Load(),
+		Children:     n.ActiveChildren(),
+		Latency:      n.latency.snapshot(),
+	}
+}
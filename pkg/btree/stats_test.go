@@ -0,0 +1,46 @@
+package btree
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestNodeStats(t *testing.T) {
+	// Nobody reads the child channel, so it fills up and later sends drop
+	parent := NewNode("parent", 1)
+	childChannel, _ := parent.GetChildChannel(0)
+	capacity := uint64(cap(childChannel))
+
+	const messages = 150
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// Read stats concurrently while messages are processed
+		for i := 0; i < messages; i++ {
+			parent.Stats()
+		}
+	}()
+
+	for i := 0; i < messages; i++ {
+		if err := parent.HandleMessage(context.Background(), Message{Content: "counted"}); err != nil {
+			t.Fatalf("Failed to handle message: %v", err)
+		}
+	}
+	wg.Wait()
+
+	stats := parent.Stats()
+	if stats.Received != messages {
+		t.Errorf("Expected %d received, got %d", messages, stats.Received)
+	}
+	if stats.Broadcast != capacity {
+		t.Errorf("Expected %d broadcast deliveries, got %d", capacity, stats.Broadcast)
+	}
+	if stats.Dropped != messages-capacity {
+		t.Errorf("Expected %d dropped, got %d", messages-capacity, stats.Dropped)
+	}
+	if stats.Children != 1 {
+		t.Errorf("Expected 1 child, got %d", stats.Children)
+	}
+}
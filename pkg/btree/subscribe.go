@@ -0,0 +1,45 @@
+package btree
+
+// Subscribe returns a channel receiving a copy of every message the node
+// handles, after middleware and broadcasting, for consumers outside the tree
+// such as loggers or sidecars. Any number of subscribers may be registered.
+// The channel is buffered like the node's child channels; a message is
+// discarded for a subscriber whose channel is full, so a slow subscriber
+// never blocks broadcasting. The returned func unsubscribes and closes the
+// channel; it is safe to call more than once.
+func (n *Node) Subscribe() (<-chan Message, func()) {
+	n.subMu.Lock()
+	defer n.subMu.Unlock()
+
+	if n.subscribers == nil {
+		n.subscribers = make(map[int]chan Message)
+	}
+	id := n.subscriberID
+	n.subscriberID++
+	ch := make(chan Message, n.bufferSize)
+	n.subscribers[id] = ch
+
+	unsubscribe := func() {
+		n.subMu.Lock()
+		defer n.subMu.Unlock()
+		if _, ok := n.subscribers[id]; ok {
+			delete(n.subscribers, id)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe
+}
+
+// publish hands a copy of msg to every subscriber with room for it
+func (n *Node) publish(msg Message) {
+	n.subMu.Lock()
+	defer n.subMu.Unlock()
+
+	for _, ch := range n.subscribers {
+		select {
+		case ch <- msg:
+		default:
+			n.log().Warn("Subscriber channel full, discarding message", "id", msg.ID)
+		}
+	}
+}
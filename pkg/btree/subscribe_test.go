@@ -0,0 +1,81 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestSubscribersReceiveHandledMessages(t *testing.T) {
+	node := NewNode("observed", 1)
+	first, unsubscribeFirst := node.Subscribe()
+	defer unsubscribeFirst()
+	second, unsubscribeSecond := node.Subscribe()
+	defer unsubscribeSecond()
+
+	if err := node.HandleMessage(context.Background(), NewMessage("hello", "sub-1")); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+
+	for i, sub := range []<-chan Message{first, second} {
+		select {
+		case msg := <-sub:
+			if msg.ID != "sub-1" || msg.Content != "hello" {
+				t.Errorf("Subscriber %d: expected sub-1, got %+v", i, msg)
+			}
+		default:
+			t.Errorf("Subscriber %d received nothing", i)
+		}
+	}
+}
+
+func TestUnsubscribeStopsDelivery(t *testing.T) {
+	node := NewNode("observed", 0)
+	kept, unsubscribeKept := node.Subscribe()
+	defer unsubscribeKept()
+	removed, unsubscribe := node.Subscribe()
+
+	unsubscribe()
+	unsubscribe()
+
+	if err := node.HandleMessage(context.Background(), NewMessage("after", "sub-2")); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+
+	if msg, ok := <-removed; ok {
+		t.Errorf("Expected the unsubscribed channel to be closed, got %+v", msg)
+	}
+	select {
+	case msg := <-kept:
+		if msg.ID != "sub-2" {
+			t.Errorf("Expected sub-2, got %s", msg.ID)
+		}
+	default:
+		t.Error("Expected the remaining subscriber to receive the message")
+	}
+}
+
+func TestSlowSubscriberDoesNotBlockBroadcast(t *testing.T) {
+	node := NewNodeWithOptions("observed", 1, WithBufferSize(2))
+	child, _ := node.GetChildChannel(0)
+	sub, unsubscribe := node.Subscribe()
+	defer unsubscribe()
+
+	// The subscriber never reads, so its buffer fills after two messages
+	for i := 0; i < 2; i++ {
+		if err := node.HandleMessage(context.Background(), NewMessage("x", fmt.Sprintf("slow-%d", i))); err != nil {
+			t.Fatalf("HandleMessage failed: %v", err)
+		}
+		<-child
+	}
+	if err := node.HandleMessage(context.Background(), NewMessage("x", "slow-2")); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+	if msg := <-child; msg.ID != "slow-2" {
+		t.Errorf("Expected slow-2 to be broadcast, got %s", msg.ID)
+	}
+
+	if len(sub) != 2 {
+		t.Errorf("Expected the subscriber to hold 2 messages, got %d", len(sub))
+	}
+}
@@ -0,0 +1,195 @@
+package btree
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTargetedMessageIsProcessedOnlyByTarget(t *testing.T) {
+	tree, err := BuildTree(3, 2)
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	defer tree.Stop()
+
+	// Subscribers are local consumers, so they only see what a node consumes
+	processed := make(chan string, 10)
+	for _, node := range tree.Nodes() {
+		name := node.name
+		messages, unsubscribe := node.Subscribe()
+		defer unsubscribe()
+		go func() {
+			for range messages {
+				processed <- name
+			}
+		}()
+	}
+
+	msg := NewMessage("for root.1 only", "target-1")
+	msg.Target = "root.1"
+	tree.Root().GetInboundChannel() <- msg
+
+	select {
+	case name := <-processed:
+		if name != "root.1" {
+			t.Fatalf("Expected only root.1 to process the message, %s did", name)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Targeted node never processed the message")
+	}
+
+	// The sibling subtree relays the message down to its leaves, while the
+	// target's children never see it
+	deadline := time.Now().Add(2 * time.Second)
+	for _, name := range []string{"root.0", "root.0.0", "root.0.1"} {
+		node, _ := tree.Lookup(name)
+		for node.Stats().Received == 0 {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %s to forward the message", name)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	for _, name := range []string{"root.1.0", "root.1.1"} {
+		node, _ := tree.Lookup(name)
+		if got := node.Stats().Received; got != 0 {
+			t.Errorf("Expected %s below the target not to receive the message, got %d", name, got)
+		}
+	}
+
+	select {
+	case name := <-processed:
+		t.Errorf("Expected no other node to process the message, %s did", name)
+	default:
+	}
+}
+
+func TestTargetedMessageSkipsLocalProcessing(t *testing.T) {
+	node := NewNode("relay", 1)
+	child, _ := node.GetChildChannel(0)
+	handled := 0
+	node.HandleType(TypeControl, func(ctx context.Context, msg Message) error {
+		handled++
+		return nil
+	})
+	messages, unsubscribe := node.Subscribe()
+	defer unsubscribe()
+
+	msg := Message{ID: "control-1", Type: TypeControl, Target: "elsewhere"}
+	if err := node.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+
+	if handled != 0 {
+		t.Error("Expected the type handler to be skipped for another node's message")
+	}
+	select {
+	case got := <-child:
+		if got.Target != "elsewhere" {
+			t.Errorf("Expected the target to be kept, got %q", got.Target)
+		}
+	default:
+		t.Error("Expected the message to be forwarded to the child")
+	}
+	select {
+	case <-messages:
+		t.Error("Expected subscribers not to see another node's message")
+	default:
+	}
+
+	msg = Message{ID: "control-2", Type: TypeControl, Target: "relay"}
+	if err := node.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+	if handled != 1 {
+		t.Errorf("Expected the target's type handler to run once, ran %d times", handled)
+	}
+}
+
+func TestOnReceiveRunsOnRelays(t *testing.T) {
+	node := NewNode("relay", 1)
+	received := make(chan Message, 1)
+	node.OnReceive(func(msg Message) { received <- msg })
+
+	msg := Message{ID: "relayed-1", Target: "elsewhere"}
+	if err := node.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+
+	select {
+	case got := <-received:
+		if got.ID != "relayed-1" {
+			t.Errorf("Expected relayed-1, got %s", got.ID)
+		}
+	default:
+		t.Error("Expected OnReceive to run for a relayed message")
+	}
+}
+
+func TestTargetDoesNotNumberConsumedMessages(t *testing.T) {
+	node := NewNode("target", 1)
+	child, _ := node.GetChildChannel(0)
+	ctx := context.Background()
+
+	if err := node.HandleMessage(ctx, Message{ID: "mine", Target: "target"}); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+	if err := node.HandleMessage(ctx, Message{ID: "everyone"}); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+
+	select {
+	case got := <-child:
+		if got.ID != "everyone" || got.Seq != 1 {
+			t.Errorf("Expected everyone with sequence 1, got %s with sequence %d", got.ID, got.Seq)
+		}
+	default:
+		t.Fatal("Expected the untargeted message to be broadcast")
+	}
+	select {
+	case got := <-child:
+		t.Errorf("Expected the targeted message not to be broadcast, got %s", got.ID)
+	default:
+	}
+}
+
+func TestRelaySkipsFilterAndMiddleware(t *testing.T) {
+	node := NewNode("relay", 1)
+	child, _ := node.GetChildChannel(0)
+	node.SetFilter(func(Message) bool { return false })
+	middlewareRuns := 0
+	node.Use(func(ctx context.Context, msg Message) (Message, error) {
+		middlewareRuns++
+		return msg, errors.New("rejected")
+	})
+
+	msg := Message{ID: "relayed-1", Target: "elsewhere"}
+	if err := node.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("Expected the relay to forward the message, got %v", err)
+	}
+	select {
+	case got := <-child:
+		if got.Source != "relay" {
+			t.Errorf("Expected the relay as last hop, got %q", got.Source)
+		}
+	default:
+		t.Fatal("Expected the message to be forwarded to the child")
+	}
+	if middlewareRuns != 0 {
+		t.Errorf("Expected middleware to be skipped on the relay, ran %d times", middlewareRuns)
+	}
+	if stats := node.Stats(); stats.Filtered != 0 {
+		t.Errorf("Expected nothing filtered, got %d", stats.Filtered)
+	}
+
+	// The target itself still filters its messages
+	if err := node.HandleMessage(context.Background(), Message{ID: "mine", Target: "relay"}); err != nil {
+		t.Fatalf("HandleMessage failed: %v", err)
+	}
+	if stats := node.Stats(); stats.Filtered != 1 {
+		t.Errorf("Expected the target to filter its message, got %d filtered", stats.Filtered)
+	}
+}
@@ -0,0 +1,121 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Tree is a complete tree of nodes running in the local process, wired
+// together with goroutines that copy between their channels
+type Tree struct {
+	root     *Node
+	nodes    []*Node
+	leaves   []*Node
+	children map[string][]string
+	registry *Registry
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+// BuildTree creates, wires and starts a tree with depth levels in which every
+// non-leaf node has branching children. Nodes are named by their path from
+// the root: "root", "root.0", "root.0.1", ...
+func BuildTree(depth int, branching int) (*Tree, error) {
+	if depth < 1 {
+		return nil, fmt.Errorf("tree depth must be at least 1, got %d", depth)
+	}
+	if depth > 1 && branching < 1 {
+		return nil, fmt.Errorf("tree branching must be at least 1, got %d", branching)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tree := &Tree{registry: NewRegistry(), children: make(map[string][]string), cancel: cancel}
+
+	root, err := tree.addNode(ctx, "root", depth, branching)
+	if err != nil {
+		tree.Stop()
+		return nil, err
+	}
+	tree.root = root
+	return tree, nil
+}
+
+// addNode creates a node with levels levels below and including it, wires it
+// to its children and starts it
+func (t *Tree) addNode(ctx context.Context, name string, levels int, branching int) (*Node, error) {
+	numChildren := 0
+	if levels > 1 {
+		numChildren = branching
+	}
+
+	node, err := t.registry.NewNode(name, numChildren)
+	if err != nil {
+		return nil, err
+	}
+	t.nodes = append(t.nodes, node)
+	if numChildren == 0 {
+		t.leaves = append(t.leaves, node)
+	}
+
+	for i := 0; i < numChildren; i++ {
+		child, err := t.addNode(ctx, fmt.Sprintf("%s.%d", name, i), levels-1, branching)
+		if err != nil {
+			return nil, err
+		}
+		t.link(ctx, node, i, child)
+	}
+
+	node.Start()
+	return node, nil
+}
+
+// link copies the parent's messages for child index to the child, and the
+// child's upstream messages back to the parent
+func (t *Tree) link(ctx context.Context, parent *Node, index int, child *Node) {
+	childChannel, _ := parent.GetChildChannel(index)
+	t.children[parent.name] = append(t.children[parent.name], child.name)
+
+	t.wg.Add(2)
+	go func() {
+		defer t.wg.Done()
+		Pipe(ctx, childChannel, child.GetInboundChannel())
+	}()
+	go func() {
+		defer t.wg.Done()
+		Pipe(ctx, child.GetUpstreamChannel(), parent.GetInboundChannel())
+	}()
+}
+
+// Root returns the root node; send to its inbound channel to broadcast
+// through the whole tree
+func (t *Tree) Root() *Node {
+	return t.root
+}
+
+// Nodes returns every node of the tree, parents before their children
+func (t *Tree) Nodes() []*Node {
+	return t.nodes
+}
+
+// Lookup returns the node with the given name, such as "root.1.0"
+func (t *Tree) Lookup(name string) (*Node, bool) {
+	return t.registry.Lookup(name)
+}
+
+// Leaves returns the nodes without children, left to right
+func (t *Tree) Leaves() []*Node {
+	return t.leaves
+}
+
+// Stop stops the wiring and every node; it is safe to call more than once
+func (t *Tree) Stop() {
+	t.once.Do(func() {
+		t.cancel()
+		t.wg.Wait()
+		for _, node := range t.nodes {
+			node.Stop()
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package btree
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildTreeBroadcastReachesAllLeaves(t *testing.T) {
+	tree, err := BuildTree(3, 2)
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	defer tree.Stop()
+
+	if len(tree.Nodes()) != 7 || len(tree.Leaves()) != 4 {
+		t.Fatalf("Expected 7 nodes and 4 leaves, got %d and %d", len(tree.Nodes()), len(tree.Leaves()))
+	}
+
+	received := make(chan string, 10)
+	for _, leaf := range tree.Leaves() {
+		name := leaf.name
+		leaf.OnReceive(func(msg Message) { received <- name })
+	}
+
+	msg := NewMessage("to every leaf", "tree-1")
+	msg.TrackReach = true
+	tree.Root().GetInboundChannel() <- msg
+
+	seen := make(map[string]bool)
+	for len(seen) < 4 {
+		select {
+		case name := <-received:
+			seen[name] = true
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Only %d of 4 leaves received the message", len(seen))
+		}
+	}
+
+	// The reach report travels back up the wiring to the root
+	select {
+	case report := <-tree.Root().GetUpstreamChannel():
+		if report.ReachCount != 7 {
+			t.Errorf("Expected reach 7, got %d", report.ReachCount)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for reach report")
+	}
+}
+
+func TestBuildTreeValidatesShape(t *testing.T) {
+	if _, err := BuildTree(0, 2); err == nil {
+		t.Error("Expected error for depth 0")
+	}
+	if _, err := BuildTree(2, 0); err == nil {
+		t.Error("Expected error for branching 0 below the root")
+	}
+
+	tree, err := BuildTree(1, 0)
+	if err != nil {
+		t.Fatalf("Expected a single-node tree, got %v", err)
+	}
+	defer tree.Stop()
+	if !tree.Root().IsLeaf() {
+		t.Error("Expected the root of a depth-1 tree to be a leaf")
+	}
+}
+
+func TestTreeLookup(t *testing.T) {
+	tree, err := BuildTree(3, 2)
+	if err != nil {
+		t.Fatalf("BuildTree failed: %v", err)
+	}
+	defer tree.Stop()
+
+	node, ok := tree.Lookup("root.1.0")
+	if !ok {
+		t.Fatal("Expected to find root.1.0")
+	}
+	if node.Name() != "root.1.0" || node.GetNumChildren() != 0 {
+		t.Errorf("Expected leaf root.1.0, got %s with %d children", node.Name(), node.GetNumChildren())
+	}
+	if _, ok := tree.Lookup("root.2"); ok {
+		t.Error("Expected no root.2 in a binary tree")
+	}
+}
@@ -0,0 +1,115 @@
+package btree
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ReasonInvalid marks an inbound message the node's validator rejected; it is
+// recorded with ChildIndex -1 since no child was involved
+const ReasonInvalid = "invalid"
+
+// ErrMissingID is returned by RequireID for messages without an ID
+var ErrMissingID = errors.New("message has no ID")
+
+// Validator checks an inbound message before the node handles it, returning
+// an error to reject the message
+type Validator func(Message) error
+
+// InvalidPolicy controls what the node does with messages its validator rejects
+type InvalidPolicy int
+
+const (
+	// InvalidDrop discards rejected messages after logging them (the default)
+	InvalidDrop InvalidPolicy = iota
+
+	// InvalidDeadLetter routes rejected messages to the dead-letter channel
+	InvalidDeadLetter
+)
+
+// SetValidator makes the message loop check every downstream message taken
+// from the inbound channel with v before handling it; rejected messages are
+// counted, logged and never reach the WAL, middleware or children. Upstream
+// messages from children are not validated. A nil v removes the validator.
+func (n *Node) SetValidator(v Validator) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.validator = v
+}
+
+// SetInvalidPolicy sets what happens to messages the validator rejects
+func (n *Node) SetInvalidPolicy(policy InvalidPolicy) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.invalidPolicy = policy
+}
+
+// validate reports whether msg passed the node's validator, dropping or
+// dead-lettering it when it did not. timer is the message's stage timer.
+func (n *Node) validate(msg Message, timer *StageTimer) bool {
+	n.mu.RLock()
+	validator := n.validator
+	policy := n.invalidPolicy
+	n.mu.RUnlock()
+
+	if validator == nil {
+		return true
+	}
+
+	var err error
+	timer.Time(StageValidate, func() {
+		err = validator(msg)
+	})
+	if err == nil {
+		return true
+	}
+
+	n.counters.invalid.Add(1)
+	n.log().Warn("Validator rejected message", "id", msg.ID, "error", err)
+	if policy == InvalidDeadLetter {
+		n.deadLetter(msg, -1, ReasonInvalid)
+	}
+	return false
+}
+
+// ValidateAll returns a validator running each of validators in order and
+// rejecting the message with the first error
+func ValidateAll(validators ...Validator) Validator {
+	return func(msg Message) error {
+		for _, v := range validators {
+			if err := v(msg); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// MaxSize returns a validator rejecting messages whose payload (ID, type,
+// content and metadata keys and values) exceeds limit bytes
+func MaxSize(limit int) Validator {
+	return func(msg Message) error {
+		if size := payloadSize(msg); size > limit {
+			return fmt.Errorf("message size %d exceeds limit of %d bytes", size, limit)
+		}
+		return nil
+	}
+}
+
+// RequireID is a validator rejecting messages without an ID
+func RequireID(msg Message) error {
+	if msg.ID == "" {
+		return ErrMissingID
+	}
+	return nil
+}
+
+// payloadSize approximates the encoded size of msg by the bytes it carries,
+// ignoring the framing every codec adds
+func payloadSize(msg Message) int {
+	size := len(msg.ID) + len(msg.Type) + len(msg.Content)
+	for k, v := range msg.Metadata {
+		size += len(k) + len(v)
+	}
+	return size
+}
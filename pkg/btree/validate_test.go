@@ -0,0 +1,106 @@
+package btree
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidatorRejectsOversizedMessage(t *testing.T) {
+	node := NewNode("validated", 1)
+	node.SetValidator(MaxSize(64))
+	node.SetInvalidPolicy(InvalidDeadLetter)
+	node.Start()
+	defer node.Stop()
+	child, _ := node.GetChildChannel(0)
+
+	node.GetInboundChannel() <- NewMessage(strings.Repeat("x", 1024), "too-big")
+	node.GetInboundChannel() <- NewMessage("fits", "ok")
+
+	select {
+	case msg := <-child:
+		if msg.ID != "ok" {
+			t.Errorf("Expected only the valid message to be broadcast, got %s", msg.ID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the valid message")
+	}
+
+	select {
+	case dropped := <-node.DeadLetters():
+		if dropped.Message.ID != "too-big" || dropped.Reason != ReasonInvalid || dropped.ChildIndex != -1 {
+			t.Errorf("Expected too-big to be dead-lettered as invalid, got %+v", dropped)
+		}
+	default:
+		t.Error("Expected the oversized message to be dead-lettered")
+	}
+
+	stats := node.Stats()
+	if stats.Invalid != 1 {
+		t.Errorf("Expected 1 invalid message, got %d", stats.Invalid)
+	}
+	if stats.Received != 1 {
+		t.Errorf("Expected 1 received message, got %d", stats.Received)
+	}
+}
+
+func TestInvalidMessagesDroppedByDefault(t *testing.T) {
+	node := NewNode("validated", 1)
+	node.SetValidator(RequireID)
+	node.Start()
+	defer node.Stop()
+	child, _ := node.GetChildChannel(0)
+
+	node.GetInboundChannel() <- Message{Content: "anonymous"}
+	node.GetInboundChannel() <- NewMessage("named", "named-1")
+
+	select {
+	case msg := <-child:
+		if msg.ID != "named-1" {
+			t.Errorf("Expected named-1, got %s", msg.ID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the valid message")
+	}
+
+	select {
+	case dropped := <-node.DeadLetters():
+		t.Errorf("Expected no dead letters under the drop policy, got %+v", dropped)
+	default:
+	}
+	if invalid := node.Stats().Invalid; invalid != 1 {
+		t.Errorf("Expected 1 invalid message, got %d", invalid)
+	}
+}
+
+func TestValidateAll(t *testing.T) {
+	errCustom := errors.New("no secrets")
+	validator := ValidateAll(RequireID, MaxSize(16), func(msg Message) error {
+		if strings.Contains(msg.Content, "secret") {
+			return errCustom
+		}
+		return nil
+	})
+
+	tests := []struct {
+		name string
+		msg  Message
+		want error
+	}{
+		{"valid", NewMessage("hello", "v-1"), nil},
+		{"missing ID", Message{Content: "hello"}, ErrMissingID},
+		{"custom check", NewMessage("secret", "v-2"), errCustom},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validator(tt.msg); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if err := validator(NewMessage(strings.Repeat("x", 32), "v-3")); err == nil {
+		t.Error("Expected an oversized message to be rejected")
+	}
+}
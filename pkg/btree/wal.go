@@ -0,0 +1,387 @@
+package btree
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Record kinds of the write-ahead log
+const (
+	walEntry   byte = 'E' // a logged message
+	walConfirm byte = 'C' // the message with the same LSN was delivered
+)
+
+// walHeaderSize is the size of a record header: kind, LSN, payload length
+// and CRC-32 of everything else
+const walHeaderSize = 1 + 8 + 4 + 4
+
+// walMaxRecordSize bounds the payload length read from a record header, so a
+// corrupt length cannot cause a huge allocation
+const walMaxRecordSize = 64 << 20
+
+// DefaultWALCheckpointInterval is how many confirmations accumulate before
+// the log is compacted to its unconfirmed entries
+const DefaultWALCheckpointInterval = 1000
+
+// WALEntry is a message in the write-ahead log with its log sequence number
+type WALEntry struct {
+	LSN     uint64
+	Message Message
+}
+
+// WAL is an append-only on-disk log of inbound messages. A node configured
+// WithWAL logs every downstream message before handling it and confirms it
+// once handled; on restart the unconfirmed entries are handled again, so
+// delivery is at-least-once. Every record is synced to disk before Append or
+// Confirm returns. A WAL is safe for concurrent use.
+type WAL struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	nextLSN  uint64
+	pending  map[uint64]Message
+	confirms int
+
+	// checkpointInterval is the number of confirmations between compactions
+	checkpointInterval int
+}
+
+// OpenWAL opens or creates the log at path and loads its unconfirmed
+// entries. A partial or corrupt record at the end, left by a crash in the
+// middle of a write, is discarded and the file truncated before it.
+func OpenWAL(path string) (*WAL, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WAL: %v", err)
+	}
+
+	w := &WAL{
+		path:               path,
+		file:               file,
+		nextLSN:            1,
+		pending:            make(map[uint64]Message),
+		checkpointInterval: DefaultWALCheckpointInterval,
+	}
+
+	valid, err := w.load()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Truncate(valid); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to truncate WAL: %v", err)
+	}
+	if _, err := file.Seek(valid, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to seek WAL: %v", err)
+	}
+	return w, nil
+}
+
+// load reads every intact record and returns the offset after the last one
+func (w *WAL) load() (int64, error) {
+	reader := bufio.NewReader(w.file)
+	var offset int64
+
+	for {
+		kind, lsn, payload, err := readWALRecord(reader)
+		if err != nil {
+			// A torn or corrupt record ends the log
+			return offset, nil
+		}
+		offset += int64(walHeaderSize + len(payload))
+
+		switch kind {
+		case walEntry:
+			var msg Message
+			if err := json.Unmarshal(payload, &msg); err != nil {
+				return offset, fmt.Errorf("malformed WAL entry %d: %v", lsn, err)
+			}
+			w.pending[lsn] = msg
+		case walConfirm:
+			delete(w.pending, lsn)
+		}
+		if lsn >= w.nextLSN {
+			w.nextLSN = lsn + 1
+		}
+	}
+}
+
+// SetCheckpointInterval sets how many confirmations accumulate before the log
+// is compacted. Values below one compact on every confirmation.
+func (w *WAL) SetCheckpointInterval(interval int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.checkpointInterval = interval
+}
+
+// Append logs msg and returns its log sequence number
+func (w *WAL) Append(msg Message) (uint64, error) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode WAL entry: %v", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, errors.New("WAL is closed")
+	}
+
+	lsn := w.nextLSN
+	if err := w.write(walEntry, lsn, payload); err != nil {
+		return 0, err
+	}
+	w.nextLSN++
+	w.pending[lsn] = msg
+	return lsn, nil
+}
+
+// Confirm marks the entry with the given LSN as delivered, so it is not
+// replayed. Once nothing is pending the log is truncated, and every
+// checkpoint interval it is compacted to its unconfirmed entries.
+func (w *WAL) Confirm(lsn uint64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return errors.New("WAL is closed")
+	}
+	if _, ok := w.pending[lsn]; !ok {
+		return nil
+	}
+
+	delete(w.pending, lsn)
+	if len(w.pending) == 0 {
+		return w.truncate()
+	}
+
+	if err := w.write(walConfirm, lsn, nil); err != nil {
+		return err
+	}
+	w.confirms++
+	if w.confirms >= w.checkpointInterval {
+		return w.checkpoint()
+	}
+	return nil
+}
+
+// Pending returns the unconfirmed entries in LSN order
+func (w *WAL) Pending() []WALEntry {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	entries := make([]WALEntry, 0, len(w.pending))
+	for lsn, msg := range w.pending {
+		entries = append(entries, WALEntry{LSN: lsn, Message: msg})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].LSN < entries[j].LSN })
+	return entries
+}
+
+// Checkpoint compacts the log to its unconfirmed entries
+func (w *WAL) Checkpoint() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return errors.New("WAL is closed")
+	}
+	return w.checkpoint()
+}
+
+// Close closes the log file; pending entries stay on disk for the next OpenWAL
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// write appends a record and syncs it to disk. Callers must hold w.mu.
+func (w *WAL) write(kind byte, lsn uint64, payload []byte) error {
+	if _, err := w.file.Write(walRecord(kind, lsn, payload)); err != nil {
+		return fmt.Errorf("failed to write WAL record: %v", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync WAL: %v", err)
+	}
+	return nil
+}
+
+// truncate empties the log file. Callers must hold w.mu.
+func (w *WAL) truncate() error {
+	if err := w.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate WAL: %v", err)
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek WAL: %v", err)
+	}
+	w.confirms = 0
+	return w.file.Sync()
+}
+
+// checkpoint rewrites the log with only the pending entries, through a
+// temporary file renamed over the log so a crash leaves one or the other
+// intact. Callers must hold w.mu.
+func (w *WAL) checkpoint() error {
+	tmpPath := w.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create WAL checkpoint: %v", err)
+	}
+
+	lsns := make([]uint64, 0, len(w.pending))
+	for lsn := range w.pending {
+		lsns = append(lsns, lsn)
+	}
+	sort.Slice(lsns, func(i, j int) bool { return lsns[i] < lsns[j] })
+
+	writer := bufio.NewWriter(tmp)
+	for _, lsn := range lsns {
+		payload, err := json.Marshal(w.pending[lsn])
+		if err == nil {
+			_, err = writer.Write(walRecord(walEntry, lsn, payload))
+		}
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+			return fmt.Errorf("failed to write WAL checkpoint: %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write WAL checkpoint: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to sync WAL checkpoint: %v", err)
+	}
+
+	if err := os.Rename(tmpPath, w.path); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace WAL: %v", err)
+	}
+
+	// Keep appending to the compacted file
+	w.file.Close()
+	if _, err := tmp.Seek(0, io.SeekEnd); err != nil {
+		return fmt.Errorf("failed to seek WAL: %v", err)
+	}
+	w.file = tmp
+	w.confirms = 0
+	return nil
+}
+
+// walRecord encodes a record: kind, big-endian LSN and payload length, a
+// CRC-32 of the other fields and the payload
+func walRecord(kind byte, lsn uint64, payload []byte) []byte {
+	record := make([]byte, walHeaderSize+len(payload))
+	record[0] = kind
+	binary.BigEndian.PutUint64(record[1:], lsn)
+	binary.BigEndian.PutUint32(record[9:], uint32(len(payload)))
+	copy(record[walHeaderSize:], payload)
+	binary.BigEndian.PutUint32(record[13:], walChecksum(record))
+	return record
+}
+
+// walChecksum covers a record's kind, LSN, length and payload
+func walChecksum(record []byte) uint32 {
+	crc := crc32.NewIEEE()
+	crc.Write(record[:13])
+	crc.Write(record[walHeaderSize:])
+	return crc.Sum32()
+}
+
+// readWALRecord reads one record, failing on a short read or bad checksum
+func readWALRecord(r io.Reader) (byte, uint64, []byte, error) {
+	header := make([]byte, walHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, 0, nil, err
+	}
+
+	kind := header[0]
+	lsn := binary.BigEndian.Uint64(header[1:])
+	size := binary.BigEndian.Uint32(header[9:])
+	if kind != walEntry && kind != walConfirm {
+		return 0, 0, nil, fmt.Errorf("unknown WAL record kind 0x%02x", kind)
+	}
+	if size > walMaxRecordSize {
+		return 0, 0, nil, fmt.Errorf("WAL record of %d bytes exceeds the limit", size)
+	}
+
+	record := make([]byte, walHeaderSize+int(size))
+	copy(record, header)
+	if _, err := io.ReadFull(r, record[walHeaderSize:]); err != nil {
+		return 0, 0, nil, err
+	}
+	if walChecksum(record) != binary.BigEndian.Uint32(header[13:]) {
+		return 0, 0, nil, errors.New("WAL record checksum mismatch")
+	}
+	return kind, lsn, record[walHeaderSize:], nil
+}
+
+// WithWAL makes a node created with NewNodeWithOptions log every downstream
+// message taken from its inbound channel to wal before handling it. Entries
+// left unconfirmed by a previous run are handled when the node starts. The
+// caller owns wal and closes it after stopping the node.
+func WithWAL(wal *WAL) NodeOption {
+	return func(o *nodeOptions) {
+		o.wal = wal
+	}
+}
+
+// replayWAL handles the entries a previous run logged but never confirmed
+func (n *Node) replayWAL() {
+	if n.wal == nil {
+		return
+	}
+
+	pending := n.wal.Pending()
+	if len(pending) == 0 {
+		return
+	}
+
+	n.log().Info("Replaying unconfirmed WAL entries", "count", len(pending))
+	for _, entry := range pending {
+		err := n.HandleMessage(n.ctx, entry.Message)
+		if err != nil {
+			n.log().Error("Error handling replayed message", "id", entry.Message.ID, "error", err)
+		}
+		if isContextError(err) {
+			return
+		}
+		n.confirmWAL(entry.LSN)
+	}
+}
+
+// confirmWAL confirms a handled entry, logging failures
+func (n *Node) confirmWAL(lsn uint64) {
+	if err := n.wal.Confirm(lsn); err != nil {
+		n.log().Error("Failed to confirm WAL entry", "lsn", lsn, "error", err)
+	}
+}
+
+// isContextError reports whether err comes from a cancelled or expired context
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
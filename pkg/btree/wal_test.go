@@ -0,0 +1,226 @@
+package btree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func openTestWAL(t *testing.T, path string) *WAL {
+	t.Helper()
+	wal, err := OpenWAL(path)
+	if err != nil {
+		t.Fatalf("Failed to open WAL: %v", err)
+	}
+	return wal
+}
+
+func TestWALReplaysUnconfirmedTailAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.wal")
+	wal := openTestWAL(t, path)
+
+	var lsns []uint64
+	for i := 0; i < 5; i++ {
+		lsn, err := wal.Append(NewMessage("logged", fmt.Sprintf("w-%d", i)))
+		if err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+		lsns = append(lsns, lsn)
+	}
+	for _, lsn := range lsns[:3] {
+		if err := wal.Confirm(lsn); err != nil {
+			t.Fatalf("Confirm failed: %v", err)
+		}
+	}
+	wal.Close()
+
+	wal = openTestWAL(t, path)
+	defer wal.Close()
+
+	pending := wal.Pending()
+	if len(pending) != 2 {
+		t.Fatalf("Expected 2 pending entries, got %d", len(pending))
+	}
+	for i, entry := range pending {
+		if want := fmt.Sprintf("w-%d", i+3); entry.Message.ID != want {
+			t.Errorf("Expected %s, got %s", want, entry.Message.ID)
+		}
+		if entry.LSN != lsns[i+3] {
+			t.Errorf("Expected LSN %d, got %d", lsns[i+3], entry.LSN)
+		}
+	}
+
+	// New entries continue after the replayed ones
+	lsn, err := wal.Append(NewMessage("later", "w-5"))
+	if err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if lsn <= lsns[4] {
+		t.Errorf("Expected a new LSN above %d, got %d", lsns[4], lsn)
+	}
+}
+
+func TestWALDiscardsPartialLastRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.wal")
+	wal := openTestWAL(t, path)
+	for i := 0; i < 2; i++ {
+		if _, err := wal.Append(NewMessage("logged", fmt.Sprintf("w-%d", i))); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	wal.Close()
+
+	intact, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+
+	// Simulate a crash halfway through writing a third record
+	record := walRecord(walEntry, 3, []byte(`{"id":"w-2","content":"torn"}`))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("Failed to open WAL file: %v", err)
+	}
+	file.Write(record[:len(record)/2])
+	file.Close()
+
+	wal = openTestWAL(t, path)
+	defer wal.Close()
+
+	if pending := wal.Pending(); len(pending) != 2 {
+		t.Fatalf("Expected the 2 intact entries, got %d", len(pending))
+	}
+	if info, _ := os.Stat(path); info.Size() != intact.Size() {
+		t.Errorf("Expected the torn record to be truncated to %d bytes, file is %d", intact.Size(), info.Size())
+	}
+
+	if _, err := wal.Append(NewMessage("after", "w-3")); err != nil {
+		t.Fatalf("Append after recovery failed: %v", err)
+	}
+	wal.Close()
+
+	wal = openTestWAL(t, path)
+	defer wal.Close()
+	if pending := wal.Pending(); len(pending) != 3 {
+		t.Errorf("Expected 3 entries after appending past the recovered tail, got %d", len(pending))
+	}
+}
+
+func TestWALConcurrentAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.wal")
+	wal := openTestWAL(t, path)
+
+	const writers, perWriter = 8, 25
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				lsn, err := wal.Append(NewMessage("concurrent", fmt.Sprintf("w%d-%d", w, i)))
+				if err != nil {
+					t.Errorf("Append failed: %v", err)
+					return
+				}
+				// Confirm every other entry while others are appending
+				if i%2 == 0 {
+					if err := wal.Confirm(lsn); err != nil {
+						t.Errorf("Confirm failed: %v", err)
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	wal.Close()
+
+	wal = openTestWAL(t, path)
+	defer wal.Close()
+
+	pending := wal.Pending()
+	if want := writers * (perWriter / 2); len(pending) != want {
+		t.Fatalf("Expected %d pending entries, got %d", want, len(pending))
+	}
+	seen := make(map[uint64]bool)
+	for _, entry := range pending {
+		if seen[entry.LSN] {
+			t.Errorf("Duplicate LSN %d", entry.LSN)
+		}
+		seen[entry.LSN] = true
+	}
+}
+
+func TestWALCheckpointCompactsLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.wal")
+	wal := openTestWAL(t, path)
+	defer wal.Close()
+	wal.SetCheckpointInterval(10)
+
+	var lsns []uint64
+	for i := 0; i < 20; i++ {
+		lsn, err := wal.Append(NewMessage("logged", fmt.Sprintf("w-%d", i)))
+		if err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+		lsns = append(lsns, lsn)
+	}
+	before, _ := os.Stat(path)
+
+	for _, lsn := range lsns[:10] {
+		wal.Confirm(lsn)
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Errorf("Expected the checkpoint to shrink the log below %d bytes, got %d", before.Size(), after.Size())
+	}
+
+	// Confirming the rest leaves nothing to replay
+	for _, lsn := range lsns[10:] {
+		wal.Confirm(lsn)
+	}
+	if info, _ := os.Stat(path); info.Size() != 0 {
+		t.Errorf("Expected an empty log once everything is confirmed, got %d bytes", info.Size())
+	}
+}
+
+func TestNodeWithWALReplaysOnStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.wal")
+
+	// A previous run logged two messages but crashed before handling them
+	wal := openTestWAL(t, path)
+	wal.Append(NewMessage("first", "crash-1"))
+	wal.Append(NewMessage("second", "crash-2"))
+	wal.Close()
+
+	wal = openTestWAL(t, path)
+	defer wal.Close()
+
+	node := NewNodeWithOptions("durable", 1, WithWAL(wal))
+	node.Start()
+	defer node.Stop()
+
+	node.GetInboundChannel() <- NewMessage("third", "live-1")
+
+	child, _ := node.GetChildChannel(0)
+	for _, want := range []string{"crash-1", "crash-2", "live-1"} {
+		select {
+		case msg := <-child:
+			if msg.ID != want {
+				t.Errorf("Expected %s, got %s", want, msg.ID)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timeout waiting for %s", want)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(wal.Pending()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected every entry to be confirmed, %d pending", len(wal.Pending()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,78 @@
+package btree
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+)
+
+// SetChildWeights sets, per child index, the probability in [0, 1] with
+// which BroadcastWeighted forwards a message to that child. Children without
+// a weight, including ones added later, always receive. A nil slice clears
+// the weights.
+func (n *Node) SetChildWeights(weights []float64) error {
+	for i, w := range weights {
+		if w < 0 || w > 1 {
+			return fmt.Errorf("weight %v of child %d is not a probability in [0, 1]", w, i)
+		}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if len(weights) > len(n.childrenOut) {
+		return fmt.Errorf("got %d weights for %d children", len(weights), len(n.childrenOut))
+	}
+	n.childWeights = append([]float64(nil), weights...)
+	return nil
+}
+
+// SetWeightSeed seeds the random source of BroadcastWeighted so the children
+// picked for a sequence of messages are reproducible
+func (n *Node) SetWeightSeed(seed uint64) {
+	n.weightMu.Lock()
+	defer n.weightMu.Unlock()
+	n.weightRand = rand.New(rand.NewPCG(seed, seed))
+}
+
+// BroadcastWeighted sends msg to each child independently with the
+// probability set by SetChildWeights, for sampling and A/B routing. Messages
+// handled by the node itself are still broadcast to every child.
+func (n *Node) BroadcastWeighted(ctx context.Context, msg Message) error {
+	selected := n.drawSelection()
+	_, err := n.broadcastFiltered(ctx, msg, func(index int, _ Message) bool {
+		return index >= len(selected) || selected[index]
+	})
+	return err
+}
+
+// drawSelection draws once, for every current child, whether a weighted
+// broadcast forwards to it, so that the children dead-lettered after a failed
+// delivery are the ones that were chosen
+func (n *Node) drawSelection() []bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	selected := make([]bool, len(n.childrenOut))
+	for i, childOut := range n.childrenOut {
+		switch {
+		case childOut == nil:
+		case i >= len(n.childWeights):
+			selected[i] = true
+		default:
+			selected[i] = n.drawWeight() < n.childWeights[i]
+		}
+	}
+	return selected
+}
+
+// drawWeight returns a random number in [0, 1) from the node's seeded source
+func (n *Node) drawWeight() float64 {
+	n.weightMu.Lock()
+	defer n.weightMu.Unlock()
+
+	if n.weightRand == nil {
+		n.weightRand = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+	return n.weightRand.Float64()
+}
@@ -0,0 +1,118 @@
+package btree
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBroadcastWeightedDistribution(t *testing.T) {
+	const total = 10000
+	weights := []float64{1, 0.5, 0.1, 0}
+
+	node := NewNodeWithOptions("sampler", len(weights), WithBufferSize(total))
+	if err := node.SetChildWeights(weights); err != nil {
+		t.Fatalf("SetChildWeights failed: %v", err)
+	}
+	node.SetWeightSeed(42)
+
+	for i := 0; i < total; i++ {
+		if err := node.BroadcastWeighted(context.Background(), NewMessage("sample", "")); err != nil {
+			t.Fatalf("BroadcastWeighted failed: %v", err)
+		}
+	}
+
+	for i, weight := range weights {
+		ch, _ := node.GetChildChannel(i)
+		share := float64(len(ch)) / total
+		if math.Abs(share-weight) > 0.02 {
+			t.Errorf("Child %d: expected a share of %.2f, got %.3f", i, weight, share)
+		}
+	}
+}
+
+func TestBroadcastWeightedIsReproducible(t *testing.T) {
+	picks := func() []int {
+		node := NewNodeWithOptions("sampler", 2, WithBufferSize(100))
+		node.SetChildWeights([]float64{0.5, 0.5})
+		node.SetWeightSeed(7)
+		for i := 0; i < 100; i++ {
+			node.BroadcastWeighted(context.Background(), NewMessage("sample", ""))
+		}
+		counts := make([]int, 2)
+		for i := range counts {
+			ch, _ := node.GetChildChannel(i)
+			counts[i] = len(ch)
+		}
+		return counts
+	}
+
+	first, second := picks(), picks()
+	if first[0] != second[0] || first[1] != second[1] {
+		t.Errorf("Expected the same seed to pick the same children, got %v and %v", first, second)
+	}
+}
+
+func TestSetChildWeightsValidates(t *testing.T) {
+	node := NewNode("sampler", 2)
+	if err := node.SetChildWeights([]float64{0.5, 1.5}); err == nil {
+		t.Error("Expected an error for a weight above 1")
+	}
+	if err := node.SetChildWeights([]float64{-0.1}); err == nil {
+		t.Error("Expected an error for a negative weight")
+	}
+	if err := node.SetChildWeights([]float64{1, 1, 1}); err == nil {
+		t.Error("Expected an error for more weights than children")
+	}
+
+	// Children without a weight always receive
+	if err := node.SetChildWeights([]float64{0}); err != nil {
+		t.Fatalf("SetChildWeights failed: %v", err)
+	}
+	if err := node.BroadcastWeighted(context.Background(), NewMessage("sample", "w-1")); err != nil {
+		t.Fatalf("BroadcastWeighted failed: %v", err)
+	}
+	left, _ := node.GetChildChannel(0)
+	right, _ := node.GetChildChannel(1)
+	if len(left) != 0 || len(right) != 1 {
+		t.Errorf("Expected only the unweighted child to receive, got %d and %d", len(left), len(right))
+	}
+}
+
+func TestBroadcastWeightedDeadLettersChosenChildren(t *testing.T) {
+	const children, seed = 6, 11
+	node := NewNodeWithOptions("sampler", children, WithBufferSize(1))
+	node.SetDeliveryMode(DeliveryDeadline)
+	node.SetBroadcastTimeout(10 * time.Millisecond)
+	weights := []float64{0.5, 0.5, 0.5, 0.5, 0.5, 0.5}
+	node.SetChildWeights(weights)
+	node.SetWeightSeed(seed)
+
+	// Fill every child so the first chosen one misses the deadline
+	for i := 0; i < children; i++ {
+		if err := node.SendToChild(context.Background(), i, NewMessage("filler", "")); err != nil {
+			t.Fatalf("Failed to fill child %d: %v", i, err)
+		}
+	}
+
+	draws := rand.New(rand.NewPCG(seed, seed))
+	var chosen []int
+	for i, w := range weights {
+		if draws.Float64() < w {
+			chosen = append(chosen, i)
+		}
+	}
+
+	err := node.BroadcastWeighted(context.Background(), NewMessage("sample", "weighted-1"))
+	var partial *PartialDeliveryError
+	if !errors.As(err, &partial) {
+		t.Fatalf("Expected a PartialDeliveryError, got %v", err)
+	}
+	if !slices.Equal(partial.Undelivered, chosen) {
+		t.Errorf("Expected the chosen children %v to be dead-lettered, got %v", chosen, partial.Undelivered)
+	}
+}
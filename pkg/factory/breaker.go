@@ -0,0 +1,151 @@
+package factory
+
+import (
+	"sync"
+	"time"
+)
+
+// Defaults of BreakerConfig
+const (
+	DefaultBreakerFailureThreshold = 5
+	DefaultBreakerOpenTimeout      = 10 * time.Second
+	DefaultBreakerSendTimeout      = 5 * time.Second
+)
+
+// ReasonCircuitOpen marks a message dead-lettered without a send attempt
+// because the child's circuit breaker was open
+const ReasonCircuitOpen = "circuit-open"
+
+// ReasonSendTimeout marks a message dead-lettered because the child's client
+// did not accept it within the breaker's send timeout
+const ReasonSendTimeout = "send-timeout"
+
+// BreakerConfig controls the circuit breaker guarding each child's outbound
+// path. Zero fields take their defaults.
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive connect or send failures
+	// that opens the circuit
+	FailureThreshold int
+
+	// OpenTimeout is how long an open circuit rejects messages before a
+	// single probe is let through
+	OpenTimeout time.Duration
+
+	// SendTimeout is how long a send may block on the child's client before
+	// it counts as a failure
+	SendTimeout time.Duration
+}
+
+// withDefaults fills in the zero fields of b
+func (b BreakerConfig) withDefaults() BreakerConfig {
+	if b.FailureThreshold <= 0 {
+		b.FailureThreshold = DefaultBreakerFailureThreshold
+	}
+	if b.OpenTimeout <= 0 {
+		b.OpenTimeout = DefaultBreakerOpenTimeout
+	}
+	if b.SendTimeout <= 0 {
+		b.SendTimeout = DefaultBreakerSendTimeout
+	}
+	return b
+}
+
+// BreakerState is the state of a child's circuit breaker
+type BreakerState string
+
+const (
+	// BreakerClosed lets every message through
+	BreakerClosed BreakerState = "closed"
+
+	// BreakerOpen rejects every message until the open timeout elapses
+	BreakerOpen BreakerState = "open"
+
+	// BreakerHalfOpen lets one probe through; its outcome closes or reopens
+	// the circuit
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// circuitBreaker counts consecutive failures to reach one child
+type circuitBreaker struct {
+	mu       sync.Mutex
+	config   BreakerConfig
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// newCircuitBreaker returns a closed breaker
+func newCircuitBreaker(config BreakerConfig) *circuitBreaker {
+	return &circuitBreaker{config: config.withDefaults(), state: BreakerClosed, now: time.Now}
+}
+
+// State returns the current state, moving an open breaker whose timeout has
+// elapsed to half-open
+func (b *circuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checkTimeout()
+	return b.state
+}
+
+// allow reports whether an attempt may be made. A half-open breaker allows a
+// single probe until its outcome is recorded.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checkTimeout()
+
+	switch b.state {
+	case BreakerClosed:
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return false
+	}
+}
+
+// success closes the breaker
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = BreakerClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// failure counts a failed attempt, opening the breaker at the threshold or
+// reopening it when a probe fails. It reports whether the breaker opened.
+func (b *circuitBreaker) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.state == BreakerOpen {
+		return false
+	}
+	if b.state == BreakerHalfOpen || b.failures >= b.config.FailureThreshold {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+		return true
+	}
+	return false
+}
+
+// checkTimeout moves an open breaker to half-open once the open timeout has
+// elapsed. Callers must hold b.mu.
+func (b *circuitBreaker) checkTimeout() {
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.config.OpenTimeout {
+		b.state = BreakerHalfOpen
+		b.probing = false
+	}
+}
@@ -0,0 +1,169 @@
+package factory
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+	"github.com/xnok/btree-server-msg/pkg/transport"
+)
+
+func TestBreakerTransitions(t *testing.T) {
+	now := time.Unix(0, 0)
+	breaker := newCircuitBreaker(BreakerConfig{FailureThreshold: 3, OpenTimeout: time.Minute})
+	breaker.now = func() time.Time { return now }
+
+	// Closed: failures below the threshold keep letting messages through
+	for i := 0; i < 2; i++ {
+		if !breaker.allow() {
+			t.Fatal("Expected a closed breaker to allow sends")
+		}
+		if breaker.failure() {
+			t.Fatalf("Expected the breaker to stay closed after %d failures", i+1)
+		}
+	}
+	if !breaker.failure() || breaker.State() != BreakerOpen {
+		t.Fatalf("Expected the third failure to open the breaker, got %s", breaker.State())
+	}
+
+	// Open: nothing goes through until the timeout elapses
+	if breaker.allow() {
+		t.Error("Expected an open breaker to reject sends")
+	}
+	now = now.Add(time.Minute)
+	if got := breaker.State(); got != BreakerHalfOpen {
+		t.Fatalf("Expected half-open after the open timeout, got %s", got)
+	}
+
+	// Half-open: a single probe, whose failure reopens the circuit
+	if !breaker.allow() {
+		t.Fatal("Expected a half-open breaker to allow a probe")
+	}
+	if breaker.allow() {
+		t.Error("Expected a half-open breaker to allow only one probe")
+	}
+	if !breaker.failure() || breaker.State() != BreakerOpen {
+		t.Fatalf("Expected a failed probe to reopen the breaker, got %s", breaker.State())
+	}
+
+	// A successful probe closes it again
+	now = now.Add(time.Minute)
+	if !breaker.allow() {
+		t.Fatal("Expected a probe after the second open timeout")
+	}
+	breaker.success()
+	if got := breaker.State(); got != BreakerClosed {
+		t.Fatalf("Expected a successful probe to close the breaker, got %s", got)
+	}
+	if breaker.failure() {
+		t.Error("Expected the failure count to restart after closing")
+	}
+}
+
+func TestBreakerOpensOnConnectFailures(t *testing.T) {
+	var attempts atomic.Int32
+	config := NewNodeConfigWithChildren("9610", []string{"9611"})
+	config.Retry = RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	config.Breaker = BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Hour}
+
+	bn, err := NewBTreeNode(config, func() transport.Transport {
+		return unreachableTransport{stalledTransport: newStalledTransport(), attempts: &attempts}
+	})
+	if err != nil {
+		t.Fatalf("Failed to create node: %v", err)
+	}
+	defer bn.cancel()
+
+	bn.connectToChild(0)
+	if got := bn.breakers[0].State(); got != BreakerOpen {
+		t.Errorf("Expected repeated connect failures to open the breaker, got %s", got)
+	}
+}
+
+// expectDeadLetter waits for the next dead letter and checks its reason
+func expectDeadLetter(t *testing.T, bn *BTreeNode, reason string) btree.DroppedMessage {
+	t.Helper()
+
+	select {
+	case dropped := <-bn.Node.DeadLetters():
+		if dropped.Reason != reason {
+			t.Fatalf("Expected a dead letter with reason %s, got %+v", reason, dropped)
+		}
+		return dropped
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timeout waiting for a %s dead letter", reason)
+		return btree.DroppedMessage{}
+	}
+}
+
+func TestBreakerGuardsStalledChild(t *testing.T) {
+	config := NewNodeConfigWithChildren("9612", []string{"9613"})
+	config.Breaker = BreakerConfig{FailureThreshold: 2, OpenTimeout: 200 * time.Millisecond, SendTimeout: 20 * time.Millisecond}
+
+	var transports []*stalledTransport
+	bn, err := NewBTreeNode(config, func() transport.Transport {
+		stalled := newStalledTransport()
+		transports = append(transports, stalled)
+		return stalled
+	})
+	if err != nil {
+		t.Fatalf("Failed to create node: %v", err)
+	}
+	if err := bn.Start(); err != nil {
+		t.Fatalf("Failed to start node: %v", err)
+	}
+	defer bn.Stop()
+	waitForHealth(t, bn, HealthHealthy)
+	child := transports[1]
+
+	send := func(id string) {
+		t.Helper()
+		if err := bn.Node.HandleMessage(bn.ctx, btree.NewMessage("payload", id)); err != nil {
+			t.Fatalf("Failed to handle %s: %v", id, err)
+		}
+	}
+
+	// The first message fills the child's outbound buffer; the next two time
+	// out and open the circuit
+	send("m-0")
+	send("m-1")
+	expectDeadLetter(t, bn, ReasonSendTimeout)
+	send("m-2")
+	expectDeadLetter(t, bn, ReasonSendTimeout)
+
+	status := waitForHealth(t, bn, HealthDegraded)
+	if got := status.Children[0].Breaker; got != BreakerOpen {
+		t.Fatalf("Expected the breaker to be open, got %s", got)
+	}
+
+	// Open: messages are rejected without an attempt
+	send("m-3")
+	if dropped := expectDeadLetter(t, bn, ReasonCircuitOpen); dropped.Message.ID != "m-3" {
+		t.Errorf("Expected m-3 to be rejected, got %s", dropped.Message.ID)
+	}
+
+	// Once the child drains and the open timeout elapses, a probe succeeds
+	// and closes the circuit
+	<-child.outbound
+	time.Sleep(config.Breaker.OpenTimeout)
+	if got := bn.breakers[0].State(); got != BreakerHalfOpen {
+		t.Fatalf("Expected half-open after the open timeout, got %s", got)
+	}
+	send("m-4")
+	select {
+	case msg := <-child.outbound:
+		if msg.ID != "m-4" {
+			t.Errorf("Expected the probe m-4, got %s", msg.ID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for the probe")
+	}
+	waitForHealth(t, bn, HealthHealthy)
+
+	select {
+	case dropped := <-bn.Node.DeadLetters():
+		t.Errorf("Expected no more dead letters, got %+v", dropped)
+	default:
+	}
+}
@@ -1,31 +1,197 @@
 package factory
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Errors returned for invalid configurations; compare them with errors.Is, as
+// details such as the offending flag or file may be wrapped around them
+var (
+	// ErrMissingPort is returned when a configuration has no port
+	ErrMissingPort = errors.New("port is required")
+
+	// ErrConflictingFlags is returned for flags that cannot be combined
+	ErrConflictingFlags = errors.New("conflicting flags")
+
+	// ErrDuplicateChildPort is returned when two children, or a child and
+	// the node itself, share a port
+	ErrDuplicateChildPort = errors.New("duplicate child port")
+
+	// ErrInvalidBindAddress is returned for a bind address that includes a
+	// port, or that is combined with a port that already names a host
+	ErrInvalidBindAddress = errors.New("invalid bind address")
 )
 
 // NodeConfig holds the configuration for a tree node
 type NodeConfig struct {
-	Port          string
-	ChildrenPorts []string // Indexed children ports (0=left, 1=right for binary trees)
+	Port            string
+	BindAddress     string        // Interface IP or host the server listens on, e.g. 127.0.0.1; empty listens on all interfaces
+	ChildrenPorts   []string      // Indexed children ports (0=left, 1=right for binary trees)
+	ParentPort      string        // Port of the parent node; empty for the root
+	HealthAddress   string        // Address of the HTTP /healthz endpoint; empty disables it
+	MetricsAddress  string        // Address of the Prometheus /metrics endpoint; empty disables it
+	Retry           RetryConfig   // Connection retries to children; the zero value uses the defaults
+	Breaker         BreakerConfig // Circuit breaker of each child; the zero value uses the defaults
+	AuthToken       string        // Pre-shared token required from peers and presented to children; empty disables it
+	ShutdownTimeout time.Duration // Time the node binary allows a graceful stop before forcing exit
+}
+
+// AuthTokenEnv is the environment variable ParseNodeConfigArgs reads the
+// auth token from, keeping it out of flags and config files
+const AuthTokenEnv = "BTREE_AUTH_TOKEN"
+
+// ShutdownTimeoutEnv is the environment variable ParseNodeConfigArgs reads
+// the shutdown timeout from when -shutdown-timeout is not given
+const ShutdownTimeoutEnv = "BTREE_SHUTDOWN_TIMEOUT"
+
+// DefaultShutdownTimeout is the shutdown timeout used when neither
+// -shutdown-timeout nor BTREE_SHUTDOWN_TIMEOUT is set
+const DefaultShutdownTimeout = 10 * time.Second
+
+// portList is a flag value collecting ports from comma-separated and
+// repeated occurrences of a flag
+type portList []string
+
+// String returns the ports as a comma-separated list
+func (p *portList) String() string {
+	return strings.Join(*p, ",")
+}
+
+// Set appends the comma-separated ports in value
+func (p *portList) Set(value string) error {
+	for _, port := range strings.Split(value, ",") {
+		port = strings.TrimSpace(port)
+		if port == "" {
+			continue
+		}
+		*p = append(*p, port)
+	}
+	return nil
 }
 
-// ParseNodeConfig parses command line flags and returns a NodeConfig for binary tree
+// ParseNodeConfig parses command line flags and returns a NodeConfig
 func ParseNodeConfig() (NodeConfig, error) {
-	port := flag.String("port", "", "Server port argument")
-	rightPort := flag.String("right", "", "Right child server port string argument")
-	leftPort := flag.String("left", "", "Left child server port string argument")
+	return ParseNodeConfigArgs(os.Args[1:])
+}
 
-	flag.Parse()
+// ParseNodeConfigArgs parses the given arguments and returns a NodeConfig.
+// Children are configured either with -children (comma-separated or
+// repeated, for any number of children) or with -left/-right for binary trees.
+// With -config the whole configuration is loaded from a file instead, and
+// only -shutdown-timeout may be combined with it. The auth token is taken
+// from the BTREE_AUTH_TOKEN environment variable, and the shutdown timeout
+// from BTREE_SHUTDOWN_TIMEOUT unless given as a flag.
+func ParseNodeConfigArgs(args []string) (NodeConfig, error) {
+	config, err := parseNodeConfigFlags(args)
+	if err != nil {
+		return NodeConfig{}, err
+	}
+	config.AuthToken = os.Getenv(AuthTokenEnv)
+	if config.ShutdownTimeout == 0 {
+		config.ShutdownTimeout, err = shutdownTimeoutFromEnv()
+		if err != nil {
+			return NodeConfig{}, err
+		}
+	}
+	return config, nil
+}
+
+// shutdownTimeoutFromEnv parses BTREE_SHUTDOWN_TIMEOUT, such as "30s", or
+// returns DefaultShutdownTimeout when it is unset
+func shutdownTimeoutFromEnv() (time.Duration, error) {
+	value := os.Getenv(ShutdownTimeoutEnv)
+	if value == "" {
+		return DefaultShutdownTimeout, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a positive duration such as 30s", ShutdownTimeoutEnv, value)
+	}
+	return timeout, nil
+}
+
+// parseNodeConfigFlags builds a NodeConfig from flags or the config file
+func parseNodeConfigFlags(args []string) (NodeConfig, error) {
+	flags := flag.NewFlagSet("node", flag.ContinueOnError)
+	port := flags.String("port", "", "Server port argument")
+	rightPort := flags.String("right", "", "Right child server port string argument")
+	leftPort := flags.String("left", "", "Left child server port string argument")
+	parentPort := flags.String("parent", "", "Parent server port, omitted for the root")
+	configPath := flags.String("config", "", "YAML or JSON config file, used instead of the other flags")
+	healthAddress := flags.String("health", "", "Address to serve /healthz on, e.g. :8080")
+	metricsAddress := flags.String("metrics", "", "Address to serve Prometheus /metrics on, e.g. :9090")
+	bindAddress := flags.String("bind", "", "Interface address to listen on, e.g. 127.0.0.1; all interfaces by default")
+	shutdownTimeout := flags.Duration("shutdown-timeout", 0, "Time allowed for a graceful stop before forcing exit, e.g. 30s (default 10s)")
+
+	var children portList
+	flags.Var(&children, "children", "Comma-separated child server ports (repeatable)")
+
+	if err := flags.Parse(args); err != nil {
+		return NodeConfig{}, err
+	}
+
+	childrenSet := false
+	otherSet := false
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "config", "shutdown-timeout":
+		case "children":
+			childrenSet = true
+			otherSet = true
+		default:
+			otherSet = true
+		}
+	})
+
+	if *shutdownTimeout < 0 {
+		return NodeConfig{}, fmt.Errorf("invalid -shutdown-timeout %v: must not be negative", *shutdownTimeout)
+	}
+
+	if *configPath != "" {
+		if otherSet {
+			return NodeConfig{}, fmt.Errorf("%w: -config cannot be combined with other flags", ErrConflictingFlags)
+		}
+		config, err := LoadNodeConfigFromFile(*configPath)
+		if err != nil {
+			return NodeConfig{}, err
+		}
+		config.ShutdownTimeout = *shutdownTimeout
+		return config, nil
+	}
 
 	if *port == "" {
-		return NodeConfig{}, fmt.Errorf("port is required")
+		return NodeConfig{}, ErrMissingPort
+	}
+
+	if childrenSet {
+		if *leftPort != "" || *rightPort != "" {
+			return NodeConfig{}, fmt.Errorf("%w: -children cannot be combined with -left or -right", ErrConflictingFlags)
+		}
+		// An empty list is a valid leaf node
+		config := NewNodeConfigWithChildren(*port, []string(children))
+		config.ParentPort = *parentPort
+		config.HealthAddress = *healthAddress
+		config.MetricsAddress = *metricsAddress
+		config.BindAddress = *bindAddress
+		config.ShutdownTimeout = *shutdownTimeout
+		return config, nil
 	}
 
 	config := NodeConfig{
-		Port:          *port,
-		ChildrenPorts: make([]string, 2), // Binary tree has 2 children
+		Port:            *port,
+		BindAddress:     *bindAddress,
+		ChildrenPorts:   make([]string, 2), // Binary tree has 2 children
+		ParentPort:      *parentPort,
+		HealthAddress:   *healthAddress,
+		MetricsAddress:  *metricsAddress,
+		ShutdownTimeout: *shutdownTimeout,
 	}
 
 	// Set child ports if provided (index 0 = left, index 1 = right)
@@ -64,6 +230,87 @@ func NewNodeConfigWithChildren(port string, childrenPorts []string) NodeConfig {
 	}
 }
 
+// String describes the node's place in the topology for logs, e.g.
+// "port=3030 parent=3029 children=[3031 3032]". Unconfigured child slots
+// are shown as "-".
+func (c NodeConfig) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "port=%s", c.Port)
+	if c.BindAddress != "" {
+		fmt.Fprintf(&b, " bind=%s", c.BindAddress)
+	}
+	if c.ParentPort != "" {
+		fmt.Fprintf(&b, " parent=%s", c.ParentPort)
+	}
+
+	children := make([]string, len(c.ChildrenPorts))
+	for i, port := range c.ChildrenPorts {
+		if port == "" {
+			port = "-"
+		}
+		children[i] = port
+	}
+	fmt.Fprintf(&b, " children=[%s]", strings.Join(children, " "))
+
+	if c.HealthAddress != "" {
+		fmt.Fprintf(&b, " health=%s", c.HealthAddress)
+	}
+	if c.MetricsAddress != "" {
+		fmt.Fprintf(&b, " metrics=%s", c.MetricsAddress)
+	}
+	return b.String()
+}
+
+// nodeConfigJSON is the JSON layout of a NodeConfig, using the keys of the
+// config file format
+type nodeConfigJSON struct {
+	Port     string   `json:"port"`
+	Bind     string   `json:"bind,omitempty"`
+	Parent   string   `json:"parent,omitempty"`
+	Children []string `json:"children"`
+	Health   string   `json:"health,omitempty"`
+	Metrics  string   `json:"metrics,omitempty"`
+}
+
+// MarshalJSON encodes the config with the keys of the config file format, so
+// LoadNodeConfigFromFile reads it back. A leaf has an empty children list;
+// the file format has no empty child slots, so unconfigured ones are left
+// out and the remaining children move up.
+func (c NodeConfig) MarshalJSON() ([]byte, error) {
+	children := configuredPorts(c.ChildrenPorts)
+	if children == nil {
+		children = []string{}
+	}
+	return json.Marshal(nodeConfigJSON{
+		Port:     c.Port,
+		Bind:     c.BindAddress,
+		Parent:   c.ParentPort,
+		Children: children,
+		Health:   c.HealthAddress,
+		Metrics:  c.MetricsAddress,
+	})
+}
+
+// ListenAddress returns the address the node's server listens on. With a
+// BindAddress the port is bound on exactly that interface; otherwise Port is
+// used as given, so a bare port listens on every interface.
+func (c *NodeConfig) ListenAddress() (string, error) {
+	if c.BindAddress == "" {
+		return c.Port, nil
+	}
+
+	bind := strings.TrimSuffix(strings.TrimPrefix(c.BindAddress, "["), "]")
+	if _, _, err := net.SplitHostPort(c.BindAddress); err == nil || bind == "" {
+		return "", fmt.Errorf("%w: %q must be a host or IP without port", ErrInvalidBindAddress, c.BindAddress)
+	}
+
+	port := strings.TrimPrefix(c.Port, ":")
+	if strings.Contains(port, ":") {
+		return "", fmt.Errorf("%w: port %s already names a host", ErrInvalidBindAddress, c.Port)
+	}
+	return net.JoinHostPort(bind, port), nil
+}
+
 // GetLeftPort returns the left child port (index 0) for binary trees
 func (c *NodeConfig) GetLeftPort() string {
 	if len(c.ChildrenPorts) > 0 {
@@ -92,3 +339,33 @@ func (c *NodeConfig) GetChildPort(index int) string {
 func (c *NodeConfig) GetNumChildren() int {
 	return len(c.ChildrenPorts)
 }
+
+// validateChildren checks that the configured child ports are distinct and
+// differ from the node's own port, whatever the number of children
+func (c *NodeConfig) validateChildren() error {
+	seen := make(map[string]int, len(c.ChildrenPorts))
+	for i, port := range c.ChildrenPorts {
+		if port == "" {
+			continue
+		}
+		if port == c.Port {
+			return fmt.Errorf("%w: child %d uses the node's own port %s", ErrDuplicateChildPort, i, port)
+		}
+		if first, ok := seen[port]; ok {
+			return fmt.Errorf("%w: children %d and %d share port %s", ErrDuplicateChildPort, first, i, port)
+		}
+		seen[port] = i
+	}
+	return nil
+}
+
+// GetNumConfiguredChildren returns the number of children with a port set
+func (c *NodeConfig) GetNumConfiguredChildren() int {
+	count := 0
+	for _, port := range c.ChildrenPorts {
+		if port != "" {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,294 @@
+package factory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseNodeConfigArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		children []string
+		wantErr  bool
+	}{
+		{
+			name:     "comma-separated children",
+			args:     []string{"-port", "8080", "-children=8081,8082,8083"},
+			children: []string{"8081", "8082", "8083"},
+		},
+		{
+			name:     "repeated children flag",
+			args:     []string{"-port", "8080", "-children", "8081", "-children", "8082,8083"},
+			children: []string{"8081", "8082", "8083"},
+		},
+		{
+			name:     "empty children list is a leaf",
+			args:     []string{"-port", "8080", "-children="},
+			children: nil,
+		},
+		{
+			name:     "legacy left and right flags",
+			args:     []string{"-port", "8080", "-left", "8081", "-right", "8082"},
+			children: []string{"8081", "8082"},
+		},
+		{
+			name:     "legacy right flag only",
+			args:     []string{"-port", "8080", "-right", "8082"},
+			children: []string{"", "8082"},
+		},
+		{
+			name:    "children combined with left",
+			args:    []string{"-port", "8080", "-children=8081", "-left", "8082"},
+			wantErr: true,
+		},
+		{
+			name:    "missing port",
+			args:    []string{"-children=8081"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseNodeConfigArgs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error: %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if config.Port != "8080" {
+				t.Errorf("Expected port 8080, got %s", config.Port)
+			}
+			if len(config.ChildrenPorts) != len(tt.children) ||
+				(len(tt.children) > 0 && !reflect.DeepEqual(config.ChildrenPorts, tt.children)) {
+				t.Errorf("Expected children %v, got %v", tt.children, config.ChildrenPorts)
+			}
+		})
+	}
+}
+
+func TestParseThreeChildren(t *testing.T) {
+	config, err := ParseNodeConfigArgs([]string{"-port", "3030", "-children=3031,3032,3033"})
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	if config.GetNumChildren() != 3 {
+		t.Fatalf("Expected 3 children, got %d", config.GetNumChildren())
+	}
+	for i, expected := range []string{"3031", "3032", "3033"} {
+		if port := config.GetChildPort(i); port != expected {
+			t.Errorf("Child %d: expected port %s, got %s", i, expected, port)
+		}
+	}
+}
+
+func TestParseParentFlag(t *testing.T) {
+	config, err := ParseNodeConfigArgs([]string{"-port", "3031", "-parent", "3030", "-children=3033"})
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	if config.ParentPort != "3030" {
+		t.Errorf("Expected parent port 3030, got %q", config.ParentPort)
+	}
+}
+
+func TestParseHealthFlag(t *testing.T) {
+	config, err := ParseNodeConfigArgs([]string{"-port", "3030", "-health", ":8080"})
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	if config.HealthAddress != ":8080" {
+		t.Errorf("Expected health address :8080, got %q", config.HealthAddress)
+	}
+}
+
+func TestParseMetricsFlag(t *testing.T) {
+	config, err := ParseNodeConfigArgs([]string{"-port", "3030", "-metrics", ":9090"})
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	if config.MetricsAddress != ":9090" {
+		t.Errorf("Expected metrics address :9090, got %q", config.MetricsAddress)
+	}
+}
+
+func TestAuthTokenFromEnv(t *testing.T) {
+	t.Setenv(AuthTokenEnv, "s3cret")
+
+	config, err := ParseNodeConfigArgs([]string{"-port", "3030"})
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	if config.AuthToken != "s3cret" {
+		t.Errorf("Expected auth token from %s, got %q", AuthTokenEnv, config.AuthToken)
+	}
+	if strings.Contains(config.String(), "s3cret") {
+		t.Errorf("Expected the token to be left out of %q", config)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	config, err := ParseNodeConfigArgs([]string{"-port", "3030"})
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	if config.ShutdownTimeout != DefaultShutdownTimeout {
+		t.Errorf("Expected default shutdown timeout %v, got %v", DefaultShutdownTimeout, config.ShutdownTimeout)
+	}
+
+	t.Setenv(ShutdownTimeoutEnv, "45s")
+	config, err = ParseNodeConfigArgs([]string{"-port", "3030"})
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	if config.ShutdownTimeout != 45*time.Second {
+		t.Errorf("Expected shutdown timeout from %s, got %v", ShutdownTimeoutEnv, config.ShutdownTimeout)
+	}
+
+	// The flag takes precedence over the environment, also with -config
+	for _, args := range [][]string{
+		{"-port", "3030", "-shutdown-timeout", "3s"},
+		{"-config", "testdata/node.yaml", "-shutdown-timeout", "3s"},
+	} {
+		config, err = ParseNodeConfigArgs(args)
+		if err != nil {
+			t.Fatalf("Failed to parse %v: %v", args, err)
+		}
+		if config.ShutdownTimeout != 3*time.Second {
+			t.Errorf("Expected shutdown timeout 3s for %v, got %v", args, config.ShutdownTimeout)
+		}
+	}
+}
+
+func TestInvalidShutdownTimeout(t *testing.T) {
+	if _, err := ParseNodeConfigArgs([]string{"-port", "3030", "-shutdown-timeout", "-1s"}); err == nil {
+		t.Error("Expected a negative -shutdown-timeout to be rejected")
+	}
+
+	t.Setenv(ShutdownTimeoutEnv, "soon")
+	if _, err := ParseNodeConfigArgs([]string{"-port", "3030"}); err == nil {
+		t.Errorf("Expected an invalid %s to be rejected", ShutdownTimeoutEnv)
+	}
+}
+
+func TestNodeConfigString(t *testing.T) {
+	config := NewNodeConfigWithChildren("3030", []string{"3031", "3032"})
+	if got, want := config.String(), "port=3030 children=[3031 3032]"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	leaf := NewNodeConfigWithChildren("3031", nil)
+	leaf.ParentPort = "3030"
+	leaf.HealthAddress = ":8080"
+	if got, want := fmt.Sprint(leaf), "port=3031 parent=3030 children=[] health=:8080"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	right := "3032"
+	if got, want := NewNodeConfigFromPorts("3030", nil, &right).String(), "port=3030 children=[- 3032]"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestNodeConfigMarshalJSON(t *testing.T) {
+	config := NewNodeConfigWithChildren("3030", []string{"3031", "3032"})
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if got, want := string(data), `{"port":"3030","children":["3031","3032"]}`; got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+
+	leaf := NewNodeConfigWithChildren("3031", nil)
+	leaf.ParentPort = "3030"
+	data, err = json.Marshal(leaf)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if got, want := string(data), `{"port":"3031","parent":"3030","children":[]}`; got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestParseNodeConfigArgsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want error
+	}{
+		{name: "missing port", args: []string{"-left", "3031"}, want: ErrMissingPort},
+		{name: "children with left", args: []string{"-port", "3030", "-children", "3031", "-left", "3032"}, want: ErrConflictingFlags},
+		{name: "config with port", args: []string{"-config", "node.yaml", "-port", "3030"}, want: ErrConflictingFlags},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseNodeConfigArgs(tt.args); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestParseBindFlag(t *testing.T) {
+	config, err := ParseNodeConfigArgs([]string{"-port", "3030", "-bind", "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	if config.BindAddress != "127.0.0.1" {
+		t.Errorf("Expected bind address 127.0.0.1, got %q", config.BindAddress)
+	}
+	if got := config.String(); !strings.Contains(got, "bind=127.0.0.1") {
+		t.Errorf("Expected the bind address in %q", got)
+	}
+}
+
+func TestNodeConfigListenAddress(t *testing.T) {
+	tests := []struct {
+		port, bind, want string
+	}{
+		{port: "3030", want: "3030"},
+		{port: "127.0.0.1:3030", want: "127.0.0.1:3030"},
+		{port: "3030", bind: "127.0.0.1", want: "127.0.0.1:3030"},
+		{port: ":3030", bind: "0.0.0.0", want: "0.0.0.0:3030"},
+		{port: "3030", bind: "::1", want: "[::1]:3030"},
+		{port: "3030", bind: "[::1]", want: "[::1]:3030"},
+	}
+
+	for _, tt := range tests {
+		config := NodeConfig{Port: tt.port, BindAddress: tt.bind}
+		got, err := config.ListenAddress()
+		if err != nil {
+			t.Errorf("ListenAddress(%q, %q) failed: %v", tt.port, tt.bind, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ListenAddress(%q, %q) = %q, want %q", tt.port, tt.bind, got, tt.want)
+		}
+	}
+
+	for _, config := range []NodeConfig{
+		{Port: "3030", BindAddress: "127.0.0.1:3030"},
+		{Port: "localhost:3030", BindAddress: "127.0.0.1"},
+		{Port: "3030", BindAddress: "[]"},
+	} {
+		if got, err := config.ListenAddress(); !errors.Is(err, ErrInvalidBindAddress) {
+			t.Errorf("Expected ErrInvalidBindAddress for %+v, got %q, %v", config, got, err)
+		}
+	}
+}
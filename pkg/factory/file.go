@@ -0,0 +1,62 @@
+package factory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig is the on-disk layout of a node configuration file
+type fileConfig struct {
+	Port     string   `json:"port" yaml:"port"`
+	Bind     string   `json:"bind" yaml:"bind"`
+	Parent   string   `json:"parent" yaml:"parent"`
+	Children []string `json:"children" yaml:"children"`
+	Health   string   `json:"health" yaml:"health"`
+	Metrics  string   `json:"metrics" yaml:"metrics"`
+}
+
+// LoadNodeConfigFromFile reads a NodeConfig from a YAML or JSON file. Files
+// ending in .json are parsed as JSON, anything else as YAML.
+func LoadNodeConfigFromFile(path string) (NodeConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return NodeConfig{}, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	var file fileConfig
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&file)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&file)
+	}
+	if err != nil && err != io.EOF {
+		return NodeConfig{}, fmt.Errorf("malformed config file %s: %v", path, err)
+	}
+
+	if file.Port == "" {
+		return NodeConfig{}, fmt.Errorf("config file %s: %w", path, ErrMissingPort)
+	}
+	for i, child := range file.Children {
+		if strings.TrimSpace(child) == "" {
+			return NodeConfig{}, fmt.Errorf("config file %s: child %d has an empty port", path, i)
+		}
+	}
+
+	config := NewNodeConfigWithChildren(file.Port, file.Children)
+	config.BindAddress = file.Bind
+	config.ParentPort = file.Parent
+	config.HealthAddress = file.Health
+	config.MetricsAddress = file.Metrics
+	return config, nil
+}
@@ -0,0 +1,114 @@
+package factory
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadNodeConfigFromYAML(t *testing.T) {
+	config, err := LoadNodeConfigFromFile("testdata/node.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if config.Port != "3030" {
+		t.Errorf("Expected port 3030, got %s", config.Port)
+	}
+	if expected := []string{"3031", "3032", "3033"}; !reflect.DeepEqual(config.ChildrenPorts, expected) {
+		t.Errorf("Expected children %v, got %v", expected, config.ChildrenPorts)
+	}
+}
+
+func TestLoadNodeConfigFromJSON(t *testing.T) {
+	config, err := LoadNodeConfigFromFile("testdata/node.json")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if config.Port != "3031" || config.ParentPort != "3030" {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+	if expected := []string{"3034", "3035"}; !reflect.DeepEqual(config.ChildrenPorts, expected) {
+		t.Errorf("Expected children %v, got %v", expected, config.ChildrenPorts)
+	}
+}
+
+func TestLoadNodeConfigErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		contains string
+	}{
+		{name: "missing port", file: "node.yaml", content: "children: [3031]\n", contains: "port is required"},
+		{name: "unknown field", file: "node.yaml", content: "port: 3030\nchildern: [3031]\n", contains: "malformed"},
+		{name: "invalid yaml", file: "node.yaml", content: "port: [3030\n", contains: "malformed"},
+		{name: "invalid json", file: "node.json", content: "{\"port\": 3030,}", contains: "malformed"},
+		{name: "empty child", file: "node.yaml", content: "port: 3030\nchildren: [3031, \"\"]\n", contains: "child 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			_, err := LoadNodeConfigFromFile(path)
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("Expected error containing %q, got %v", tt.contains, err)
+			}
+		})
+	}
+}
+
+func TestParseConfigFlag(t *testing.T) {
+	config, err := ParseNodeConfigArgs([]string{"-config", "testdata/node.yaml"})
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	if config.Port != "3030" || config.GetNumChildren() != 3 {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+
+	if _, err := ParseNodeConfigArgs([]string{"-config", "testdata/node.yaml", "-port", "8080"}); err == nil {
+		t.Error("Expected error when -config is combined with other flags")
+	}
+}
+
+func TestMarshaledConfigLoadsBack(t *testing.T) {
+	right := "3032"
+	config := NewNodeConfigFromPorts("3030", nil, &right)
+	config.BindAddress = "127.0.0.1"
+	config.ParentPort = "3029"
+	config.HealthAddress = ":8080"
+	config.MetricsAddress = ":9090"
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "node.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	loaded, err := LoadNodeConfigFromFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load marshaled config %s: %v", data, err)
+	}
+
+	// The unconfigured left slot is left out, so the right child moves up
+	want := NewNodeConfigWithChildren("3030", []string{"3032"})
+	want.BindAddress = config.BindAddress
+	want.ParentPort = config.ParentPort
+	want.HealthAddress = config.HealthAddress
+	want.MetricsAddress = config.MetricsAddress
+	if !reflect.DeepEqual(loaded, want) {
+		t.Errorf("Expected %+v, got %+v", want, loaded)
+	}
+}
@@ -0,0 +1,106 @@
+package factory
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// HealthState summarises a node's health
+type HealthState string
+
+const (
+	// HealthHealthy means the node is running, listening and connected to
+	// every configured child
+	HealthHealthy HealthState = "healthy"
+
+	// HealthDegraded means the node is serving but some configured child is
+	// not connected or its circuit breaker is not closed, so part of the
+	// subtree is unreachable
+	HealthDegraded HealthState = "degraded"
+
+	// HealthUnhealthy means the node is stopped or its server is not listening
+	HealthUnhealthy HealthState = "unhealthy"
+)
+
+// ChildHealth is the connection state of one configured child
+type ChildHealth struct {
+	Index     int    `json:"index"`
+	Address   string `json:"address"`
+	Connected bool   `json:"connected"`
+
+	// Breaker is the state of the circuit breaker guarding the child
+	Breaker BreakerState `json:"breaker"`
+}
+
+// HealthStatus reports whether a node is running, whether its server is
+// listening and which configured children it is connected to and can reach
+type HealthStatus struct {
+	State     HealthState   `json:"state"`
+	Running   bool          `json:"running"`
+	Listening bool          `json:"listening"`
+	Children  []ChildHealth `json:"children,omitempty"`
+}
+
+// IsHealthy returns the node's current health. A child counts as connected
+// once its client has connected, until the client is closed or detached.
+func (bn *BTreeNode) IsHealthy() HealthStatus {
+	status := HealthStatus{
+		Running:   bn.running.Load(),
+		Listening: bn.listening.Load(),
+	}
+
+	allReachable := true
+	for i, client := range bn.ChildrenClients {
+		if client == nil {
+			continue
+		}
+		connected := bn.childConnected[i].Load()
+		breaker := bn.breakers[i].State()
+		allReachable = allReachable && connected && breaker == BreakerClosed
+		status.Children = append(status.Children, ChildHealth{Index: i, Address: bn.config.GetChildPort(i), Connected: connected, Breaker: breaker})
+	}
+
+	switch {
+	case !status.Running || !status.Listening:
+		status.State = HealthUnhealthy
+	case !allReachable:
+		status.State = HealthDegraded
+	default:
+		status.State = HealthHealthy
+	}
+	return status
+}
+
+// HealthHandler returns an HTTP handler reporting IsHealthy as JSON. It
+// responds 503 when the node is unhealthy and 200 otherwise, so a degraded
+// node that can still serve its reachable children stays in rotation.
+func (bn *BTreeNode) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := bn.IsHealthy()
+
+		w.Header().Set("Content-Type", "application/json")
+		if status.State == HealthUnhealthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			bn.log().Warn("Failed to write health status", "error", err)
+		}
+	})
+}
+
+// startHealthServer serves HealthHandler at /healthz on the configured
+// health address until Stop closes it
+func (bn *BTreeNode) startHealthServer() {
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", bn.HealthHandler())
+	bn.healthServer = &http.Server{Addr: bn.config.HealthAddress, Handler: mux}
+
+	server := bn.healthServer
+	bn.spawn(func() {
+		bn.log().Info("Serving health checks", "address", server.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			bn.log().Error("Health server error", "error", err)
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package factory
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/transport"
+	"github.com/xnok/btree-server-msg/pkg/transport/inmem"
+)
+
+// waitForHealth polls IsHealthy until it reports the expected state
+func waitForHealth(t *testing.T, bn *BTreeNode, expected HealthState) HealthStatus {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		status := bn.IsHealthy()
+		if status.State == expected {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected state %s, got %+v", expected, status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHealthyWithConnectedChild(t *testing.T) {
+	registry := inmem.NewRegistry()
+	inMemoryFactory := func() transport.Transport {
+		return inmem.NewInMemoryTransportWithRegistry(registry)
+	}
+
+	leaf, err := NewBTreeNode(NewNodeConfigWithChildren("1", nil), inMemoryFactory)
+	if err != nil {
+		t.Fatalf("Failed to create leaf: %v", err)
+	}
+	if err := leaf.Start(); err != nil {
+		t.Fatalf("Failed to start leaf: %v", err)
+	}
+	defer leaf.Stop()
+	waitForHealth(t, leaf, HealthHealthy)
+
+	parent, err := NewBTreeNode(NewNodeConfigWithChildren("0", []string{"1"}), inMemoryFactory)
+	if err != nil {
+		t.Fatalf("Failed to create parent: %v", err)
+	}
+	if status := parent.IsHealthy(); status.State != HealthUnhealthy {
+		t.Errorf("Expected a node that was never started to be unhealthy, got %s", status.State)
+	}
+	if err := parent.Start(); err != nil {
+		t.Fatalf("Failed to start parent: %v", err)
+	}
+
+	status := waitForHealth(t, parent, HealthHealthy)
+	if !status.Running || !status.Listening {
+		t.Errorf("Expected running and listening, got %+v", status)
+	}
+	if len(status.Children) != 1 || !status.Children[0].Connected || status.Children[0].Address != "1" {
+		t.Errorf("Expected child 1 to be connected, got %+v", status.Children)
+	}
+
+	parent.Stop()
+	if status := parent.IsHealthy(); status.State != HealthUnhealthy {
+		t.Errorf("Expected a stopped node to be unhealthy, got %s", status.State)
+	}
+}
+
+func TestDegradedWhenChildNeverComesUp(t *testing.T) {
+	registry := inmem.NewRegistry()
+	inMemoryFactory := func() transport.Transport {
+		return inmem.NewInMemoryTransportWithRegistry(registry)
+	}
+
+	// Nothing ever listens on "9"
+	parent, err := NewBTreeNode(NewNodeConfigWithChildren("0", []string{"9"}), inMemoryFactory)
+	if err != nil {
+		t.Fatalf("Failed to create parent: %v", err)
+	}
+	if err := parent.Start(); err != nil {
+		t.Fatalf("Failed to start parent: %v", err)
+	}
+	defer parent.Stop()
+
+	status := waitForHealth(t, parent, HealthDegraded)
+	if len(status.Children) != 1 || status.Children[0].Connected {
+		t.Errorf("Expected child 9 to be disconnected, got %+v", status.Children)
+	}
+
+	// A degraded node still serves, so the endpoint reports 200
+	recorder := httptest.NewRecorder()
+	parent.HealthHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected 200 for a degraded node, got %d", recorder.Code)
+	}
+
+	var body HealthStatus
+	if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode health body: %v", err)
+	}
+	if body.State != HealthDegraded {
+		t.Errorf("Expected degraded in body, got %s", body.State)
+	}
+}
+
+func TestHealthEndpointServedAlongsideNode(t *testing.T) {
+	config := NewNodeConfigWithChildren("0", nil)
+	config.HealthAddress = "127.0.0.1:39121"
+
+	node, err := NewBTreeNode(config, func() transport.Transport {
+		return inmem.NewInMemoryTransportWithRegistry(inmem.NewRegistry())
+	})
+	if err != nil {
+		t.Fatalf("Failed to create node: %v", err)
+	}
+	if err := node.Start(); err != nil {
+		t.Fatalf("Failed to start node: %v", err)
+	}
+	defer node.Stop()
+	waitForHealth(t, node, HealthHealthy)
+
+	var resp *http.Response
+	for i := 0; ; i++ {
+		if resp, err = http.Get("http://" + config.HealthAddress + "/healthz"); err == nil {
+			break
+		}
+		if i == 20 {
+			t.Fatalf("Failed to reach health endpoint: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestChildDisconnectEventDegradesHealth(t *testing.T) {
+	leafPort := "39152"
+	root, err := NewBTreeNodeWithTCP(NewNodeConfigFromPorts("39151", &leafPort, nil))
+	if err != nil {
+		t.Fatalf("Failed to create root: %v", err)
+	}
+	leaf, err := NewBTreeNodeWithTCP(NewNodeConfigFromPorts(leafPort, nil, nil))
+	if err != nil {
+		t.Fatalf("Failed to create leaf: %v", err)
+	}
+
+	if err := leaf.Start(); err != nil {
+		t.Fatalf("Failed to start leaf: %v", err)
+	}
+	if err := root.Start(); err != nil {
+		leaf.Stop()
+		t.Fatalf("Failed to start root: %v", err)
+	}
+	defer root.Stop()
+	waitForHealth(t, root, HealthHealthy)
+
+	// The leaf going away is reported by the TCP client's events
+	leaf.Stop()
+	status := waitForHealth(t, root, HealthDegraded)
+	if status.Children[0].Connected {
+		t.Errorf("Expected the stopped leaf to be reported disconnected, got %+v", status.Children[0])
+	}
+}
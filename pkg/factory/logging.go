@@ -0,0 +1,27 @@
+package factory
+
+import "log/slog"
+
+// SetLogger sets the structured logger of the node, its server, its child
+// clients and the wiring between them; records carry a "port" attribute. A
+// nil logger restores slog.Default(). It must be called before Start.
+func (bn *BTreeNode) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger = logger.With("port", bn.config.Port)
+
+	bn.logger = logger
+	bn.Node.SetLogger(logger)
+	bn.Server.SetLogger(logger)
+	for _, client := range bn.ChildrenClients {
+		if client != nil {
+			client.SetLogger(logger)
+		}
+	}
+}
+
+// log returns the logger used for the node's wiring
+func (bn *BTreeNode) log() *slog.Logger {
+	return bn.logger
+}
@@ -0,0 +1,109 @@
+package factory
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsNamespace prefixes every exported metric name
+const MetricsNamespace = "btree"
+
+// Descriptions of the exported metrics; every metric carries a node label
+var (
+	metricReceived = prometheus.NewDesc(
+		prometheus.BuildFQName(MetricsNamespace, "messages", "received_total"),
+		"Messages handled for broadcast.", []string{"node"}, nil)
+	metricBroadcast = prometheus.NewDesc(
+		prometheus.BuildFQName(MetricsNamespace, "messages", "broadcast_total"),
+		"Successful deliveries to child channels.", []string{"node"}, nil)
+	metricDropped = prometheus.NewDesc(
+		prometheus.BuildFQName(MetricsNamespace, "messages", "dropped_total"),
+		"Deliveries skipped because a child channel was full.", []string{"node"}, nil)
+	metricDuplicates = prometheus.NewDesc(
+		prometheus.BuildFQName(MetricsNamespace, "messages", "duplicates_total"),
+		"Messages dropped because their ID was already seen.", []string{"node"}, nil)
+	metricRateLimited = prometheus.NewDesc(
+		prometheus.BuildFQName(MetricsNamespace, "messages", "rate_limited_total"),
+		"Inbound messages dropped by the rate limit.", []string{"node"}, nil)
+	metricInvalid = prometheus.NewDesc(
+		prometheus.BuildFQName(MetricsNamespace, "messages", "invalid_total"),
+		"Inbound messages rejected by the node's validator.", []string{"node"}, nil)
+	metricActiveConnections = prometheus.NewDesc(
+		prometheus.BuildFQName(MetricsNamespace, "", "active_connections"),
+		"Connections currently open on the node's server.", []string{"node"}, nil)
+	metricChildConnected = prometheus.NewDesc(
+		prometheus.BuildFQName(MetricsNamespace, "", "child_connected"),
+		"Whether the node is connected to a configured child (1) or not (0).", []string{"node", "child", "address"}, nil)
+)
+
+// nodeCollector reads a node's counters and connection state at scrape time,
+// so the node keeps no Prometheus state of its own
+type nodeCollector struct {
+	bn *BTreeNode
+}
+
+// Describe sends the descriptions of every metric the collector exports
+func (c nodeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- metricReceived
+	ch <- metricBroadcast
+	ch <- metricDropped
+	ch <- metricDuplicates
+	ch <- metricRateLimited
+	ch <- metricInvalid
+	ch <- metricActiveConnections
+	ch <- metricChildConnected
+}
+
+// Collect sends the current value of every metric
+func (c nodeCollector) Collect(ch chan<- prometheus.Metric) {
+	node := c.bn.Node.Name()
+	stats := c.bn.Node.Stats()
+
+	ch <- prometheus.MustNewConstMetric(metricReceived, prometheus.CounterValue, float64(stats.Received), node)
+	ch <- prometheus.MustNewConstMetric(metricBroadcast, prometheus.CounterValue, float64(stats.Broadcast), node)
+	ch <- prometheus.MustNewConstMetric(metricDropped, prometheus.CounterValue, float64(stats.Dropped), node)
+	ch <- prometheus.MustNewConstMetric(metricDuplicates, prometheus.CounterValue, float64(stats.Duplicates), node)
+	ch <- prometheus.MustNewConstMetric(metricRateLimited, prometheus.CounterValue, float64(stats.RateLimited), node)
+	ch <- prometheus.MustNewConstMetric(metricInvalid, prometheus.CounterValue, float64(stats.Invalid), node)
+
+	// Transports that do not count connections export no connection gauge
+	if active, ok := c.bn.Server.ActiveConnections(); ok {
+		ch <- prometheus.MustNewConstMetric(metricActiveConnections, prometheus.GaugeValue, float64(active), node)
+	}
+
+	for _, child := range c.bn.IsHealthy().Children {
+		connected := 0.0
+		if child.Connected {
+			connected = 1
+		}
+		ch <- prometheus.MustNewConstMetric(metricChildConnected, prometheus.GaugeValue, connected, node, strconv.Itoa(child.Index), child.Address)
+	}
+}
+
+// MetricsHandler returns an HTTP handler exposing the node's metrics in the
+// Prometheus text format
+func (bn *BTreeNode) MetricsHandler() http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(nodeCollector{bn: bn})
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// startMetricsServer serves MetricsHandler at /metrics on the configured
+// metrics address until Stop closes it
+func (bn *BTreeNode) startMetricsServer() {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", bn.MetricsHandler())
+	bn.metricsServer = &http.Server{Addr: bn.config.MetricsAddress, Handler: mux}
+
+	server := bn.metricsServer
+	bn.spawn(func() {
+		bn.log().Info("Serving metrics", "address", server.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			bn.log().Error("Metrics server error", "error", err)
+		}
+	})
+}
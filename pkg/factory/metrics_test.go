@@ -0,0 +1,100 @@
+package factory
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+)
+
+// scrapeMetrics fetches the metrics page at url, retrying until the server is up
+func scrapeMetrics(t *testing.T, url string) string {
+	t.Helper()
+
+	var resp *http.Response
+	var err error
+	for i := 0; ; i++ {
+		if resp, err = http.Get(url); err == nil {
+			break
+		}
+		if i == 20 {
+			t.Fatalf("Failed to reach metrics endpoint: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read metrics: %v", err)
+	}
+	return string(body)
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	leafPort := "39132"
+	leaf, err := NewBTreeNodeWithTCP(NewNodeConfigFromPorts(leafPort, nil, nil))
+	if err != nil {
+		t.Fatalf("Failed to create leaf: %v", err)
+	}
+	if err := leaf.Start(); err != nil {
+		t.Fatalf("Failed to start leaf: %v", err)
+	}
+	defer leaf.Stop()
+	waitForHealth(t, leaf, HealthHealthy)
+
+	config := NewNodeConfigWithChildren("39131", []string{leafPort})
+	config.MetricsAddress = "127.0.0.1:39133"
+	root, err := NewBTreeNodeWithTCP(config)
+	if err != nil {
+		t.Fatalf("Failed to create root: %v", err)
+	}
+	if err := root.Start(); err != nil {
+		t.Fatalf("Failed to start root: %v", err)
+	}
+	defer root.Stop()
+	waitForHealth(t, root, HealthHealthy)
+
+	const total = 3
+	for i := 0; i < total; i++ {
+		root.Node.GetInboundChannel() <- btree.NewMessage("measured", fmt.Sprintf("m-%d", i))
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for leaf.Node.Stats().Received < total {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the leaf to receive %d messages, got %d", total, leaf.Node.Stats().Received)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	metrics := scrapeMetrics(t, "http://"+config.MetricsAddress+"/metrics")
+	for _, expected := range []string{
+		`btree_messages_received_total{node="node-39131"} 3`,
+		`btree_messages_broadcast_total{node="node-39131"} 3`,
+		`btree_messages_dropped_total{node="node-39131"} 0`,
+		`btree_messages_invalid_total{node="node-39131"} 0`,
+		`btree_active_connections{node="node-39131"} 0`,
+		`btree_child_connected{address="39132",child="0",node="node-39131"} 1`,
+	} {
+		if !strings.Contains(metrics, expected) {
+			t.Errorf("Expected %q in metrics:\n%s", expected, metrics)
+		}
+	}
+
+	// The leaf's server holds the root's connection; the handler works
+	// without a metrics server of its own
+	recorder := httptest.NewRecorder()
+	leaf.MetricsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	leafMetrics := recorder.Body.String()
+	if !strings.Contains(leafMetrics, `btree_active_connections{node="node-39132"} 1`) {
+		t.Errorf("Expected one active connection on the leaf:\n%s", leafMetrics)
+	}
+}
@@ -2,8 +2,13 @@ package factory
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/xnok/btree-server-msg/pkg/btree"
@@ -16,8 +21,30 @@ type BTreeNode struct {
 	Node            *btree.Node
 	Server          *transport.Server
 	ChildrenClients []*transport.Client
+	config          NodeConfig
+	logger          *slog.Logger
+	wg              sync.WaitGroup
 	ctx             context.Context
 	cancel          context.CancelFunc
+
+	// Health state reported by IsHealthy; childConnected is indexed like
+	// ChildrenClients
+	running        atomic.Bool
+	listening      atomic.Bool
+	childConnected []atomic.Bool
+	healthServer   *http.Server
+
+	// breakers guard each configured child's outbound path and are indexed
+	// like ChildrenClients
+	breakers []*circuitBreaker
+
+	// sending counts messages taken from the node's child channels that the
+	// children's clients have not accepted yet; those whose send Stop cut
+	// short stay counted
+	sending atomic.Int64
+
+	// metricsServer serves /metrics when a metrics address is configured
+	metricsServer *http.Server
 }
 
 // TransportFactory defines a function that creates transport instances
@@ -25,35 +52,103 @@ type TransportFactory func() transport.Transport
 
 // NewBTreeNode creates a fully wired btree node with the specified transport
 func NewBTreeNode(config NodeConfig, transportFactory TransportFactory) (*BTreeNode, error) {
+	if err := config.validateChildren(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	listenAddress, err := config.ListenAddress()
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Create the btree node with the number of children specified in config
 	nodeName := fmt.Sprintf("node-%s", config.Port)
 	node := btree.NewNode(nodeName, config.GetNumChildren())
 
+	// Unconfigured children get no channel, so broadcasts cannot pile up
+	// messages nobody drains; the remaining children keep their indices
+	for i, childPort := range config.ChildrenPorts {
+		if childPort == "" {
+			if err := node.RemoveChild(i); err != nil {
+				cancel()
+				return nil, fmt.Errorf("failed to skip unconfigured child %d: %v", i, err)
+			}
+		}
+	}
+
 	// Create and configure the server with the specified transport
 	serverTransport := transportFactory()
-	server := transport.NewServer(serverTransport, config.Port)
+	if err := applyAuthToken(serverTransport, config.AuthToken); err != nil {
+		cancel()
+		return nil, err
+	}
+	server := transport.NewServer(serverTransport, listenAddress)
 
 	btreeNode := &BTreeNode{
 		Node:            node,
 		Server:          server,
 		ChildrenClients: make([]*transport.Client, config.GetNumChildren()),
+		childConnected:  make([]atomic.Bool, config.GetNumChildren()),
+		breakers:        make([]*circuitBreaker, config.GetNumChildren()),
+		config:          config,
 		ctx:             ctx,
 		cancel:          cancel,
 	}
 
-	// Create child clients for each configured child port
+	// Create child clients for each configured child port; every client owns
+	// a transport of its own so closing it cannot affect the server or siblings
+	owned := []transport.Transport{serverTransport}
 	for i, childPort := range config.ChildrenPorts {
 		if childPort != "" {
 			childTransport := transportFactory()
+			if containsTransport(owned, childTransport) {
+				cancel()
+				return nil, fmt.Errorf("transport factory returned a transport already in use; each server and client needs its own")
+			}
+			owned = append(owned, childTransport)
+			if err := applyAuthToken(childTransport, config.AuthToken); err != nil {
+				cancel()
+				return nil, err
+			}
 			btreeNode.ChildrenClients[i] = transport.NewClient(childTransport, childPort)
+			btreeNode.breakers[i] = newCircuitBreaker(config.Breaker)
 		}
 	}
+	btreeNode.SetLogger(nil)
+	node.HandleType(TypeTopology, btreeNode.handleTopologyQuery)
 
 	return btreeNode, nil
 }
 
+// containsTransport reports whether t is one of transports. Transports of
+// non-comparable types cannot be told apart and are assumed distinct.
+func containsTransport(transports []transport.Transport, t transport.Transport) bool {
+	if !reflect.TypeOf(t).Comparable() {
+		return false
+	}
+	for _, existing := range transports {
+		if reflect.TypeOf(existing) == reflect.TypeOf(t) && existing == t {
+			return true
+		}
+	}
+	return false
+}
+
+// applyAuthToken makes t require and present token; transports that cannot
+// authenticate are refused rather than left open
+func applyAuthToken(t transport.Transport, token string) error {
+	if token == "" {
+		return nil
+	}
+	authenticator, ok := t.(transport.Authenticator)
+	if !ok {
+		return fmt.Errorf("transport %T does not support auth tokens", t)
+	}
+	authenticator.SetAuthToken(token)
+	return nil
+}
+
 // NewBTreeNodeWithTCP creates a btree node using TCP transport (convenience function)
 func NewBTreeNodeWithTCP(config NodeConfig) (*BTreeNode, error) {
 	return NewBTreeNode(config, func() transport.Transport {
@@ -61,41 +156,89 @@ func NewBTreeNodeWithTCP(config NodeConfig) (*BTreeNode, error) {
 	})
 }
 
-// Start begins all components and wires them together
+// Start begins all components and wires them together. The server is
+// bound first, so a listen failure such as a port already in use is
+// returned before anything else is started.
 func (bn *BTreeNode) Start() error {
+	if err := bn.Server.Start(bn.ctx); err != nil {
+		return fmt.Errorf("failed to start server: %w", err)
+	}
+	bn.listening.Store(true)
+
 	// Start the btree node
 	bn.Node.Start()
+	bn.running.Store(true)
 
-	// Start the server
-	go func() {
-		if err := bn.Server.Start(bn.ctx); err != nil {
-			log.Printf("Server error: %v", err)
-		}
-	}()
+	if bn.config.HealthAddress != "" {
+		bn.startHealthServer()
+	}
+	if bn.config.MetricsAddress != "" {
+		bn.startMetricsServer()
+	}
 
 	// Wire inbound messages from server to node
-	go bn.wireInbound()
+	bn.spawn(bn.wireInbound)
 
-	// Connect to children and wire outbound messages
+	// Wire upstream messages from node back to the parent
+	bn.spawn(bn.wireUpstream)
+
+	// Connect to children and wire outbound and upstream messages; indices
+	// without a configured child have no client and are not wired
 	for i, client := range bn.ChildrenClients {
 		if client != nil {
-			go bn.connectToChild(client, fmt.Sprintf("child-%d", i))
-			go bn.wireChildOutbound(i)
+			bn.spawn(func() {
+				if bn.connectToChild(i) {
+					bn.childConnected[i].Store(true)
+				}
+			})
+			bn.spawn(func() { bn.wireChildOutbound(i) })
+			bn.spawn(func() { bn.wireChildInbound(i) })
+			bn.spawn(func() { bn.watchChildEvents(i) })
 		}
 	}
 
 	return nil
 }
 
-// Stop gracefully shuts down the node
-func (bn *BTreeNode) Stop() error {
-	log.Println("Shutting down btree node...")
+// spawn runs fn in a goroutine that Stop waits for before closing transports
+func (bn *BTreeNode) spawn(fn func()) {
+	bn.wg.Add(1)
+	go func() {
+		defer bn.wg.Done()
+		fn()
+	}()
+}
 
-	// Cancel context to stop all goroutines
-	bn.cancel()
+// Stop shuts down the node immediately. The returned summary reports the
+// messages still queued in the node and its transports when it stopped,
+// which are discarded; Stop drains nothing itself, so Drained only counts
+// messages a preceding drain handled. StopContext drains first.
+func (bn *BTreeNode) Stop() (btree.StopSummary, error) {
+	bn.log().Info("Shutting down btree node")
 
-	// Stop node
-	bn.Node.Stop()
+	bn.running.Store(false)
+
+	// Cancel context to stop all goroutines, and wait for the wiring to
+	// return so nothing sends to a transport after it is closed
+	bn.cancel()
+	if bn.healthServer != nil {
+		bn.healthServer.Close()
+	}
+	if bn.metricsServer != nil {
+		bn.metricsServer.Close()
+	}
+	bn.wg.Wait()
+
+	// Stop node, counting the messages the server had received but not yet
+	// handed over and those the children's pipelines had yet to send
+	summary := bn.Node.Stop()
+	inbound, pending := bn.transportBacklog()
+	summary.Inbound += inbound
+	summary.Pending += pending
+	summary.Discarded = summary.Inbound + summary.Pending
+	if summary.Discarded > 0 {
+		bn.log().Warn("Discarding queued messages", "inbound", summary.Inbound, "pending", summary.Pending)
+	}
 
 	// Close all child clients
 	for _, client := range bn.ChildrenClients {
@@ -106,8 +249,25 @@ func (bn *BTreeNode) Stop() error {
 
 	// Close server
 	bn.Server.Close()
+	bn.listening.Store(false)
+	for i := range bn.childConnected {
+		bn.childConnected[i].Store(false)
+	}
 
-	return nil
+	return summary, nil
+}
+
+// transportBacklog returns the messages the server has received but not yet
+// handed to the node, and those taken from the node's child channels that
+// the children's clients have yet to send
+func (bn *BTreeNode) transportBacklog() (inbound, pending int) {
+	inbound = len(bn.Server.GetInboundChannel())
+	for _, client := range bn.ChildrenClients {
+		if client != nil {
+			pending += len(client.GetOutboundChannel())
+		}
+	}
+	return inbound, pending + int(bn.sending.Load())
 }
 
 // wireInbound connects server inbound messages to node
@@ -126,11 +286,59 @@ func (bn *BTreeNode) wireInbound() {
 	}
 }
 
-// wireChildOutbound connects node child channel to corresponding client
+// wireUpstream connects node upstream messages to the server, which sends
+// them back over the connection the parent opened
+func (bn *BTreeNode) wireUpstream() {
+	for {
+		select {
+		case msg := <-bn.Node.GetUpstreamChannel():
+			select {
+			case bn.Server.GetOutboundChannel() <- msg:
+			case <-bn.ctx.Done():
+				return
+			}
+		case <-bn.ctx.Done():
+			return
+		}
+	}
+}
+
+// wireChildInbound connects messages a child sends upstream to the node
+func (bn *BTreeNode) wireChildInbound(childIndex int) {
+	client := bn.ChildrenClients[childIndex]
+	if client == nil {
+		return
+	}
+
+	for {
+		select {
+		case msg, ok := <-client.GetInboundChannel():
+			if !ok {
+				return
+			}
+			select {
+			case bn.Node.GetInboundChannel() <- msg:
+			case <-bn.ctx.Done():
+				return
+			}
+		case <-client.Done():
+			return
+		case <-bn.ctx.Done():
+			return
+		}
+	}
+}
+
+// wireChildOutbound connects node child channel to corresponding client.
+// Every child has its own channel in the node and its own goroutine here, so
+// a stalled child only backs up its own channel; what happens once that
+// channel is full is up to the node's delivery mode and overflow policy.
+// Sends go through the child's circuit breaker: while it is open, messages
+// are dead-lettered without an attempt.
 func (bn *BTreeNode) wireChildOutbound(childIndex int) {
 	childChannel, err := bn.Node.GetChildChannel(childIndex)
 	if err != nil {
-		log.Printf("Error getting child channel %d: %v", childIndex, err)
+		bn.log().Error("Error getting child channel", "child", childIndex, "error", err)
 		return
 	}
 
@@ -139,47 +347,162 @@ func (bn *BTreeNode) wireChildOutbound(childIndex int) {
 		return
 	}
 
+	breaker := bn.breakers[childIndex]
 	for {
 		select {
-		case msg := <-childChannel:
-			select {
-			case client.GetOutboundChannel() <- msg:
-			case <-bn.ctx.Done():
+		case msg, ok := <-childChannel:
+			if !ok {
+				return
+			}
+			if !breaker.allow() {
+				bn.Node.DeadLetter(msg, childIndex, ReasonCircuitOpen)
+				continue
+			}
+			bn.sending.Add(1)
+			err := bn.sendToChild(childIndex, client, breaker, msg)
+			if err != nil && bn.ctx.Err() != nil {
+				return
+			}
+			bn.sending.Add(-1)
+			if err != nil {
+				if errors.Is(err, transport.ErrClientClosed) {
+					bn.detachChild(childIndex)
+				} else {
+					bn.log().Error("Failed to send to child", "child", childIndex, "error", err)
+				}
 				return
 			}
+		case <-client.Done():
+			bn.detachChild(childIndex)
+			return
 		case <-bn.ctx.Done():
 			return
 		}
 	}
 }
 
-// connectToChild handles connection with retry logic
-func (bn *BTreeNode) connectToChild(client *transport.Client, childName string) {
-	for i := 0; i < 10; i++ {
+// sendToChild sends msg to a child's client, waiting up to the breaker's send
+// timeout. A timed-out send is dead-lettered and counted as a breaker failure;
+// only errors that end the child's pipeline are returned.
+func (bn *BTreeNode) sendToChild(childIndex int, client *transport.Client, breaker *circuitBreaker, msg btree.Message) error {
+	ctx, cancel := context.WithTimeout(bn.ctx, breaker.config.SendTimeout)
+	defer cancel()
+
+	err := client.Send(ctx, msg)
+	switch {
+	case err == nil:
+		breaker.success()
+		return nil
+	case errors.Is(err, context.DeadlineExceeded) && bn.ctx.Err() == nil:
+		bn.Node.DeadLetter(msg, childIndex, ReasonSendTimeout)
+		bn.recordChildFailure(childIndex, breaker)
+		return nil
+	default:
+		return err
+	}
+}
+
+// recordChildFailure counts a failure on a child's breaker, logging when the
+// circuit opens
+func (bn *BTreeNode) recordChildFailure(childIndex int, breaker *circuitBreaker) {
+	if breaker.failure() {
+		bn.log().Warn("Circuit breaker opened, rejecting messages to child", "child", childIndex, "timeout", breaker.config.OpenTimeout)
+	}
+}
+
+// detachChild removes the node's channel for a child whose client was
+// closed, so broadcasts stop queueing messages for it
+func (bn *BTreeNode) detachChild(childIndex int) {
+	bn.childConnected[childIndex].Store(false)
+	if bn.ctx.Err() != nil {
+		return
+	}
+	if err := bn.Node.RemoveChild(childIndex); err != nil {
+		bn.log().Warn("Failed to detach closed child", "child", childIndex, "error", err)
+		return
+	}
+	bn.log().Info("Child client closed, detached child", "child", childIndex)
+}
+
+// watchChildEvents keeps a child's connection state and circuit breaker in
+// step with the lifecycle events of its client's transport, such as a TCP
+// child dropping and being redialed. Transports without events are skipped.
+func (bn *BTreeNode) watchChildEvents(childIndex int) {
+	client := bn.ChildrenClients[childIndex]
+	events := client.Events()
+	if events == nil {
+		return
+	}
+
+	for {
 		select {
+		case event := <-events:
+			switch event.Type {
+			case transport.EventConnected:
+				bn.childConnected[childIndex].Store(true)
+				bn.breakers[childIndex].success()
+			case transport.EventDisconnected:
+				bn.childConnected[childIndex].Store(false)
+				bn.log().Info("Child disconnected", "child", childIndex, "remote", event.Addr)
+			case transport.EventError:
+				bn.childConnected[childIndex].Store(false)
+				bn.log().Warn("Child connection failed", "child", childIndex, "remote", event.Addr, "error", event.Err)
+				bn.recordChildFailure(childIndex, bn.breakers[childIndex])
+			}
+		case <-client.Done():
+			return
 		case <-bn.ctx.Done():
 			return
+		}
+	}
+}
+
+// connectToChild handles connection with retry logic and reports whether the
+// client connected. Every failed attempt counts against the child's breaker.
+func (bn *BTreeNode) connectToChild(childIndex int) bool {
+	client := bn.ChildrenClients[childIndex]
+	breaker := bn.breakers[childIndex]
+	childName := fmt.Sprintf("child-%d", childIndex)
+	retry := bn.config.Retry.withDefaults()
+
+	for attempt := 1; attempt <= retry.MaxAttempts; attempt++ {
+		select {
+		case <-bn.ctx.Done():
+			return false
+		default:
+		}
+
+		select {
+		case <-client.Done():
+			return false
 		default:
 		}
 
 		if err := client.Connect(bn.ctx); err != nil {
-			log.Printf("Failed to connect to %s (attempt %d): %v", childName, i+1, err)
+			bn.log().Info("Failed to connect to child", "child", childName, "attempt", attempt, "error", err)
+			bn.recordChildFailure(childIndex, breaker)
+			if attempt == retry.MaxAttempts {
+				break
+			}
 			select {
-			case <-time.After(time.Second):
+			case <-time.After(retry.retryDelay(attempt)):
 			case <-bn.ctx.Done():
-				return
+				return false
 			}
 			continue
 		}
 
-		log.Printf("Connected to %s", childName)
-		return
+		bn.log().Info("Connected to child", "child", childName)
+		breaker.success()
+		return true
 	}
 
-	log.Printf("Failed to connect to %s after 10 attempts", childName)
+	bn.log().Error("Giving up connecting to child", "child", childName, "attempts", retry.MaxAttempts)
+	return false
 }
 
-// GetLeftClient returns the left child client (index 0) - convenience for binary trees
+// GetLeftClient returns the left child client (index 0) - convenience for
+// binary trees; use GetChildClient for nodes with more children
 func (bn *BTreeNode) GetLeftClient() *transport.Client {
 	if len(bn.ChildrenClients) > 0 {
 		return bn.ChildrenClients[0]
@@ -187,7 +510,8 @@ func (bn *BTreeNode) GetLeftClient() *transport.Client {
 	return nil
 }
 
-// GetRightClient returns the right child client (index 1) - convenience for binary trees
+// GetRightClient returns the right child client (index 1) - convenience for
+// binary trees; use GetChildClient for nodes with more children
 func (bn *BTreeNode) GetRightClient() *transport.Client {
 	if len(bn.ChildrenClients) > 1 {
 		return bn.ChildrenClients[1]
@@ -202,3 +526,16 @@ func (bn *BTreeNode) GetChildClient(index int) *transport.Client {
 	}
 	return nil
 }
+
+// ChildClockSkew estimates how far the clock of the child at the specified
+// index is ahead (positive) or behind (negative) of the local clock
+func (bn *BTreeNode) ChildClockSkew(index int) (time.Duration, error) {
+	client := bn.GetChildClient(index)
+	if client == nil {
+		return 0, fmt.Errorf("no child configured at index %d", index)
+	}
+
+	ctx, cancel := context.WithTimeout(bn.ctx, 5*time.Second)
+	defer cancel()
+	return client.EstimateClockSkew(ctx)
+}
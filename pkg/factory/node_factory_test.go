@@ -1,10 +1,16 @@
 package factory
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
 	"testing"
 	"time"
 
+	"github.com/xnok/btree-server-msg/pkg/btree"
 	"github.com/xnok/btree-server-msg/pkg/transport"
+	"github.com/xnok/btree-server-msg/pkg/transport/inmem"
 	"github.com/xnok/btree-server-msg/pkg/transport/tcp"
 )
 
@@ -30,11 +36,11 @@ func TestNewBTreeNode(t *testing.T) {
 		t.Fatal("Server should not be nil")
 	}
 
-	if node.LeftClient != nil {
+	if node.GetLeftClient() != nil {
 		t.Error("LeftClient should be nil when no left port configured")
 	}
 
-	if node.RightClient != nil {
+	if node.GetRightClient() != nil {
 		t.Error("RightClient should be nil when no right port configured")
 	}
 }
@@ -50,11 +56,11 @@ func TestNewBTreeNodeWithChildren(t *testing.T) {
 		t.Fatalf("Failed to create node: %v", err)
 	}
 
-	if node.LeftClient == nil {
+	if node.GetLeftClient() == nil {
 		t.Error("LeftClient should not be nil when left port configured")
 	}
 
-	if node.RightClient == nil {
+	if node.GetRightClient() == nil {
 		t.Error("RightClient should not be nil when right port configured")
 	}
 }
@@ -78,7 +84,7 @@ func TestBTreeNodeLifecycle(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 
 	// Stop the node
-	err = node.Stop()
+	_, err = node.Stop()
 	if err != nil {
 		t.Fatalf("Failed to stop node: %v", err)
 	}
@@ -125,12 +131,12 @@ func TestNewNodeConfigFromPorts(t *testing.T) {
 				t.Errorf("Expected port %s, got %s", tt.port, config.Port)
 			}
 
-			if (config.LeftPort == nil) != (tt.leftPort == nil) {
-				t.Errorf("LeftPort mismatch: expected %v, got %v", tt.leftPort, config.LeftPort)
+			if (config.GetLeftPort() == "") != (tt.leftPort == nil) {
+				t.Errorf("LeftPort mismatch: expected %v, got %q", tt.leftPort, config.GetLeftPort())
 			}
 
-			if (config.RightPort == nil) != (tt.rightPort == nil) {
-				t.Errorf("RightPort mismatch: expected %v, got %v", tt.rightPort, config.RightPort)
+			if (config.GetRightPort() == "") != (tt.rightPort == nil) {
+				t.Errorf("RightPort mismatch: expected %v, got %q", tt.rightPort, config.GetRightPort())
 			}
 		})
 	}
@@ -167,8 +173,635 @@ func TestNewBTreeNodeWithCustomTransport(t *testing.T) {
 
 	time.Sleep(50 * time.Millisecond)
 
-	err = node.Stop()
+	_, err = node.Stop()
 	if err != nil {
 		t.Fatalf("Failed to stop node: %v", err)
 	}
 }
+
+// TestUpstreamMessageReachesParent tests that a message sent upstream by a
+// child node travels over TCP to its parent and out of the root
+func TestUpstreamMessageReachesParent(t *testing.T) {
+	leafPort := "39102"
+	root, err := NewBTreeNodeWithTCP(NewNodeConfigFromPorts("39101", &leafPort, nil))
+	if err != nil {
+		t.Fatalf("Failed to create root: %v", err)
+	}
+	leaf, err := NewBTreeNodeWithTCP(NewNodeConfigFromPorts(leafPort, nil, nil))
+	if err != nil {
+		t.Fatalf("Failed to create leaf: %v", err)
+	}
+
+	if err := leaf.Start(); err != nil {
+		t.Fatalf("Failed to start leaf: %v", err)
+	}
+	defer leaf.Stop()
+	if err := root.Start(); err != nil {
+		t.Fatalf("Failed to start root: %v", err)
+	}
+	defer root.Stop()
+
+	// Connect an external client to the root to observe what leaves the tree
+	observer := tcp.NewTCPTransport()
+	defer observer.Close()
+	for i := 0; ; i++ {
+		if err = observer.Connect(context.Background(), "39101"); err == nil {
+			break
+		}
+		if i == 20 {
+			t.Fatalf("Failed to connect to root: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// Give the root time to connect to its child
+	time.Sleep(200 * time.Millisecond)
+
+	ack := btree.NewMessage("ack from leaf", "ack-1")
+	if err := leaf.Node.SendToParent(context.Background(), ack); err != nil {
+		t.Fatalf("Failed to send to parent: %v", err)
+	}
+
+	select {
+	case msg := <-observer.GetInboundChannel():
+		if msg.ID != ack.ID || msg.Content != ack.Content {
+			t.Errorf("Expected %+v, got %+v", ack, msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for upstream message")
+	}
+}
+
+// TestChildClockSkew tests that a node estimates the clock offset of a child
+// whose clock runs behind
+func TestChildClockSkew(t *testing.T) {
+	injected := -2 * time.Second
+	skewedFactory := func() transport.Transport {
+		tr := tcp.NewTCPTransport()
+		tr.SetClock(func() time.Time { return time.Now().Add(injected) })
+		return tr
+	}
+
+	childPort := "39112"
+	child, err := NewBTreeNode(NewNodeConfigFromPorts(childPort, nil, nil), skewedFactory)
+	if err != nil {
+		t.Fatalf("Failed to create child: %v", err)
+	}
+	parent, err := NewBTreeNodeWithTCP(NewNodeConfigFromPorts("39111", &childPort, nil))
+	if err != nil {
+		t.Fatalf("Failed to create parent: %v", err)
+	}
+
+	if err := child.Start(); err != nil {
+		t.Fatalf("Failed to start child: %v", err)
+	}
+	defer child.Stop()
+	if err := parent.Start(); err != nil {
+		t.Fatalf("Failed to start parent: %v", err)
+	}
+	defer parent.Stop()
+
+	var skew time.Duration
+	for i := 0; ; i++ {
+		if skew, err = parent.ChildClockSkew(0); err == nil {
+			break
+		}
+		if i == 20 {
+			t.Fatalf("Failed to estimate child clock skew: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if diff := skew - injected; diff > 50*time.Millisecond || diff < -50*time.Millisecond {
+		t.Errorf("Expected skew close to %v, got %v", injected, skew)
+	}
+
+	if _, err := parent.ChildClockSkew(1); err == nil {
+		t.Error("Expected error for unconfigured child")
+	}
+}
+
+// TestBroadcastOverInMemoryTransport wires a parent and two children with the
+// in-memory transport, so no sockets are opened
+func TestBroadcastOverInMemoryTransport(t *testing.T) {
+	registry := inmem.NewRegistry()
+	inMemoryFactory := func() transport.Transport {
+		return inmem.NewInMemoryTransportWithRegistry(registry)
+	}
+
+	for _, port := range []string{"1", "2"} {
+		leaf, err := NewBTreeNode(NewNodeConfigWithChildren(port, nil), inMemoryFactory)
+		if err != nil {
+			t.Fatalf("Failed to create leaf: %v", err)
+		}
+		if err := leaf.Start(); err != nil {
+			t.Fatalf("Failed to start leaf: %v", err)
+		}
+		defer leaf.Stop()
+	}
+
+	left, right := "1", "2"
+	root, err := NewBTreeNode(NewNodeConfigFromPorts("0", &left, &right), inMemoryFactory)
+	if err != nil {
+		t.Fatalf("Failed to create root: %v", err)
+	}
+	if err := root.Start(); err != nil {
+		t.Fatalf("Failed to start root: %v", err)
+	}
+	defer root.Stop()
+
+	// Wait until the server is registered, then inject a message
+	injector := inmem.NewInMemoryTransportWithRegistry(registry)
+	defer injector.Close()
+	for i := 0; ; i++ {
+		if err = injector.Connect(context.Background(), "0"); err == nil {
+			break
+		}
+		if i == 20 {
+			t.Fatalf("Failed to connect to root: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The reach report proves the broadcast reached both leaves and came back
+	msg := btree.NewMessage("in-memory broadcast", "mem-1")
+	msg.TrackReach = true
+	injector.GetOutboundChannel() <- msg
+
+	select {
+	case report := <-injector.GetInboundChannel():
+		if report.CorrelationID != msg.ID || report.ReachCount != 3 {
+			t.Errorf("Expected reach 3 for %s, got %+v", msg.ID, report)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Timeout waiting for reach report from root")
+	}
+}
+
+// TestUnconfiguredChildHasNoBacklog checks that a node with only a left child
+// neither wires nor buffers anything for the missing right child
+func TestUnconfiguredChildHasNoBacklog(t *testing.T) {
+	registry := inmem.NewRegistry()
+	inMemoryFactory := func() transport.Transport {
+		return inmem.NewInMemoryTransportWithRegistry(registry)
+	}
+
+	leaf, err := NewBTreeNode(NewNodeConfigWithChildren("1", nil), inMemoryFactory)
+	if err != nil {
+		t.Fatalf("Failed to create leaf: %v", err)
+	}
+	if err := leaf.Start(); err != nil {
+		t.Fatalf("Failed to start leaf: %v", err)
+	}
+	defer leaf.Stop()
+
+	left := "1"
+	parent, err := NewBTreeNode(NewNodeConfigFromPorts("0", &left, nil), inMemoryFactory)
+	if err != nil {
+		t.Fatalf("Failed to create parent: %v", err)
+	}
+
+	if _, err := parent.Node.GetChildChannel(1); err == nil {
+		t.Error("Expected no channel for the unconfigured right child")
+	}
+	if _, err := parent.Node.GetChildChannel(0); err != nil {
+		t.Errorf("Expected a channel for the left child: %v", err)
+	}
+
+	if err := parent.Start(); err != nil {
+		t.Fatalf("Failed to start parent: %v", err)
+	}
+	defer parent.Stop()
+
+	// More messages than a child channel holds; none may be dropped
+	ctx := context.Background()
+	for i := 0; i < btree.DefaultBufferSize+50; i++ {
+		msg := btree.NewMessage("left only", "")
+		if err := parent.Node.HandleMessage(ctx, msg); err != nil {
+			t.Fatalf("Failed to handle message: %v", err)
+		}
+		if i%50 == 0 {
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	if stats := parent.Node.Stats(); stats.Dropped != 0 {
+		t.Errorf("Expected no dropped deliveries, got %d", stats.Dropped)
+	}
+}
+
+// TestClosingOneChildClientLeavesSiblingWorking checks that each child client
+// owns its transport, so closing one does not affect the other
+func TestClosingOneChildClientLeavesSiblingWorking(t *testing.T) {
+	registry := inmem.NewRegistry()
+	inMemoryFactory := func() transport.Transport {
+		return inmem.NewInMemoryTransportWithRegistry(registry)
+	}
+
+	received := make(chan btree.Message, 10)
+	for _, port := range []string{"1", "2"} {
+		leaf, err := NewBTreeNode(NewNodeConfigWithChildren(port, nil), inMemoryFactory)
+		if err != nil {
+			t.Fatalf("Failed to create leaf: %v", err)
+		}
+		if port == "2" {
+			leaf.Node.OnReceive(func(msg btree.Message) { received <- msg })
+		}
+		if err := leaf.Start(); err != nil {
+			t.Fatalf("Failed to start leaf: %v", err)
+		}
+		defer leaf.Stop()
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	left, right := "1", "2"
+	root, err := NewBTreeNode(NewNodeConfigFromPorts("0", &left, &right), inMemoryFactory)
+	if err != nil {
+		t.Fatalf("Failed to create root: %v", err)
+	}
+	if err := root.Start(); err != nil {
+		t.Fatalf("Failed to start root: %v", err)
+	}
+	defer root.Stop()
+
+	if err := root.GetLeftClient().Close(); err != nil {
+		t.Fatalf("Failed to close left client: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := root.Node.HandleMessage(context.Background(), btree.NewMessage("after close", "")); err != nil {
+			t.Fatalf("Failed to handle message: %v", err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case msg := <-received:
+			if msg.Content != "after close" {
+				t.Errorf("Unexpected message: %+v", msg)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Right child stopped receiving after the left client closed (got %d)", i)
+		}
+	}
+}
+
+// stalledTransport connects successfully but never sends anything queued on
+// its outbound channel, like a child that stopped reading
+type stalledTransport struct {
+	inbound  chan btree.Message
+	outbound chan btree.Message
+}
+
+func newStalledTransport() *stalledTransport {
+	return &stalledTransport{inbound: make(chan btree.Message), outbound: make(chan btree.Message, 1)}
+}
+
+func (s *stalledTransport) Listen(ctx context.Context, address string) error  { return nil }
+func (s *stalledTransport) Connect(ctx context.Context, address string) error { return nil }
+func (s *stalledTransport) Close() error                                      { return nil }
+func (s *stalledTransport) GetInboundChannel() <-chan btree.Message           { return s.inbound }
+func (s *stalledTransport) GetOutboundChannel() chan<- btree.Message          { return s.outbound }
+
+// TestStalledChildDoesNotBlockSibling checks that each child's outbound
+// pipeline is independent: a child that never drains must not hold up
+// broadcasts to its healthy sibling, and what overflows its channel is
+// counted as dropped by the node
+func TestStalledChildDoesNotBlockSibling(t *testing.T) {
+	registry := inmem.NewRegistry()
+
+	received := make(chan btree.Message, 1000)
+	leaf, err := NewBTreeNode(NewNodeConfigWithChildren("2", nil), func() transport.Transport {
+		return inmem.NewInMemoryTransportWithRegistry(registry)
+	})
+	if err != nil {
+		t.Fatalf("Failed to create leaf: %v", err)
+	}
+	leaf.Node.OnReceive(func(msg btree.Message) { received <- msg })
+	if err := leaf.Start(); err != nil {
+		t.Fatalf("Failed to start leaf: %v", err)
+	}
+	defer leaf.Stop()
+	waitForHealth(t, leaf, HealthHealthy)
+
+	// Transports are created for the server, then child 0, then child 1
+	created := 0
+	root, err := NewBTreeNode(NewNodeConfigWithChildren("0", []string{"1", "2"}), func() transport.Transport {
+		created++
+		if created == 2 {
+			return newStalledTransport()
+		}
+		return inmem.NewInMemoryTransportWithRegistry(registry)
+	})
+	if err != nil {
+		t.Fatalf("Failed to create root: %v", err)
+	}
+	if err := root.Start(); err != nil {
+		t.Fatalf("Failed to start root: %v", err)
+	}
+	defer root.Stop()
+	waitForHealth(t, root, HealthHealthy)
+
+	// Far more than the stalled child's channel and transport hold
+	total := 5 * btree.DefaultBufferSize
+	done := make(chan error, 1)
+	go func() {
+		for i := 0; i < total; i++ {
+			if err := root.Node.HandleMessage(context.Background(), btree.NewMessage("keep going", "")); err != nil {
+				done <- err
+				return
+			}
+			// Pace the sends so the healthy child can keep up
+			if i%50 == 0 {
+				time.Sleep(20 * time.Millisecond)
+			}
+		}
+		done <- nil
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Failed to handle message: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Broadcasting stalled behind the stalled child")
+	}
+
+	for i := 0; i < total; i++ {
+		select {
+		case <-received:
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Healthy child received only %d of %d messages", i, total)
+		}
+	}
+	if stats := root.Node.Stats(); stats.Dropped == 0 {
+		t.Error("Expected the stalled child's overflow to be counted as dropped")
+	}
+}
+
+// TestStopReportsQueuedMessages checks that Stop counts the messages left in
+// the node's channels and in the child client's outbound queue
+func TestStopReportsQueuedMessages(t *testing.T) {
+	var transports []*stalledTransport
+	node, err := NewBTreeNode(NewNodeConfigWithChildren("0", []string{"1"}), func() transport.Transport {
+		stalled := newStalledTransport()
+		transports = append(transports, stalled)
+		return stalled
+	})
+	if err != nil {
+		t.Fatalf("Failed to create node: %v", err)
+	}
+	child := transports[len(transports)-1]
+
+	// Two messages wait for the child's wiring, one sits in the stalled
+	// client and three were never taken from the inbound channel
+	for i := 0; i < 2; i++ {
+		if err := node.Node.HandleMessage(context.Background(), btree.NewMessage("queued", fmt.Sprintf("c-%d", i))); err != nil {
+			t.Fatalf("HandleMessage failed: %v", err)
+		}
+	}
+	child.outbound <- btree.NewMessage("queued", "client-0")
+	for i := 0; i < 3; i++ {
+		node.Node.GetInboundChannel() <- btree.NewMessage("queued", fmt.Sprintf("in-%d", i))
+	}
+
+	summary, err := node.Stop()
+	if err != nil {
+		t.Fatalf("Failed to stop node: %v", err)
+	}
+	want := btree.StopSummary{Inbound: 3, Pending: 3, Discarded: 6}
+	if summary != want {
+		t.Errorf("Expected %+v, got %+v", want, summary)
+	}
+}
+
+// TestStopCountsChildQueues checks that messages waiting in a stalled
+// child's outbound pipeline, including the one its send holds, are
+// reported as pending rather than lost
+func TestStopCountsChildQueues(t *testing.T) {
+	var transports []*stalledTransport
+	node, err := NewBTreeNode(NewNodeConfigWithChildren("0", []string{"1"}), func() transport.Transport {
+		stalled := newStalledTransport()
+		transports = append(transports, stalled)
+		return stalled
+	})
+	if err != nil {
+		t.Fatalf("Failed to create node: %v", err)
+	}
+	child := transports[len(transports)-1]
+	if err := node.Start(); err != nil {
+		t.Fatalf("Failed to start node: %v", err)
+	}
+
+	// The stalled client takes one message and blocks on the next, leaving
+	// the rest in the node's channel for the child
+	const total = 5
+	for i := 0; i < total; i++ {
+		if err := node.Node.HandleMessage(context.Background(), btree.NewMessage("queued", fmt.Sprintf("q-%d", i))); err != nil {
+			t.Fatalf("HandleMessage failed: %v", err)
+		}
+	}
+	childChannel, err := node.Node.GetChildChannel(0)
+	if err != nil {
+		t.Fatalf("Failed to get child channel: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(childChannel) != total-2 || len(child.outbound) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Child pipeline never stalled: %d queued, %d in the client", len(childChannel), len(child.outbound))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	summary, err := node.Stop()
+	if err != nil {
+		t.Fatalf("Failed to stop node: %v", err)
+	}
+	want := btree.StopSummary{Pending: total, Discarded: total}
+	if summary != want {
+		t.Errorf("Expected %+v, got %+v", want, summary)
+	}
+}
+
+func TestSharedTransportIsRejected(t *testing.T) {
+	shared := inmem.NewInMemoryTransportWithRegistry(inmem.NewRegistry())
+	defer shared.Close()
+
+	left, right := "1", "2"
+	_, err := NewBTreeNode(NewNodeConfigFromPorts("0", &left, &right), func() transport.Transport {
+		return shared
+	})
+	if err == nil {
+		t.Error("Expected an error when the factory hands out the same transport twice")
+	}
+}
+
+func TestAuthTokenGatesConnections(t *testing.T) {
+	leafPort := "39162"
+	rootConfig := NewNodeConfigFromPorts("39161", &leafPort, nil)
+	rootConfig.AuthToken = "s3cret"
+	leafConfig := NewNodeConfigFromPorts(leafPort, nil, nil)
+	leafConfig.AuthToken = "s3cret"
+
+	leaf, err := NewBTreeNodeWithTCP(leafConfig)
+	if err != nil {
+		t.Fatalf("Failed to create leaf: %v", err)
+	}
+	root, err := NewBTreeNodeWithTCP(rootConfig)
+	if err != nil {
+		t.Fatalf("Failed to create root: %v", err)
+	}
+	if err := leaf.Start(); err != nil {
+		t.Fatalf("Failed to start leaf: %v", err)
+	}
+	defer leaf.Stop()
+	if err := root.Start(); err != nil {
+		t.Fatalf("Failed to start root: %v", err)
+	}
+	defer root.Stop()
+
+	// The root presents the token to its child
+	waitForHealth(t, root, HealthHealthy)
+
+	intruder := tcp.NewTCPTransport()
+	defer intruder.Close()
+	if err := intruder.Connect(context.Background(), "39161"); !errors.Is(err, tcp.ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized without a token, got %v", err)
+	}
+
+	client := tcp.NewTCPTransport()
+	client.SetAuthToken("s3cret")
+	defer client.Close()
+	if err := client.Connect(context.Background(), "39161"); err != nil {
+		t.Errorf("Failed to connect with the token: %v", err)
+	}
+}
+
+func TestAuthTokenRequiresCapableTransport(t *testing.T) {
+	config := NewNodeConfigWithChildren("0", nil)
+	config.AuthToken = "s3cret"
+
+	registry := inmem.NewRegistry()
+	_, err := NewBTreeNode(config, func() transport.Transport {
+		return inmem.NewInMemoryTransportWithRegistry(registry)
+	})
+	if err == nil {
+		t.Error("Expected an error for a transport without auth support")
+	}
+}
+
+// TestFourChildren checks that a node configured with more than two children
+// creates, wires and connects a client for each of them
+func TestFourChildren(t *testing.T) {
+	childPorts := []string{"39172", "39173", "39174", "39175"}
+
+	received := make([]<-chan btree.Message, len(childPorts))
+	for i, port := range childPorts {
+		leaf, err := NewBTreeNodeWithTCP(NewNodeConfigWithChildren(port, nil))
+		if err != nil {
+			t.Fatalf("Failed to create leaf %s: %v", port, err)
+		}
+		var unsubscribe func()
+		received[i], unsubscribe = leaf.Node.Subscribe()
+		defer unsubscribe()
+		if err := leaf.Start(); err != nil {
+			t.Fatalf("Failed to start leaf %s: %v", port, err)
+		}
+		defer leaf.Stop()
+	}
+
+	root, err := NewBTreeNodeWithTCP(NewNodeConfigWithChildren("39171", childPorts))
+	if err != nil {
+		t.Fatalf("Failed to create root: %v", err)
+	}
+	if got := root.Node.GetNumChildren(); got != len(childPorts) {
+		t.Fatalf("Expected %d node children, got %d", len(childPorts), got)
+	}
+	for i := range childPorts {
+		if root.GetChildClient(i) == nil {
+			t.Fatalf("Expected a client for child %d", i)
+		}
+	}
+
+	if err := root.Start(); err != nil {
+		t.Fatalf("Failed to start root: %v", err)
+	}
+	defer root.Stop()
+
+	status := waitForHealth(t, root, HealthHealthy)
+	if len(status.Children) != len(childPorts) {
+		t.Fatalf("Expected %d children in health status, got %+v", len(childPorts), status.Children)
+	}
+
+	msg := btree.NewMessage("to every child", "four-1")
+	root.Node.GetInboundChannel() <- msg
+
+	for i, ch := range received {
+		select {
+		case got := <-ch:
+			if got.ID != msg.ID {
+				t.Errorf("Child %d: expected %s, got %s", i, msg.ID, got.ID)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Child %d did not receive the broadcast", i)
+		}
+	}
+}
+
+func TestDuplicateChildPortsAreRejected(t *testing.T) {
+	tests := map[string]NodeConfig{
+		"shared child port": NewNodeConfigWithChildren("39180", []string{"39181", "39182", "39181"}),
+		"own port":          NewNodeConfigWithChildren("39180", []string{"39181", "39180"}),
+	}
+
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewBTreeNodeWithTCP(config); !errors.Is(err, ErrDuplicateChildPort) {
+				t.Errorf("Expected ErrDuplicateChildPort, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStartFailsWhenPortIsInUse(t *testing.T) {
+	first, err := NewBTreeNodeWithTCP(NewNodeConfigWithChildren("39191", nil))
+	if err != nil {
+		t.Fatalf("Failed to create first node: %v", err)
+	}
+	if err := first.Start(); err != nil {
+		t.Fatalf("Failed to start first node: %v", err)
+	}
+	defer first.Stop()
+
+	second, err := NewBTreeNodeWithTCP(NewNodeConfigWithChildren("39191", nil))
+	if err != nil {
+		t.Fatalf("Failed to create second node: %v", err)
+	}
+	defer second.Stop()
+	if err := second.Start(); err == nil {
+		t.Fatal("Expected Start to fail on a port already in use")
+	}
+	if status := second.IsHealthy(); status.State == HealthHealthy {
+		t.Errorf("Expected a node that failed to start not to be healthy, got %+v", status)
+	}
+}
+
+func TestBindAddressRestrictsListener(t *testing.T) {
+	config := NewNodeConfigWithChildren("0", nil)
+	config.BindAddress = "127.0.0.1"
+
+	node, err := NewBTreeNodeWithTCP(config)
+	if err != nil {
+		t.Fatalf("Failed to create node: %v", err)
+	}
+	if err := node.Start(); err != nil {
+		t.Fatalf("Failed to start node: %v", err)
+	}
+	defer node.Stop()
+
+	addr, ok := node.Server.Addr().(*net.TCPAddr)
+	if !ok || !addr.IP.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("Expected the server bound to 127.0.0.1, got %v", node.Server.Addr())
+	}
+}
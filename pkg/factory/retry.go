@@ -0,0 +1,81 @@
+package factory
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Defaults of RetryConfig: ten attempts one second apart
+const (
+	DefaultRetryMaxAttempts = 10
+	DefaultRetryBaseDelay   = time.Second
+)
+
+// RetryConfig controls how a node retries connecting to its children. Zero
+// fields take their defaults, so the zero value retries ten times with a
+// fixed one-second delay.
+type RetryConfig struct {
+	// MaxAttempts is the number of connection attempts before giving up
+	MaxAttempts int
+
+	// BaseDelay is the wait after the first failed attempt
+	BaseDelay time.Duration
+
+	// MaxDelay caps the wait between attempts; zero means no cap
+	MaxDelay time.Duration
+
+	// Multiplier grows the delay after every failed attempt; values below 1
+	// keep it fixed
+	Multiplier float64
+
+	// Jitter randomizes each delay by up to this fraction in either
+	// direction, e.g. 0.2 for ±20%, so nodes restarted together do not
+	// retry in lockstep
+	Jitter float64
+}
+
+// withDefaults fills in the zero fields of r
+func (r RetryConfig) withDefaults() RetryConfig {
+	if r.MaxAttempts <= 0 {
+		r.MaxAttempts = DefaultRetryMaxAttempts
+	}
+	if r.BaseDelay <= 0 {
+		r.BaseDelay = DefaultRetryBaseDelay
+	}
+	if r.Multiplier < 1 {
+		r.Multiplier = 1
+	}
+	return r
+}
+
+// Backoff returns the delay, without jitter, after the given failed attempt
+// (counting from 1)
+func (r RetryConfig) Backoff(attempt int) time.Duration {
+	r = r.withDefaults()
+
+	delay := float64(r.BaseDelay)
+	for i := 1; i < attempt; i++ {
+		delay *= r.Multiplier
+		if r.MaxDelay > 0 && delay >= float64(r.MaxDelay) {
+			return r.MaxDelay
+		}
+	}
+	if r.MaxDelay > 0 && delay > float64(r.MaxDelay) {
+		return r.MaxDelay
+	}
+	return time.Duration(delay)
+}
+
+// jittered spreads delay by r.Jitter using random, a value in [0, 1)
+func (r RetryConfig) jittered(delay time.Duration, random float64) time.Duration {
+	if r.Jitter <= 0 {
+		return delay
+	}
+	jitter := min(r.Jitter, 1)
+	return time.Duration(float64(delay) * (1 + jitter*(2*random-1)))
+}
+
+// retryDelay returns the randomized wait after the given failed attempt
+func (r RetryConfig) retryDelay(attempt int) time.Duration {
+	return r.jittered(r.Backoff(attempt), rand.Float64())
+}
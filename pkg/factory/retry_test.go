@@ -0,0 +1,125 @@
+package factory
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/transport"
+)
+
+func TestRetryBackoffSequence(t *testing.T) {
+	retry := RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, Multiplier: 2}
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, want := range expected {
+		if got := retry.Backoff(i + 1); got != want {
+			t.Errorf("Attempt %d: expected %v, got %v", i+1, want, got)
+		}
+	}
+}
+
+func TestRetryDefaultsKeepFixedDelay(t *testing.T) {
+	var retry RetryConfig
+	for attempt := 1; attempt <= 3; attempt++ {
+		if got := retry.Backoff(attempt); got != DefaultRetryBaseDelay {
+			t.Errorf("Attempt %d: expected %v, got %v", attempt, DefaultRetryBaseDelay, got)
+		}
+	}
+	if got := retry.withDefaults().MaxAttempts; got != DefaultRetryMaxAttempts {
+		t.Errorf("Expected %d attempts by default, got %d", DefaultRetryMaxAttempts, got)
+	}
+}
+
+func TestRetryJitter(t *testing.T) {
+	retry := RetryConfig{Jitter: 0.5}
+	delay := time.Second
+
+	cases := map[float64]time.Duration{
+		0:    500 * time.Millisecond,
+		0.5:  time.Second,
+		0.75: 1250 * time.Millisecond,
+	}
+	for random, want := range cases {
+		if got := retry.jittered(delay, random); got != want {
+			t.Errorf("random %v: expected %v, got %v", random, want, got)
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		if got := retry.retryDelay(1); got < DefaultRetryBaseDelay/2 || got > DefaultRetryBaseDelay*3/2 {
+			t.Fatalf("Expected a delay within ±50%% of %v, got %v", DefaultRetryBaseDelay, got)
+		}
+	}
+}
+
+// unreachableTransport fails every Connect and counts the attempts
+type unreachableTransport struct {
+	*stalledTransport
+	attempts *atomic.Int32
+}
+
+func (u unreachableTransport) Connect(ctx context.Context, address string) error {
+	u.attempts.Add(1)
+	return errors.New("connection refused")
+}
+
+func TestConnectToChildGivesUpAfterMaxAttempts(t *testing.T) {
+	var attempts atomic.Int32
+	config := NewNodeConfigWithChildren("9600", []string{"9601"})
+	config.Retry = RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	bn, err := NewBTreeNode(config, func() transport.Transport {
+		return unreachableTransport{stalledTransport: newStalledTransport(), attempts: &attempts}
+	})
+	if err != nil {
+		t.Fatalf("Failed to create node: %v", err)
+	}
+
+	connected := bn.connectToChild(0)
+	if connected {
+		t.Error("Expected connecting to an unreachable child to fail")
+	}
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+	bn.cancel()
+}
+
+func TestConnectToChildStopsWithNode(t *testing.T) {
+	var attempts atomic.Int32
+	config := NewNodeConfigWithChildren("9602", []string{"9603"})
+	config.Retry = RetryConfig{MaxAttempts: 100, BaseDelay: time.Hour}
+
+	bn, err := NewBTreeNode(config, func() transport.Transport {
+		return unreachableTransport{stalledTransport: newStalledTransport(), attempts: &attempts}
+	})
+	if err != nil {
+		t.Fatalf("Failed to create node: %v", err)
+	}
+
+	done := make(chan bool, 1)
+	go func() { done <- bn.connectToChild(0) }()
+
+	time.Sleep(20 * time.Millisecond)
+	bn.cancel()
+	select {
+	case connected := <-done:
+		if connected {
+			t.Error("Expected no connection")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected retries to stop when the node stops")
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("Expected 1 attempt before the long backoff, got %d", got)
+	}
+}
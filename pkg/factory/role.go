@@ -0,0 +1,26 @@
+package factory
+
+// Role describes a node's position in the tree
+type Role string
+
+const (
+	// RoleRoot is a node without a parent that has children
+	RoleRoot Role = "root"
+	// RoleInternal is a node with both a parent and children
+	RoleInternal Role = "internal"
+	// RoleLeaf is a node without children
+	RoleLeaf Role = "leaf"
+)
+
+// Role returns the node's position in the tree based on its configured
+// parent and children. Unset child ports do not count as children.
+func (bn *BTreeNode) Role() Role {
+	switch {
+	case bn.config.GetNumConfiguredChildren() == 0:
+		return RoleLeaf
+	case bn.config.ParentPort == "":
+		return RoleRoot
+	default:
+		return RoleInternal
+	}
+}
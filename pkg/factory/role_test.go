@@ -0,0 +1,52 @@
+package factory
+
+import "testing"
+
+func TestRole(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   NodeConfig
+		expected Role
+	}{
+		{
+			name:     "root with children",
+			config:   NewNodeConfigWithChildren("8080", []string{"8081", "8082"}),
+			expected: RoleRoot,
+		},
+		{
+			name: "internal node",
+			config: NodeConfig{
+				Port:          "8081",
+				ChildrenPorts: []string{"8083", "8084"},
+				ParentPort:    "8080",
+			},
+			expected: RoleInternal,
+		},
+		{
+			name: "leaf",
+			config: NodeConfig{
+				Port:       "8083",
+				ParentPort: "8081",
+			},
+			expected: RoleLeaf,
+		},
+		{
+			name:     "binary leaf with unset child ports",
+			config:   NewNodeConfigFromPorts("8084", nil, nil),
+			expected: RoleLeaf,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := NewBTreeNodeWithTCP(tt.config)
+			if err != nil {
+				t.Fatalf("Failed to create node: %v", err)
+			}
+
+			if role := node.Role(); role != tt.expected {
+				t.Errorf("Expected role %s, got %s", tt.expected, role)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package factory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+)
+
+// ErrStopTimeout is returned by StopContext when the stop does not finish
+// before its context ends
+var ErrStopTimeout = errors.New("stop did not finish in time")
+
+// drainPollInterval is how often StopContext checks whether the children's
+// clients have sent what was queued for them
+const drainPollInterval = 10 * time.Millisecond
+
+// StopContext stops the node gracefully: the node handles the messages it
+// has already received, the children's pipelines send what is queued for
+// them, and then the node stops like Stop. If ctx ends first, StopContext
+// gives up so that a hung child or listener cannot block shutdown forever:
+// it logs and returns the messages still queued, with an error wrapping
+// ErrStopTimeout; the stop carries on in the background, so callers are
+// expected to exit rather than reuse the node.
+func (bn *BTreeNode) StopContext(ctx context.Context) (btree.StopSummary, error) {
+	bn.running.Store(false)
+	drainErr := bn.drain(ctx)
+
+	type result struct {
+		summary btree.StopSummary
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		summary, err := bn.Stop()
+		done <- result{summary, err}
+	}()
+
+	if drainErr == nil {
+		select {
+		case r := <-done:
+			return r.summary, r.err
+		case <-ctx.Done():
+		}
+	}
+
+	summary := bn.Node.Stop()
+	inbound, pending := bn.transportBacklog()
+	summary.Inbound += inbound
+	summary.Pending += pending
+	summary.Discarded = summary.Inbound + summary.Pending
+
+	bn.log().Error("Stop did not finish in time, abandoning it", "inbound", summary.Inbound, "pending", summary.Pending, "error", ctx.Err())
+	return summary, fmt.Errorf("%w: %w", ErrStopTimeout, ctx.Err())
+}
+
+// drain has the node handle the messages it has received and waits until
+// the children's clients have sent everything queued for them, or ctx ends
+func (bn *BTreeNode) drain(ctx context.Context) error {
+	if err := bn.Node.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, pending := bn.transportBacklog(); pending == 0 {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package factory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+	"github.com/xnok/btree-server-msg/pkg/transport"
+	"github.com/xnok/btree-server-msg/pkg/transport/inmem"
+)
+
+// hangingTransport is a child transport whose Connect and Close hang
+// until released, ignoring their context
+type hangingTransport struct {
+	*stalledTransport
+	release chan struct{}
+}
+
+func (u *hangingTransport) Connect(ctx context.Context, address string) error {
+	<-u.release
+	return errors.New("unreachable")
+}
+
+func (u *hangingTransport) Close() error {
+	<-u.release
+	return nil
+}
+
+func TestStopContextReturnsWithinDeadline(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	created := 0
+	node, err := NewBTreeNode(NewNodeConfigWithChildren("0", []string{"1"}), func() transport.Transport {
+		created++
+		if created == 2 {
+			return &hangingTransport{stalledTransport: newStalledTransport(), release: release}
+		}
+		return newStalledTransport()
+	})
+	if err != nil {
+		t.Fatalf("Failed to create node: %v", err)
+	}
+	if err := node.Start(); err != nil {
+		t.Fatalf("Failed to start node: %v", err)
+	}
+
+	const deadline = 200 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), deadline)
+	defer cancel()
+
+	start := time.Now()
+	_, err = node.StopContext(ctx)
+	if elapsed := time.Since(start); elapsed > deadline+time.Second {
+		t.Errorf("Expected stop to return within %v, took %v", deadline, elapsed)
+	}
+	if !errors.Is(err, ErrStopTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected ErrStopTimeout wrapping the deadline, got %v", err)
+	}
+}
+
+func TestStopContextStopsHealthyNode(t *testing.T) {
+	registry := inmem.NewRegistry()
+	node, err := NewBTreeNode(NewNodeConfigWithChildren("0", nil), func() transport.Transport {
+		return inmem.NewInMemoryTransportWithRegistry(registry)
+	})
+	if err != nil {
+		t.Fatalf("Failed to create node: %v", err)
+	}
+	if err := node.Start(); err != nil {
+		t.Fatalf("Failed to start node: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := node.StopContext(ctx); err != nil {
+		t.Fatalf("Failed to stop node: %v", err)
+	}
+	if node.IsHealthy().State == HealthHealthy {
+		t.Error("Expected node to be unhealthy after stopping")
+	}
+}
+
+// TestStopContextDeliversQueuedMessages checks that StopContext lets the
+// children's pipelines send what is queued for them before stopping
+func TestStopContextDeliversQueuedMessages(t *testing.T) {
+	registry := inmem.NewRegistry()
+	newTransport := func() transport.Transport {
+		return inmem.NewInMemoryTransportWithRegistry(registry)
+	}
+
+	received := make(chan btree.Message, 1000)
+	leaf, err := NewBTreeNode(NewNodeConfigWithChildren("2", nil), newTransport)
+	if err != nil {
+		t.Fatalf("Failed to create leaf: %v", err)
+	}
+	leaf.Node.OnReceive(func(msg btree.Message) { received <- msg })
+	if err := leaf.Start(); err != nil {
+		t.Fatalf("Failed to start leaf: %v", err)
+	}
+	defer leaf.Stop()
+	waitForHealth(t, leaf, HealthHealthy)
+
+	root, err := NewBTreeNode(NewNodeConfigWithChildren("0", []string{"2"}), newTransport)
+	if err != nil {
+		t.Fatalf("Failed to create root: %v", err)
+	}
+	root.Node.SetDeliveryMode(btree.DeliveryBlocking)
+	if err := root.Start(); err != nil {
+		t.Fatalf("Failed to start root: %v", err)
+	}
+	waitForHealth(t, root, HealthHealthy)
+
+	const total = 200
+	for i := 0; i < total; i++ {
+		if err := root.Node.HandleMessage(context.Background(), btree.NewMessage("queued", fmt.Sprintf("q-%d", i))); err != nil {
+			t.Fatalf("HandleMessage failed: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	summary, err := root.StopContext(ctx)
+	if err != nil {
+		t.Fatalf("Failed to stop root: %v", err)
+	}
+	if summary.Discarded != 0 {
+		t.Errorf("Expected nothing discarded, got %+v", summary)
+	}
+	for i := 0; i < total; i++ {
+		select {
+		case <-received:
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Leaf received only %d of %d messages", i, total)
+		}
+	}
+}
+
+// TestStopContextReportsDrained checks that messages the node had received
+// are handled, and counted as drained, rather than discarded
+func TestStopContextReportsDrained(t *testing.T) {
+	node, err := NewBTreeNode(NewNodeConfigWithChildren("0", nil), func() transport.Transport {
+		return newStalledTransport()
+	})
+	if err != nil {
+		t.Fatalf("Failed to create node: %v", err)
+	}
+
+	const total = 3
+	for i := 0; i < total; i++ {
+		node.Node.GetInboundChannel() <- btree.NewMessage("queued", fmt.Sprintf("in-%d", i))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	summary, err := node.StopContext(ctx)
+	if err != nil {
+		t.Fatalf("Failed to stop node: %v", err)
+	}
+	want := btree.StopSummary{Drained: total}
+	if summary != want {
+		t.Errorf("Expected %+v, got %+v", want, summary)
+	}
+}
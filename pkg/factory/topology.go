@@ -0,0 +1,141 @@
+package factory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+)
+
+// TypeTopology is the message type of topology discovery queries
+const TypeTopology = "topology"
+
+// DefaultDiscoveryTimeout bounds DiscoverTopology when ctx has no deadline
+const DefaultDiscoveryTimeout = 2 * time.Second
+
+// topologyTimeoutKey is the metadata key carrying how long the receiving
+// node has to answer a discovery query
+const topologyTimeoutKey = "topology-timeout"
+
+// TopologyInfo describes a node and, recursively, the subtree below it
+type TopologyInfo struct {
+	Name       string          `json:"name,omitempty"`
+	Port       string          `json:"port"`
+	ChildPorts []string        `json:"child_ports,omitempty"`
+	Children   []*TopologyInfo `json:"children,omitempty"`
+
+	// Unreachable marks a configured child that did not answer in time;
+	// only Port is known for it
+	Unreachable bool `json:"unreachable,omitempty"`
+}
+
+// DiscoverTopology asks the subtree below this node to describe itself and
+// assembles the answers into a tree. Children that do not answer before the
+// deadline of ctx, or DefaultDiscoveryTimeout if it has none, are reported
+// as Unreachable rather than failing the discovery.
+func (bn *BTreeNode) DiscoverTopology(ctx context.Context) (*TopologyInfo, error) {
+	timeout := DefaultDiscoveryTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("no time left to discover topology: %w", context.DeadlineExceeded)
+	}
+	return bn.discover(ctx, timeout), nil
+}
+
+// discover describes this node, gathering its children's descriptions for
+// up to timeout. Each level passes on three quarters of its budget so
+// answers from below arrive before the level above gives up.
+func (bn *BTreeNode) discover(ctx context.Context, timeout time.Duration) *TopologyInfo {
+	info := &TopologyInfo{
+		Name:       bn.Node.Name(),
+		Port:       bn.config.Port,
+		ChildPorts: configuredPorts(bn.config.ChildrenPorts),
+	}
+	if len(info.ChildPorts) == 0 {
+		return info
+	}
+
+	childTimeout := timeout * 3 / 4
+	query := btree.Message{Type: TypeTopology, Timestamp: time.Now()}.
+		WithMetadata(topologyTimeoutKey, childTimeout.String())
+
+	responses, err := bn.Node.Gather(ctx, query, timeout)
+	if err != nil {
+		bn.log().Warn("Topology discovery incomplete", "error", err)
+	}
+
+	// Place each answer under the port that answered; ports left over are
+	// unreachable. A child may be configured as host:port while it reports
+	// a bare port, or the other way round, so answers are also matched on
+	// the port number alone.
+	answered := make(map[string]*TopologyInfo)
+	byNumber := make(map[string]*TopologyInfo)
+	for _, response := range responses {
+		var child TopologyInfo
+		if err := json.Unmarshal([]byte(response.Content), &child); err != nil {
+			bn.log().Warn("Ignoring malformed topology response", "error", err)
+			continue
+		}
+		answered[child.Port] = &child
+		byNumber[portNumber(child.Port)] = &child
+	}
+	for _, port := range info.ChildPorts {
+		child, ok := answered[port]
+		if !ok {
+			child, ok = byNumber[portNumber(port)]
+		}
+		if !ok {
+			child = &TopologyInfo{Port: port, Unreachable: true}
+		}
+		info.Children = append(info.Children, child)
+	}
+	return info
+}
+
+// handleTopologyQuery answers a discovery query from the parent once the
+// subtree below has answered. It returns immediately: the answers from
+// below arrive through the message loop that is running this handler.
+func (bn *BTreeNode) handleTopologyQuery(ctx context.Context, query btree.Message) error {
+	timeout, err := time.ParseDuration(query.GetMetadata(topologyTimeoutKey))
+	if err != nil || timeout <= 0 {
+		timeout = DefaultDiscoveryTimeout
+	}
+
+	go func() {
+		info := bn.discover(bn.ctx, timeout)
+		content, err := json.Marshal(info)
+		if err != nil {
+			bn.log().Error("Failed to encode topology", "error", err)
+			return
+		}
+		if err := bn.Node.SendToParent(bn.ctx, btree.NewResponse(query, string(content))); err != nil && bn.ctx.Err() == nil {
+			bn.log().Error("Failed to answer topology query", "error", err)
+		}
+	}()
+	return nil
+}
+
+// configuredPorts returns the non-empty ports of a child port list
+func configuredPorts(ports []string) []string {
+	var configured []string
+	for _, port := range ports {
+		if port != "" {
+			configured = append(configured, port)
+		}
+	}
+	return configured
+}
+
+// portNumber returns the port of an address given as host:port, :port or a
+// bare port
+func portNumber(address string) string {
+	if _, port, err := net.SplitHostPort(address); err == nil {
+		return port
+	}
+	return address
+}
@@ -0,0 +1,129 @@
+package factory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/transport"
+	"github.com/xnok/btree-server-msg/pkg/transport/inmem"
+)
+
+// startInMemoryNode creates and starts a node on an in-memory registry,
+// stopping it when the test ends
+func startInMemoryNode(t *testing.T, registry *inmem.Registry, config NodeConfig) *BTreeNode {
+	t.Helper()
+
+	node, err := NewBTreeNode(config, func() transport.Transport {
+		return inmem.NewInMemoryTransportWithRegistry(registry)
+	})
+	if err != nil {
+		t.Fatalf("Failed to create node %s: %v", config.Port, err)
+	}
+	if err := node.Start(); err != nil {
+		t.Fatalf("Failed to start node %s: %v", config.Port, err)
+	}
+	t.Cleanup(func() { node.Stop() })
+	return node
+}
+
+func TestDiscoverTopology(t *testing.T) {
+	registry := inmem.NewRegistry()
+
+	for _, port := range []string{"1", "2"} {
+		leaf := startInMemoryNode(t, registry, NewNodeConfigWithChildren(port, nil))
+		waitForHealth(t, leaf, HealthHealthy)
+	}
+	root := startInMemoryNode(t, registry, NewNodeConfigWithChildren("0", []string{"1", "2"}))
+	waitForHealth(t, root, HealthHealthy)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	topology, err := root.DiscoverTopology(ctx)
+	if err != nil {
+		t.Fatalf("DiscoverTopology failed: %v", err)
+	}
+
+	if topology.Name != "node-0" || topology.Port != "0" || len(topology.Children) != 2 {
+		t.Fatalf("Unexpected root: %+v", topology)
+	}
+	for i, port := range []string{"1", "2"} {
+		child := topology.Children[i]
+		if child.Port != port || child.Name != "node-"+port || child.Unreachable || len(child.Children) != 0 {
+			t.Errorf("Child %d: expected reachable leaf node-%s, got %+v", i, port, child)
+		}
+	}
+}
+
+func TestDiscoverTopologyMarksSilentChildUnreachable(t *testing.T) {
+	registry := inmem.NewRegistry()
+
+	leaf := startInMemoryNode(t, registry, NewNodeConfigWithChildren("1", nil))
+	waitForHealth(t, leaf, HealthHealthy)
+
+	// Nothing listens on "9", so it never answers
+	root := startInMemoryNode(t, registry, NewNodeConfigWithChildren("0", []string{"1", "9"}))
+	waitForHealth(t, root, HealthDegraded)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	topology, err := root.DiscoverTopology(ctx)
+	if err != nil {
+		t.Fatalf("DiscoverTopology failed: %v", err)
+	}
+
+	if len(topology.Children) != 2 {
+		t.Fatalf("Expected 2 children, got %+v", topology.Children)
+	}
+	if child := topology.Children[0]; child.Port != "1" || child.Unreachable {
+		t.Errorf("Expected child 1 to answer, got %+v", child)
+	}
+	if child := topology.Children[1]; child.Port != "9" || !child.Unreachable {
+		t.Errorf("Expected child 9 to be unreachable, got %+v", child)
+	}
+}
+
+func TestDiscoverTopologyRecurses(t *testing.T) {
+	registry := inmem.NewRegistry()
+
+	// A chain 0 -> 1 -> 2, started leaf first
+	waitForHealth(t, startInMemoryNode(t, registry, NewNodeConfigWithChildren("2", nil)), HealthHealthy)
+	waitForHealth(t, startInMemoryNode(t, registry, NewNodeConfigWithChildren("1", []string{"2"})), HealthHealthy)
+	root := startInMemoryNode(t, registry, NewNodeConfigWithChildren("0", []string{"1"}))
+	waitForHealth(t, root, HealthHealthy)
+
+	topology, err := root.DiscoverTopology(context.Background())
+	if err != nil {
+		t.Fatalf("DiscoverTopology failed: %v", err)
+	}
+
+	if len(topology.Children) != 1 || len(topology.Children[0].Children) != 1 {
+		t.Fatalf("Expected a chain of three nodes, got %+v", topology)
+	}
+	if grandchild := topology.Children[0].Children[0]; grandchild.Port != "2" || grandchild.Unreachable {
+		t.Errorf("Expected reachable grandchild 2, got %+v", grandchild)
+	}
+}
+
+func TestDiscoverTopologyMatchesChildByPortNumber(t *testing.T) {
+	registry := inmem.NewRegistry()
+
+	// The leaf reports its bare port while the root knows it as host:port
+	leafConfig := NewNodeConfigWithChildren("7", nil)
+	leafConfig.BindAddress = "localhost"
+	waitForHealth(t, startInMemoryNode(t, registry, leafConfig), HealthHealthy)
+	root := startInMemoryNode(t, registry, NewNodeConfigWithChildren("0", []string{"localhost:7"}))
+	waitForHealth(t, root, HealthHealthy)
+
+	topology, err := root.DiscoverTopology(context.Background())
+	if err != nil {
+		t.Fatalf("DiscoverTopology failed: %v", err)
+	}
+
+	if len(topology.Children) != 1 {
+		t.Fatalf("Expected 1 child, got %+v", topology.Children)
+	}
+	if child := topology.Children[0]; child.Name != "node-7" || child.Unreachable {
+		t.Errorf("Expected the leaf's answer under localhost:7, got %+v", child)
+	}
+}
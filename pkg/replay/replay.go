@@ -0,0 +1,107 @@
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+)
+
+// maxLineSize bounds a single journal line
+const maxLineSize = 1024 * 1024
+
+// ReadJournal parses a journal of newline-delimited JSON messages, skipping
+// blank lines. Entries without an ID get one from btree.DefaultIDGenerator.
+func ReadJournal(r io.Reader) ([]btree.Message, error) {
+	return ReadJournalWithGenerator(r, nil)
+}
+
+// ReadJournalWithGenerator parses a journal like ReadJournal, assigning IDs
+// to entries without one from gen
+func ReadJournalWithGenerator(r io.Reader, gen btree.IDGenerator) ([]btree.Message, error) {
+	var messages []btree.Message
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var msg btree.Message
+		if err := json.Unmarshal([]byte(text), &msg); err != nil {
+			return nil, fmt.Errorf("invalid journal entry on line %d: %v", line, err)
+		}
+		messages = append(messages, btree.AssignID(msg, gen))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %v", err)
+	}
+
+	return messages, nil
+}
+
+// Replay sends messages to out at the given rate in messages per second.
+// A rate of zero or less replays as fast as out accepts them. Each message is
+// logged at debug level only; callers report the run as a whole.
+func Replay(ctx context.Context, messages []btree.Message, out chan<- btree.Message, rate int) (int, error) {
+	var tick <-chan time.Time
+	if rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for i, msg := range messages {
+		if tick != nil && i > 0 {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				return i, ctx.Err()
+			}
+		}
+
+		select {
+		case out <- msg:
+			slog.Debug("Replayed message", "index", i+1, "total", len(messages), "id", msg.ID)
+		case <-ctx.Done():
+			return i, ctx.Err()
+		}
+	}
+
+	return len(messages), nil
+}
+
+// WaitForDrain waits until a buffered outbound channel has been emptied by
+// its transport, so a short-lived tool does not close the connection early
+func WaitForDrain(ctx context.Context, out chan<- btree.Message) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for len(out) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// Give the transport a moment to write the last message it dequeued
+	select {
+	case <-time.After(50 * time.Millisecond):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
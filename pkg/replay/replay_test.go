@@ -0,0 +1,146 @@
+package replay
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+	"github.com/xnok/btree-server-msg/pkg/factory"
+	"github.com/xnok/btree-server-msg/pkg/transport"
+	"github.com/xnok/btree-server-msg/pkg/transport/tcp"
+)
+
+const journal = `{"content":"first","id":"j-1"}
+
+{"content":"second\nline","id":"j-2"}
+{"content":"third","id":"j-3"}
+`
+
+func TestReadJournal(t *testing.T) {
+	messages, err := ReadJournal(strings.NewReader(journal))
+	if err != nil {
+		t.Fatalf("Failed to read journal: %v", err)
+	}
+
+	if len(messages) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(messages))
+	}
+	if messages[1].Content != "second\nline" || messages[1].ID != "j-2" {
+		t.Errorf("Unexpected second message: %+v", messages[1])
+	}
+}
+
+func TestReadJournalInvalidLine(t *testing.T) {
+	_, err := ReadJournal(strings.NewReader("{\"content\":\"ok\"}\nnot json\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected error mentioning line 2, got %v", err)
+	}
+}
+
+func TestReadJournalAssignsMissingIDs(t *testing.T) {
+	input := "{\"content\":\"keeps\",\"id\":\"j-1\"}\n{\"content\":\"needs one\"}\n"
+	messages, err := ReadJournalWithGenerator(strings.NewReader(input), btree.NewSequentialIDGenerator("replay"))
+	if err != nil {
+		t.Fatalf("Failed to read journal: %v", err)
+	}
+
+	if messages[0].ID != "j-1" {
+		t.Errorf("Expected existing ID j-1, got %s", messages[0].ID)
+	}
+	if messages[1].ID != "replay-1" {
+		t.Errorf("Expected assigned ID replay-1, got %s", messages[1].ID)
+	}
+}
+
+func TestReplayRate(t *testing.T) {
+	messages := make([]btree.Message, 5)
+	out := make(chan btree.Message, len(messages))
+
+	start := time.Now()
+	sent, err := Replay(context.Background(), messages, out, 100)
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+
+	// Four intervals of 10ms separate five messages
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("Replay at 100 msg/s finished too quickly: %v", elapsed)
+	}
+	if sent != len(messages) {
+		t.Errorf("Expected %d messages sent, got %d", len(messages), sent)
+	}
+}
+
+// TestReplayIntoNode replays a journal file into a running node and checks
+// that the node broadcasts every message to its child
+func TestReplayIntoNode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	if err := os.WriteFile(path, []byte(journal), 0o644); err != nil {
+		t.Fatalf("Failed to write journal: %v", err)
+	}
+
+	// A plain listener stands in for the node's child
+	child := tcp.NewTCPTransport()
+	if err := child.Listen(context.Background(), "127.0.0.1:39122"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer child.Close()
+
+	childPort := "39122"
+	node, err := factory.NewBTreeNodeWithTCP(factory.NewNodeConfigFromPorts("39121", &childPort, nil))
+	if err != nil {
+		t.Fatalf("Failed to create node: %v", err)
+	}
+	if err := node.Start(); err != nil {
+		t.Fatalf("Failed to start node: %v", err)
+	}
+	defer node.Stop()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open journal: %v", err)
+	}
+	defer f.Close()
+
+	messages, err := ReadJournal(f)
+	if err != nil {
+		t.Fatalf("Failed to read journal: %v", err)
+	}
+
+	client := transport.NewClient(tcp.NewTCPTransport(), "39121")
+	for i := 0; ; i++ {
+		if err = client.Connect(context.Background()); err == nil {
+			break
+		}
+		if i == 20 {
+			t.Fatalf("Failed to connect to node: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	defer client.Close()
+
+	// Give the node time to connect to its child
+	time.Sleep(200 * time.Millisecond)
+
+	if _, err := Replay(context.Background(), messages, client.GetOutboundChannel(), 50); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+
+	for _, expected := range messages {
+		select {
+		case msg := <-child.GetInboundChannel():
+			if msg.ID != expected.ID || msg.Content != expected.Content {
+				t.Errorf("Expected %+v, got %+v", expected, msg)
+			}
+			if msg.Source != "node-39121" {
+				t.Errorf("Expected message broadcast by node-39121, got source %q", msg.Source)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timeout waiting for replayed message %s", expected.ID)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package transport
+
+import (
+	"fmt"
+	"net"
+)
+
+// DefaultDialHost is the host dialed when an address names only a port
+const DefaultDialHost = "localhost"
+
+// ListenAddress normalizes an address to listen on. A bare port such as
+// "3030" listens on every interface; host:port forms, including bracketed
+// IPv6 literals like "[::1]:3030", are kept as given.
+func ListenAddress(address string) (string, error) {
+	host, port, err := splitAddress(address)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// DialAddress normalizes an address to connect to. A bare port ("3030") or
+// an empty host (":3030") dials DefaultDialHost; host:port forms, including
+// bracketed IPv6 literals like "[::1]:3030", are kept as given.
+func DialAddress(address string) (string, error) {
+	host, port, err := splitAddress(address)
+	if err != nil {
+		return "", err
+	}
+	if host == "" {
+		host = DefaultDialHost
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// splitAddress splits host:port, accepting a bare port with an empty host
+func splitAddress(address string) (string, string, error) {
+	if isPort(address) {
+		return "", address, nil
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid address: %v", err)
+	}
+	if port == "" {
+		return "", "", fmt.Errorf("invalid address %q: missing port", address)
+	}
+	return host, port, nil
+}
+
+// isPort reports whether s is a non-empty string of digits
+func isPort(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
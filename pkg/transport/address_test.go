@@ -0,0 +1,65 @@
+package transport
+
+import "testing"
+
+func TestListenAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"3030", ":3030"},
+		{":3030", ":3030"},
+		{"localhost:3030", "localhost:3030"},
+		{"0.0.0.0:3030", "0.0.0.0:3030"},
+		{"[::1]:3030", "[::1]:3030"},
+		{"[::]:3030", "[::]:3030"},
+	}
+
+	for _, tt := range tests {
+		got, err := ListenAddress(tt.address)
+		if err != nil {
+			t.Errorf("ListenAddress(%q) failed: %v", tt.address, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ListenAddress(%q) = %q, want %q", tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestDialAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"3030", "localhost:3030"},
+		{":3030", "localhost:3030"},
+		{"localhost:3030", "localhost:3030"},
+		{"node-1.example.com:3030", "node-1.example.com:3030"},
+		{"127.0.0.1:3030", "127.0.0.1:3030"},
+		{"[::1]:3030", "[::1]:3030"},
+		{"[fe80::1%eth0]:3030", "[fe80::1%eth0]:3030"},
+	}
+
+	for _, tt := range tests {
+		got, err := DialAddress(tt.address)
+		if err != nil {
+			t.Errorf("DialAddress(%q) failed: %v", tt.address, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("DialAddress(%q) = %q, want %q", tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestInvalidAddresses(t *testing.T) {
+	for _, address := range []string{"", "::1", "[::1]", "localhost", "localhost:", "[::1]:"} {
+		if got, err := DialAddress(address); err == nil {
+			t.Errorf("Expected DialAddress(%q) to fail, got %q", address, got)
+		}
+		if got, err := ListenAddress(address); err == nil {
+			t.Errorf("Expected ListenAddress(%q) to fail, got %q", address, got)
+		}
+	}
+}
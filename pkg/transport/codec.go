@@ -0,0 +1,126 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+)
+
+// Codec serializes messages for transports that carry them as bytes. Both
+// ends of a connection must use matching codecs. Decode must not retain data
+// after it returns, since transports reuse their read buffers.
+type Codec interface {
+	// Encode serializes a message
+	Encode(msg btree.Message) ([]byte, error)
+
+	// Decode deserializes a message produced by Encode
+	Decode(data []byte) (btree.Message, error)
+}
+
+// CodecName returns the identifier peers exchange to check that they use
+// matching codecs: the codec's Name method if it has one, its type otherwise
+func CodecName(codec Codec) string {
+	if named, ok := codec.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+	return fmt.Sprintf("%T", codec)
+}
+
+// JSONCodec encodes the whole message as JSON, so every field, including
+// flags such as Upstream and binary payloads, survives the wire
+type JSONCodec struct{}
+
+// Name identifies the codec during connection handshakes
+func (JSONCodec) Name() string {
+	return "json"
+}
+
+// Encode serializes msg as JSON
+func (JSONCodec) Encode(msg btree.Message) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+// Decode parses a JSON-encoded message
+func (JSONCodec) Decode(data []byte) (btree.Message, error) {
+	var msg btree.Message
+	err := json.Unmarshal(data, &msg)
+	return msg, err
+}
+
+// TextCodec carries only a message's Content as raw text, for peers such as
+// netcat that speak plain lines. Every other field is dropped on encode.
+type TextCodec struct{}
+
+// Name identifies the codec during connection handshakes
+func (TextCodec) Name() string {
+	return "text"
+}
+
+// Encode returns the message content
+func (TextCodec) Encode(msg btree.Message) ([]byte, error) {
+	return []byte(msg.Content), nil
+}
+
+// Decode returns a message whose content is data
+func (TextCodec) Decode(data []byte) (btree.Message, error) {
+	return btree.Message{Content: string(data)}, nil
+}
+
+// AutoCodec accepts both JSON and plain text, for fleets migrating from one
+// to the other where a listener temporarily receives both. Decode reads a
+// payload as a JSON message when it starts with '{' and parses as one, and
+// as text otherwise; text that is itself a valid JSON object is therefore
+// read as JSON, which is why detection is only done when a transport is
+// explicitly given an AutoCodec. Encode uses Encoder, or JSON when nil.
+type AutoCodec struct {
+	Encoder Codec
+}
+
+// encoder returns the codec used for encoding
+func (c AutoCodec) encoder() Codec {
+	if c.Encoder == nil {
+		return JSONCodec{}
+	}
+	return c.Encoder
+}
+
+// Name identifies the codec during connection handshakes by the name of its
+// encoder, so peers receiving what it sends accept it
+func (c AutoCodec) Name() string {
+	return CodecName(c.encoder())
+}
+
+// Accepts reports whether the codec decodes what a peer using the named
+// codec sends
+func (c AutoCodec) Accepts(name string) bool {
+	return name == "json" || name == "text" || name == c.Name()
+}
+
+// Encode serializes msg with the encoder
+func (c AutoCodec) Encode(msg btree.Message) ([]byte, error) {
+	return c.encoder().Encode(msg)
+}
+
+// Decode parses data as a JSON message if it looks like one, and as text
+// otherwise
+func (c AutoCodec) Decode(data []byte) (btree.Message, error) {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		if msg, err := (JSONCodec{}).Decode(data); err == nil {
+			return msg, nil
+		}
+	}
+	return TextCodec{}.Decode(data)
+}
+
+// CodecAccepts reports whether codec decodes what a peer using the named
+// codec sends: codecs with an Accepts method decide, others accept only
+// their own name
+func CodecAccepts(codec Codec, name string) bool {
+	if acceptor, ok := codec.(interface{ Accepts(string) bool }); ok {
+		return acceptor.Accepts(name)
+	}
+	return CodecName(codec) == name
+}
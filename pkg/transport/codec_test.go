@@ -0,0 +1,111 @@
+package transport
+
+import (
+	"testing"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+)
+
+func TestJSONCodecRoundTrip(t *testing.T) {
+	codec := JSONCodec{}
+	original := btree.Message{Content: "json", ID: "j-1", Upstream: true, Payload: []byte{0x00, 0x0A}}
+
+	data, err := codec.Encode(original)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	msg, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if msg.Content != original.Content || msg.ID != original.ID || !msg.Upstream || string(msg.Payload) != string(original.Payload) {
+		t.Errorf("Expected %+v, got %+v", original, msg)
+	}
+
+	if _, err := codec.Decode([]byte("not json")); err == nil {
+		t.Error("Expected an error decoding malformed JSON")
+	}
+}
+
+func TestTextCodecCarriesContentOnly(t *testing.T) {
+	codec := TextCodec{}
+
+	data, err := codec.Encode(btree.Message{Content: "plain text", ID: "dropped"})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if string(data) != "plain text" {
+		t.Errorf("Expected raw content, got %q", data)
+	}
+
+	msg, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if msg.Content != "plain text" || msg.ID != "" {
+		t.Errorf("Expected only content to survive, got %+v", msg)
+	}
+}
+
+type unnamedCodec struct{}
+
+func (unnamedCodec) Encode(msg btree.Message) ([]byte, error) { return nil, nil }
+
+func (unnamedCodec) Decode(data []byte) (btree.Message, error) { return btree.Message{}, nil }
+
+func TestCodecName(t *testing.T) {
+	cases := map[string]Codec{
+		"json":                   JSONCodec{},
+		"text":                   TextCodec{},
+		"transport.unnamedCodec": unnamedCodec{},
+	}
+	for want, codec := range cases {
+		if got := CodecName(codec); got != want {
+			t.Errorf("Expected codec name %q, got %q", want, got)
+		}
+	}
+}
+
+func TestAutoCodecDetectsFormat(t *testing.T) {
+	codec := AutoCodec{}
+
+	msg, err := codec.Decode([]byte(`{"content":"structured","id":"a-1","upstream":true}`))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if msg.Content != "structured" || msg.ID != "a-1" || !msg.Upstream {
+		t.Errorf("Expected a decoded JSON message, got %+v", msg)
+	}
+
+	for _, text := range []string{"plain text", "{not json at all", "{}trailing", ""} {
+		msg, err := codec.Decode([]byte(text))
+		if err != nil {
+			t.Fatalf("Decode(%q) failed: %v", text, err)
+		}
+		if msg.Content != text || msg.ID != "" {
+			t.Errorf("Expected %q as text, got %+v", text, msg)
+		}
+	}
+}
+
+func TestAutoCodecEncodesWithEncoder(t *testing.T) {
+	msg := btree.Message{Content: "out", ID: "a-2"}
+
+	data, _ := AutoCodec{}.Encode(msg)
+	if decoded, err := (JSONCodec{}).Decode(data); err != nil || decoded.ID != "a-2" {
+		t.Errorf("Expected JSON by default, got %q", data)
+	}
+	if data, _ := (AutoCodec{Encoder: TextCodec{}}).Encode(msg); string(data) != "out" {
+		t.Errorf("Expected text with a text encoder, got %q", data)
+	}
+
+	if name := CodecName(AutoCodec{Encoder: TextCodec{}}); name != "text" {
+		t.Errorf("Expected the encoder's name, got %q", name)
+	}
+	if !CodecAccepts(AutoCodec{}, "text") || !CodecAccepts(AutoCodec{}, "json") {
+		t.Error("Expected AutoCodec to accept both text and JSON peers")
+	}
+	if CodecAccepts(JSONCodec{}, "text") {
+		t.Error("Expected JSONCodec to accept only JSON peers")
+	}
+}
@@ -0,0 +1,54 @@
+package transport
+
+import (
+	"errors"
+	"io"
+)
+
+// ConnectionEventType is the kind of a connection lifecycle event
+type ConnectionEventType int
+
+const (
+	// EventConnected reports a dialed connection established or
+	// re-established, or a connection accepted by the listener
+	EventConnected ConnectionEventType = iota
+
+	// EventDisconnected reports a connection closed cleanly, by either side
+	EventDisconnected
+
+	// EventError reports a connection that ended with an error, or a failure
+	// to accept one
+	EventError
+)
+
+// String returns the name of the event type
+func (e ConnectionEventType) String() string {
+	switch e {
+	case EventConnected:
+		return "connected"
+	case EventDisconnected:
+		return "disconnected"
+	case EventError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// ConnectionEvent is a connection lifecycle transition. Addr is the remote
+// address of the connection, empty for accept failures; Err is set only for
+// EventError.
+type ConnectionEvent struct {
+	Type ConnectionEventType
+	Addr string
+	Err  error
+}
+
+// DisconnectEvent classifies a connection that ended with err: nil and EOF
+// are clean closes, anything else is an error
+func DisconnectEvent(addr string, err error) ConnectionEvent {
+	if err == nil || errors.Is(err, io.EOF) {
+		return ConnectionEvent{Type: EventDisconnected, Addr: addr}
+	}
+	return ConnectionEvent{Type: EventError, Addr: addr, Err: err}
+}
@@ -0,0 +1,39 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestDisconnectEvent(t *testing.T) {
+	reset := errors.New("connection reset by peer")
+
+	tests := []struct {
+		err  error
+		want ConnectionEventType
+	}{
+		{nil, EventDisconnected},
+		{io.EOF, EventDisconnected},
+		{fmt.Errorf("read: %w", io.EOF), EventDisconnected},
+		{io.ErrUnexpectedEOF, EventError},
+		{reset, EventError},
+	}
+
+	for _, tt := range tests {
+		event := DisconnectEvent("127.0.0.1:3030", tt.err)
+		if event.Type != tt.want {
+			t.Errorf("DisconnectEvent(%v): expected %s, got %s", tt.err, tt.want, event.Type)
+		}
+		if event.Addr != "127.0.0.1:3030" {
+			t.Errorf("Expected the address to be kept, got %q", event.Addr)
+		}
+		if tt.want == EventDisconnected && event.Err != nil {
+			t.Errorf("Expected no error on a clean close, got %v", event.Err)
+		}
+		if tt.want == EventError && event.Err != tt.err {
+			t.Errorf("Expected the error %v, got %v", tt.err, event.Err)
+		}
+	}
+}
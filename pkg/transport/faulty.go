@@ -0,0 +1,182 @@
+package transport
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+)
+
+// FaultyTransport wraps another transport and injects faults into its
+// outbound path for chaos tests: every message is delayed by a fixed latency
+// and dropped with a fixed probability. Delayed messages keep their order
+// and are delayed concurrently, so the latency does not limit throughput.
+// Inbound messages pass through untouched.
+type FaultyTransport struct {
+	inner    Transport
+	latency  time.Duration
+	lossRate float64
+	outbound chan btree.Message
+	delayed  chan delayedMessage
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	once     sync.Once
+
+	// randMu guards random, the source of loss decisions
+	randMu sync.Mutex
+	random *rand.Rand
+
+	// Fault counters
+	dropped   atomic.Uint64
+	delivered atomic.Uint64
+}
+
+// delayedMessage is a message waiting for its injected latency to elapse
+type delayedMessage struct {
+	msg btree.Message
+	due time.Time
+}
+
+// NewFaultyTransport wraps inner so that outbound messages are delayed by
+// latency and dropped with probability lossRate, clamped to [0, 1]. The
+// wrapper owns inner and closes it on Close.
+func NewFaultyTransport(inner Transport, latency time.Duration, lossRate float64) *FaultyTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	f := &FaultyTransport{
+		inner:    inner,
+		latency:  max(latency, 0),
+		lossRate: min(max(lossRate, 0), 1),
+		outbound: make(chan btree.Message, btree.DefaultBufferSize),
+		delayed:  make(chan delayedMessage, btree.DefaultBufferSize),
+		ctx:      ctx,
+		cancel:   cancel,
+		random:   rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+	}
+
+	f.wg.Add(2)
+	go f.injectLoss()
+	go f.injectLatency()
+	return f
+}
+
+// SetSeed seeds the source of loss decisions so a test run is reproducible
+func (f *FaultyTransport) SetSeed(seed uint64) {
+	f.randMu.Lock()
+	defer f.randMu.Unlock()
+	f.random = rand.New(rand.NewPCG(seed, seed))
+}
+
+// Dropped returns how many outbound messages were deliberately lost
+func (f *FaultyTransport) Dropped() uint64 {
+	return f.dropped.Load()
+}
+
+// Delivered returns how many outbound messages were passed to the wrapped
+// transport
+func (f *FaultyTransport) Delivered() uint64 {
+	return f.delivered.Load()
+}
+
+// Listen starts listening on the wrapped transport
+func (f *FaultyTransport) Listen(ctx context.Context, address string) error {
+	return f.inner.Listen(ctx, address)
+}
+
+// Connect connects the wrapped transport
+func (f *FaultyTransport) Connect(ctx context.Context, address string) error {
+	return f.inner.Connect(ctx, address)
+}
+
+// Close stops injecting faults, discarding messages still delayed, and
+// closes the wrapped transport
+func (f *FaultyTransport) Close() error {
+	var err error
+	f.once.Do(func() {
+		f.cancel()
+		f.wg.Wait()
+		err = f.inner.Close()
+	})
+	return err
+}
+
+// GetInboundChannel returns the wrapped transport's inbound channel
+func (f *FaultyTransport) GetInboundChannel() <-chan btree.Message {
+	return f.inner.GetInboundChannel()
+}
+
+// GetOutboundChannel returns the channel whose messages are delayed,
+// dropped or passed on to the wrapped transport
+func (f *FaultyTransport) GetOutboundChannel() chan<- btree.Message {
+	return f.outbound
+}
+
+// SetLogger sets the logger of the wrapped transport, if it logs
+func (f *FaultyTransport) SetLogger(logger *slog.Logger) {
+	setLogger(f.inner, logger)
+}
+
+// lose decides whether the next message is dropped
+func (f *FaultyTransport) lose() bool {
+	if f.lossRate == 0 {
+		return false
+	}
+	f.randMu.Lock()
+	defer f.randMu.Unlock()
+	return f.random.Float64() < f.lossRate
+}
+
+// injectLoss drops outbound messages at the loss rate and stamps the rest
+// with the time they are due
+func (f *FaultyTransport) injectLoss() {
+	defer f.wg.Done()
+
+	for {
+		select {
+		case msg := <-f.outbound:
+			if f.lose() {
+				f.dropped.Add(1)
+				continue
+			}
+			select {
+			case f.delayed <- delayedMessage{msg: msg, due: time.Now().Add(f.latency)}:
+			case <-f.ctx.Done():
+				return
+			}
+		case <-f.ctx.Done():
+			return
+		}
+	}
+}
+
+// injectLatency passes each message to the wrapped transport once it is due
+func (f *FaultyTransport) injectLatency() {
+	defer f.wg.Done()
+
+	for {
+		select {
+		case delayed := <-f.delayed:
+			if wait := time.Until(delayed.due); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-f.ctx.Done():
+					timer.Stop()
+					return
+				}
+			}
+			select {
+			case f.inner.GetOutboundChannel() <- delayed.msg:
+				f.delivered.Add(1)
+			case <-f.ctx.Done():
+				return
+			}
+		case <-f.ctx.Done():
+			return
+		}
+	}
+}
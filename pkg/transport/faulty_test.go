@@ -0,0 +1,132 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+	"github.com/xnok/btree-server-msg/pkg/transport/inmem"
+)
+
+// startFaultyPair connects a faulty client to an in-memory server
+func startFaultyPair(t *testing.T, latency time.Duration, lossRate float64) (server Transport, client *FaultyTransport) {
+	t.Helper()
+
+	registry := inmem.NewRegistry()
+	server = inmem.NewInMemoryTransportWithRegistry(registry)
+	if err := server.Listen(context.Background(), "3030"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	client = NewFaultyTransport(inmem.NewInMemoryTransportWithRegistry(registry), latency, lossRate)
+	if err := client.Connect(context.Background(), "3030"); err != nil {
+		server.Close()
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return server, client
+}
+
+func TestFaultyTransportAddsLatency(t *testing.T) {
+	const latency = 20 * time.Millisecond
+	const total = 20
+	server, client := startFaultyPair(t, latency, 0)
+
+	var added time.Duration
+	for i := 0; i < total; i++ {
+		sent := time.Now()
+		client.GetOutboundChannel() <- btree.Message{ID: fmt.Sprintf("l-%d", i)}
+
+		select {
+		case msg := <-server.GetInboundChannel():
+			if msg.ID != fmt.Sprintf("l-%d", i) {
+				t.Fatalf("Expected l-%d, got %s", i, msg.ID)
+			}
+			added += time.Since(sent)
+		case <-time.After(time.Second):
+			t.Fatalf("Timeout waiting for l-%d", i)
+		}
+	}
+
+	average := added / total
+	if average < latency || average > latency*3 {
+		t.Errorf("Expected an average latency near %v, got %v", latency, average)
+	}
+}
+
+func TestFaultyTransportKeepsOrderAndThroughput(t *testing.T) {
+	const latency = 50 * time.Millisecond
+	const total = 50
+	server, client := startFaultyPair(t, latency, 0)
+
+	start := time.Now()
+	for i := 0; i < total; i++ {
+		client.GetOutboundChannel() <- btree.Message{ID: fmt.Sprintf("o-%d", i)}
+	}
+	for i := 0; i < total; i++ {
+		select {
+		case msg := <-server.GetInboundChannel():
+			if msg.ID != fmt.Sprintf("o-%d", i) {
+				t.Fatalf("Expected o-%d, got %s", i, msg.ID)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timeout waiting for o-%d", i)
+		}
+	}
+
+	// Messages are delayed concurrently, not one latency after another
+	if elapsed := time.Since(start); elapsed > latency*total/2 {
+		t.Errorf("Expected the latency to overlap, %d messages took %v", total, elapsed)
+	}
+}
+
+func TestFaultyTransportLossRate(t *testing.T) {
+	const lossRate = 0.3
+	const total = 5000
+	server, client := startFaultyPair(t, 0, lossRate)
+	client.SetSeed(42)
+
+	// Drain the server concurrently so its buffer never holds up delivery
+	var received atomic.Uint64
+	go func() {
+		for range server.GetInboundChannel() {
+			received.Add(1)
+		}
+	}()
+
+	for i := 0; i < total; i++ {
+		client.GetOutboundChannel() <- btree.Message{ID: fmt.Sprintf("d-%d", i)}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for client.Dropped()+received.Load() < total {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected every message to be dropped or received, got %d dropped and %d received", client.Dropped(), received.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	observed := float64(client.Dropped()) / total
+	if math.Abs(observed-lossRate) > 0.03 {
+		t.Errorf("Expected a loss rate near %.2f, observed %.3f", lossRate, observed)
+	}
+	if client.Delivered() != received.Load() {
+		t.Errorf("Expected %d delivered messages to arrive, got %d", client.Delivered(), received.Load())
+	}
+}
+
+func TestFaultyTransportClampsLossRate(t *testing.T) {
+	if f := NewFaultyTransport(inmem.NewInMemoryTransport(), -time.Second, 2); f.lossRate != 1 || f.latency != 0 {
+		t.Errorf("Expected loss 1 and latency 0, got %v and %v", f.lossRate, f.latency)
+	} else {
+		f.Close()
+	}
+}
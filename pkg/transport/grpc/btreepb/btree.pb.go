@@ -0,0 +1,276 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: btree.proto
+
+package btreepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Message mirrors btree.Message on the wire.
+type Message struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Content string                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
+	Id      string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	// Creation time in nanoseconds since the Unix epoch; zero means unset.
+	TimestampUnixNano int64             `protobuf:"varint,3,opt,name=timestamp_unix_nano,json=timestampUnixNano,proto3" json:"timestamp_unix_nano,omitempty"`
+	Source            string            `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`
+	Type              string            `protobuf:"bytes,5,opt,name=type,proto3" json:"type,omitempty"`
+	Metadata          map[string]string `protobuf:"bytes,6,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Upstream          bool              `protobuf:"varint,7,opt,name=upstream,proto3" json:"upstream,omitempty"`
+	TrackReach        bool              `protobuf:"varint,8,opt,name=track_reach,json=trackReach,proto3" json:"track_reach,omitempty"`
+	CorrelationId     string            `protobuf:"bytes,9,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	ReachCount        int64             `protobuf:"varint,10,opt,name=reach_count,json=reachCount,proto3" json:"reach_count,omitempty"`
+	AckRequested      bool              `protobuf:"varint,11,opt,name=ack_requested,json=ackRequested,proto3" json:"ack_requested,omitempty"`
+	// Per-source sequence number; zero means unsequenced.
+	Seq uint64 `protobuf:"varint,12,opt,name=seq,proto3" json:"seq,omitempty"`
+	// Raw binary content.
+	Payload []byte `protobuf:"bytes,13,opt,name=payload,proto3" json:"payload,omitempty"`
+	// Names of the nodes that forwarded the message, root first.
+	Path []string `protobuf:"bytes,14,rep,name=path,proto3" json:"path,omitempty"`
+	// Expiry time in nanoseconds since the Unix epoch; zero means never.
+	ExpiresAtUnixNano int64 `protobuf:"varint,15,opt,name=expires_at_unix_nano,json=expiresAtUnixNano,proto3" json:"expires_at_unix_nano,omitempty"`
+	// Name of the only node that processes the message; empty means every node.
+	Target        string `protobuf:"bytes,16,opt,name=target,proto3" json:"target,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Message) Reset() {
+	*x = Message{}
+	mi := &file_btree_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Message) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Message) ProtoMessage() {}
+
+func (x *Message) ProtoReflect() protoreflect.Message {
+	mi := &file_btree_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Message.ProtoReflect.Descriptor instead.
+func (*Message) Descriptor() ([]byte, []int) {
+	return file_btree_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Message) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *Message) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Message) GetTimestampUnixNano() int64 {
+	if x != nil {
+		return x.TimestampUnixNano
+	}
+	return 0
+}
+
+func (x *Message) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Message) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Message) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *Message) GetUpstream() bool {
+	if x != nil {
+		return x.Upstream
+	}
+	return false
+}
+
+func (x *Message) GetTrackReach() bool {
+	if x != nil {
+		return x.TrackReach
+	}
+	return false
+}
+
+func (x *Message) GetCorrelationId() string {
+	if x != nil {
+		return x.CorrelationId
+	}
+	return ""
+}
+
+func (x *Message) GetReachCount() int64 {
+	if x != nil {
+		return x.ReachCount
+	}
+	return 0
+}
+
+func (x *Message) GetAckRequested() bool {
+	if x != nil {
+		return x.AckRequested
+	}
+	return false
+}
+
+func (x *Message) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Message) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *Message) GetPath() []string {
+	if x != nil {
+		return x.Path
+	}
+	return nil
+}
+
+func (x *Message) GetExpiresAtUnixNano() int64 {
+	if x != nil {
+		return x.ExpiresAtUnixNano
+	}
+	return 0
+}
+
+func (x *Message) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+var File_btree_proto protoreflect.FileDescriptor
+
+const file_btree_proto_rawDesc = "" +
+	"\n" +
+	"\vbtree.proto\x12\bbtree.v1\"\xbc\x04\n" +
+	"\aMessage\x12\x18\n" +
+	"\acontent\x18\x01 \x01(\tR\acontent\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x12.\n" +
+	"\x13timestamp_unix_nano\x18\x03 \x01(\x03R\x11timestampUnixNano\x12\x16\n" +
+	"\x06source\x18\x04 \x01(\tR\x06source\x12\x12\n" +
+	"\x04type\x18\x05 \x01(\tR\x04type\x12;\n" +
+	"\bmetadata\x18\x06 \x03(\v2\x1f.btree.v1.Message.MetadataEntryR\bmetadata\x12\x1a\n" +
+	"\bupstream\x18\a \x01(\bR\bupstream\x12\x1f\n" +
+	"\vtrack_reach\x18\b \x01(\bR\n" +
+	"trackReach\x12%\n" +
+	"\x0ecorrelation_id\x18\t \x01(\tR\rcorrelationId\x12\x1f\n" +
+	"\vreach_count\x18\n" +
+	" \x01(\x03R\n" +
+	"reachCount\x12#\n" +
+	"\rack_requested\x18\v \x01(\bR\fackRequested\x12\x10\n" +
+	"\x03seq\x18\f \x01(\x04R\x03seq\x12\x18\n" +
+	"\apayload\x18\r \x01(\fR\apayload\x12\x12\n" +
+	"\x04path\x18\x0e \x03(\tR\x04path\x12/\n" +
+	"\x14expires_at_unix_nano\x18\x0f \x01(\x03R\x11expiresAtUnixNano\x12\x16\n" +
+	"\x06target\x18\x10 \x01(\tR\x06target\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x012;\n" +
+	"\x05BTree\x122\n" +
+	"\x06Stream\x12\x11.btree.v1.Message\x1a\x11.btree.v1.Message(\x010\x01B=Z;github.com/xnok/btree-server-msg/pkg/transport/grpc/btreepbb\x06proto3"
+
+var (
+	file_btree_proto_rawDescOnce sync.Once
+	file_btree_proto_rawDescData []byte
+)
+
+func file_btree_proto_rawDescGZIP() []byte {
+	file_btree_proto_rawDescOnce.Do(func() {
+		file_btree_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_btree_proto_rawDesc), len(file_btree_proto_rawDesc)))
+	})
+	return file_btree_proto_rawDescData
+}
+
+var file_btree_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_btree_proto_goTypes = []any{
+	(*Message)(nil), // 0: btree.v1.Message
+	nil,             // 1: btree.v1.Message.MetadataEntry
+}
+var file_btree_proto_depIdxs = []int32{
+	1, // 0: btree.v1.Message.metadata:type_name -> btree.v1.Message.MetadataEntry
+	0, // 1: btree.v1.BTree.Stream:input_type -> btree.v1.Message
+	0, // 2: btree.v1.BTree.Stream:output_type -> btree.v1.Message
+	2, // [2:3] is the sub-list for method output_type
+	1, // [1:2] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_btree_proto_init() }
+func file_btree_proto_init() {
+	if File_btree_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_btree_proto_rawDesc), len(file_btree_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_btree_proto_goTypes,
+		DependencyIndexes: file_btree_proto_depIdxs,
+		MessageInfos:      file_btree_proto_msgTypes,
+	}.Build()
+	File_btree_proto = out.File
+	file_btree_proto_goTypes = nil
+	file_btree_proto_depIdxs = nil
+}
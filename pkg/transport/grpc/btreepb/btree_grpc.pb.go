@@ -0,0 +1,123 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: btree.proto
+
+package btreepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	BTree_Stream_FullMethodName = "/btree.v1.BTree/Stream"
+)
+
+// BTreeClient is the client API for BTree service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// BTree carries messages between a parent and a child node.
+type BTreeClient interface {
+	// Stream exchanges messages in both directions for the life of the
+	// connection.
+	Stream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Message, Message], error)
+}
+
+type bTreeClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBTreeClient(cc grpc.ClientConnInterface) BTreeClient {
+	return &bTreeClient{cc}
+}
+
+func (c *bTreeClient) Stream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Message, Message], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BTree_ServiceDesc.Streams[0], BTree_Stream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Message, Message]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BTree_StreamClient = grpc.BidiStreamingClient[Message, Message]
+
+// BTreeServer is the server API for BTree service.
+// All implementations must embed UnimplementedBTreeServer
+// for forward compatibility.
+//
+// BTree carries messages between a parent and a child node.
+type BTreeServer interface {
+	// Stream exchanges messages in both directions for the life of the
+	// connection.
+	Stream(grpc.BidiStreamingServer[Message, Message]) error
+	mustEmbedUnimplementedBTreeServer()
+}
+
+// UnimplementedBTreeServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedBTreeServer struct{}
+
+func (UnimplementedBTreeServer) Stream(grpc.BidiStreamingServer[Message, Message]) error {
+	return status.Errorf(codes.Unimplemented, "method Stream not implemented")
+}
+func (UnimplementedBTreeServer) mustEmbedUnimplementedBTreeServer() {}
+func (UnimplementedBTreeServer) testEmbeddedByValue()               {}
+
+// UnsafeBTreeServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BTreeServer will
+// result in compilation errors.
+type UnsafeBTreeServer interface {
+	mustEmbedUnimplementedBTreeServer()
+}
+
+func RegisterBTreeServer(s grpc.ServiceRegistrar, srv BTreeServer) {
+	// If the following call pancis, it indicates UnimplementedBTreeServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&BTree_ServiceDesc, srv)
+}
+
+func _BTree_Stream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(BTreeServer).Stream(&grpc.GenericServerStream[Message, Message]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BTree_StreamServer = grpc.BidiStreamingServer[Message, Message]
+
+// BTree_ServiceDesc is the grpc.ServiceDesc for BTree service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BTree_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "btree.v1.BTree",
+	HandlerType: (*BTreeServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       _BTree_Stream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "btree.proto",
+}
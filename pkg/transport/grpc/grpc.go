@@ -0,0 +1,361 @@
+// Package grpc implements the Transport interface over a bidirectional
+// gRPC stream, so nodes can exchange messages with other gRPC services.
+package grpc
+
+//go:generate protoc -I btreepb --go_out=btreepb --go_opt=paths=source_relative --go-grpc_out=btreepb --go-grpc_opt=paths=source_relative btree.proto
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+	"github.com/xnok/btree-server-msg/pkg/transport"
+	"github.com/xnok/btree-server-msg/pkg/transport/grpc/btreepb"
+)
+
+// messageStream is the half of a Stream RPC the transport reads and writes;
+// both the server and client ends satisfy it
+type messageStream interface {
+	Send(*btreepb.Message) error
+	Recv() (*btreepb.Message, error)
+}
+
+// GRPCTransport implements the Transport interface over the BTree.Stream
+// RPC. A listening transport sends outbound messages to every open stream;
+// a connected transport sends them on the one stream it opened.
+type GRPCTransport struct {
+	btreepb.UnimplementedBTreeServer
+
+	inbound  chan btree.Message
+	outbound chan btree.Message
+	server   *grpclib.Server
+	conn     *grpclib.ClientConn
+	streams  map[messageStream]struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	once     sync.Once
+	isServer bool
+	isClient bool
+
+	// dialOptions are appended to the defaults used by Connect
+	dialOptions []grpclib.DialOption
+
+	// listenAddr is the address of the listener passed to Serve
+	listenAddr net.Addr
+
+	// logger receives the transport's structured log records
+	logger atomic.Pointer[slog.Logger]
+}
+
+// NewGRPCTransport creates a new gRPC transport
+func NewGRPCTransport() *GRPCTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &GRPCTransport{
+		inbound:  make(chan btree.Message, btree.DefaultBufferSize),
+		outbound: make(chan btree.Message, btree.DefaultBufferSize),
+		streams:  make(map[messageStream]struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	t.SetLogger(nil)
+	return t
+}
+
+// SetDialOptions sets extra options used by Connect, such as TLS
+// credentials or a custom dialer. Connections are insecure unless the
+// options override the transport credentials.
+func (t *GRPCTransport) SetDialOptions(opts ...grpclib.DialOption) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.dialOptions = opts
+}
+
+// Listen starts a gRPC server on the specified address
+func (t *GRPCTransport) Listen(ctx context.Context, address string) error {
+	address, err := transport.ListenAddress(address)
+	if err != nil {
+		return err
+	}
+
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", address, err)
+	}
+
+	if err := t.Serve(lis); err != nil {
+		lis.Close()
+		return err
+	}
+	return nil
+}
+
+// Serve starts a gRPC server on an existing listener; the transport closes
+// the listener when it is closed
+func (t *GRPCTransport) Serve(lis net.Listener) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.ctx.Err() != nil {
+		return fmt.Errorf("transport is closed")
+	}
+	if t.isServer || t.isClient {
+		return fmt.Errorf("already in use")
+	}
+
+	t.server = grpclib.NewServer()
+	btreepb.RegisterBTreeServer(t.server, t)
+	t.isServer = true
+	t.listenAddr = lis.Addr()
+
+	t.log().Info("Listening", "address", lis.Addr().String())
+
+	t.wg.Add(2)
+	go func() {
+		defer t.wg.Done()
+		if err := t.server.Serve(lis); err != nil && t.ctx.Err() == nil {
+			t.log().Error("Server error", "error", err)
+		}
+	}()
+	go t.processOutbound()
+
+	return nil
+}
+
+// ListenAddr returns the address the server is bound to, including the port
+// the OS assigned when listening on port 0, or nil when not listening
+func (t *GRPCTransport) ListenAddr() net.Addr {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.listenAddr
+}
+
+// Connect dials the address and opens the message stream
+func (t *GRPCTransport) Connect(ctx context.Context, address string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.ctx.Err() != nil {
+		return fmt.Errorf("transport is closed")
+	}
+	if t.isServer || t.isClient {
+		return fmt.Errorf("already in use")
+	}
+
+	address, err := transport.DialAddress(address)
+	if err != nil {
+		return err
+	}
+
+	opts := append([]grpclib.DialOption{grpclib.WithTransportCredentials(insecure.NewCredentials())}, t.dialOptions...)
+	conn, err := grpclib.NewClient(address, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %v", address, err)
+	}
+
+	// The stream lives as long as the transport, not the caller's context
+	stream, err := btreepb.NewBTreeClient(conn).Stream(t.ctx)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to open stream to %s: %v", address, err)
+	}
+
+	t.conn = conn
+	t.streams[stream] = struct{}{}
+	t.isClient = true
+
+	t.log().Info("Connected", "remote", address)
+
+	t.wg.Add(2)
+	go func() {
+		defer t.wg.Done()
+		t.receive(stream)
+	}()
+	go t.processOutbound()
+
+	return nil
+}
+
+// Stream implements btreepb.BTreeServer: it delivers messages from a
+// connected client until the stream ends
+func (t *GRPCTransport) Stream(stream grpclib.BidiStreamingServer[btreepb.Message, btreepb.Message]) error {
+	t.mu.Lock()
+	if t.ctx.Err() != nil {
+		t.mu.Unlock()
+		return fmt.Errorf("transport is closed")
+	}
+	t.wg.Add(1)
+	t.streams[stream] = struct{}{}
+	t.mu.Unlock()
+
+	defer t.wg.Done()
+	defer func() {
+		t.mu.Lock()
+		delete(t.streams, stream)
+		t.mu.Unlock()
+	}()
+
+	t.log().Info("Stream opened")
+	return t.receive(stream)
+}
+
+// Close closes the transport; it is safe to call more than once
+func (t *GRPCTransport) Close() error {
+	t.once.Do(func() {
+		t.mu.Lock()
+		t.cancel()
+		server, conn := t.server, t.conn
+		t.mu.Unlock()
+
+		if server != nil {
+			server.Stop()
+		}
+		if conn != nil {
+			conn.Close()
+		}
+
+		// Wait for goroutines and stream handlers to finish
+		t.wg.Wait()
+
+		close(t.inbound)
+		close(t.outbound)
+	})
+	return nil
+}
+
+// GetInboundChannel returns the channel for incoming messages
+func (t *GRPCTransport) GetInboundChannel() <-chan btree.Message {
+	return t.inbound
+}
+
+// GetOutboundChannel returns the channel for outgoing messages
+func (t *GRPCTransport) GetOutboundChannel() chan<- btree.Message {
+	return t.outbound
+}
+
+// receive delivers messages from a stream to the inbound channel until the
+// stream ends or the transport is closed
+func (t *GRPCTransport) receive(stream messageStream) error {
+	for {
+		pb, err := stream.Recv()
+		if err != nil {
+			if t.ctx.Err() != nil {
+				return nil
+			}
+			t.log().Info("Stream closed", "error", err)
+			return err
+		}
+
+		msg := btree.AssignID(fromProto(pb), nil)
+
+		select {
+		case t.inbound <- msg:
+			t.log().Debug("Received message", "content", msg.Content, "id", msg.ID)
+		case <-t.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// processOutbound sends outbound messages on the open streams
+func (t *GRPCTransport) processOutbound() {
+	defer t.wg.Done()
+
+	for {
+		select {
+		case msg := <-t.outbound:
+			if err := t.sendMessage(msg); err != nil {
+				t.log().Error("Failed to send message", "id", msg.ID, "error", err)
+			}
+		case <-t.ctx.Done():
+			return
+		}
+	}
+}
+
+// sendMessage writes a message to every open stream
+func (t *GRPCTransport) sendMessage(msg btree.Message) error {
+	t.mu.Lock()
+	streams := make([]messageStream, 0, len(t.streams))
+	for stream := range t.streams {
+		streams = append(streams, stream)
+	}
+	t.mu.Unlock()
+
+	if len(streams) == 0 {
+		return fmt.Errorf("no streams to send to")
+	}
+
+	pb := toProto(msg)
+	for _, stream := range streams {
+		if err := stream.Send(pb); err != nil {
+			return fmt.Errorf("failed to send on stream: %v", err)
+		}
+	}
+
+	t.log().Debug("Sent message", "content", msg.Content, "id", msg.ID)
+	return nil
+}
+
+// toProto converts a message to its wire form
+func toProto(msg btree.Message) *btreepb.Message {
+	pb := &btreepb.Message{
+		Content:       msg.Content,
+		Id:            msg.ID,
+		Source:        msg.Source,
+		Path:          msg.Path,
+		Target:        msg.Target,
+		Type:          msg.Type,
+		Metadata:      msg.Metadata,
+		Upstream:      msg.Upstream,
+		TrackReach:    msg.TrackReach,
+		CorrelationId: msg.CorrelationID,
+		ReachCount:    int64(msg.ReachCount),
+		AckRequested:  msg.AckRequested,
+		Seq:           msg.Seq,
+		Payload:       msg.Payload,
+	}
+	if !msg.Timestamp.IsZero() {
+		pb.TimestampUnixNano = msg.Timestamp.UnixNano()
+	}
+	if !msg.ExpiresAt.IsZero() {
+		pb.ExpiresAtUnixNano = msg.ExpiresAt.UnixNano()
+	}
+	return pb
+}
+
+// fromProto converts a wire message back to a btree.Message
+func fromProto(pb *btreepb.Message) btree.Message {
+	msg := btree.Message{
+		Content:       pb.GetContent(),
+		ID:            pb.GetId(),
+		Source:        pb.GetSource(),
+		Path:          pb.GetPath(),
+		Target:        pb.GetTarget(),
+		Type:          pb.GetType(),
+		Metadata:      pb.GetMetadata(),
+		Upstream:      pb.GetUpstream(),
+		TrackReach:    pb.GetTrackReach(),
+		CorrelationID: pb.GetCorrelationId(),
+		ReachCount:    int(pb.GetReachCount()),
+		AckRequested:  pb.GetAckRequested(),
+		Seq:           pb.GetSeq(),
+		Payload:       pb.GetPayload(),
+	}
+	if nanos := pb.GetTimestampUnixNano(); nanos != 0 {
+		msg.Timestamp = time.Unix(0, nanos)
+	}
+	if nanos := pb.GetExpiresAtUnixNano(); nanos != 0 {
+		msg.ExpiresAt = time.Unix(0, nanos)
+	}
+	return msg
+}
@@ -0,0 +1,166 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+)
+
+// startPair serves a transport on an in-memory bufconn listener and
+// connects a client transport to it
+func startPair(t *testing.T) (server, client *GRPCTransport) {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+
+	server = NewGRPCTransport()
+	if err := server.Serve(lis); err != nil {
+		t.Fatalf("Failed to serve: %v", err)
+	}
+
+	client = NewGRPCTransport()
+	client.SetDialOptions(grpclib.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}))
+	if err := client.Connect(context.Background(), "passthrough:///bufnet"); err != nil {
+		server.Close()
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return server, client
+}
+
+// receive waits for the next inbound message on a transport
+func receive(t *testing.T, tr *GRPCTransport) btree.Message {
+	t.Helper()
+
+	select {
+	case msg := <-tr.GetInboundChannel():
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timeout waiting for message")
+		return btree.Message{}
+	}
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	server, client := startPair(t)
+
+	sent := btree.Message{
+		Content:   "over grpc",
+		ID:        "g-1",
+		Timestamp: time.Now(),
+		Source:    "root",
+		Type:      btree.TypeControl,
+	}.WithMetadata("trace-id", "abc123")
+	client.GetOutboundChannel() <- sent
+
+	msg := receive(t, server)
+	if msg.Content != sent.Content || msg.ID != sent.ID || msg.Source != sent.Source || msg.Type != sent.Type {
+		t.Errorf("Expected %+v, got %+v", sent, msg)
+	}
+	if !msg.Timestamp.Equal(sent.Timestamp) {
+		t.Errorf("Expected timestamp %v, got %v", sent.Timestamp, msg.Timestamp)
+	}
+	if got := msg.GetMetadata("trace-id"); got != "abc123" {
+		t.Errorf("Expected trace-id abc123, got %q", got)
+	}
+
+	// The server sends back on the stream the client opened
+	for i := 0; i < 3; i++ {
+		server.GetOutboundChannel() <- btree.Message{Content: fmt.Sprintf("reply %d", i), ID: fmt.Sprintf("r-%d", i)}
+	}
+	for i := 0; i < 3; i++ {
+		if msg := receive(t, client); msg.ID != fmt.Sprintf("r-%d", i) {
+			t.Errorf("Expected r-%d, got %q", i, msg.ID)
+		}
+	}
+}
+
+func TestProtoConversion(t *testing.T) {
+	original := btree.Message{
+		Content:       "reach report",
+		ID:            "p-1",
+		Timestamp:     time.Unix(1700000000, 42),
+		Upstream:      true,
+		TrackReach:    true,
+		CorrelationID: "q-1",
+		ReachCount:    7,
+		AckRequested:  true,
+		Seq:           12,
+		Payload:       []byte{0x00, 0x0A, 0xFF},
+		Path:          []string{"root", "root.0"},
+		ExpiresAt:     time.Unix(1700000060, 7),
+		Target:        "root.0.1",
+	}
+
+	got := fromProto(toProto(original))
+	if got.Content != original.Content || got.ID != original.ID || !got.Timestamp.Equal(original.Timestamp) ||
+		got.Upstream != original.Upstream || got.TrackReach != original.TrackReach ||
+		got.CorrelationID != original.CorrelationID || got.ReachCount != original.ReachCount ||
+		got.AckRequested != original.AckRequested || got.Seq != original.Seq ||
+		!bytes.Equal(got.Payload, original.Payload) || !slices.Equal(got.Path, original.Path) ||
+		!got.ExpiresAt.Equal(original.ExpiresAt) || got.Target != original.Target {
+		t.Errorf("Expected %+v, got %+v", original, got)
+	}
+
+	zero := fromProto(toProto(btree.Message{Content: "x"}))
+	if !zero.Timestamp.IsZero() {
+		t.Errorf("Expected zero timestamp to stay zero, got %v", zero.Timestamp)
+	}
+	if !zero.ExpiresAt.IsZero() {
+		t.Errorf("Expected zero expiry to stay zero, got %v", zero.ExpiresAt)
+	}
+}
+
+func TestCloseTwice(t *testing.T) {
+	server, client := startPair(t)
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("First close failed: %v", err)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("Second close failed: %v", err)
+	}
+	server.Close()
+
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err == nil {
+		t.Error("Expected Listen on a closed transport to fail")
+	}
+}
+
+func TestListenAddrReportsAssignedPort(t *testing.T) {
+	server := NewGRPCTransport()
+	defer server.Close()
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	addr, ok := server.ListenAddr().(*net.TCPAddr)
+	if !ok || addr.Port == 0 {
+		t.Fatalf("Expected an assigned port, got %v", server.ListenAddr())
+	}
+
+	client := NewGRPCTransport()
+	defer client.Close()
+	if err := client.Connect(context.Background(), addr.String()); err != nil {
+		t.Fatalf("Failed to connect to the assigned port: %v", err)
+	}
+	client.GetOutboundChannel() <- btree.Message{Content: "found you"}
+	if msg := receive(t, server); msg.Content != "found you" {
+		t.Errorf("Expected %q, got %q", "found you", msg.Content)
+	}
+}
@@ -0,0 +1,17 @@
+package grpc
+
+import "log/slog"
+
+// SetLogger sets the structured logger the transport writes to; records
+// carry a "transport" attribute. A nil logger restores slog.Default().
+func (t *GRPCTransport) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	t.logger.Store(logger.With("transport", "grpc"))
+}
+
+// log returns the transport's logger
+func (t *GRPCTransport) log() *slog.Logger {
+	return t.logger.Load()
+}
@@ -0,0 +1,264 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+)
+
+// Registry maps listening addresses to in-memory transports
+type Registry struct {
+	mu        sync.Mutex
+	listeners map[string]*InMemoryTransport
+}
+
+// NewRegistry creates an empty registry, useful to isolate tests
+func NewRegistry() *Registry {
+	return &Registry{
+		listeners: make(map[string]*InMemoryTransport),
+	}
+}
+
+// defaultRegistry is shared by transports created with NewInMemoryTransport
+var defaultRegistry = NewRegistry()
+
+// register binds a transport to an address
+func (r *Registry) register(address string, t *InMemoryTransport) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.listeners[address]; exists {
+		return fmt.Errorf("address %s already in use", address)
+	}
+	r.listeners[address] = t
+	return nil
+}
+
+// unregister releases an address if it is still bound to the transport
+func (r *Registry) unregister(address string, t *InMemoryTransport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.listeners[address] == t {
+		delete(r.listeners, address)
+	}
+}
+
+// lookup returns the transport listening on an address
+func (r *Registry) lookup(address string) (*InMemoryTransport, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.listeners[address]
+	return t, ok
+}
+
+// normalizeAddress maps "3030", ":3030" and "localhost:3030" to the same key
+func normalizeAddress(address string) string {
+	address = strings.TrimPrefix(address, "localhost")
+	if !strings.Contains(address, ":") {
+		address = ":" + address
+	}
+	return address
+}
+
+// InMemoryTransport implements the Transport interface with channels, linking
+// peers through a registry instead of the network
+type InMemoryTransport struct {
+	registry *Registry
+	inbound  chan btree.Message
+	outbound chan btree.Message
+	address  string
+	remote   *InMemoryTransport
+	peers    map[*InMemoryTransport]struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	mu       sync.RWMutex
+	closed   bool
+	once     sync.Once
+	isServer bool
+	isClient bool
+}
+
+// NewInMemoryTransport creates an in-memory transport using the default registry
+func NewInMemoryTransport() *InMemoryTransport {
+	return NewInMemoryTransportWithRegistry(defaultRegistry)
+}
+
+// NewInMemoryTransportWithRegistry creates an in-memory transport bound to a registry
+func NewInMemoryTransportWithRegistry(registry *Registry) *InMemoryTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &InMemoryTransport{
+		registry: registry,
+		inbound:  make(chan btree.Message, 100),
+		outbound: make(chan btree.Message, 100),
+		peers:    make(map[*InMemoryTransport]struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Listen registers the transport under the specified address
+func (t *InMemoryTransport) Listen(ctx context.Context, address string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.isServer {
+		return fmt.Errorf("already listening")
+	}
+
+	address = normalizeAddress(address)
+	if err := t.registry.register(address, t); err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", address, err)
+	}
+
+	t.address = address
+	t.isServer = true
+
+	// Start delivering outbound messages to connected peers
+	t.wg.Add(1)
+	go t.processOutbound()
+
+	return nil
+}
+
+// Connect links the transport to the transport listening on address
+func (t *InMemoryTransport) Connect(ctx context.Context, address string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.isClient {
+		return fmt.Errorf("already connected")
+	}
+
+	address = normalizeAddress(address)
+	remote, ok := t.registry.lookup(address)
+	if !ok {
+		return fmt.Errorf("failed to connect to %s: no listener", address)
+	}
+	if !remote.addPeer(t) {
+		return fmt.Errorf("failed to connect to %s: listener closed", address)
+	}
+
+	t.remote = remote
+	t.isClient = true
+
+	// Start delivering outbound messages to the remote listener
+	t.wg.Add(1)
+	go t.processOutbound()
+
+	return nil
+}
+
+// Close unregisters and closes the transport; it is safe to call more than once
+func (t *InMemoryTransport) Close() error {
+	t.once.Do(func() {
+		t.cancel()
+
+		t.mu.Lock()
+		t.closed = true
+		if t.isServer {
+			t.registry.unregister(t.address, t)
+		}
+		remote := t.remote
+		t.mu.Unlock()
+
+		if remote != nil {
+			remote.removePeer(t)
+		}
+
+		// Wait for goroutines to finish
+		t.wg.Wait()
+
+		close(t.inbound)
+		close(t.outbound)
+	})
+	return nil
+}
+
+// GetInboundChannel returns the channel for incoming messages
+func (t *InMemoryTransport) GetInboundChannel() <-chan btree.Message {
+	return t.inbound
+}
+
+// GetOutboundChannel returns the channel for outgoing messages
+func (t *InMemoryTransport) GetOutboundChannel() chan<- btree.Message {
+	return t.outbound
+}
+
+// addPeer records a connected client so the listener can reply to it
+func (t *InMemoryTransport) addPeer(peer *InMemoryTransport) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return false
+	}
+	t.peers[peer] = struct{}{}
+	return true
+}
+
+// removePeer forgets a client that closed
+func (t *InMemoryTransport) removePeer(peer *InMemoryTransport) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.peers, peer)
+}
+
+// deliver places a message on the inbound channel unless the transport is
+// closed or ctx is done; the read lock keeps Close from closing the channel
+// mid-send
+func (t *InMemoryTransport) deliver(ctx context.Context, msg btree.Message) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.closed {
+		return false
+	}
+
+	select {
+	case t.inbound <- msg:
+		return true
+	case <-t.ctx.Done():
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// processOutbound delivers outbound messages to the remote listener, or back
+// to every connected peer when the transport is listening
+func (t *InMemoryTransport) processOutbound() {
+	defer t.wg.Done()
+
+	for {
+		select {
+		case msg := <-t.outbound:
+			for _, target := range t.targets() {
+				target.deliver(t.ctx, msg)
+			}
+		case <-t.ctx.Done():
+			return
+		}
+	}
+}
+
+// targets returns the transports outbound messages are delivered to
+func (t *InMemoryTransport) targets() []*InMemoryTransport {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.remote != nil {
+		return []*InMemoryTransport{t.remote}
+	}
+
+	targets := make([]*InMemoryTransport, 0, len(t.peers))
+	for peer := range t.peers {
+		targets = append(targets, peer)
+	}
+	return targets
+}
@@ -0,0 +1,91 @@
+package inmem
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+)
+
+// receive waits for the next inbound message on a transport
+func receive(t *testing.T, tr *InMemoryTransport) btree.Message {
+	t.Helper()
+
+	select {
+	case msg := <-tr.GetInboundChannel():
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for message")
+		return btree.Message{}
+	}
+}
+
+func TestInMemoryExchange(t *testing.T) {
+	registry := NewRegistry()
+	ctx := context.Background()
+
+	server := NewInMemoryTransportWithRegistry(registry)
+	if err := server.Listen(ctx, "3030"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	client := NewInMemoryTransportWithRegistry(registry)
+	if err := client.Connect(ctx, "localhost:3030"); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	// Client to server
+	for _, content := range []string{"first", "second", "third"} {
+		client.GetOutboundChannel() <- btree.Message{Content: content}
+	}
+	for _, expected := range []string{"first", "second", "third"} {
+		if msg := receive(t, server); msg.Content != expected {
+			t.Errorf("Expected %q, got %q", expected, msg.Content)
+		}
+	}
+
+	// Server back to client
+	server.GetOutboundChannel() <- btree.Message{Content: "reply", Upstream: true}
+	if msg := receive(t, client); msg.Content != "reply" || !msg.Upstream {
+		t.Errorf("Unexpected reply: %+v", msg)
+	}
+}
+
+func TestInMemoryConnectWithoutListener(t *testing.T) {
+	client := NewInMemoryTransportWithRegistry(NewRegistry())
+	defer client.Close()
+
+	if err := client.Connect(context.Background(), "4040"); err == nil {
+		t.Error("Expected error connecting to an unregistered address")
+	}
+}
+
+func TestInMemoryAddressInUse(t *testing.T) {
+	registry := NewRegistry()
+
+	first := NewInMemoryTransportWithRegistry(registry)
+	if err := first.Listen(context.Background(), ":5050"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	second := NewInMemoryTransportWithRegistry(registry)
+	defer second.Close()
+	if err := second.Listen(context.Background(), "5050"); err == nil {
+		t.Error("Expected error listening on an address in use")
+	}
+
+	// Closing releases the address
+	first.Close()
+	if err := second.Listen(context.Background(), "5050"); err != nil {
+		t.Errorf("Expected address to be free after close: %v", err)
+	}
+}
+
+func TestInMemoryCloseIsIdempotent(t *testing.T) {
+	tr := NewInMemoryTransportWithRegistry(NewRegistry())
+	tr.Close()
+	tr.Close()
+}
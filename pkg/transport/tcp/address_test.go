@@ -0,0 +1,147 @@
+package tcp
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+	"github.com/xnok/btree-server-msg/pkg/transport"
+)
+
+// connectVia listens on listenAddr, connects to the address dialAddr builds
+// from the bound port and checks a message gets through
+func connectVia(t *testing.T, listenAddr string, dialAddr func(port string) string) {
+	t.Helper()
+
+	server := NewTCPTransport()
+	if err := server.Listen(context.Background(), listenAddr); err != nil {
+		t.Fatalf("Failed to listen on %s: %v", listenAddr, err)
+	}
+	defer server.Close()
+
+	_, port, err := net.SplitHostPort(server.ListenAddr().String())
+	if err != nil {
+		t.Fatalf("Unexpected listener address: %v", err)
+	}
+
+	client := NewTCPTransport()
+	defer client.Close()
+	address := dialAddr(port)
+	if err := client.Connect(context.Background(), address); err != nil {
+		t.Fatalf("Failed to connect to %s: %v", address, err)
+	}
+
+	client.GetOutboundChannel() <- btree.Message{Content: "hello " + address}
+	if msg := receive(t, server); msg.Content != "hello "+address {
+		t.Errorf("Expected %q, got %q", "hello "+address, msg.Content)
+	}
+}
+
+func TestIPv6LoopbackAddress(t *testing.T) {
+	probe, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	probe.Close()
+
+	connectVia(t, "[::1]:0", func(port string) string { return "[::1]:" + port })
+}
+
+func TestBarePortAddresses(t *testing.T) {
+	connectVia(t, "0", func(port string) string { return port })
+	connectVia(t, ":0", func(port string) string { return ":" + port })
+}
+
+func TestHostPortAddresses(t *testing.T) {
+	connectVia(t, "127.0.0.1:0", func(port string) string { return "localhost:" + port })
+	connectVia(t, "localhost:0", func(port string) string { return net.JoinHostPort("127.0.0.1", port) })
+}
+
+func TestInvalidAddressRejected(t *testing.T) {
+	server := NewTCPTransport()
+	defer server.Close()
+	if err := server.Listen(context.Background(), "::1"); err == nil {
+		t.Error("Expected an unbracketed IPv6 literal without port to be rejected")
+	}
+
+	client := NewTCPTransport()
+	defer client.Close()
+	if err := client.Connect(context.Background(), "localhost:"); err == nil {
+		t.Error("Expected an address without port to be rejected")
+	}
+	if err := client.Connect(context.Background(), "[::1]"); err == nil {
+		t.Error("Expected a bracketed host without port to be rejected")
+	}
+}
+
+func TestListenAddrReportsAssignedPort(t *testing.T) {
+	server := NewTCPTransport()
+	defer server.Close()
+	if addr := server.ListenAddr(); addr != nil {
+		t.Errorf("Expected no address before listening, got %v", addr)
+	}
+
+	if err := server.Listen(context.Background(), ":0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr, ok := transport.NewServer(server, ":0").Addr().(*net.TCPAddr)
+	if !ok {
+		t.Fatalf("Expected a TCP address, got %v", server.ListenAddr())
+	}
+	if addr.Port == 0 {
+		t.Fatal("Expected the OS to assign a port")
+	}
+
+	client := NewTCPTransport()
+	defer client.Close()
+	if err := client.Connect(context.Background(), strconv.Itoa(addr.Port)); err != nil {
+		t.Fatalf("Failed to connect to the assigned port: %v", err)
+	}
+	client.GetOutboundChannel() <- btree.Message{Content: "found you"}
+	if msg := receive(t, server); msg.Content != "found you" {
+		t.Errorf("Expected %q, got %q", "found you", msg.Content)
+	}
+}
+
+// externalIPv4 returns a non-loopback IPv4 address of this host
+func externalIPv4(t *testing.T) net.IP {
+	t.Helper()
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		t.Skipf("Cannot list interface addresses: %v", err)
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP
+		}
+	}
+	t.Skip("No external IPv4 interface")
+	return nil
+}
+
+func TestLoopbackListenerRefusesExternalInterface(t *testing.T) {
+	external := externalIPv4(t)
+
+	server := NewTCPTransport()
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	_, port, _ := net.SplitHostPort(server.ListenAddr().String())
+	client := NewTCPTransport()
+	defer client.Close()
+	if err := client.Connect(context.Background(), net.JoinHostPort(external.String(), port)); err == nil {
+		t.Errorf("Expected a dial on %s to be refused by a loopback-only listener", external)
+	}
+}
+
+func TestWildcardListenerAcceptsEveryInterface(t *testing.T) {
+	external := externalIPv4(t)
+
+	connectVia(t, "0.0.0.0:0", func(port string) string { return net.JoinHostPort(external.String(), port) })
+	connectVia(t, "0.0.0.0:0", func(port string) string { return net.JoinHostPort("127.0.0.1", port) })
+}
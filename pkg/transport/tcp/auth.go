@@ -0,0 +1,40 @@
+package tcp
+
+import (
+	"crypto/subtle"
+	"errors"
+)
+
+// ErrUnauthorized is returned when a listener refuses a dialer because its
+// handshake carried a missing or wrong auth token
+var ErrUnauthorized = errors.New("unauthorized")
+
+// SetAuthToken sets a pre-shared token. A dialer presents it in its
+// handshake, and a listener with a token closes every connection that does
+// not present the same one, including peers that send no handshake. The
+// token travels in clear text, so it only keeps out peers that do not know
+// it; it is no substitute for TLS. An empty token disables authentication,
+// the default.
+func (t *TCPTransport) SetAuthToken(token string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.authToken = token
+}
+
+// authorize checks the token presented in a dialer's handshake, returning
+// why it is refused or "" when it is accepted
+func (t *TCPTransport) authorize(peer handshake) string {
+	t.mu.RLock()
+	token := t.authToken
+	t.mu.RUnlock()
+
+	switch {
+	case token == "":
+		return ""
+	case peer.Token == "":
+		return "missing token"
+	case subtle.ConstantTimeCompare([]byte(peer.Token), []byte(token)) != 1:
+		return "invalid token"
+	}
+	return ""
+}
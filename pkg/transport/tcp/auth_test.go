@@ -0,0 +1,94 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+)
+
+// startAuthServer starts a listener that requires token
+func startAuthServer(t *testing.T, token string) *TCPTransport {
+	t.Helper()
+
+	server := NewTCPTransport()
+	server.SetAuthToken(token)
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+func TestValidTokenIsAccepted(t *testing.T) {
+	server := startAuthServer(t, "s3cret")
+
+	client := NewTCPTransport()
+	client.SetAuthToken("s3cret")
+	defer client.Close()
+	if err := client.Connect(context.Background(), server.ListenAddr().String()); err != nil {
+		t.Fatalf("Failed to connect with a valid token: %v", err)
+	}
+
+	client.GetOutboundChannel() <- btree.Message{Content: "authorized", ID: "a-1"}
+	if msg := receive(t, server); msg.ID != "a-1" {
+		t.Errorf("Expected a-1, got %+v", msg)
+	}
+}
+
+func TestInvalidTokenIsRejected(t *testing.T) {
+	server := startAuthServer(t, "s3cret")
+
+	for _, token := range []string{"wrong", ""} {
+		client := NewTCPTransport()
+		client.SetAuthToken(token)
+
+		err := client.Connect(context.Background(), server.ListenAddr().String())
+		client.Close()
+		if !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("Token %q: expected ErrUnauthorized, got %v", token, err)
+		}
+	}
+	waitFor(t, time.Second, func() bool { return server.ActiveConnections() == 0 })
+}
+
+func TestPeerWithoutHandshakeIsRejectedWhenTokenRequired(t *testing.T) {
+	server := startAuthServer(t, "s3cret")
+
+	conn, err := net.Dial("tcp", server.ListenAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "N{\"content\":\"injected\"}\n")
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Expected the connection to be closed with EOF, got %v", err)
+	}
+	select {
+	case msg := <-server.GetInboundChannel():
+		t.Errorf("Expected no message from an unauthenticated peer, got %+v", msg)
+	default:
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	tr := NewTCPTransport()
+	if reason := tr.authorize(handshake{}); reason != "" {
+		t.Errorf("Expected any peer to be accepted without a token, got %q", reason)
+	}
+
+	tr.SetAuthToken("s3cret")
+	tests := map[string]string{"s3cret": "", "": "missing token", "s3cre": "invalid token"}
+	for token, want := range tests {
+		if got := tr.authorize(handshake{Token: token}); got != want {
+			t.Errorf("Token %q: expected %q, got %q", token, want, got)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package tcp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+	"github.com/xnok/btree-server-msg/pkg/transport"
+)
+
+func TestAutoCodecListenerAcceptsJSONAndText(t *testing.T) {
+	server := NewTCPTransportWithCodec(transport.AutoCodec{})
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	// A legacy peer mixing both formats on the same connection
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "N{\"content\":\"from json\",\"id\":\"j-1\"}\n")
+	fmt.Fprint(conn, "Nfrom text\n")
+	fmt.Fprint(conn, "N{braces in text}\n")
+
+	if msg := receive(t, server); msg.Content != "from json" || msg.ID != "j-1" {
+		t.Errorf("Expected the JSON message j-1, got %+v", msg)
+	}
+	if msg := receive(t, server); msg.Content != "from text" {
+		t.Errorf("Expected the text line, got %+v", msg)
+	}
+	if msg := receive(t, server); msg.Content != "{braces in text}" {
+		t.Errorf("Expected text starting with a brace to stay text, got %+v", msg)
+	}
+
+	// Peers that handshake with the JSON codec are accepted too
+	client := NewTCPTransport()
+	defer client.Close()
+	if err := client.Connect(context.Background(), server.listener.Addr().String()); err != nil {
+		t.Fatalf("Expected a JSON peer to be accepted: %v", err)
+	}
+	client.GetOutboundChannel() <- btree.Message{Content: "handshaken", ID: "j-2"}
+	if msg := receive(t, server); msg.ID != "j-2" {
+		t.Errorf("Expected j-2, got %+v", msg)
+	}
+}
+
+func TestJSONListenerDropsTextLines(t *testing.T) {
+	server, _ := startPair(t)
+
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "Nplain text\n")
+	fmt.Fprint(conn, "N{\"content\":\"json\",\"id\":\"j-3\"}\n")
+
+	// Without auto-detection the text line is malformed JSON and dropped
+	if msg := receive(t, server); msg.ID != "j-3" {
+		t.Errorf("Expected only the JSON message, got %+v", msg)
+	}
+}
@@ -0,0 +1,193 @@
+package tcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+)
+
+// SetBatching makes the outbound loop write queued messages together in one
+// batch frame, saving a syscall per message. After taking a message it waits
+// up to window for more, writing the batch once size messages are collected
+// or the window expires; a zero window only batches messages already queued.
+// Every message of a failed batch is retried together. The peer must
+// understand batch frames, i.e. run a version of this transport that
+// supports them. A size of one or less disables batching, the default.
+func (t *TCPTransport) SetBatching(size int, window time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.batchSize = size
+	t.batchWindow = window
+}
+
+// collectBatch returns first followed by further outbound messages, up to
+// the batch size. When wait is set it waits for them until the batch window
+// expires, otherwise it only takes messages that are already queued.
+func (t *TCPTransport) collectBatch(first btree.Message, wait bool) []btree.Message {
+	t.mu.RLock()
+	size := t.batchSize
+	window := t.batchWindow
+	t.mu.RUnlock()
+
+	batch := []btree.Message{first}
+	if size <= 1 {
+		return batch
+	}
+
+	var expired <-chan time.Time
+	if wait && window > 0 {
+		timer := time.NewTimer(window)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for len(batch) < size {
+		if expired == nil {
+			select {
+			case msg := <-t.outbound:
+				batch = append(batch, msg)
+			default:
+				return batch
+			}
+			continue
+		}
+
+		select {
+		case msg := <-t.outbound:
+			batch = append(batch, msg)
+		case <-expired:
+			return batch
+		case <-t.ctx.Done():
+			return batch
+		}
+	}
+	return batch
+}
+
+// deliverBatch sends a batch collected by collectBatch, falling back to a
+// plain frame for a single message
+func (t *TCPTransport) deliverBatch(batch []btree.Message) {
+	if len(batch) == 1 {
+		t.deliver(batch[0])
+		return
+	}
+	t.retrySend(slog.Int("batch", len(batch)), func() error { return t.sendBatch(batch) })
+}
+
+// sendBatch encodes messages into batch frames and writes them at once like
+// sendMessage. Messages over the maximum message size are dropped, and the
+// rest are split across batch frames that each stay within it.
+func (t *TCPTransport) sendBatch(batch []btree.Message) error {
+	t.mu.RLock()
+	threshold := t.framingThreshold
+	writeTimeout := t.writeTimeout
+	limit := t.maxMessageSize
+	t.mu.RUnlock()
+
+	conns, isClient := t.activeConns()
+	if len(conns) == 0 {
+		return ErrNoConnection
+	}
+
+	payloads := make([][]byte, 0, len(batch))
+	for _, msg := range batch {
+		stopEncode := t.sampleTimer(msg).Start(btree.StageEncode)
+		payload, err := encodeMessage(t.codec, msg)
+		stopEncode()
+		if err != nil {
+			return err
+		}
+		if err := checkMessageSize(payload, limit); err != nil {
+			t.log().Error("Dropping oversized message", "id", msg.ID, "error", err)
+			continue
+		}
+		payloads = append(payloads, payload)
+	}
+	if len(payloads) == 0 {
+		return nil
+	}
+
+	frame := batchFrames(payloads, limit, threshold)
+	err := t.writeConns(conns, isClient, writeTimeout, func(w io.Writer) error {
+		return writeFrameBytes(w, frame)
+	})
+	if err != nil {
+		return err
+	}
+
+	t.log().Debug("Sent batch", "messages", len(payloads), "bytes", len(frame))
+	return nil
+}
+
+// batchFrame builds a batch frame holding each payload behind its own
+// 4-byte big-endian length
+func batchFrame(payloads [][]byte) []byte {
+	size := 0
+	for _, payload := range payloads {
+		size += frameHeaderSize + len(payload)
+	}
+
+	frame := make([]byte, 1+frameHeaderSize, 1+frameHeaderSize+size)
+	frame[0] = frameBatch
+	binary.BigEndian.PutUint32(frame[1:], uint32(size))
+	for _, payload := range payloads {
+		frame = binary.BigEndian.AppendUint32(frame, uint32(len(payload)))
+		frame = append(frame, payload...)
+	}
+	return frame
+}
+
+// batchFrames frames payloads into as few batch frames as limit allows,
+// concatenated so they are written at once. A payload left alone in a frame
+// is framed like writeFrame does. Zero limit puts every payload in one batch
+// frame.
+func batchFrames(payloads [][]byte, limit, threshold int) []byte {
+	var frames []byte
+	var group [][]byte
+	size := 0
+
+	flush := func() {
+		switch len(group) {
+		case 0:
+		case 1:
+			frames = append(frames, messageFrame(group[0], threshold)...)
+		default:
+			frames = append(frames, batchFrame(group)...)
+		}
+		group, size = nil, 0
+	}
+
+	for _, payload := range payloads {
+		entry := frameHeaderSize + len(payload)
+		if limit > 0 && size+entry > limit {
+			flush()
+		}
+		group = append(group, payload)
+		size += entry
+	}
+	flush()
+	return frames
+}
+
+// splitBatch splits the payload of a batch frame back into the message
+// payloads it holds. They alias payload.
+func splitBatch(payload []byte) ([][]byte, error) {
+	var payloads [][]byte
+	for len(payload) > 0 {
+		if len(payload) < frameHeaderSize {
+			return nil, fmt.Errorf("truncated batch entry header")
+		}
+		size := int(binary.BigEndian.Uint32(payload))
+		payload = payload[frameHeaderSize:]
+		if size > len(payload) {
+			return nil, fmt.Errorf("batch entry of %d bytes exceeds remaining %d", size, len(payload))
+		}
+		payloads = append(payloads, payload[:size])
+		payload = payload[size:]
+	}
+	return payloads, nil
+}
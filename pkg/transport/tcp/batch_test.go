@@ -0,0 +1,140 @@
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+)
+
+// countingConn counts the Write calls made on a connection
+type countingConn struct {
+	net.Conn
+	writes *atomic.Int64
+}
+
+func (c countingConn) Write(p []byte) (int, error) {
+	c.writes.Add(1)
+	return c.Conn.Write(p)
+}
+
+// countWrites wraps the dialed connection of a client to count its writes
+func countWrites(client *TCPTransport) *atomic.Int64 {
+	writes := &atomic.Int64{}
+	client.mu.Lock()
+	client.conn = countingConn{Conn: client.conn, writes: writes}
+	client.mu.Unlock()
+	return writes
+}
+
+func TestBatchFrameRoundTrip(t *testing.T) {
+	payloads := [][]byte{[]byte("first"), {}, []byte("line\nbreak"), {0x00, 0x0A}}
+
+	var buf bytes.Buffer
+	if err := writeFrameBytes(&buf, batchFrame(payloads)); err != nil {
+		t.Fatalf("Failed to write batch: %v", err)
+	}
+
+	payload, frameType, err := readFrame(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatalf("Failed to read batch: %v", err)
+	}
+	if frameType != frameBatch {
+		t.Fatalf("Expected batch frame, got %q", frameType)
+	}
+
+	got, err := splitBatch(payload)
+	if err != nil {
+		t.Fatalf("Failed to split batch: %v", err)
+	}
+	if len(got) != len(payloads) {
+		t.Fatalf("Expected %d payloads, got %d", len(payloads), len(got))
+	}
+	for i := range payloads {
+		if !bytes.Equal(got[i], payloads[i]) {
+			t.Errorf("Payload %d: expected %q, got %q", i, payloads[i], got[i])
+		}
+	}
+}
+
+func TestSplitBatchRejectsTruncatedEntries(t *testing.T) {
+	frame := batchFrame([][]byte{[]byte("payload")})
+	payload := frame[1+frameHeaderSize:]
+
+	for _, truncated := range [][]byte{payload[:2], payload[:len(payload)-1]} {
+		if _, err := splitBatch(truncated); err == nil {
+			t.Errorf("Expected error splitting %d-byte truncated batch", len(truncated))
+		}
+	}
+}
+
+func TestBatchedMessagesAreUnbatched(t *testing.T) {
+	server, client := startPair(t)
+	client.SetBatching(10, 50*time.Millisecond)
+	writes := countWrites(client)
+
+	const count = 25
+	for i := 0; i < count; i++ {
+		client.GetOutboundChannel() <- btree.Message{ID: fmt.Sprintf("msg-%d", i), Content: fmt.Sprintf("content %d", i)}
+	}
+
+	for i := 0; i < count; i++ {
+		msg := receive(t, server)
+		if want := fmt.Sprintf("msg-%d", i); msg.ID != want {
+			t.Fatalf("Expected %s, got %s", want, msg.ID)
+		}
+		if want := fmt.Sprintf("content %d", i); msg.Content != want {
+			t.Errorf("Expected content %q, got %q", want, msg.Content)
+		}
+	}
+
+	if got := writes.Load(); got >= count {
+		t.Errorf("Expected fewer than %d writes with batching, got %d", count, got)
+	}
+}
+
+func TestBatchingDisabledByDefault(t *testing.T) {
+	server, client := startPair(t)
+	writes := countWrites(client)
+
+	const count = 5
+	for i := 0; i < count; i++ {
+		client.GetOutboundChannel() <- btree.Message{Content: fmt.Sprintf("content %d", i)}
+	}
+	for i := 0; i < count; i++ {
+		receive(t, server)
+	}
+
+	if got := writes.Load(); got != count {
+		t.Errorf("Expected one write per message, got %d writes for %d messages", got, count)
+	}
+}
+
+func BenchmarkBatching(b *testing.B) {
+	for _, size := range []int{1, 16, 64} {
+		b.Run(fmt.Sprintf("batch-%d", size), func(b *testing.B) {
+			server, client := startPair(b)
+			client.SetBatching(size, time.Millisecond)
+			writes := countWrites(client)
+
+			go func() {
+				for i := 0; i < b.N; i++ {
+					client.GetOutboundChannel() <- btree.Message{Content: "benchmark payload"}
+				}
+			}()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				<-server.GetInboundChannel()
+			}
+			b.StopTimer()
+
+			b.ReportMetric(float64(writes.Load())/float64(b.N), "writes/msg")
+		})
+	}
+}
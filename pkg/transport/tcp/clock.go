@@ -0,0 +1,145 @@
+package tcp
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DefaultSkewThreshold is the clock offset above which a warning is logged
+const DefaultSkewThreshold = time.Second
+
+// pongSample holds the four timestamps of a ping round trip: t0 client send,
+// t1 server receive, t2 server send and t3 client receive
+type pongSample struct {
+	t0, t1, t2, t3 time.Time
+}
+
+// offset estimates the peer's clock offset using NTP's basic algorithm
+func (p pongSample) offset() time.Duration {
+	return (p.t1.Sub(p.t0) + p.t2.Sub(p.t3)) / 2
+}
+
+// roundTrip returns the network round-trip time excluding peer processing
+func (p pongSample) roundTrip() time.Duration {
+	return p.t3.Sub(p.t0) - p.t2.Sub(p.t1)
+}
+
+// SetClock replaces the clock used for ping timestamps, mainly for tests
+func (t *TCPTransport) SetClock(now func() time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.now = now
+}
+
+// SetSkewThreshold sets the clock offset above which a warning is logged
+func (t *TCPTransport) SetSkewThreshold(threshold time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.skewThreshold = threshold
+}
+
+// ClockSkew returns the last estimated clock offset of the peer and whether
+// an estimate is available
+func (t *TCPTransport) ClockSkew() (time.Duration, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.skew, t.hasSkew
+}
+
+// EstimateClockSkew pings the connected peer and estimates how far its clock
+// is ahead (positive) or behind (negative) of the local clock
+func (t *TCPTransport) EstimateClockSkew(ctx context.Context) (time.Duration, error) {
+	t.pingMu.Lock()
+	defer t.pingMu.Unlock()
+
+	t.mu.RLock()
+	conn := t.conn
+	now := t.now
+	threshold := t.skewThreshold
+	t.mu.RUnlock()
+
+	if conn == nil {
+		return 0, ErrNoConnection
+	}
+
+	t0 := now()
+	payload := make([]byte, 8)
+	binary.BigEndian.PutUint64(payload, uint64(t0.UnixNano()))
+
+	t.writeMu.Lock()
+	err := writeControlFrame(conn, framePing, payload)
+	t.writeMu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		select {
+		case sample := <-t.pongs:
+			// Ignore replies to earlier pings that timed out
+			if !sample.t0.Equal(t0) {
+				continue
+			}
+
+			offset := sample.offset()
+			t.mu.Lock()
+			t.skew = offset
+			t.hasSkew = true
+			t.mu.Unlock()
+
+			if offset > threshold || offset < -threshold {
+				t.log().Warn("Clock skew exceeds threshold", "skew", offset, "remote", conn.RemoteAddr().String(), "threshold", threshold)
+			}
+			return offset, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-t.ctx.Done():
+			return 0, t.ctx.Err()
+		}
+	}
+}
+
+// handlePing answers a ping with the original send time and local receive
+// and send times
+func (t *TCPTransport) handlePing(conn io.Writer, payload []byte, received time.Time) error {
+	if len(payload) != 8 {
+		return fmt.Errorf("malformed ping of %d bytes", len(payload))
+	}
+
+	t.mu.RLock()
+	now := t.now
+	t.mu.RUnlock()
+
+	reply := make([]byte, 24)
+	copy(reply, payload)
+	binary.BigEndian.PutUint64(reply[8:], uint64(received.UnixNano()))
+	binary.BigEndian.PutUint64(reply[16:], uint64(now().UnixNano()))
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	return writeControlFrame(conn, framePong, reply)
+}
+
+// handlePong hands a ping reply to the waiting EstimateClockSkew call
+func (t *TCPTransport) handlePong(payload []byte, received time.Time) error {
+	if len(payload) != 24 {
+		return fmt.Errorf("malformed pong of %d bytes", len(payload))
+	}
+
+	sample := pongSample{
+		t0: time.Unix(0, int64(binary.BigEndian.Uint64(payload[0:]))),
+		t1: time.Unix(0, int64(binary.BigEndian.Uint64(payload[8:]))),
+		t2: time.Unix(0, int64(binary.BigEndian.Uint64(payload[16:]))),
+		t3: received,
+	}
+
+	select {
+	case t.pongs <- sample:
+	default:
+		// Nobody is waiting for this reply
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package tcp
+
+import "net"
+
+// SetMaxConnections limits how many accepted connections the listener keeps
+// open at once; connections beyond the limit are closed as soon as they are
+// accepted. Zero, the default, means no limit.
+func (t *TCPTransport) SetMaxConnections(max int) {
+	t.connsMu.Lock()
+	defer t.connsMu.Unlock()
+	t.maxConns = max
+}
+
+// ActiveConnections returns the number of accepted connections currently open
+func (t *TCPTransport) ActiveConnections() int {
+	t.connsMu.Lock()
+	defer t.connsMu.Unlock()
+	return len(t.accepted)
+}
+
+// RejectedConnections returns how many connections were closed on accept
+// because the connection limit was reached
+func (t *TCPTransport) RejectedConnections() uint64 {
+	return t.rejectedConns.Load()
+}
+
+// admitConnection registers an accepted connection, or closes it and
+// returns false if the transport is closed or at its connection limit
+func (t *TCPTransport) admitConnection(conn net.Conn) bool {
+	t.connsMu.Lock()
+	defer t.connsMu.Unlock()
+
+	if t.ctx.Err() != nil {
+		conn.Close()
+		return false
+	}
+	if t.maxConns > 0 && len(t.accepted) >= t.maxConns {
+		t.rejectedConns.Add(1)
+		t.log().Warn("Connection limit reached, rejecting connection", "remote", conn.RemoteAddr().String(), "limit", t.maxConns)
+		conn.Close()
+		return false
+	}
+
+	t.accepted[conn] = struct{}{}
+	return true
+}
@@ -0,0 +1,30 @@
+package tcp
+
+import (
+	"fmt"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+	"github.com/xnok/btree-server-msg/pkg/transport"
+)
+
+// defaultCodec encodes the whole message, not just its content, so flags
+// such as Upstream survive the wire
+var defaultCodec transport.Codec = transport.JSONCodec{}
+
+// encodeMessage serializes a message into a frame payload
+func encodeMessage(codec transport.Codec, msg btree.Message) ([]byte, error) {
+	payload, err := codec.Encode(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %v", err)
+	}
+	return payload, nil
+}
+
+// decodeMessage deserializes a frame payload into a message
+func decodeMessage(codec transport.Codec, payload []byte) (btree.Message, error) {
+	msg, err := codec.Decode(payload)
+	if err != nil {
+		return btree.Message{}, fmt.Errorf("failed to decode message: %v", err)
+	}
+	return msg, nil
+}
@@ -0,0 +1,103 @@
+package tcp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// Errors returned by Listen, Connect, Flush and sends; compare them with
+// errors.Is, as details may be wrapped around them
+var (
+	// ErrAlreadyListening is returned by Listen on a transport that listens
+	ErrAlreadyListening = errors.New("already listening")
+
+	// ErrAlreadyConnected is returned by Connect on a transport that has
+	// dialed a peer
+	ErrAlreadyConnected = errors.New("already connected")
+
+	// ErrTransportClosed is returned once the transport has been closed
+	ErrTransportClosed = errors.New("transport is closed")
+
+	// ErrNoConnection is returned when there is no connection to send on
+	ErrNoConnection = errors.New("no active connection")
+
+	// ErrMessageTooLarge is returned for a message or frame larger than the
+	// transport's maximum message size
+	ErrMessageTooLarge = errors.New("message too large")
+)
+
+// errPartialWrite marks a write that failed after part of a frame was sent
+var errPartialWrite = errors.New("partial frame written")
+
+// WriteErrorKind classifies a failed write by how the transport recovers
+type WriteErrorKind int
+
+const (
+	// WriteErrorFatal means the connection is unusable and must be
+	// re-established
+	WriteErrorFatal WriteErrorKind = iota
+	// WriteErrorTransient means the write may succeed if retried on the
+	// same connection
+	WriteErrorTransient
+)
+
+// String returns the name of the kind
+func (k WriteErrorKind) String() string {
+	if k == WriteErrorTransient {
+		return "transient"
+	}
+	return "fatal"
+}
+
+// WriteError is returned when writing a message to a connection fails
+type WriteError struct {
+	Kind WriteErrorKind
+	Err  error
+}
+
+// Error describes the failed write and its classification
+func (e *WriteError) Error() string {
+	return "failed to write message (" + e.Kind.String() + "): " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *WriteError) Unwrap() error {
+	return e.Err
+}
+
+// IsTransientWriteError reports whether err is a write error that may
+// succeed if retried on the same connection
+func IsTransientWriteError(err error) bool {
+	var writeErr *WriteError
+	return errors.As(err, &writeErr) && writeErr.Kind == WriteErrorTransient
+}
+
+// classifyWriteError decides whether a write error leaves the connection
+// usable. Timeouts and temporary errors are transient unless part of the
+// frame was already written; resets, broken pipes, closed connections and
+// anything unrecognised are fatal.
+func classifyWriteError(err error) WriteErrorKind {
+	switch {
+	case errors.Is(err, errPartialWrite),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EPIPE),
+		errors.Is(err, net.ErrClosed),
+		errors.Is(err, io.ErrClosedPipe):
+		return WriteErrorFatal
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return WriteErrorTransient
+		}
+		// Temporary is deprecated but still the only signal some errors carry
+		if temporary, ok := netErr.(interface{ Temporary() bool }); ok && temporary.Temporary() {
+			return WriteErrorTransient
+		}
+	}
+
+	return WriteErrorFatal
+}
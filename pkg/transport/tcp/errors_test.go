@@ -0,0 +1,127 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+)
+
+// temporaryError is a net.Error that reports itself as temporary
+type temporaryError struct{}
+
+func (temporaryError) Error() string   { return "resource temporarily unavailable" }
+func (temporaryError) Timeout() bool   { return false }
+func (temporaryError) Temporary() bool { return true }
+
+// failingConn is a net.Conn whose writes fail with a fixed error
+type failingConn struct {
+	net.Conn
+	err    error
+	closed bool
+}
+
+func (c *failingConn) Write(p []byte) (int, error) { return 0, c.err }
+func (c *failingConn) Close() error                { c.closed = true; return nil }
+
+// resetError is the error a write to a connection reset by the peer returns
+func resetError() error {
+	return &net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.ECONNRESET)}
+}
+
+func TestClassifyWriteError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected WriteErrorKind
+	}{
+		{"temporary", temporaryError{}, WriteErrorTransient},
+		{"timeout", os.ErrDeadlineExceeded, WriteErrorTransient},
+		{"connection reset", resetError(), WriteErrorFatal},
+		{"broken pipe", syscall.EPIPE, WriteErrorFatal},
+		{"closed connection", net.ErrClosed, WriteErrorFatal},
+		{"partial temporary write", errors.Join(errPartialWrite, temporaryError{}), WriteErrorFatal},
+		{"unknown", errors.New("boom"), WriteErrorFatal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if kind := classifyWriteError(tt.err); kind != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, kind)
+			}
+		})
+	}
+}
+
+func TestWriteErrorClassificationDrivesRecovery(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		transient bool
+	}{
+		{"temporary error keeps the connection", temporaryError{}, true},
+		{"reset error drops the connection", resetError(), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &failingConn{err: tt.err}
+
+			tr := NewTCPTransport()
+			defer tr.Close()
+			tr.SetReconnect(false)
+			tr.isClient = true
+			tr.conn = conn
+
+			err := tr.sendMessage(btree.Message{Content: "hello"})
+			if err == nil {
+				t.Fatal("Expected send to fail")
+			}
+			if IsTransientWriteError(err) != tt.transient {
+				t.Errorf("Expected transient=%v, got error %v", tt.transient, err)
+			}
+
+			// Transient errors retry on the same connection, fatal ones drop it
+			if tr.IsConnected() != tt.transient {
+				t.Errorf("Expected connected=%v after the failure", tt.transient)
+			}
+			if conn.closed == tt.transient {
+				t.Errorf("Expected connection closed=%v", !tt.transient)
+			}
+		})
+	}
+}
+
+func TestLifecycleErrorsMatchSentinels(t *testing.T) {
+	server, client := startPair(t)
+
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); !errors.Is(err, ErrAlreadyListening) {
+		t.Errorf("Expected ErrAlreadyListening, got %v", err)
+	}
+	if err := client.Connect(context.Background(), server.ListenAddr().String()); !errors.Is(err, ErrAlreadyConnected) {
+		t.Errorf("Expected ErrAlreadyConnected, got %v", err)
+	}
+
+	// The port is still taken by server
+	other := NewTCPTransport()
+	defer other.Close()
+	if err := other.Listen(context.Background(), server.ListenAddr().String()); !errors.Is(err, syscall.EADDRINUSE) {
+		t.Errorf("Expected the listen error to wrap EADDRINUSE, got %v", err)
+	}
+
+	idle := NewTCPTransport()
+	if err := idle.sendMessage(btree.Message{Content: "nowhere"}); !errors.Is(err, ErrNoConnection) {
+		t.Errorf("Expected ErrNoConnection, got %v", err)
+	}
+	idle.Close()
+	if err := idle.Listen(context.Background(), "127.0.0.1:0"); !errors.Is(err, ErrTransportClosed) {
+		t.Errorf("Listen: expected ErrTransportClosed, got %v", err)
+	}
+	if err := idle.Connect(context.Background(), server.ListenAddr().String()); !errors.Is(err, ErrTransportClosed) {
+		t.Errorf("Connect: expected ErrTransportClosed, got %v", err)
+	}
+}
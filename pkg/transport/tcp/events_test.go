@@ -0,0 +1,120 @@
+package tcp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/transport"
+)
+
+// nextEvent waits for the next lifecycle event of a transport
+func nextEvent(t *testing.T, tr *TCPTransport) transport.ConnectionEvent {
+	t.Helper()
+
+	select {
+	case event := <-tr.Events():
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for a connection event")
+		return transport.ConnectionEvent{}
+	}
+}
+
+func TestDisconnectEventCarriesRemoteAddress(t *testing.T) {
+	server, client := startPair(t)
+
+	connected := nextEvent(t, server)
+	if connected.Type != transport.EventConnected {
+		t.Fatalf("Expected a connected event, got %+v", connected)
+	}
+
+	client.mu.RLock()
+	clientAddr := client.conn.LocalAddr().String()
+	client.mu.RUnlock()
+	if connected.Addr != clientAddr {
+		t.Errorf("Expected the client address %s, got %s", clientAddr, connected.Addr)
+	}
+
+	client.Close()
+
+	disconnected := nextEvent(t, server)
+	if disconnected.Type != transport.EventDisconnected {
+		t.Fatalf("Expected a clean disconnect, got %+v", disconnected)
+	}
+	if disconnected.Addr != clientAddr {
+		t.Errorf("Expected the disconnect from %s, got %s", clientAddr, disconnected.Addr)
+	}
+	if disconnected.Err != nil {
+		t.Errorf("Expected no error on a clean close, got %v", disconnected.Err)
+	}
+}
+
+func TestClientEventsOnPeerClose(t *testing.T) {
+	server, client := startPair(t)
+	client.SetReconnect(false)
+
+	if event := nextEvent(t, client); event.Type != transport.EventConnected || event.Addr != client.remote {
+		t.Fatalf("Expected a connected event for %s, got %+v", client.remote, event)
+	}
+
+	// The listener going away is an EOF on the client side, not an error
+	server.Close()
+	if event := nextEvent(t, client); event.Type != transport.EventDisconnected || event.Addr != client.remote {
+		t.Fatalf("Expected a clean disconnect from %s, got %+v", client.remote, event)
+	}
+}
+
+func TestRejectedConnectionEmitsErrorEvent(t *testing.T) {
+	server := NewTCPTransport()
+	server.SetRequireHandshake(true)
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "N{\"content\":\"legacy\"}\n")
+
+	if event := nextEvent(t, server); event.Type != transport.EventConnected {
+		t.Fatalf("Expected a connected event, got %+v", event)
+	}
+	event := nextEvent(t, server)
+	if event.Type != transport.EventError || !errors.Is(event.Err, ErrHandshakeRequired) {
+		t.Fatalf("Expected an error event for the missing handshake, got %+v", event)
+	}
+	if event.Addr != conn.LocalAddr().String() {
+		t.Errorf("Expected the error from %s, got %s", conn.LocalAddr(), event.Addr)
+	}
+}
+
+func TestUnreadEventsAreDiscardedQuietly(t *testing.T) {
+	var logs bytes.Buffer
+	tr := NewTCPTransport()
+	tr.SetLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	// Nobody reads the events, so a full channel is not worth a warning
+	for i := 0; i <= eventBufferSize; i++ {
+		tr.notifyAccept(fmt.Sprintf("127.0.0.1:%d", 40000+i))
+	}
+	if logs.Len() != 0 {
+		t.Fatalf("Expected no warnings without a subscriber, got %q", logs.String())
+	}
+
+	// A subscriber missing events is
+	tr.Events()
+	tr.notifyAccept("127.0.0.1:50000")
+	if !strings.Contains(logs.String(), "discarding event") {
+		t.Errorf("Expected a warning once events are read, got %q", logs.String())
+	}
+}
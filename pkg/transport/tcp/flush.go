@@ -0,0 +1,65 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotStarted is returned by Flush on a transport that is neither
+// listening nor connected, so nothing is sending its outbound messages
+var ErrNotStarted = errors.New("transport is not listening or connected")
+
+// flushRequest asks the outbound loop to send everything queued and close
+// done when the queue is empty
+type flushRequest struct {
+	done chan struct{}
+}
+
+// Flush writes every message queued on the outbound channel, including
+// messages queued while it runs, and returns once the queue is empty. It
+// returns ctx's error if the deadline passes first, in which case the
+// remaining messages are still sent in the background. Call it before Close
+// so the tail of a stream is not dropped.
+func (t *TCPTransport) Flush(ctx context.Context) error {
+	t.mu.RLock()
+	started := t.isServer || t.isClient
+	t.mu.RUnlock()
+	if !started {
+		return ErrNotStarted
+	}
+
+	req := flushRequest{done: make(chan struct{})}
+	select {
+	case t.flushes <- req:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.ctx.Done():
+		return ErrTransportClosed
+	}
+
+	select {
+	case <-req.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.ctx.Done():
+		return ErrTransportClosed
+	}
+}
+
+// flushOutbound sends queued messages until the outbound channel is empty.
+// Only the outbound loop calls it, so messages keep their order.
+func (t *TCPTransport) flushOutbound(req flushRequest) {
+	defer close(req.done)
+
+	for {
+		select {
+		case msg := <-t.outbound:
+			t.deliverBatch(t.collectBatch(msg, false))
+		case <-t.ctx.Done():
+			return
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+)
+
+func TestFlushDeliversQueuedMessagesBeforeClose(t *testing.T) {
+	server, client := startPair(t)
+
+	const total = 50
+	for i := 0; i < total; i++ {
+		client.GetOutboundChannel() <- btree.Message{ID: fmt.Sprintf("f-%d", i), Content: "tail"}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := client.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	client.Close()
+
+	for i := 0; i < total; i++ {
+		if msg := receive(t, server); msg.ID != fmt.Sprintf("f-%d", i) {
+			t.Fatalf("Expected f-%d, got %s", i, msg.ID)
+		}
+	}
+}
+
+func TestFlushBeforeStart(t *testing.T) {
+	tr := NewTCPTransport()
+	defer tr.Close()
+
+	if err := tr.Flush(context.Background()); !errors.Is(err, ErrNotStarted) {
+		t.Errorf("Expected ErrNotStarted, got %v", err)
+	}
+}
+
+func TestFlushStopsAtDeadline(t *testing.T) {
+	address, _ := startStalledPeer(t)
+
+	client := NewTCPTransport()
+	defer client.Close()
+	if err := client.Connect(context.Background(), address); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	// Large messages fill the socket buffers of a peer that never reads
+	large := strings.Repeat("x", 1<<20)
+	for i := 0; i < 16; i++ {
+		client.GetOutboundChannel() <- btree.Message{ID: fmt.Sprintf("big-%d", i), Content: large}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := client.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the flush to hit its deadline, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Flush to return at its deadline, took %v", elapsed)
+	}
+}
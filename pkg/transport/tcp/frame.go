@@ -0,0 +1,178 @@
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Frame types written as the first byte of every frame so the receiver can
+// tell how the payload is delimited
+const (
+	frameNewline   byte = 'N' // payload terminated by '\n'
+	frameLength    byte = 'L' // payload prefixed with a 4-byte big-endian length
+	framePing      byte = 'P' // length-prefixed clock probe, never delivered as a message
+	framePong      byte = 'O' // length-prefixed reply to a ping
+	frameHeartbeat byte = 'H' // empty length-prefixed keepalive, never delivered as a message
+	frameHandshake byte = 'S' // length-prefixed protocol version and codec exchanged on connect
+	frameBatch     byte = 'B' // length-prefixed sequence of length-prefixed message payloads
+)
+
+// frameHeaderSize is the size of the big-endian length prefix of length frames
+const frameHeaderSize = 4
+
+// chooseFrameType picks newline framing for payloads smaller than threshold
+// that contain no newline, and length-prefix framing otherwise
+func chooseFrameType(payload []byte, threshold int) byte {
+	if len(payload) < threshold && bytes.IndexByte(payload, '\n') < 0 {
+		return frameNewline
+	}
+	return frameLength
+}
+
+// writeFrame writes a payload using the framing selected by threshold
+func writeFrame(w io.Writer, payload []byte, threshold int) error {
+	return writeFrameBytes(w, messageFrame(payload, threshold))
+}
+
+// messageFrame builds the frame of a payload using the framing selected by
+// threshold
+func messageFrame(payload []byte, threshold int) []byte {
+	if chooseFrameType(payload, threshold) == frameNewline {
+		frame := make([]byte, 0, len(payload)+2)
+		frame = append(frame, frameNewline)
+		frame = append(frame, payload...)
+		return append(frame, '\n')
+	}
+	return lengthFrame(frameLength, payload)
+}
+
+// writeFrameBytes writes a complete frame in a single call
+func writeFrameBytes(w io.Writer, frame []byte) error {
+	if n, err := w.Write(frame); err != nil {
+		// A partly written frame leaves the stream unusable even if the
+		// underlying error was temporary
+		if n > 0 {
+			return fmt.Errorf("failed to write frame after %d of %d bytes: %w: %w", n, len(frame), errPartialWrite, err)
+		}
+		return fmt.Errorf("failed to write frame: %w", err)
+	}
+	return nil
+}
+
+// writeControlFrame writes a length-prefixed frame of the given control type
+func writeControlFrame(w io.Writer, frameType byte, payload []byte) error {
+	if _, err := w.Write(lengthFrame(frameType, payload)); err != nil {
+		return fmt.Errorf("failed to write control frame: %w", err)
+	}
+	return nil
+}
+
+// lengthFrame builds a frame of type byte, 4-byte big-endian length and payload
+func lengthFrame(frameType byte, payload []byte) []byte {
+	frame := make([]byte, 1+frameHeaderSize+len(payload))
+	frame[0] = frameType
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+	copy(frame[1+frameHeaderSize:], payload)
+	return frame
+}
+
+// maxPooledFrameBuffer is the largest read buffer returned to framePool, so
+// one huge message does not pin memory for the lifetime of the process
+const maxPooledFrameBuffer = 64 * 1024
+
+// framePool recycles read buffers across connections
+var framePool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, 4096)
+		return &buf
+	},
+}
+
+// getFrameBuffer takes a read buffer from the pool
+func getFrameBuffer() *[]byte {
+	return framePool.Get().(*[]byte)
+}
+
+// putFrameBuffer returns a read buffer to the pool
+func putFrameBuffer(buf *[]byte) {
+	if cap(*buf) > maxPooledFrameBuffer {
+		return
+	}
+	*buf = (*buf)[:0]
+	framePool.Put(buf)
+}
+
+// readFrame reads a single frame and returns a newly allocated payload and
+// its frame type
+func readFrame(r *bufio.Reader) ([]byte, byte, error) {
+	return readFrameInto(r, nil)
+}
+
+// readFrameInto reads a single frame into buf, growing it if needed, and
+// returns the payload and frame type. The payload aliases buf, so it must be
+// copied (e.g. by decoding) before buf is reused for the next frame.
+func readFrameInto(r *bufio.Reader, buf []byte) ([]byte, byte, error) {
+	return readFrameLimited(r, buf, 0)
+}
+
+// readFrameLimited reads a frame like readFrameInto, but fails with
+// ErrMessageTooLarge instead of buffering a frame whose payload exceeds
+// limit. Control frames may always reach maxHandshakeSize. Zero means no
+// limit.
+func readFrameLimited(r *bufio.Reader, buf []byte, limit int) ([]byte, byte, error) {
+	frameType, err := r.ReadByte()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	buf = buf[:0]
+	switch frameType {
+	case frameNewline:
+		for {
+			chunk, err := r.ReadSlice('\n')
+			buf = append(buf, chunk...)
+			if err == nil {
+				if limit > 0 && len(buf)-1 > limit {
+					return nil, frameType, fmt.Errorf("%w: newline frame of %d bytes exceeds %d", ErrMessageTooLarge, len(buf)-1, limit)
+				}
+				return buf[:len(buf)-1], frameType, nil
+			}
+			if err != bufio.ErrBufferFull {
+				return nil, frameType, fmt.Errorf("failed to read newline frame: %v", err)
+			}
+			if limit > 0 && len(buf) > limit {
+				return nil, frameType, fmt.Errorf("%w: newline frame exceeds %d bytes", ErrMessageTooLarge, limit)
+			}
+		}
+	case frameLength, framePing, framePong, frameHeartbeat, frameHandshake, frameBatch:
+		var header [frameHeaderSize]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, frameType, fmt.Errorf("failed to read frame header: %v", err)
+		}
+
+		size := int(binary.BigEndian.Uint32(header[:]))
+		if limit > 0 {
+			frameLimit := limit
+			if frameType != frameLength && frameType != frameBatch {
+				frameLimit = max(limit, maxHandshakeSize)
+			}
+			if size > frameLimit {
+				return nil, frameType, fmt.Errorf("%w: frame of %d bytes exceeds %d", ErrMessageTooLarge, size, frameLimit)
+			}
+		}
+		if cap(buf) < size {
+			buf = make([]byte, size)
+		}
+		buf = buf[:size]
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, frameType, fmt.Errorf("failed to read frame payload: %v", err)
+		}
+		return buf, frameType, nil
+	default:
+		return nil, frameType, fmt.Errorf("unknown frame type 0x%02x", frameType)
+	}
+}
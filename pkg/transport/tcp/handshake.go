@@ -0,0 +1,183 @@
+package tcp
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/transport"
+)
+
+// ProtocolVersion is the version of the framing protocol this transport
+// speaks; peers announcing a different version are rejected
+const ProtocolVersion = 1
+
+// DefaultHandshakeTimeout bounds how long a dialing transport waits for the
+// listener's handshake reply
+const DefaultHandshakeTimeout = 5 * time.Second
+
+// maxHandshakeSize caps the handshake payload read from an unknown peer
+const maxHandshakeSize = 1024
+
+// ErrIncompatiblePeer is returned when the peer's handshake announces a
+// protocol version or codec this transport cannot talk to
+var ErrIncompatiblePeer = errors.New("incompatible peer")
+
+// ErrHandshakeRequired is returned when a listener that requires handshakes
+// receives any other frame first
+var ErrHandshakeRequired = errors.New("handshake required")
+
+// handshake is exchanged when a connection is established: the dialer sends
+// its own and the listener answers with its own, then both sides check that
+// they match. Session identifies the sending transport across reconnects.
+// Token is the dialer's auth token, and Rejected carries the listener's
+// reason for refusing the connection.
+type handshake struct {
+	Version  int    `json:"version"`
+	Codec    string `json:"codec"`
+	Session  string `json:"session,omitempty"`
+	Token    string `json:"token,omitempty"`
+	Rejected string `json:"rejected,omitempty"`
+}
+
+// SetRequireHandshake makes the listener close connections whose first frame
+// is not a handshake. By default such peers (e.g. netcat) are accepted as
+// legacy clients without any compatibility check.
+func (t *TCPTransport) SetRequireHandshake(required bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.requireHandshake = required
+}
+
+// SetHandshakeTimeout sets how long Connect and reconnects wait for the
+// listener's handshake reply
+func (t *TCPTransport) SetHandshakeTimeout(timeout time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handshakeTimeout = timeout
+}
+
+// localHandshake describes this transport's protocol version, codec and session
+func (t *TCPTransport) localHandshake() handshake {
+	return handshake{Version: t.protocolVersion, Codec: transport.CodecName(t.codec), Session: t.session}
+}
+
+// checkHandshake verifies that the peer's handshake matches local and that
+// codec decodes what the peer sends
+func checkHandshake(local, peer handshake, codec transport.Codec) error {
+	if peer.Version != local.Version {
+		return fmt.Errorf("%w: peer speaks protocol version %d, want %d", ErrIncompatiblePeer, peer.Version, local.Version)
+	}
+	if peer.Codec != local.Codec && !transport.CodecAccepts(codec, peer.Codec) {
+		return fmt.Errorf("%w: peer uses codec %q, want %q", ErrIncompatiblePeer, peer.Codec, local.Codec)
+	}
+	return nil
+}
+
+// writeHandshake sends hs as a handshake frame
+func writeHandshake(w io.Writer, hs handshake) error {
+	payload, err := json.Marshal(hs)
+	if err != nil {
+		return fmt.Errorf("failed to encode handshake: %v", err)
+	}
+	return writeControlFrame(w, frameHandshake, payload)
+}
+
+// parseHandshake decodes a handshake frame payload
+func parseHandshake(payload []byte) (handshake, error) {
+	var hs handshake
+	if err := json.Unmarshal(payload, &hs); err != nil {
+		return hs, fmt.Errorf("%w: malformed handshake: %v", ErrIncompatiblePeer, err)
+	}
+	return hs, nil
+}
+
+// dialPeer opens a connection to address and completes the handshake on it,
+// presenting token and waiting up to timeout for the reply
+func (t *TCPTransport) dialPeer(address string, timeout time.Duration, token string) (net.Conn, error) {
+	conn, err := net.Dial(t.network, address)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.clientHandshake(conn, timeout, token); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// clientHandshake sends the local handshake, carrying token, on a freshly
+// dialed connection and waits for the listener's reply. It reads without
+// buffering so that frames following the reply are left for readMessages.
+func (t *TCPTransport) clientHandshake(conn net.Conn, timeout time.Duration, token string) error {
+	local := t.localHandshake()
+	local.Token = token
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	if err := writeHandshake(conn, local); err != nil {
+		return fmt.Errorf("handshake failed: %w", err)
+	}
+
+	var header [1 + frameHeaderSize]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("%w: connection closed before handshake reply", ErrIncompatiblePeer)
+		}
+		return fmt.Errorf("handshake failed: %w", err)
+	}
+	if header[0] != frameHandshake {
+		return fmt.Errorf("%w: expected handshake reply, got frame type 0x%02x", ErrIncompatiblePeer, header[0])
+	}
+
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxHandshakeSize {
+		return fmt.Errorf("%w: handshake reply of %d bytes", ErrIncompatiblePeer, size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return fmt.Errorf("handshake failed: %w", err)
+	}
+
+	peer, err := parseHandshake(payload)
+	if err != nil {
+		return err
+	}
+	if peer.Rejected != "" {
+		return fmt.Errorf("%w: listener rejected the connection: %s", ErrUnauthorized, peer.Rejected)
+	}
+	return checkHandshake(local, peer, t.codec)
+}
+
+// serverHandshake answers a dialer's handshake with the local one, checks
+// compatibility and returns the dialer's handshake. The reply is sent even on
+// mismatch or failed authentication so the dialer can report the reason.
+func (t *TCPTransport) serverHandshake(conn net.Conn, payload []byte) (handshake, error) {
+	local := t.localHandshake()
+
+	peer, err := parseHandshake(payload)
+	if err == nil {
+		if reason := t.authorize(peer); reason != "" {
+			local.Rejected = reason
+			err = fmt.Errorf("%w: %s", ErrUnauthorized, reason)
+		}
+	}
+
+	t.writeMu.Lock()
+	writeErr := writeHandshake(conn, local)
+	t.writeMu.Unlock()
+	if writeErr != nil {
+		return handshake{}, fmt.Errorf("handshake failed: %w", writeErr)
+	}
+
+	if err != nil {
+		return handshake{}, err
+	}
+	return peer, checkHandshake(local, peer, t.codec)
+}
@@ -0,0 +1,126 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+	"github.com/xnok/btree-server-msg/pkg/transport"
+)
+
+func TestHandshakeSucceeds(t *testing.T) {
+	server, client := startPair(t)
+
+	client.GetOutboundChannel() <- btree.Message{Content: "after handshake", ID: "h-1"}
+	if msg := receive(t, server); msg.ID != "h-1" {
+		t.Errorf("Expected h-1, got %+v", msg)
+	}
+
+	// Frames the server sends back are not mistaken for the handshake reply
+	server.GetOutboundChannel() <- btree.Message{Content: "back", ID: "h-2"}
+	if msg := receive(t, client); msg.ID != "h-2" {
+		t.Errorf("Expected h-2, got %+v", msg)
+	}
+}
+
+func TestVersionMismatchIsRejected(t *testing.T) {
+	server := NewTCPTransport()
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	client := NewTCPTransport()
+	client.protocolVersion = ProtocolVersion + 1
+	defer client.Close()
+
+	err := client.Connect(context.Background(), server.listener.Addr().String())
+	if !errors.Is(err, ErrIncompatiblePeer) {
+		t.Fatalf("Expected ErrIncompatiblePeer, got %v", err)
+	}
+	want := fmt.Sprintf("protocol version %d, want %d", ProtocolVersion, ProtocolVersion+1)
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected the error to name both versions (%q), got %q", want, err)
+	}
+	if client.IsConnected() {
+		t.Error("Expected the client not to be connected")
+	}
+	waitFor(t, time.Second, func() bool { return server.ActiveConnections() == 0 })
+}
+
+func TestCodecMismatchIsRejected(t *testing.T) {
+	server := NewTCPTransport()
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	client := NewTCPTransportWithCodec(transport.TextCodec{})
+	defer client.Close()
+
+	err := client.Connect(context.Background(), server.listener.Addr().String())
+	if !errors.Is(err, ErrIncompatiblePeer) {
+		t.Fatalf("Expected ErrIncompatiblePeer, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `codec "json", want "text"`) {
+		t.Errorf("Expected the error to name both codecs, got %q", err)
+	}
+}
+
+func TestLegacyPeerWithoutHandshake(t *testing.T) {
+	server := NewTCPTransport()
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	// A netcat-style peer that starts sending straight away is accepted
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "N{\"content\":\"legacy\",\"id\":\"l-1\"}\n")
+	if msg := receive(t, server); msg.ID != "l-1" {
+		t.Errorf("Expected l-1, got %+v", msg)
+	}
+}
+
+func TestRequireHandshakeRejectsLegacyPeer(t *testing.T) {
+	server := NewTCPTransport()
+	server.SetRequireHandshake(true)
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "N{\"content\":\"legacy\"}\n")
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Expected the connection to be closed with EOF, got %v", err)
+	}
+	select {
+	case msg := <-server.GetInboundChannel():
+		t.Errorf("Expected no message from a peer without handshake, got %+v", msg)
+	default:
+	}
+
+	// Transports that handshake are still accepted
+	client := NewTCPTransport()
+	defer client.Close()
+	if err := client.Connect(context.Background(), server.listener.Addr().String()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package tcp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrHeartbeatTimeout is returned when a connection is closed because
+// nothing, not even a heartbeat, arrived within the heartbeat timeout
+var ErrHeartbeatTimeout = errors.New("heartbeat timeout")
+
+// SetHeartbeat enables keepalives: a heartbeat frame is written to every
+// connection that has been idle for interval, and a connection is closed
+// when nothing arrives from the peer within timeout. A zero interval or
+// timeout disables that half. It must be called before Listen or Connect.
+func (t *TCPTransport) SetHeartbeat(interval, timeout time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.heartbeatInterval = interval
+	t.heartbeatTimeout = timeout
+}
+
+// startHeartbeat starts the heartbeat loop if an interval is configured.
+// Callers must hold t.mu.
+func (t *TCPTransport) startHeartbeat() {
+	if t.heartbeatInterval <= 0 {
+		return
+	}
+
+	t.wg.Add(1)
+	go t.heartbeatLoop(t.heartbeatInterval)
+}
+
+// heartbeatLoop writes heartbeat frames whenever no frame was written for
+// a whole interval
+func (t *TCPTransport) heartbeatLoop(interval time.Duration) {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if time.Since(time.Unix(0, t.lastWrite.Load())) < interval {
+				continue
+			}
+			t.sendHeartbeat()
+		case <-t.ctx.Done():
+			return
+		}
+	}
+}
+
+// sendHeartbeat writes a heartbeat frame to every active connection
+func (t *TCPTransport) sendHeartbeat() {
+	conns, isClient := t.activeConns()
+	if len(conns) == 0 {
+		return
+	}
+
+	writeTimeout := t.getWriteTimeout()
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	for _, conn := range conns {
+		setWriteDeadline(conn, writeTimeout)
+		if err := writeControlFrame(conn, frameHeartbeat, nil); err != nil {
+			t.log().Warn("Failed to send heartbeat", "remote", conn.RemoteAddr().String(), "error", err)
+			if isWriteTimeout(err, writeTimeout) {
+				t.closeStalled(conn, isClient, fmt.Errorf("%w after %v: %w", ErrWriteTimeout, writeTimeout, err))
+				continue
+			}
+			if isClient && classifyWriteError(err) == WriteErrorFatal {
+				t.connectionLost(conn, err)
+			}
+		}
+	}
+	t.lastWrite.Store(time.Now().UnixNano())
+}
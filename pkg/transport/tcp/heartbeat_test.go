@@ -0,0 +1,77 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+)
+
+func TestHalfOpenConnectionIsClosed(t *testing.T) {
+	observer := &recordingObserver{}
+	server := NewTCPTransport()
+	server.SetObserver(observer)
+	server.SetHeartbeat(0, 100*time.Millisecond)
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	// A peer that connects and then goes silent, like one whose host died
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("Expected the server to close the connection, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the connection to be closed within the timeout, took %v", elapsed)
+	}
+
+	waitFor(t, time.Second, func() bool {
+		events, _ := observer.snapshot()
+		return len(events) == 2
+	})
+	if _, errs := observer.snapshot(); !errors.Is(errs[1], ErrHeartbeatTimeout) {
+		t.Errorf("Expected disconnect with ErrHeartbeatTimeout, got %v", errs[1])
+	}
+}
+
+func TestHeartbeatsKeepIdleConnectionAlive(t *testing.T) {
+	observer := &recordingObserver{}
+	server := NewTCPTransport()
+	server.SetObserver(observer)
+	server.SetHeartbeat(0, 150*time.Millisecond)
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	client := NewTCPTransport()
+	client.SetHeartbeat(30*time.Millisecond, 0)
+	if err := client.Connect(context.Background(), server.listener.Addr().String()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	// Stay idle for several timeouts; only heartbeats cross the connection
+	time.Sleep(500 * time.Millisecond)
+
+	client.GetOutboundChannel() <- btree.Message{Content: "still here"}
+	if msg := receive(t, server); msg.Content != "still here" {
+		t.Errorf("Expected the message after the idle period, got %q", msg.Content)
+	}
+
+	if events, _ := observer.snapshot(); len(events) != 1 {
+		t.Errorf("Expected only the accept event, got %v", events)
+	}
+}
@@ -0,0 +1,17 @@
+package tcp
+
+import "log/slog"
+
+// SetLogger sets the structured logger the transport writes to; records
+// carry a "transport" attribute. A nil logger restores slog.Default().
+func (t *TCPTransport) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	t.logger.Store(logger.With("transport", t.network))
+}
+
+// log returns the transport's logger
+func (t *TCPTransport) log() *slog.Logger {
+	return t.logger.Load()
+}
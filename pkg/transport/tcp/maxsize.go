@@ -0,0 +1,22 @@
+package tcp
+
+import "fmt"
+
+// SetMaxMessageSize bounds the size of encoded messages. Larger messages are
+// dropped instead of sent, and a peer announcing a larger frame has its
+// connection closed before anything is allocated for it, so one huge or
+// malicious length prefix cannot exhaust memory. Both ends should use the
+// same limit. Zero, the default, disables the limit.
+func (t *TCPTransport) SetMaxMessageSize(size int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.maxMessageSize = size
+}
+
+// checkMessageSize returns ErrMessageTooLarge if payload exceeds limit
+func checkMessageSize(payload []byte, limit int) error {
+	if limit > 0 && len(payload) > limit {
+		return fmt.Errorf("%w: %d bytes exceeds %d", ErrMessageTooLarge, len(payload), limit)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+)
+
+func TestOversizedSendIsRejected(t *testing.T) {
+	server, client := startPair(t)
+	client.SetMaxMessageSize(256)
+
+	huge := btree.Message{ID: "huge", Content: strings.Repeat("x", 1024)}
+	if err := client.sendMessage(huge); !errors.Is(err, ErrMessageTooLarge) {
+		t.Fatalf("Expected ErrMessageTooLarge, got %v", err)
+	}
+
+	// The oversized message is dropped without holding up the next one
+	client.GetOutboundChannel() <- huge
+	client.GetOutboundChannel() <- btree.Message{ID: "small", Content: "fits"}
+	if msg := receive(t, server); msg.ID != "small" {
+		t.Errorf("Expected only the small message to arrive, got %+v", msg)
+	}
+	if !client.IsConnected() {
+		t.Error("Expected the client to stay connected")
+	}
+}
+
+// expectClosed checks that the server closes conn without delivering anything
+func expectClosed(t *testing.T, server *TCPTransport, conn net.Conn) {
+	t.Helper()
+
+	// Unread data makes the close show up as a reset rather than EOF
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Expected the connection to be closed, got %v", err)
+	}
+	select {
+	case msg := <-server.GetInboundChannel():
+		t.Errorf("Expected no message, got %+v", msg)
+	default:
+	}
+}
+
+func TestOversizedLengthPrefixClosesConnection(t *testing.T) {
+	server := NewTCPTransport()
+	server.SetMaxMessageSize(1024)
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	for _, frameType := range []byte{frameLength, frameBatch} {
+		conn, err := net.Dial("tcp", server.ListenAddr().String())
+		if err != nil {
+			t.Fatalf("Failed to dial: %v", err)
+		}
+
+		// Announce a 4 GiB payload that never follows
+		header := []byte{frameType, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(header[1:], 0xFFFFFFFF)
+		conn.Write(header)
+
+		expectClosed(t, server, conn)
+		conn.Close()
+	}
+}
+
+func TestOversizedNewlineFrameClosesConnection(t *testing.T) {
+	server := NewTCPTransport()
+	server.SetMaxMessageSize(1024)
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.ListenAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	// A newline frame that never ends
+	conn.Write(append([]byte{frameNewline}, bytes.Repeat([]byte("x"), 16*1024)...))
+
+	expectClosed(t, server, conn)
+}
+
+func TestNewlineFrameJustOverLimitIsRejected(t *testing.T) {
+	const limit = 64
+	frame := func(size int) []byte {
+		return append(append([]byte{frameNewline}, bytes.Repeat([]byte("x"), size)...), '\n')
+	}
+
+	// Both fit in the reader's buffer, so neither is read in several chunks
+	_, _, err := readFrameLimited(bufio.NewReader(bytes.NewReader(frame(limit))), nil, limit)
+	if err != nil {
+		t.Errorf("Expected a frame at the limit to be read, got %v", err)
+	}
+	_, _, err = readFrameLimited(bufio.NewReader(bytes.NewReader(frame(limit+1))), nil, limit)
+	if !errors.Is(err, ErrMessageTooLarge) {
+		t.Errorf("Expected ErrMessageTooLarge for a frame just over the limit, got %v", err)
+	}
+}
+
+func TestBatchFramesStayWithinLimit(t *testing.T) {
+	const limit = 64
+	payloads := [][]byte{
+		bytes.Repeat([]byte("a"), 20),
+		bytes.Repeat([]byte("b"), 20),
+		bytes.Repeat([]byte("c"), 20),
+		bytes.Repeat([]byte("d"), 62),
+		bytes.Repeat([]byte("e"), 5),
+	}
+
+	reader := bufio.NewReader(bytes.NewReader(batchFrames(payloads, limit, 0)))
+	var got [][]byte
+	for {
+		payload, frameType, err := readFrameLimited(reader, nil, limit)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read frame: %v", err)
+		}
+		if frameType != frameBatch {
+			got = append(got, payload)
+			continue
+		}
+		entries, err := splitBatch(payload)
+		if err != nil {
+			t.Fatalf("Failed to split batch: %v", err)
+		}
+		got = append(got, entries...)
+	}
+
+	if len(got) != len(payloads) {
+		t.Fatalf("Expected %d payloads, got %d", len(payloads), len(got))
+	}
+	for i := range payloads {
+		if !bytes.Equal(got[i], payloads[i]) {
+			t.Errorf("Payload %d: expected %q, got %q", i, payloads[i], got[i])
+		}
+	}
+}
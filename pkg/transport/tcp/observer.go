@@ -0,0 +1,96 @@
+package tcp
+
+import (
+	"github.com/xnok/btree-server-msg/pkg/transport"
+)
+
+// eventBufferSize is how many lifecycle events Events holds before further
+// events are discarded
+const eventBufferSize = 64
+
+// ConnectionObserver receives connection lifecycle notifications from a
+// TCPTransport. Callbacks run on transport goroutines and must not block.
+type ConnectionObserver interface {
+	// OnConnect is called when a dialed connection is established or re-established
+	OnConnect(addr string)
+
+	// OnDisconnect is called when a connection ends; err is nil for a clean close
+	OnDisconnect(addr string, err error)
+
+	// OnAccept is called when the listener accepts a connection from remote
+	OnAccept(remote string)
+
+	// OnAcceptError is called when accepting a connection fails
+	OnAcceptError(err error)
+}
+
+// SetObserver sets the observer notified of connection lifecycle transitions
+func (t *TCPTransport) SetObserver(observer ConnectionObserver) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.observer = observer
+}
+
+// getObserver returns the current observer, or nil
+func (t *TCPTransport) getObserver() ConnectionObserver {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.observer
+}
+
+// Events returns the channel connection lifecycle events are delivered on.
+// Clean closes, including a peer closing with EOF, are reported as
+// EventDisconnected and anything else as EventError. The channel is buffered
+// and never closed; events are discarded when it is full, so a slow consumer
+// never stalls the transport.
+func (t *TCPTransport) Events() <-chan transport.ConnectionEvent {
+	t.subscribed.Store(true)
+	return t.events
+}
+
+// emit delivers event without ever blocking the caller. Discarded events are
+// only worth a warning once someone reads the channel; until then it simply
+// fills up.
+func (t *TCPTransport) emit(event transport.ConnectionEvent) {
+	select {
+	case t.events <- event:
+	default:
+		if t.subscribed.Load() {
+			t.log().Warn("Event channel full, discarding event", "event", event.Type, "remote", event.Addr)
+		} else {
+			t.log().Debug("Event channel full, discarding event", "event", event.Type, "remote", event.Addr)
+		}
+	}
+}
+
+// notifyConnect reports an established dialed connection
+func (t *TCPTransport) notifyConnect(addr string) {
+	if observer := t.getObserver(); observer != nil {
+		observer.OnConnect(addr)
+	}
+	t.emit(transport.ConnectionEvent{Type: transport.EventConnected, Addr: addr})
+}
+
+// notifyDisconnect reports a connection that ended; err is nil for a clean close
+func (t *TCPTransport) notifyDisconnect(addr string, err error) {
+	if observer := t.getObserver(); observer != nil {
+		observer.OnDisconnect(addr, err)
+	}
+	t.emit(transport.DisconnectEvent(addr, err))
+}
+
+// notifyAccept reports a connection accepted by the listener
+func (t *TCPTransport) notifyAccept(remote string) {
+	if observer := t.getObserver(); observer != nil {
+		observer.OnAccept(remote)
+	}
+	t.emit(transport.ConnectionEvent{Type: transport.EventConnected, Addr: remote})
+}
+
+// notifyAcceptError reports a failure to accept a connection
+func (t *TCPTransport) notifyAcceptError(err error) {
+	if observer := t.getObserver(); observer != nil {
+		observer.OnAcceptError(err)
+	}
+	t.emit(transport.ConnectionEvent{Type: transport.EventError, Err: err})
+}
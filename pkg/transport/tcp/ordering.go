@@ -0,0 +1,63 @@
+package tcp
+
+import (
+	"math/rand/v2"
+	"strconv"
+)
+
+// SetPreserveOrder makes the listener deliver the messages of a reconnecting
+// dialer in the order they were sent. A connection that replaces a dropped
+// one may otherwise be read while frames of the old connection are still
+// buffered, letting newer messages overtake the backlog. With ordering on, a
+// new connection from the same dialer is not read until every earlier one
+// has been drained and closed, which delays the newer messages. A half-open
+// old connection holds them until its read or heartbeat timeout expires, so
+// enable one of those as well. Disabled by default.
+func (t *TCPTransport) SetPreserveOrder(enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.preserveOrder = enabled
+}
+
+// newSessionID returns a random identifier for a transport's handshakes
+func newSessionID() string {
+	return strconv.FormatUint(rand.Uint64(), 36)
+}
+
+// awaitSession registers an accepted connection of a dialer session and,
+// when ordering is preserved, waits until the session's previous connection
+// has been read to the end. The returned func must be called once the
+// connection is done being read. Peers that sent no session are not ordered.
+func (t *TCPTransport) awaitSession(session, remoteAddr string) (release func()) {
+	t.mu.RLock()
+	preserveOrder := t.preserveOrder
+	t.mu.RUnlock()
+
+	if !preserveOrder || session == "" {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	t.sessionMu.Lock()
+	previous := t.sessions[session]
+	t.sessions[session] = done
+	t.sessionMu.Unlock()
+
+	release = func() {
+		close(done)
+		t.sessionMu.Lock()
+		if t.sessions[session] == done {
+			delete(t.sessions, session)
+		}
+		t.sessionMu.Unlock()
+	}
+
+	if previous != nil {
+		t.log().Info("Holding reconnected peer until its previous connection drains", "remote", remoteAddr)
+		select {
+		case <-previous:
+		case <-t.ctx.Done():
+		}
+	}
+	return release
+}
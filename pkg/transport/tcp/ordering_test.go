@@ -0,0 +1,66 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+)
+
+func TestPreserveOrderAcrossReconnect(t *testing.T) {
+	// A one-message inbound buffer stalls the listener's reader, leaving most
+	// of the first batch buffered on the connection that is dropped
+	server := NewTCPTransportWithBufferSize(1)
+	server.SetPreserveOrder(true)
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	client := NewTCPTransport()
+	client.SetReconnectBackoff(10*time.Millisecond, 50*time.Millisecond)
+	if err := client.Connect(context.Background(), server.listener.Addr().String()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	send := func(from, to int) {
+		for seq := from; seq <= to; seq++ {
+			client.GetOutboundChannel() <- btree.Message{ID: fmt.Sprintf("o-%d", seq), Content: "ordered", Seq: uint64(seq)}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := client.Flush(ctx); err != nil {
+			t.Fatalf("Flush failed: %v", err)
+		}
+	}
+
+	const batch = 50
+	send(1, batch)
+	client.dropConnection(errors.New("simulated outage"))
+	waitFor(t, 2*time.Second, client.IsConnected)
+	send(batch+1, 2*batch)
+
+	// Give the listener time to read the new connection before the stalled
+	// consumer catches up
+	time.Sleep(50 * time.Millisecond)
+
+	for want := uint64(1); want <= 2*batch; want++ {
+		if msg := receive(t, server); msg.Seq != want {
+			t.Fatalf("Expected sequence %d, got %d", want, msg.Seq)
+		}
+	}
+}
+
+func TestHandshakeCarriesSession(t *testing.T) {
+	a, b := NewTCPTransport(), NewTCPTransport()
+	if a.localHandshake().Session == "" {
+		t.Fatal("Expected a session in the handshake")
+	}
+	if a.localHandshake().Session == b.localHandshake().Session {
+		t.Error("Expected transports to have distinct sessions")
+	}
+}
@@ -0,0 +1,129 @@
+package tcp
+
+import (
+	"net"
+	"time"
+)
+
+// Default backoff between attempts to re-establish a dropped connection
+const (
+	DefaultReconnectBaseDelay = 100 * time.Millisecond
+	DefaultReconnectMaxDelay  = 5 * time.Second
+)
+
+// Retries of a transient write failure before the connection is given up
+const (
+	maxTransientRetries = 3
+	transientRetryDelay = 10 * time.Millisecond
+)
+
+// SetReconnect enables or disables redialing a dropped connection (enabled
+// by default)
+func (t *TCPTransport) SetReconnect(enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reconnect = enabled
+}
+
+// SetReconnectBackoff sets the initial and maximum delay between redial
+// attempts; the delay doubles after each failed attempt
+func (t *TCPTransport) SetReconnectBackoff(base, max time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reconnectBase = base
+	t.reconnectMax = max
+}
+
+// IsConnected reports whether a dialed connection is currently established
+func (t *TCPTransport) IsConnected() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.conn != nil
+}
+
+// connectionLost discards a broken dialed connection and starts redialing.
+// It is a no-op if conn was already replaced or the transport is closing.
+func (t *TCPTransport) connectionLost(conn net.Conn, cause error) {
+	t.mu.Lock()
+	if t.conn != conn || t.ctx.Err() != nil {
+		t.mu.Unlock()
+		return
+	}
+	t.conn = nil
+	reconnect := t.reconnect
+	t.mu.Unlock()
+
+	conn.Close()
+	t.log().Warn("Connection lost", "remote", t.remote, "error", cause)
+	t.notifyDisconnect(t.remote, cause)
+
+	if reconnect {
+		t.wg.Add(1)
+		go t.redial()
+	}
+}
+
+// dropConnection gives up on the current dialed connection, if any, as if it
+// had failed fatally
+func (t *TCPTransport) dropConnection(cause error) {
+	t.mu.RLock()
+	conn := t.conn
+	isClient := t.isClient
+	t.mu.RUnlock()
+
+	if isClient && conn != nil {
+		t.connectionLost(conn, cause)
+	}
+}
+
+// redial re-establishes the dialed connection with exponential backoff
+func (t *TCPTransport) redial() {
+	defer t.wg.Done()
+
+	t.mu.RLock()
+	delay := t.reconnectBase
+	maxDelay := t.reconnectMax
+	handshakeTimeout := t.handshakeTimeout
+	token := t.authToken
+	t.mu.RUnlock()
+
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-time.After(delay):
+		case <-t.ctx.Done():
+			return
+		}
+
+		conn, err := t.dialPeer(t.remote, handshakeTimeout, token)
+		if err != nil {
+			t.log().Info("Reconnect failed", "remote", t.remote, "attempt", attempt, "error", err)
+			delay *= 2
+			if delay > maxDelay {
+				delay = maxDelay
+			}
+			continue
+		}
+
+		t.mu.Lock()
+		if t.ctx.Err() != nil {
+			t.mu.Unlock()
+			conn.Close()
+			return
+		}
+		t.conn = conn
+		t.mu.Unlock()
+
+		t.log().Info("Reconnected", "remote", t.remote, "attempts", attempt)
+		t.notifyConnect(t.remote)
+
+		t.wg.Add(1)
+		go t.readConnection(conn)
+
+		// Wake up a sender waiting for the connection
+		select {
+		case t.connected <- struct{}{}:
+		default:
+		}
+		return
+	}
+}
@@ -3,13 +3,18 @@ package tcp
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net"
-	"strings"
+	"os"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/xnok/btree-server-msg/pkg/btree"
+	"github.com/xnok/btree-server-msg/pkg/transport"
 )
 
 // TCPTransport implements the Transport interface using TCP
@@ -18,23 +23,150 @@ type TCPTransport struct {
 	outbound chan btree.Message
 	listener net.Listener
 	conn     net.Conn
+	accepted map[net.Conn]struct{}
+	connsMu  sync.Mutex
 	ctx      context.Context
 	cancel   context.CancelFunc
 	wg       sync.WaitGroup
 	mu       sync.RWMutex
 	isServer bool
 	isClient bool
+
+	// closeOnce makes Close idempotent
+	closeOnce sync.Once
+
+	// codec serializes messages into frame payloads
+	codec transport.Codec
+
+	// framingThreshold is the payload size from which length-prefix framing
+	// is used; smaller payloads without newlines use newline framing
+	framingThreshold int
+
+	// flushes carries Flush requests to the outbound loop
+	flushes chan flushRequest
+
+	// writeMu serializes frames written by the outbound loop and ping replies
+	writeMu sync.Mutex
+
+	// Clock skew estimation state
+	now           func() time.Time
+	skewThreshold time.Duration
+	skew          time.Duration
+	hasSkew       bool
+	pingMu        sync.Mutex
+	pongs         chan pongSample
+
+	// profiler samples encode and decode durations when set
+	profiler *btree.Profiler
+
+	// observer is notified of connection lifecycle transitions when set, and
+	// events receives them as well; subscribed is set once Events is called
+	observer   ConnectionObserver
+	events     chan transport.ConnectionEvent
+	subscribed atomic.Bool
+
+	// Reconnection state of a dialed connection
+	remote        string
+	reconnect     bool
+	reconnectBase time.Duration
+	reconnectMax  time.Duration
+	connected     chan struct{}
+
+	// idGenerator assigns IDs to inbound messages that arrive without one
+	idGenerator btree.IDGenerator
+
+	// Keepalive settings; lastWrite is the UnixNano time of the last frame
+	// written so heartbeats are only sent on idle connections
+	heartbeatInterval time.Duration
+	heartbeatTimeout  time.Duration
+	lastWrite         atomic.Int64
+
+	// logger receives the transport's structured log records
+	logger atomic.Pointer[slog.Logger]
+
+	// Connection limit of the listener; maxConns is guarded by connsMu
+	maxConns      int
+	rejectedConns atomic.Uint64
+
+	// Handshake settings; protocolVersion is only changed by tests
+	protocolVersion  int
+	requireHandshake bool
+	handshakeTimeout time.Duration
+
+	// Deadlines applied to every frame read and written; zero disables them
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+
+	// network is "tcp", or "unix" for a transport created by NewUnixTransport
+	network string
+
+	// session identifies this transport to listeners across reconnects;
+	// when preserveOrder is set, sessions holds the done channel of the
+	// latest connection accepted for each dialer session
+	session       string
+	preserveOrder bool
+	sessionMu     sync.Mutex
+	sessions      map[string]chan struct{}
+
+	// Outbound batching; batches are only written when batchSize exceeds one
+	batchSize   int
+	batchWindow time.Duration
+
+	// authToken is presented by a dialer and required by a listener when set
+	authToken string
+
+	// maxMessageSize bounds the encoded messages sent and received when set
+	maxMessageSize int
 }
 
 // NewTCPTransport creates a new TCP transport
 func NewTCPTransport() *TCPTransport {
+	return NewTCPTransportWithBufferSize(btree.DefaultBufferSize)
+}
+
+// NewTCPTransportWithBufferSize creates a new TCP transport whose inbound and
+// outbound channels hold up to size messages
+func NewTCPTransportWithBufferSize(size int) *TCPTransport {
+	return newTCPTransport(size, defaultCodec)
+}
+
+// NewTCPTransportWithCodec creates a new TCP transport that serializes
+// messages with codec; the peer must use a matching codec
+func NewTCPTransportWithCodec(codec transport.Codec) *TCPTransport {
+	return newTCPTransport(btree.DefaultBufferSize, codec)
+}
+
+// newTCPTransport creates a TCP transport with the given channel capacity and codec
+func newTCPTransport(size int, codec transport.Codec) *TCPTransport {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &TCPTransport{
-		inbound:  make(chan btree.Message, 100),
-		outbound: make(chan btree.Message, 100),
+	t := &TCPTransport{
+		inbound:  make(chan btree.Message, size),
+		outbound: make(chan btree.Message, size),
+		accepted: make(map[net.Conn]struct{}),
 		ctx:      ctx,
 		cancel:   cancel,
+		codec:    codec,
+		events:   make(chan transport.ConnectionEvent, eventBufferSize),
+		flushes:  make(chan flushRequest),
+
+		now:           time.Now,
+		skewThreshold: DefaultSkewThreshold,
+		pongs:         make(chan pongSample, 1),
+
+		reconnect:     true,
+		reconnectBase: DefaultReconnectBaseDelay,
+		reconnectMax:  DefaultReconnectMaxDelay,
+		connected:     make(chan struct{}, 1),
+
+		protocolVersion:  ProtocolVersion,
+		handshakeTimeout: DefaultHandshakeTimeout,
+
+		network:  "tcp",
+		session:  newSessionID(),
+		sessions: make(map[string]chan struct{}),
 	}
+	t.SetLogger(nil)
+	return t
 }
 
 // Listen starts listening for incoming TCP connections
@@ -43,23 +175,26 @@ func (t *TCPTransport) Listen(ctx context.Context, address string) error {
 	defer t.mu.Unlock()
 
 	if t.isServer {
-		return fmt.Errorf("already listening")
+		return ErrAlreadyListening
+	}
+	if t.ctx.Err() != nil {
+		return ErrTransportClosed
 	}
 
-	// Ensure address has port format
-	if !strings.Contains(address, ":") {
-		address = ":" + address
+	address, err := t.listenAddress(address)
+	if err != nil {
+		return err
 	}
 
-	listener, err := net.Listen("tcp", address)
+	listener, err := t.listen(address)
 	if err != nil {
-		return fmt.Errorf("failed to listen on %s: %v", address, err)
+		return fmt.Errorf("failed to listen on %s: %w", address, err)
 	}
 
 	t.listener = listener
 	t.isServer = true
 
-	log.Printf("TCP transport listening on %s", address)
+	t.log().Info("Listening", "address", address)
 
 	// Start accepting connections
 	t.wg.Add(1)
@@ -69,56 +204,108 @@ func (t *TCPTransport) Listen(ctx context.Context, address string) error {
 	t.wg.Add(1)
 	go t.processOutbound()
 
+	t.startHeartbeat()
+
 	return nil
 }
 
+// ListenAddr returns the address the listener is bound to, including the
+// port the OS assigned when listening on port 0, or nil when not listening
+func (t *TCPTransport) ListenAddr() net.Addr {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.listener == nil {
+		return nil
+	}
+	return t.listener.Addr()
+}
+
 // Connect establishes a TCP connection to the specified address
 func (t *TCPTransport) Connect(ctx context.Context, address string) error {
+	address, err := t.dial(address)
+	if err != nil {
+		return err
+	}
+
+	t.notifyConnect(address)
+	return nil
+}
+
+// dial opens the client connection and starts its goroutines, returning the
+// resolved address
+func (t *TCPTransport) dial(address string) (string, error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
 	if t.isClient {
-		return fmt.Errorf("already connected")
+		return "", ErrAlreadyConnected
+	}
+	if t.ctx.Err() != nil {
+		return "", ErrTransportClosed
 	}
 
-	// Ensure address has localhost prefix if just port
-	if !strings.Contains(address, ":") {
-		address = "localhost:" + address
-	} else if strings.HasPrefix(address, ":") {
-		address = "localhost" + address
+	address, err := t.dialAddress(address)
+	if err != nil {
+		return "", err
 	}
 
-	conn, err := net.Dial("tcp", address)
+	conn, err := t.dialPeer(address, t.handshakeTimeout, t.authToken)
 	if err != nil {
-		return fmt.Errorf("failed to connect to %s: %v", address, err)
+		return "", fmt.Errorf("failed to connect to %s: %w", address, err)
 	}
 
 	t.conn = conn
+	t.remote = address
 	t.isClient = true
 
-	log.Printf("TCP transport connected to %s", address)
+	t.log().Info("Connected", "remote", address)
+
+	// Read messages the peer sends back upstream
+	t.wg.Add(1)
+	go t.readConnection(conn)
 
 	// Start processing outbound messages
 	t.wg.Add(1)
 	go t.processOutbound()
 
-	return nil
+	t.startHeartbeat()
+
+	return address, nil
 }
 
-// Close closes the TCP transport
+// Close closes the TCP transport; it is safe to call more than once. The
+// inbound and outbound channels are closed only after every goroutine of the
+// transport has returned, so nothing writes to them afterwards.
 func (t *TCPTransport) Close() error {
+	t.closeOnce.Do(t.close)
+	return nil
+}
+
+// close tears the transport down; it runs once
+func (t *TCPTransport) close() {
 	t.cancel()
 
 	t.mu.Lock()
-	defer t.mu.Unlock()
-
 	if t.listener != nil {
 		t.listener.Close()
 	}
 
-	if t.conn != nil {
-		t.conn.Close()
+	conn := t.conn
+	if conn != nil {
+		conn.Close()
+	}
+	t.mu.Unlock()
+
+	if conn != nil {
+		t.notifyDisconnect(t.remote, nil)
+	}
+
+	// Close accepted connections so their readers return
+	t.connsMu.Lock()
+	for conn := range t.accepted {
+		conn.Close()
 	}
+	t.connsMu.Unlock()
 
 	// Wait for goroutines to finish
 	t.wg.Wait()
@@ -126,8 +313,51 @@ func (t *TCPTransport) Close() error {
 	// Close channels
 	close(t.inbound)
 	close(t.outbound)
+}
 
-	return nil
+// SetProfiler enables profiling of message encoding and decoding; nil disables it
+func (t *TCPTransport) SetProfiler(p *btree.Profiler) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.profiler = p
+}
+
+// sampleTimer returns a stage timer if msg is sampled. The decision follows
+// the message's ID, so it matches the node's for the same message.
+func (t *TCPTransport) sampleTimer(msg btree.Message) *btree.StageTimer {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.profiler.SampleMessage(msg)
+}
+
+// clock returns the current time of the transport's clock
+func (t *TCPTransport) clock() time.Time {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.now()
+}
+
+// SetFramingThreshold sets the payload size from which messages are sent with
+// length-prefix framing instead of newline framing. The default of 0 frames
+// every message with a length prefix.
+func (t *TCPTransport) SetFramingThreshold(threshold int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.framingThreshold = threshold
+}
+
+// SetIDGenerator sets the generator used for inbound messages without an ID;
+// nil uses btree.DefaultIDGenerator
+func (t *TCPTransport) SetIDGenerator(gen btree.IDGenerator) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.idGenerator = gen
+}
+
+func (t *TCPTransport) getIDGenerator() btree.IDGenerator {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.idGenerator
 }
 
 // GetInboundChannel returns the channel for incoming messages
@@ -154,12 +384,21 @@ func (t *TCPTransport) acceptConnections(ctx context.Context) {
 				select {
 				case <-ctx.Done():
 					return
+				case <-t.ctx.Done():
+					return
 				default:
-					log.Printf("Failed to accept connection: %v", err)
+					t.log().Error("Failed to accept connection", "error", err)
+					t.notifyAcceptError(err)
 					continue
 				}
 			}
 
+			if !t.admitConnection(conn) {
+				continue
+			}
+
+			t.notifyAccept(conn.RemoteAddr().String())
+
 			// Handle each connection in a separate goroutine
 			t.wg.Add(1)
 			go t.handleConnection(conn)
@@ -167,36 +406,164 @@ func (t *TCPTransport) acceptConnections(ctx context.Context) {
 	}
 }
 
-// handleConnection handles a single TCP connection
+// handleConnection handles a single accepted TCP connection, which
+// admitConnection has already registered
 func (t *TCPTransport) handleConnection(conn net.Conn) {
 	defer t.wg.Done()
 	defer conn.Close()
 
-	scanner := bufio.NewScanner(conn)
-	for scanner.Scan() {
-		select {
-		case <-t.ctx.Done():
-			return
-		default:
-			text := scanner.Text()
-			if text != "" {
-				msg := btree.Message{
-					Content: text,
-					ID:      "", // Could generate UUID here if needed
+	defer func() {
+		t.connsMu.Lock()
+		delete(t.accepted, conn)
+		t.connsMu.Unlock()
+	}()
+
+	err := t.readMessages(conn, true)
+	t.notifyDisconnect(conn.RemoteAddr().String(), err)
+}
+
+// readConnection reads messages sent back by the peer of a dialed connection
+// and starts reconnecting when the connection drops
+func (t *TCPTransport) readConnection(conn net.Conn) {
+	defer t.wg.Done()
+
+	err := t.readMessages(conn, false)
+	if err == nil {
+		err = io.EOF
+	}
+	t.connectionLost(conn, err)
+}
+
+// readMessages decodes frames from a connection into the inbound channel
+// until the connection is closed. It returns nil when the peer closed the
+// connection cleanly or the transport is shutting down. accepted marks
+// connections taken by the listener, which answer the dialer's handshake.
+func (t *TCPTransport) readMessages(conn net.Conn, accepted bool) error {
+	reader := bufio.NewReader(conn)
+
+	// The payload buffer is reused for every frame; decoding copies the
+	// content out before the next read overwrites it
+	buf := getFrameBuffer()
+	defer putFrameBuffer(buf)
+
+	t.mu.RLock()
+	timeout, timeoutErr := readDeadline(t.heartbeatTimeout, t.readTimeout)
+	requireHandshake := t.requireHandshake
+	authenticate := t.authToken != ""
+	limit := t.maxMessageSize
+	t.mu.RUnlock()
+
+	remoteAddr := conn.RemoteAddr().String()
+
+	// release lets the next connection of the dialer's session be read
+	release := func() {}
+	defer func() { release() }()
+
+	for first := true; ; first = false {
+		// Any frame, heartbeats included, proves the peer is alive
+		if timeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(timeout))
+		}
+
+		payload, frameType, err := readFrameLimited(reader, *buf, limit)
+		if cap(payload) > cap(*buf) {
+			*buf = payload[:0]
+		}
+		if err != nil {
+			if err == io.EOF || t.ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				t.log().Warn("Nothing received within timeout, closing connection", "remote", remoteAddr, "timeout", timeout, "error", timeoutErr)
+				return fmt.Errorf("%w after %v", timeoutErr, timeout)
+			}
+			t.log().Warn("Connection read error", "remote", remoteAddr, "error", err)
+			return err
+		}
+
+		// Only dialers send handshakes, and only as their first frame; a
+		// listener may insist on one before anything else
+		if accepted && first {
+			if frameType == frameHandshake {
+				peer, err := t.serverHandshake(conn, payload)
+				if err != nil {
+					t.log().Warn("Rejecting incompatible peer", "remote", remoteAddr, "error", err)
+					return err
 				}
+				release = t.awaitSession(peer.Session, remoteAddr)
+				continue
+			}
+			if authenticate {
+				t.log().Warn("Rejecting unauthenticated peer", "remote", remoteAddr)
+				return fmt.Errorf("%w: missing token", ErrUnauthorized)
+			}
+			if requireHandshake {
+				t.log().Warn("Rejecting peer without handshake", "remote", remoteAddr)
+				return ErrHandshakeRequired
+			}
+		}
 
-				select {
-				case t.inbound <- msg:
-					log.Printf("TCP: Received message: %s", text)
-				case <-t.ctx.Done():
-					return
+		// Clock probes are answered here and never reach the inbound channel
+		switch frameType {
+		case framePing:
+			if err := t.handlePing(conn, payload, t.clock()); err != nil {
+				t.log().Warn("Failed to answer ping", "error", err)
+			}
+			continue
+		case framePong:
+			if err := t.handlePong(payload, t.clock()); err != nil {
+				t.log().Warn("Failed to handle pong", "error", err)
+			}
+			continue
+		case frameHeartbeat, frameHandshake:
+			continue
+		}
+
+		if frameType == frameBatch {
+			payloads, err := splitBatch(payload)
+			if err != nil {
+				t.log().Warn("Dropping malformed batch", "error", err)
+				continue
+			}
+			for _, p := range payloads {
+				if !t.receivePayload(p, accepted, remoteAddr) {
+					return nil
 				}
 			}
+			continue
+		}
+
+		if !t.receivePayload(payload, accepted, remoteAddr) {
+			return nil
 		}
 	}
+}
+
+// receivePayload decodes a message payload and queues it on the inbound
+// channel. It returns false once the transport is closing. Frames delimit
+// messages, so an empty payload is still a message, e.g. empty text.
+func (t *TCPTransport) receivePayload(payload []byte, accepted bool, remoteAddr string) bool {
+	// Decoding is recorded once the message, and so its sampling, is known
+	start := time.Now()
+	msg, err := decodeMessage(t.codec, payload)
+	decoded := time.Since(start)
+	if err != nil {
+		t.log().Warn("Dropping malformed message", "error", err)
+		return true
+	}
+	msg = btree.AssignID(msg, t.getIDGenerator())
+	t.sampleTimer(msg).Observe(btree.StageDecode, decoded)
+	if accepted {
+		// Tell apart messages from the listener's many connections
+		msg = msg.WithMetadata(transport.RemoteAddrKey, remoteAddr)
+	}
 
-	if err := scanner.Err(); err != nil {
-		log.Printf("TCP: Connection scan error: %v", err)
+	select {
+	case t.inbound <- msg:
+		t.log().Debug("Received message", "content", msg.Content, "id", msg.ID)
+		return true
+	case <-t.ctx.Done():
+		return false
 	}
 }
 
@@ -207,35 +574,155 @@ func (t *TCPTransport) processOutbound() {
 	for {
 		select {
 		case msg := <-t.outbound:
-			if err := t.sendMessage(msg); err != nil {
-				log.Printf("TCP: Failed to send message: %v", err)
+			t.deliverBatch(t.collectBatch(msg, true))
+		case req := <-t.flushes:
+			t.flushOutbound(req)
+		case <-t.ctx.Done():
+			return
+		}
+	}
+}
+
+// deliver sends a message, holding it while a dialed connection is being
+// re-established so that it is delivered once reconnected. Messages queued
+// behind it wait on the outbound channel, up to its capacity.
+//
+// Transient write errors are retried on the same connection a few times
+// before the connection is given up as lost.
+func (t *TCPTransport) deliver(msg btree.Message) {
+	t.retrySend(slog.String("id", msg.ID), func() error { return t.sendMessage(msg) })
+}
+
+// retrySend runs send with the retry and reconnection semantics of deliver;
+// attr identifies what is being sent in log records
+func (t *TCPTransport) retrySend(attr slog.Attr, send func() error) {
+	transientRetries := 0
+	for {
+		err := send()
+		if err == nil {
+			return
+		}
+		if errors.Is(err, ErrMessageTooLarge) {
+			t.log().Error("Dropping oversized message", attr, "error", err)
+			return
+		}
+
+		if IsTransientWriteError(err) {
+			if transientRetries < maxTransientRetries {
+				transientRetries++
+				t.log().Info("Transient send failure, retrying", "retry", transientRetries, "max", maxTransientRetries, "error", err)
+				select {
+				case <-time.After(transientRetryDelay):
+				case <-t.ctx.Done():
+					return
+				}
+				continue
 			}
+			t.dropConnection(err)
+		}
+		transientRetries = 0
+
+		t.mu.RLock()
+		waitForReconnect := t.isClient && t.reconnect
+		t.mu.RUnlock()
+
+		if !waitForReconnect {
+			t.log().Error("Failed to send message", attr, "error", err)
+			return
+		}
+
+		t.log().Warn("Send failed, waiting for reconnection", attr, "error", err)
+		select {
+		case <-t.connected:
 		case <-t.ctx.Done():
 			return
 		}
 	}
 }
 
-// sendMessage sends a message over the TCP connection
+// sendMessage sends a message over the dialed connection, or back to every
+// accepted peer when the transport is listening
 func (t *TCPTransport) sendMessage(msg btree.Message) error {
 	t.mu.RLock()
-	conn := t.conn
+	threshold := t.framingThreshold
+	writeTimeout := t.writeTimeout
+	limit := t.maxMessageSize
 	t.mu.RUnlock()
 
-	if conn == nil {
-		return fmt.Errorf("no active connection")
+	conns, isClient := t.activeConns()
+	if len(conns) == 0 {
+		return ErrNoConnection
 	}
 
-	message := msg.Content
-	if !strings.HasSuffix(message, "\n") {
-		message += "\n"
+	stopEncode := t.sampleTimer(msg).Start(btree.StageEncode)
+	payload, err := encodeMessage(t.codec, msg)
+	stopEncode()
+	if err != nil {
+		return err
+	}
+	if err := checkMessageSize(payload, limit); err != nil {
+		return err
 	}
 
-	_, err := conn.Write([]byte(message))
+	err = t.writeConns(conns, isClient, writeTimeout, func(w io.Writer) error {
+		return writeFrame(w, payload, threshold)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to write message: %v", err)
+		return err
 	}
 
-	log.Printf("TCP: Sent message: %s", strings.TrimSpace(message))
+	t.log().Debug("Sent message", "content", msg.Content, "id", msg.ID)
+	return nil
+}
+
+// writeConns writes one frame to each connection under writeMu, classifying
+// write errors and giving up connections that failed fatally
+func (t *TCPTransport) writeConns(conns []net.Conn, isClient bool, writeTimeout time.Duration, write func(io.Writer) error) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	for _, c := range conns {
+		setWriteDeadline(c, writeTimeout)
+		if err := write(c); err != nil {
+			// A peer that stopped reading will not start again, so a write
+			// timeout gives the connection up rather than retrying on it
+			if isWriteTimeout(err, writeTimeout) {
+				err = fmt.Errorf("%w after %v: %w", ErrWriteTimeout, writeTimeout, err)
+				t.closeStalled(c, isClient, err)
+				return &WriteError{Kind: WriteErrorFatal, Err: err}
+			}
+
+			writeErr := &WriteError{Kind: classifyWriteError(err), Err: err}
+			if isClient && writeErr.Kind == WriteErrorFatal {
+				t.connectionLost(c, err)
+			}
+			return writeErr
+		}
+	}
+	t.lastWrite.Store(time.Now().UnixNano())
 	return nil
 }
+
+// activeConns returns the dialed connection of a client, or every accepted
+// connection of a listening transport
+func (t *TCPTransport) activeConns() ([]net.Conn, bool) {
+	t.mu.RLock()
+	conn := t.conn
+	isClient := t.isClient
+	t.mu.RUnlock()
+
+	conns := []net.Conn{}
+	if isClient {
+		if conn != nil {
+			conns = append(conns, conn)
+		}
+		return conns, true
+	}
+
+	t.connsMu.Lock()
+	for accepted := range t.accepted {
+		conns = append(conns, accepted)
+	}
+	t.connsMu.Unlock()
+	return conns, false
+}
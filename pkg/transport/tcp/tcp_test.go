@@ -0,0 +1,693 @@
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+	"github.com/xnok/btree-server-msg/pkg/transport"
+)
+
+// startPair starts a listening transport on a random loopback port and a
+// client transport connected to it
+func startPair(t testing.TB) (server, client *TCPTransport) {
+	t.Helper()
+
+	server = NewTCPTransport()
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	client = NewTCPTransport()
+	if err := client.Connect(context.Background(), server.listener.Addr().String()); err != nil {
+		server.Close()
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return server, client
+}
+
+// receive waits for the next inbound message on a transport
+func receive(t *testing.T, tr *TCPTransport) btree.Message {
+	t.Helper()
+
+	select {
+	case msg := <-tr.GetInboundChannel():
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for message")
+		return btree.Message{}
+	}
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	payloads := [][]byte{
+		[]byte("single line"),
+		[]byte("first line\nsecond line\n"),
+		{'a', 0x00, 'b', 0x00, 0x0A},
+	}
+
+	for _, payload := range payloads {
+		if err := writeFrame(&buf, payload, 0); err != nil {
+			t.Fatalf("Failed to write frame: %v", err)
+		}
+	}
+
+	reader := bufio.NewReader(&buf)
+	for _, expected := range payloads {
+		got, _, err := readFrame(reader)
+		if err != nil {
+			t.Fatalf("Failed to read frame: %v", err)
+		}
+		if !bytes.Equal(got, expected) {
+			t.Errorf("Expected %q, got %q", expected, got)
+		}
+	}
+}
+
+func TestMultiLineContent(t *testing.T) {
+	server, client := startPair(t)
+
+	content := "line one\nline two\n\nline four"
+	client.GetOutboundChannel() <- btree.Message{Content: content}
+
+	msg := receive(t, server)
+	if msg.Content != content {
+		t.Errorf("Expected %q, got %q", content, msg.Content)
+	}
+}
+
+func TestNullByteContent(t *testing.T) {
+	server, client := startPair(t)
+
+	content := "before\x00after\x00\n"
+	client.GetOutboundChannel() <- btree.Message{Content: content}
+
+	msg := receive(t, server)
+	if msg.Content != content {
+		t.Errorf("Expected %q, got %q", content, msg.Content)
+	}
+}
+
+func TestEmptyContentIsDelivered(t *testing.T) {
+	server, client := startPair(t)
+
+	client.GetOutboundChannel() <- btree.Message{ID: "empty-1"}.WithMetadata("kind", "marker")
+
+	msg := receive(t, server)
+	if msg.ID != "empty-1" || msg.Content != "" {
+		t.Errorf("Expected empty message empty-1, got %q with content %q", msg.ID, msg.Content)
+	}
+	if got := msg.GetMetadata("kind"); got != "marker" {
+		t.Errorf("Expected kind marker, got %q", got)
+	}
+}
+
+func TestEmptyTextIsDelivered(t *testing.T) {
+	server := NewTCPTransportWithCodec(transport.TextCodec{})
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	client := NewTCPTransportWithCodec(transport.TextCodec{})
+	if err := client.Connect(context.Background(), server.listener.Addr().String()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	client.GetOutboundChannel() <- btree.Message{}
+	client.GetOutboundChannel() <- btree.Message{Content: "after"}
+
+	if msg := receive(t, server); msg.Content != "" {
+		t.Errorf("Expected empty content first, got %q", msg.Content)
+	}
+	if msg := receive(t, server); msg.Content != "after" {
+		t.Errorf("Expected after, got %q", msg.Content)
+	}
+}
+
+func TestBinaryPayloadRoundTrip(t *testing.T) {
+	server, client := startPair(t)
+
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	blobs := [][]byte{
+		{0x00},
+		{0x0A},
+		{'a', 0x00, 0x0A, 'b', 0x0D, 0x0A, 0xFF},
+		all,
+	}
+
+	for i, blob := range blobs {
+		client.GetOutboundChannel() <- btree.NewBinaryMessage(blob, fmt.Sprintf("bin-%d", i))
+	}
+	for i, blob := range blobs {
+		msg := receive(t, server)
+		if !bytes.Equal(msg.Payload, blob) {
+			t.Errorf("Blob %d: expected %v, got %v", i, blob, msg.Payload)
+		}
+	}
+}
+
+func TestCodecPairs(t *testing.T) {
+	codecs := map[string]transport.Codec{
+		"text": transport.TextCodec{},
+		"json": transport.JSONCodec{},
+	}
+
+	for name, codec := range codecs {
+		t.Run(name, func(t *testing.T) {
+			server := NewTCPTransportWithCodec(codec)
+			if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+				t.Fatalf("Failed to listen: %v", err)
+			}
+			defer server.Close()
+
+			client := NewTCPTransportWithCodec(codec)
+			if err := client.Connect(context.Background(), server.listener.Addr().String()); err != nil {
+				t.Fatalf("Failed to connect: %v", err)
+			}
+			defer client.Close()
+
+			content := "hello over " + name + "\nwith a second line"
+			client.GetOutboundChannel() <- btree.Message{Content: content}
+			if msg := receive(t, server); msg.Content != content {
+				t.Errorf("Expected %q, got %q", content, msg.Content)
+			}
+		})
+	}
+}
+
+func TestMetadataRoundTrip(t *testing.T) {
+	server, client := startPair(t)
+
+	sent := btree.Message{Content: "traced"}.
+		WithMetadata("trace-id", "abc123").
+		WithMetadata("tenant", "acme")
+	client.GetOutboundChannel() <- sent
+
+	msg := receive(t, server)
+	if got := msg.GetMetadata("trace-id"); got != "abc123" {
+		t.Errorf("Expected trace-id abc123, got %q", got)
+	}
+	if got := msg.GetMetadata("tenant"); got != "acme" {
+		t.Errorf("Expected tenant acme, got %q", got)
+	}
+}
+
+func TestFramingThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		payload   []byte
+		threshold int
+		frameType byte
+	}{
+		{"small payload uses newline framing", []byte("ping"), 16, frameNewline},
+		{"large payload uses length framing", bytes.Repeat([]byte("x"), 64), 16, frameLength},
+		{"small payload with newline uses length framing", []byte("a\nb"), 16, frameLength},
+		{"zero threshold always uses length framing", []byte("ping"), 0, frameLength},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeFrame(&buf, tt.payload, tt.threshold); err != nil {
+				t.Fatalf("Failed to write frame: %v", err)
+			}
+
+			got, frameType, err := readFrame(bufio.NewReader(&buf))
+			if err != nil {
+				t.Fatalf("Failed to read frame: %v", err)
+			}
+			if frameType != tt.frameType {
+				t.Errorf("Expected frame type %q, got %q", tt.frameType, frameType)
+			}
+			if !bytes.Equal(got, tt.payload) {
+				t.Errorf("Expected %q, got %q", tt.payload, got)
+			}
+		})
+	}
+}
+
+func TestMixedFramingOverOneConnection(t *testing.T) {
+	server, client := startPair(t)
+	client.SetFramingThreshold(16)
+
+	small := "ctl"
+	large := string(bytes.Repeat([]byte("payload "), 32))
+
+	client.GetOutboundChannel() <- btree.Message{Content: small}
+	client.GetOutboundChannel() <- btree.Message{Content: large}
+
+	if msg := receive(t, server); msg.Content != small {
+		t.Errorf("Expected %q, got %q", small, msg.Content)
+	}
+	if msg := receive(t, server); msg.Content != large {
+		t.Errorf("Expected large message of %d bytes, got %d bytes", len(large), len(msg.Content))
+	}
+}
+
+func TestServerSendsBackToClient(t *testing.T) {
+	server, client := startPair(t)
+
+	// Make sure the server has accepted the connection before replying
+	client.GetOutboundChannel() <- btree.Message{Content: "hello"}
+	receive(t, server)
+
+	reply := btree.Message{Content: "reply", ID: "reply-1", Upstream: true}
+	server.GetOutboundChannel() <- reply
+
+	msg := receive(t, client)
+	if msg.Content != reply.Content || msg.ID != reply.ID || !msg.Upstream {
+		t.Errorf("Expected %+v, got %+v", reply, msg)
+	}
+}
+
+func TestInboundMessagesWithoutIDGetOne(t *testing.T) {
+	server, client := startPair(t)
+	server.SetIDGenerator(btree.NewSequentialIDGenerator("tcp"))
+
+	client.GetOutboundChannel() <- btree.Message{Content: "no id"}
+	client.GetOutboundChannel() <- btree.Message{Content: "has id", ID: "kept"}
+
+	if msg := receive(t, server); msg.ID != "tcp-1" {
+		t.Errorf("Expected assigned ID tcp-1, got %q", msg.ID)
+	}
+	if msg := receive(t, server); msg.ID != "kept" {
+		t.Errorf("Expected existing ID kept, got %q", msg.ID)
+	}
+}
+
+func TestPongSampleOffset(t *testing.T) {
+	base := time.Unix(1000, 0)
+	sample := pongSample{
+		t0: base,
+		t1: base.Add(3*time.Second + 10*time.Millisecond),
+		t2: base.Add(3*time.Second + 20*time.Millisecond),
+		t3: base.Add(30 * time.Millisecond),
+	}
+
+	if offset := sample.offset(); offset != 3*time.Second {
+		t.Errorf("Expected offset 3s, got %v", offset)
+	}
+	if rtt := sample.roundTrip(); rtt != 20*time.Millisecond {
+		t.Errorf("Expected round trip 20ms, got %v", rtt)
+	}
+}
+
+func TestEstimateClockSkew(t *testing.T) {
+	server, client := startPair(t)
+
+	// The server's clock runs five seconds ahead of the client's
+	injected := 5 * time.Second
+	server.SetClock(func() time.Time { return time.Now().Add(injected) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	skew, err := client.EstimateClockSkew(ctx)
+	if err != nil {
+		t.Fatalf("Failed to estimate clock skew: %v", err)
+	}
+
+	if diff := skew - injected; diff > 50*time.Millisecond || diff < -50*time.Millisecond {
+		t.Errorf("Expected skew close to %v, got %v", injected, skew)
+	}
+
+	if cached, ok := client.ClockSkew(); !ok || cached != skew {
+		t.Errorf("Expected cached skew %v, got %v (available: %v)", skew, cached, ok)
+	}
+
+	// Pings must never surface as messages
+	select {
+	case msg := <-server.GetInboundChannel():
+		t.Errorf("Ping leaked into inbound channel: %+v", msg)
+	default:
+	}
+}
+
+func TestTransportProfilesEncodeAndDecode(t *testing.T) {
+	server, client := startPair(t)
+
+	profiler := btree.NewProfiler(1.0)
+	server.SetProfiler(profiler)
+	client.SetProfiler(profiler)
+
+	client.GetOutboundChannel() <- btree.Message{Content: "profiled"}
+	receive(t, server)
+
+	if h := profiler.Histogram(btree.StageEncode); h.Count != 1 {
+		t.Errorf("Expected 1 encode sample, got %d", h.Count)
+	}
+	if h := profiler.Histogram(btree.StageDecode); h.Count != 1 {
+		t.Errorf("Expected 1 decode sample, got %d", h.Count)
+	}
+}
+
+// waitFor polls cond until it holds or the timeout expires
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timeout waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReconnectAfterListenerRestart(t *testing.T) {
+	server := NewTCPTransport()
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	address := server.listener.Addr().String()
+
+	client := NewTCPTransport()
+	client.SetReconnectBackoff(20*time.Millisecond, 100*time.Millisecond)
+	if err := client.Connect(context.Background(), address); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	client.GetOutboundChannel() <- btree.Message{Content: "before restart"}
+	if msg := receive(t, server); msg.Content != "before restart" {
+		t.Errorf("Unexpected message: %+v", msg)
+	}
+
+	// Kill the listener and wait for the client to notice
+	server.Close()
+	waitFor(t, time.Second, func() bool { return !client.IsConnected() })
+
+	// Messages queued during the outage are held until reconnection
+	for _, content := range []string{"during outage 1", "during outage 2"} {
+		client.GetOutboundChannel() <- btree.Message{Content: content}
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	restarted := NewTCPTransport()
+	if err := restarted.Listen(context.Background(), address); err != nil {
+		t.Fatalf("Failed to restart listener: %v", err)
+	}
+	defer restarted.Close()
+
+	for _, expected := range []string{"during outage 1", "during outage 2"} {
+		if msg := receive(t, restarted); msg.Content != expected {
+			t.Errorf("Expected %q, got %q", expected, msg.Content)
+		}
+	}
+
+	client.GetOutboundChannel() <- btree.Message{Content: "after restart"}
+	if msg := receive(t, restarted); msg.Content != "after restart" {
+		t.Errorf("Unexpected message: %+v", msg)
+	}
+}
+
+func TestReusedFrameBufferDoesNotCorruptQueuedMessages(t *testing.T) {
+	var stream bytes.Buffer
+	contents := []string{"first message", "second", "a much longer third message that grows the buffer"}
+	for _, content := range contents {
+		payload, err := encodeMessage(defaultCodec, btree.Message{Content: content})
+		if err != nil {
+			t.Fatalf("Failed to encode: %v", err)
+		}
+		if err := writeFrame(&stream, payload, 0); err != nil {
+			t.Fatalf("Failed to write frame: %v", err)
+		}
+	}
+
+	// Queue every decoded message before looking at any of them, as the
+	// inbound channel does, while one buffer is reused for all reads
+	reader := bufio.NewReader(&stream)
+	buf := make([]byte, 0, 8)
+	queued := make(chan btree.Message, len(contents))
+	for range contents {
+		payload, _, err := readFrameInto(reader, buf)
+		if err != nil {
+			t.Fatalf("Failed to read frame: %v", err)
+		}
+		buf = payload[:0]
+
+		msg, err := decodeMessage(defaultCodec, payload)
+		if err != nil {
+			t.Fatalf("Failed to decode: %v", err)
+		}
+		queued <- msg
+	}
+
+	for _, expected := range contents {
+		if msg := <-queued; msg.Content != expected {
+			t.Errorf("Expected %q, got %q", expected, msg.Content)
+		}
+	}
+}
+
+// frameStream builds a stream of n encoded message frames for benchmarks
+func frameStream(b *testing.B, n int) []byte {
+	b.Helper()
+
+	var stream bytes.Buffer
+	payload, err := encodeMessage(defaultCodec, btree.Message{Content: string(bytes.Repeat([]byte("x"), 256)), ID: "bench"})
+	if err != nil {
+		b.Fatalf("Failed to encode: %v", err)
+	}
+	for i := 0; i < n; i++ {
+		writeFrame(&stream, payload, 0)
+	}
+	return stream.Bytes()
+}
+
+// BenchmarkReadFrameAllocating reads each frame into a fresh buffer
+func BenchmarkReadFrameAllocating(b *testing.B) {
+	stream := frameStream(b, b.N)
+	reader := bufio.NewReader(bytes.NewReader(stream))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		payload, _, err := readFrame(reader)
+		if err != nil {
+			b.Fatalf("Failed to read frame: %v", err)
+		}
+		if _, err := decodeMessage(defaultCodec, payload); err != nil {
+			b.Fatalf("Failed to decode: %v", err)
+		}
+	}
+}
+
+// BenchmarkReadFrameReusing reads every frame into one pooled buffer
+func BenchmarkReadFrameReusing(b *testing.B) {
+	stream := frameStream(b, b.N)
+	reader := bufio.NewReader(bytes.NewReader(stream))
+	buf := getFrameBuffer()
+	defer putFrameBuffer(buf)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		payload, _, err := readFrameInto(reader, *buf)
+		if err != nil {
+			b.Fatalf("Failed to read frame: %v", err)
+		}
+		*buf = payload[:0]
+		if _, err := decodeMessage(defaultCodec, payload); err != nil {
+			b.Fatalf("Failed to decode: %v", err)
+		}
+	}
+}
+
+// recordingObserver records connection lifecycle callbacks
+type recordingObserver struct {
+	mu     sync.Mutex
+	events []string
+	errs   []error
+}
+
+func (o *recordingObserver) record(event string, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, event)
+	o.errs = append(o.errs, err)
+}
+
+func (o *recordingObserver) OnConnect(addr string)               { o.record("connect", nil) }
+func (o *recordingObserver) OnDisconnect(addr string, err error) { o.record("disconnect", err) }
+func (o *recordingObserver) OnAccept(remote string)              { o.record("accept", nil) }
+func (o *recordingObserver) OnAcceptError(err error)             { o.record("accept-error", err) }
+
+// snapshot returns the recorded events
+func (o *recordingObserver) snapshot() ([]string, []error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]string(nil), o.events...), append([]error(nil), o.errs...)
+}
+
+func TestConnectionObserver(t *testing.T) {
+	serverObserver := &recordingObserver{}
+	server := NewTCPTransport()
+	server.SetObserver(serverObserver)
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	clientObserver := &recordingObserver{}
+	client := NewTCPTransport()
+	client.SetObserver(clientObserver)
+	if err := client.Connect(context.Background(), server.listener.Addr().String()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	client.Close()
+
+	events, errs := clientObserver.snapshot()
+	if len(events) != 2 || events[0] != "connect" || events[1] != "disconnect" {
+		t.Fatalf("Expected client connect then disconnect, got %v", events)
+	}
+	if errs[1] != nil {
+		t.Errorf("Expected clean disconnect, got %v", errs[1])
+	}
+
+	// The server sees the accept and the peer going away
+	waitFor(t, time.Second, func() bool {
+		events, _ := serverObserver.snapshot()
+		return len(events) == 2
+	})
+	events, errs = serverObserver.snapshot()
+	if events[0] != "accept" || events[1] != "disconnect" {
+		t.Errorf("Expected server accept then disconnect, got %v", events)
+	}
+	if errs[1] != nil {
+		t.Errorf("Expected clean disconnect on server, got %v", errs[1])
+	}
+}
+
+func TestNewTCPTransportWithBufferSize(t *testing.T) {
+	tr := NewTCPTransportWithBufferSize(5)
+	defer tr.Close()
+
+	if cap(tr.inbound) != 5 || cap(tr.outbound) != 5 {
+		t.Errorf("Expected capacity 5, got inbound %d outbound %d", cap(tr.inbound), cap(tr.outbound))
+	}
+}
+
+func TestCloseTwice(t *testing.T) {
+	server, client := startPair(t)
+
+	for _, tr := range []*TCPTransport{client, server} {
+		if err := tr.Close(); err != nil {
+			t.Fatalf("First close failed: %v", err)
+		}
+		if err := tr.Close(); err != nil {
+			t.Fatalf("Second close failed: %v", err)
+		}
+	}
+
+	// A closed transport cannot be reused
+	unused := NewTCPTransport()
+	unused.Close()
+	if err := unused.Listen(context.Background(), "127.0.0.1:0"); err == nil {
+		t.Error("Expected listening on a closed transport to fail")
+	}
+}
+
+func TestConnectionLimit(t *testing.T) {
+	server := NewTCPTransport()
+	server.SetMaxConnections(2)
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+	address := server.listener.Addr().String()
+
+	dial := func() net.Conn {
+		conn, err := net.Dial("tcp", address)
+		if err != nil {
+			t.Fatalf("Failed to dial: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	first, second := dial(), dial()
+	waitFor(t, time.Second, func() bool { return server.ActiveConnections() == 2 })
+
+	// The listener closes the excess connection straight away
+	excess := dial()
+	excess.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := excess.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Expected the excess connection to be closed with EOF, got %v", err)
+	}
+	if rejected := server.RejectedConnections(); rejected != 1 {
+		t.Errorf("Expected 1 rejected connection, got %d", rejected)
+	}
+
+	// Admitted connections keep working
+	for i, conn := range []net.Conn{first, second} {
+		if _, err := fmt.Fprintf(conn, "N{\"content\":\"still here\",\"id\":\"c-%d\"}\n", i); err != nil {
+			t.Fatalf("Failed to write on connection %d: %v", i, err)
+		}
+		if msg := receive(t, server); msg.ID != fmt.Sprintf("c-%d", i) {
+			t.Errorf("Expected c-%d, got %q", i, msg.ID)
+		}
+	}
+
+	// Closing one frees a slot for a new connection
+	first.Close()
+	waitFor(t, time.Second, func() bool { return server.ActiveConnections() == 1 })
+	dial()
+	waitFor(t, time.Second, func() bool { return server.ActiveConnections() == 2 })
+	if rejected := server.RejectedConnections(); rejected != 1 {
+		t.Errorf("Expected the replacement connection to be admitted, got %d rejections", rejected)
+	}
+}
+
+func TestInboundMessagesCarryRemoteAddr(t *testing.T) {
+	server := NewTCPTransport()
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	clients := make([]*TCPTransport, 2)
+	for i := range clients {
+		clients[i] = NewTCPTransport()
+		if err := clients[i].Connect(context.Background(), server.listener.Addr().String()); err != nil {
+			t.Fatalf("Failed to connect client %d: %v", i, err)
+		}
+		defer clients[i].Close()
+	}
+
+	for i, client := range clients {
+		client.GetOutboundChannel() <- btree.Message{Content: "tagged", ID: fmt.Sprintf("r-%d", i)}
+		msg := receive(t, server)
+
+		client.mu.RLock()
+		want := client.conn.LocalAddr().String()
+		client.mu.RUnlock()
+		if got := transport.RemoteAddr(msg); got != want {
+			t.Errorf("Expected %s from %s, got %q", msg.ID, want, got)
+		}
+	}
+
+	// Messages read by the dialing side are not tagged
+	server.GetOutboundChannel() <- btree.Message{Content: "back", ID: "r-back"}
+	if msg := receive(t, clients[0]); transport.RemoteAddr(msg) != "" {
+		t.Errorf("Expected no remote address on a dialed connection, got %q", transport.RemoteAddr(msg))
+	}
+}
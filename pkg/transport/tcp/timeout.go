@@ -0,0 +1,80 @@
+package tcp
+
+import (
+	"errors"
+	"net"
+	"os"
+	"time"
+)
+
+// ErrReadTimeout is returned when a connection is closed because a read
+// blocked for longer than the read timeout
+var ErrReadTimeout = errors.New("read timeout")
+
+// ErrWriteTimeout is returned when a write blocked for longer than the write
+// timeout, e.g. because the peer stopped reading. The connection is closed
+// and, if it was dialed, redialed.
+var ErrWriteTimeout = errors.New("write timeout")
+
+// SetReadTimeout bounds how long a connection may go without a frame
+// arriving before it is closed; zero (the default) waits forever. Unlike the
+// heartbeat timeout it needs no cooperation from the peer, so an idle but
+// healthy connection is closed too. It must be called before Listen or
+// Connect.
+func (t *TCPTransport) SetReadTimeout(timeout time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.readTimeout = timeout
+}
+
+// SetWriteTimeout bounds how long writing one frame may block; zero (the
+// default) waits forever. A connection whose write times out is closed.
+func (t *TCPTransport) SetWriteTimeout(timeout time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.writeTimeout = timeout
+}
+
+// getWriteTimeout returns the configured write timeout
+func (t *TCPTransport) getWriteTimeout() time.Duration {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.writeTimeout
+}
+
+// setWriteDeadline arms the write timeout on conn before a write
+func setWriteDeadline(conn net.Conn, timeout time.Duration) {
+	if timeout > 0 {
+		conn.SetWriteDeadline(time.Now().Add(timeout))
+	}
+}
+
+// isWriteTimeout reports whether err is the expiry of a write deadline set by
+// setWriteDeadline
+func isWriteTimeout(err error, timeout time.Duration) bool {
+	return timeout > 0 && errors.Is(err, os.ErrDeadlineExceeded)
+}
+
+// closeStalled closes a connection whose write timed out: a dialed connection
+// is given up and redialed, an accepted one is closed so its reader cleans up
+func (t *TCPTransport) closeStalled(conn net.Conn, isClient bool, cause error) {
+	if isClient {
+		t.connectionLost(conn, cause)
+		return
+	}
+	t.log().Warn("Closing stalled connection", "remote", conn.RemoteAddr().String(), "error", cause)
+	conn.Close()
+}
+
+// readDeadline picks the read deadline of the next frame from the heartbeat
+// and read timeouts, and the error reported when it expires
+func readDeadline(heartbeatTimeout, readTimeout time.Duration) (time.Duration, error) {
+	switch {
+	case readTimeout > 0 && (heartbeatTimeout <= 0 || readTimeout < heartbeatTimeout):
+		return readTimeout, ErrReadTimeout
+	case heartbeatTimeout > 0:
+		return heartbeatTimeout, ErrHeartbeatTimeout
+	default:
+		return 0, nil
+	}
+}
@@ -0,0 +1,140 @@
+package tcp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+)
+
+// startStalledPeer listens on a loopback port and completes the handshake of
+// every connection, then never reads from it again. Accepted connections are
+// sent on the returned channel.
+func startStalledPeer(t *testing.T) (string, <-chan net.Conn) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	accepted := make(chan net.Conn, 4)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+
+			if _, frameType, err := readFrame(bufio.NewReaderSize(conn, 16)); err != nil || frameType != frameHandshake {
+				conn.Close()
+				continue
+			}
+			writeHandshake(conn, handshake{Version: ProtocolVersion, Codec: "json"})
+			accepted <- conn
+		}
+	}()
+	return listener.Addr().String(), accepted
+}
+
+func TestWriteTimeoutOnStalledPeer(t *testing.T) {
+	address, accepted := startStalledPeer(t)
+
+	observer := &recordingObserver{}
+	client := NewTCPTransport()
+	client.SetObserver(observer)
+	client.SetWriteTimeout(100 * time.Millisecond)
+	client.SetReconnectBackoff(10*time.Millisecond, 10*time.Millisecond)
+	if err := client.Connect(context.Background(), address); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+	<-accepted
+
+	// Fill the socket buffers until the peer's refusal to read blocks a write
+	big := btree.Message{Content: strings.Repeat("x", 256*1024)}
+	go func() {
+		for i := 0; i < 64; i++ {
+			select {
+			case client.GetOutboundChannel() <- big:
+			case <-time.After(5 * time.Second):
+				return
+			}
+		}
+	}()
+
+	waitFor(t, 5*time.Second, func() bool {
+		_, errs := observer.snapshot()
+		for _, err := range errs {
+			if errors.Is(err, ErrWriteTimeout) {
+				return true
+			}
+		}
+		return false
+	})
+
+	// The stalled connection is given up and redialed
+	select {
+	case <-accepted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the client to redial after the write timeout")
+	}
+}
+
+func TestReadTimeoutClosesSilentPeer(t *testing.T) {
+	observer := &recordingObserver{}
+	server := NewTCPTransport()
+	server.SetObserver(observer)
+	server.SetReadTimeout(100 * time.Millisecond)
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("Expected the server to close the connection, got %v", err)
+	}
+
+	waitFor(t, time.Second, func() bool {
+		events, _ := observer.snapshot()
+		return len(events) == 2
+	})
+	if _, errs := observer.snapshot(); !errors.Is(errs[1], ErrReadTimeout) {
+		t.Errorf("Expected disconnect with ErrReadTimeout, got %v", errs[1])
+	}
+}
+
+func TestReadDeadline(t *testing.T) {
+	cases := []struct {
+		heartbeat, read time.Duration
+		want            time.Duration
+		wantErr         error
+	}{
+		{0, 0, 0, nil},
+		{time.Second, 0, time.Second, ErrHeartbeatTimeout},
+		{0, time.Second, time.Second, ErrReadTimeout},
+		{time.Second, 2 * time.Second, time.Second, ErrHeartbeatTimeout},
+		{2 * time.Second, time.Second, time.Second, ErrReadTimeout},
+	}
+	for _, c := range cases {
+		got, err := readDeadline(c.heartbeat, c.read)
+		if got != c.want || err != c.wantErr {
+			t.Errorf("readDeadline(%v, %v) = %v, %v; want %v, %v", c.heartbeat, c.read, got, err, c.want, c.wantErr)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package tcp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+	"github.com/xnok/btree-server-msg/pkg/transport"
+)
+
+// NewUnixTransport creates a transport speaking the TCP transport's protocol
+// over Unix domain sockets, which avoids the TCP stack for nodes on the same
+// host. Listen and Connect take a socket file path instead of an address.
+// The listener removes its socket file when closed, and replaces a stale one
+// left behind by a listener that did not shut down cleanly.
+func NewUnixTransport() *TCPTransport {
+	t := newTCPTransport(btree.DefaultBufferSize, defaultCodec)
+	t.network = "unix"
+	t.SetLogger(nil)
+	return t
+}
+
+// listenAddress validates the address passed to Listen
+func (t *TCPTransport) listenAddress(address string) (string, error) {
+	if t.network == "unix" {
+		return socketPath(address)
+	}
+	return transport.ListenAddress(address)
+}
+
+// dialAddress validates the address passed to Connect
+func (t *TCPTransport) dialAddress(address string) (string, error) {
+	if t.network == "unix" {
+		return socketPath(address)
+	}
+	return transport.DialAddress(address)
+}
+
+// listen opens the transport's listener on address
+func (t *TCPTransport) listen(address string) (net.Listener, error) {
+	if t.network != "unix" {
+		return net.Listen(t.network, address)
+	}
+
+	if err := removeStaleSocket(address); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", address)
+	if err != nil {
+		return nil, err
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(true)
+	return listener, nil
+}
+
+// socketPath checks that address can name a socket file
+func socketPath(address string) (string, error) {
+	if address == "" {
+		return "", fmt.Errorf("socket path is empty")
+	}
+	return address, nil
+}
+
+// removeStaleSocket deletes the socket file at path if no listener answers
+// on it. Files other than sockets are left alone, so Listen fails on them.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("socket %s is in use", path)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale socket: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+)
+
+// startUnixPair connects a Unix socket client to a listener at a fresh path
+func startUnixPair(t *testing.T) (server, client *TCPTransport, path string) {
+	t.Helper()
+
+	path = filepath.Join(t.TempDir(), "node.sock")
+	server = NewUnixTransport()
+	if err := server.Listen(context.Background(), path); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	client = NewUnixTransport()
+	if err := client.Connect(context.Background(), path); err != nil {
+		server.Close()
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return server, client, path
+}
+
+func TestUnixTransportRoundTrip(t *testing.T) {
+	server, client, path := startUnixPair(t)
+
+	client.GetOutboundChannel() <- btree.NewMessage("over a socket file", "unix-1")
+	if msg := receive(t, server); msg.Content != "over a socket file" || msg.ID != "unix-1" {
+		t.Errorf("Unexpected message at the listener: %+v", msg)
+	}
+
+	server.GetOutboundChannel() <- btree.Message{Content: "reply", Upstream: true}
+	if msg := receive(t, client); msg.Content != "reply" {
+		t.Errorf("Unexpected message at the client: %+v", msg)
+	}
+
+	client.Close()
+	server.Close()
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the socket file to be removed on close, got %v", err)
+	}
+}
+
+func TestUnixTransportReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stale.sock")
+
+	// A listener that exits without unlinking leaves its socket file behind
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	server := NewUnixTransport()
+	defer server.Close()
+	if err := server.Listen(context.Background(), path); err != nil {
+		t.Fatalf("Expected the stale socket to be replaced, got %v", err)
+	}
+}
+
+func TestUnixTransportRejectsSocketInUse(t *testing.T) {
+	_, _, path := startUnixPair(t)
+
+	second := NewUnixTransport()
+	defer second.Close()
+	if err := second.Listen(context.Background(), path); err == nil {
+		t.Fatal("Expected listening on a socket in use to fail")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected the live socket to be kept, got %v", err)
+	}
+}
+
+func TestUnixTransportRejectsEmptyPath(t *testing.T) {
+	tr := NewUnixTransport()
+	defer tr.Close()
+	if err := tr.Listen(context.Background(), ""); err == nil {
+		t.Error("Expected an empty socket path to be rejected")
+	}
+}
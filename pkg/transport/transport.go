@@ -2,13 +2,31 @@ package transport
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
 
 	"github.com/xnok/btree-server-msg/pkg/btree"
 )
 
+// RemoteAddrKey is the metadata key under which listening transports record
+// the address of the connection a message arrived on
+const RemoteAddrKey = "remote-addr"
+
+// RemoteAddr returns the address of the connection msg arrived on, or "" if
+// the transport did not record it
+func RemoteAddr(msg btree.Message) string {
+	return msg.GetMetadata(RemoteAddrKey)
+}
+
 // Transport defines the interface for network transport layers
 type Transport interface {
-	// Listen starts listening for incoming connections on the specified address
+	// Listen starts listening for incoming connections on the specified
+	// address. It returns once the address is bound, or with the error that
+	// prevented binding it; connections are accepted in the background.
 	Listen(ctx context.Context, address string) error
 
 	// Connect establishes a connection to the specified address
@@ -24,6 +42,54 @@ type Transport interface {
 	GetOutboundChannel() chan<- btree.Message
 }
 
+// ClockSkewEstimator is implemented by transports that can estimate the clock
+// offset of the connected peer
+type ClockSkewEstimator interface {
+	// EstimateClockSkew returns how far the peer's clock is ahead of ours
+	EstimateClockSkew(ctx context.Context) (time.Duration, error)
+}
+
+// ConnectionCounter is implemented by transports that track the connections
+// their listener accepted
+type ConnectionCounter interface {
+	// ActiveConnections returns the number of currently open connections
+	ActiveConnections() int
+}
+
+// EventSource is implemented by transports that report connection lifecycle
+// events
+type EventSource interface {
+	// Events returns the channel lifecycle events are delivered on
+	Events() <-chan ConnectionEvent
+}
+
+// ListenAddresser is implemented by transports that can report the address
+// their listener is bound to, such as the port the OS picked for port 0
+type ListenAddresser interface {
+	// ListenAddr returns the bound address, or nil when not listening
+	ListenAddr() net.Addr
+}
+
+// Authenticator is implemented by transports that can gate connections
+// with a pre-shared token
+type Authenticator interface {
+	// SetAuthToken sets the token dialers present and listeners require
+	SetAuthToken(token string)
+}
+
+// LoggerSetter is implemented by transports that write structured logs
+type LoggerSetter interface {
+	// SetLogger sets the logger the transport writes to
+	SetLogger(logger *slog.Logger)
+}
+
+// setLogger passes logger to a transport that supports it
+func setLogger(t Transport, logger *slog.Logger) {
+	if setter, ok := t.(LoggerSetter); ok {
+		setter.SetLogger(logger)
+	}
+}
+
 // Server wraps a transport and provides server functionality
 type Server struct {
 	transport Transport
@@ -53,15 +119,54 @@ func (s *Server) GetOutboundChannel() chan<- btree.Message {
 	return s.transport.GetOutboundChannel()
 }
 
+// SetLogger sets the logger of the underlying transport, if it logs
+func (s *Server) SetLogger(logger *slog.Logger) {
+	setLogger(s.transport, logger)
+}
+
+// ActiveConnections returns the number of connections the server's transport
+// has open, and false if the transport does not track them
+func (s *Server) ActiveConnections() (int, bool) {
+	counter, ok := s.transport.(ConnectionCounter)
+	if !ok {
+		return 0, false
+	}
+	return counter.ActiveConnections(), true
+}
+
+// Addr returns the address the server's transport is bound to, or nil if
+// the server is not listening or its transport cannot report it. Listening
+// on port 0 lets the OS pick a free port, which Addr then reveals.
+func (s *Server) Addr() net.Addr {
+	addresser, ok := s.transport.(ListenAddresser)
+	if !ok {
+		return nil
+	}
+	return addresser.ListenAddr()
+}
+
 // Close closes the server
 func (s *Server) Close() error {
 	return s.transport.Close()
 }
 
-// Client wraps a transport and provides client functionality
+// ErrClientClosed is returned when sending through a closed client
+var ErrClientClosed = errors.New("client is closed")
+
+// Client wraps a transport and provides client functionality. A client owns
+// its transport and the single connection it opens: closing the client closes
+// the transport, so a transport must never be shared with a server or another
+// client.
 type Client struct {
 	transport Transport
 	address   string
+
+	// done is closed when Close starts; closed guards sends against the
+	// transport's outbound channel being closed underneath them
+	done      chan struct{}
+	closeOnce sync.Once
+	mu        sync.RWMutex
+	closed    bool
 }
 
 // NewClient creates a new transport client
@@ -69,6 +174,7 @@ func NewClient(transport Transport, address string) *Client {
 	return &Client{
 		transport: transport,
 		address:   address,
+		done:      make(chan struct{}),
 	}
 }
 
@@ -77,12 +183,80 @@ func (c *Client) Connect(ctx context.Context) error {
 	return c.transport.Connect(ctx, c.address)
 }
 
+// GetInboundChannel returns the channel for messages the remote peer sends back
+func (c *Client) GetInboundChannel() <-chan btree.Message {
+	return c.transport.GetInboundChannel()
+}
+
 // GetOutboundChannel returns the outbound channel to send messages
 func (c *Client) GetOutboundChannel() chan<- btree.Message {
 	return c.transport.GetOutboundChannel()
 }
 
-// Close closes the client connection
+// SetLogger sets the logger of the underlying transport, if it logs
+func (c *Client) SetLogger(logger *slog.Logger) {
+	setLogger(c.transport, logger)
+}
+
+// Send queues a message on the client's outbound channel. Unlike writing to
+// GetOutboundChannel directly, it is safe to call concurrently with Close and
+// returns ErrClientClosed once the client is closed.
+func (c *Client) Send(ctx context.Context, msg btree.Message) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.closed {
+		return ErrClientClosed
+	}
+
+	select {
+	case c.transport.GetOutboundChannel() <- msg:
+		return nil
+	case <-c.done:
+		return ErrClientClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Done returns a channel that is closed when the client is closed
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Events returns the connection lifecycle events of the client's transport,
+// or nil if the transport does not report them
+func (c *Client) Events() <-chan ConnectionEvent {
+	source, ok := c.transport.(EventSource)
+	if !ok {
+		return nil
+	}
+	return source.Events()
+}
+
+// Close closes the client's transport and connection without affecting any
+// other client or server; it is safe to call more than once
 func (c *Client) Close() error {
-	return c.transport.Close()
+	var err error
+	c.closeOnce.Do(func() {
+		// Wake up pending sends before waiting for them to release the lock
+		close(c.done)
+
+		c.mu.Lock()
+		c.closed = true
+		c.mu.Unlock()
+
+		err = c.transport.Close()
+	})
+	return err
+}
+
+// EstimateClockSkew estimates the clock offset of the remote peer when the
+// underlying transport supports it
+func (c *Client) EstimateClockSkew(ctx context.Context) (time.Duration, error) {
+	estimator, ok := c.transport.(ClockSkewEstimator)
+	if !ok {
+		return 0, fmt.Errorf("transport does not support clock skew estimation")
+	}
+	return estimator.EstimateClockSkew(ctx)
 }
@@ -0,0 +1,17 @@
+package udp
+
+import "log/slog"
+
+// SetLogger sets the structured logger the transport writes to; records
+// carry a "transport" attribute. A nil logger restores slog.Default().
+func (t *UDPTransport) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	t.logger.Store(logger.With("transport", "udp"))
+}
+
+// log returns the transport's logger
+func (t *UDPTransport) log() *slog.Logger {
+	return t.logger.Load()
+}
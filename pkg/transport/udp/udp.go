@@ -0,0 +1,292 @@
+package udp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+	"github.com/xnok/btree-server-msg/pkg/transport"
+)
+
+// DefaultMaxDatagramSize keeps a datagram within a 1500-byte Ethernet MTU
+// after the IPv4 and UDP headers
+const DefaultMaxDatagramSize = 1472
+
+// maxReadSize is the largest datagram the transport can receive
+const maxReadSize = 64 * 1024
+
+// ErrDatagramTooLarge is returned when an encoded message does not fit in a
+// single datagram
+var ErrDatagramTooLarge = errors.New("message exceeds maximum datagram size")
+
+// UDPTransport implements the Transport interface over UDP datagrams, one
+// encoded message per packet, JSON unless another codec is configured.
+// Delivery is not guaranteed: datagrams may be lost, duplicated or reordered.
+type UDPTransport struct {
+	inbound  chan btree.Message
+	outbound chan btree.Message
+	conn     net.PacketConn
+	remote   net.Addr
+	peers    map[string]net.Addr
+	peersMu  sync.Mutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	mu       sync.RWMutex
+	once     sync.Once
+	isServer bool
+	isClient bool
+
+	// maxDatagramSize bounds the encoded size of an outbound message
+	maxDatagramSize int
+
+	// codec serializes messages into datagrams
+	codec transport.Codec
+
+	// logger receives the transport's structured log records
+	logger atomic.Pointer[slog.Logger]
+}
+
+// NewUDPTransport creates a new UDP transport
+func NewUDPTransport() *UDPTransport {
+	return NewUDPTransportWithCodec(transport.JSONCodec{})
+}
+
+// NewUDPTransportWithCodec creates a new UDP transport that serializes
+// messages with codec; the peer must use a matching codec
+func NewUDPTransportWithCodec(codec transport.Codec) *UDPTransport {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &UDPTransport{
+		inbound:         make(chan btree.Message, btree.DefaultBufferSize),
+		outbound:        make(chan btree.Message, btree.DefaultBufferSize),
+		peers:           make(map[string]net.Addr),
+		ctx:             ctx,
+		cancel:          cancel,
+		maxDatagramSize: DefaultMaxDatagramSize,
+		codec:           codec,
+	}
+	t.SetLogger(nil)
+	return t
+}
+
+// SetMaxDatagramSize sets the largest encoded message the transport sends;
+// larger messages are dropped with ErrDatagramTooLarge
+func (t *UDPTransport) SetMaxDatagramSize(size int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.maxDatagramSize = size
+}
+
+// Listen binds a UDP socket and delivers received datagrams to the inbound
+// channel. Outbound messages are sent to every peer a datagram came from.
+func (t *UDPTransport) Listen(ctx context.Context, address string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.isServer || t.isClient {
+		return fmt.Errorf("already in use")
+	}
+
+	address, err := transport.ListenAddress(address)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", address, err)
+	}
+
+	t.conn = conn
+	t.isServer = true
+
+	t.log().Info("Listening", "address", conn.LocalAddr().String())
+
+	t.wg.Add(2)
+	go t.readDatagrams()
+	go t.processOutbound()
+
+	return nil
+}
+
+// ListenAddr returns the address the listening socket is bound to, including
+// the port the OS assigned when listening on port 0, or nil when not listening
+func (t *UDPTransport) ListenAddr() net.Addr {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if !t.isServer {
+		return nil
+	}
+	return t.conn.LocalAddr()
+}
+
+// Connect sets the address outbound messages are sent to. Replies from that
+// address are delivered to the inbound channel.
+func (t *UDPTransport) Connect(ctx context.Context, address string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.isServer || t.isClient {
+		return fmt.Errorf("already in use")
+	}
+
+	address, err := transport.DialAddress(address)
+	if err != nil {
+		return err
+	}
+
+	remote, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %v", address, err)
+	}
+
+	conn, err := net.ListenPacket("udp", ":0")
+	if err != nil {
+		return fmt.Errorf("failed to open UDP socket: %v", err)
+	}
+
+	t.conn = conn
+	t.remote = remote
+	t.isClient = true
+
+	t.log().Info("Sending", "remote", remote.String())
+
+	t.wg.Add(2)
+	go t.readDatagrams()
+	go t.processOutbound()
+
+	return nil
+}
+
+// Close closes the transport; it is safe to call more than once
+func (t *UDPTransport) Close() error {
+	t.once.Do(func() {
+		t.cancel()
+
+		t.mu.Lock()
+		if t.conn != nil {
+			t.conn.Close()
+		}
+		t.mu.Unlock()
+
+		// Wait for goroutines to finish
+		t.wg.Wait()
+
+		close(t.inbound)
+		close(t.outbound)
+	})
+	return nil
+}
+
+// GetInboundChannel returns the channel for incoming messages
+func (t *UDPTransport) GetInboundChannel() <-chan btree.Message {
+	return t.inbound
+}
+
+// GetOutboundChannel returns the channel for outgoing messages
+func (t *UDPTransport) GetOutboundChannel() chan<- btree.Message {
+	return t.outbound
+}
+
+// readDatagrams decodes datagrams into the inbound channel until the socket
+// is closed
+func (t *UDPTransport) readDatagrams() {
+	defer t.wg.Done()
+
+	buf := make([]byte, maxReadSize)
+	for {
+		n, addr, err := t.conn.ReadFrom(buf)
+		if err != nil {
+			if t.ctx.Err() == nil {
+				t.log().Error("Read error", "error", err)
+			}
+			return
+		}
+
+		// A client only accepts replies from the address it sends to
+		if t.isClient && addr.String() != t.remote.String() {
+			continue
+		}
+		if t.isServer {
+			t.peersMu.Lock()
+			t.peers[addr.String()] = addr
+			t.peersMu.Unlock()
+		}
+
+		msg, err := t.codec.Decode(buf[:n])
+		if err != nil {
+			t.log().Warn("Dropping malformed datagram", "remote", addr.String(), "error", err)
+			continue
+		}
+		msg = btree.AssignID(msg, nil)
+
+		select {
+		case t.inbound <- msg:
+			t.log().Debug("Received message", "content", msg.Content, "id", msg.ID)
+		case <-t.ctx.Done():
+			return
+		}
+	}
+}
+
+// processOutbound sends outbound messages as datagrams
+func (t *UDPTransport) processOutbound() {
+	defer t.wg.Done()
+
+	for {
+		select {
+		case msg := <-t.outbound:
+			if err := t.sendMessage(msg); err != nil {
+				t.log().Error("Failed to send message", "id", msg.ID, "error", err)
+			}
+		case <-t.ctx.Done():
+			return
+		}
+	}
+}
+
+// sendMessage writes a message as one datagram to the remote address, or to
+// every known peer when the transport is listening
+func (t *UDPTransport) sendMessage(msg btree.Message) error {
+	payload, err := t.codec.Encode(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %v", err)
+	}
+
+	t.mu.RLock()
+	maxSize := t.maxDatagramSize
+	t.mu.RUnlock()
+
+	if len(payload) > maxSize {
+		return fmt.Errorf("%w: %d bytes, limit %d (ID: %s)", ErrDatagramTooLarge, len(payload), maxSize, msg.ID)
+	}
+
+	var targets []net.Addr
+	if t.isClient {
+		targets = append(targets, t.remote)
+	} else {
+		t.peersMu.Lock()
+		for _, addr := range t.peers {
+			targets = append(targets, addr)
+		}
+		t.peersMu.Unlock()
+	}
+
+	if len(targets) == 0 {
+		return fmt.Errorf("no peers to send to")
+	}
+
+	for _, addr := range targets {
+		if _, err := t.conn.WriteTo(payload, addr); err != nil {
+			return fmt.Errorf("failed to write datagram to %s: %v", addr, err)
+		}
+	}
+
+	t.log().Debug("Sent message", "content", msg.Content, "id", msg.ID)
+	return nil
+}
@@ -0,0 +1,125 @@
+package udp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xnok/btree-server-msg/pkg/btree"
+	"github.com/xnok/btree-server-msg/pkg/transport"
+)
+
+// startPair starts a listening transport on a random loopback port and a
+// client transport sending to it
+func startPair(t *testing.T) (server, client *UDPTransport) {
+	t.Helper()
+
+	server = NewUDPTransport()
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	client = NewUDPTransport()
+	if err := client.Connect(context.Background(), server.conn.LocalAddr().String()); err != nil {
+		server.Close()
+		t.Fatalf("Failed to connect: %v", err)
+	}
+
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return server, client
+}
+
+// receive waits for the next inbound message on a transport
+func receive(t *testing.T, tr *UDPTransport) btree.Message {
+	t.Helper()
+
+	select {
+	case msg := <-tr.GetInboundChannel():
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for datagram")
+		return btree.Message{}
+	}
+}
+
+func TestBinaryPayloadDatagram(t *testing.T) {
+	server, client := startPair(t)
+
+	blob := []byte{'a', 0x00, 0x0A, 'b', 0xFF}
+	client.GetOutboundChannel() <- btree.NewBinaryMessage(blob, "bin-1")
+
+	if msg := receive(t, server); !bytes.Equal(msg.Payload, blob) {
+		t.Errorf("Expected %v, got %v", blob, msg.Payload)
+	}
+}
+
+func TestLoopbackDatagrams(t *testing.T) {
+	server, client := startPair(t)
+
+	for i := 0; i < 5; i++ {
+		client.GetOutboundChannel() <- btree.Message{Content: fmt.Sprintf("datagram %d", i), ID: fmt.Sprintf("u-%d", i)}
+	}
+
+	// Loopback does not drop or reorder, so every datagram arrives in order
+	for i := 0; i < 5; i++ {
+		msg := receive(t, server)
+		if expected := fmt.Sprintf("u-%d", i); msg.ID != expected {
+			t.Errorf("Expected %s, got %s", expected, msg.ID)
+		}
+	}
+
+	// The listener replies to the peer it heard from
+	server.GetOutboundChannel() <- btree.Message{Content: "reply", Upstream: true}
+	if msg := receive(t, client); msg.Content != "reply" || !msg.Upstream {
+		t.Errorf("Unexpected reply: %+v", msg)
+	}
+}
+
+func TestOversizedMessageIsRejected(t *testing.T) {
+	_, client := startPair(t)
+	client.SetMaxDatagramSize(100)
+
+	err := client.sendMessage(btree.Message{Content: strings.Repeat("x", 200)})
+	if !errors.Is(err, ErrDatagramTooLarge) {
+		t.Errorf("Expected ErrDatagramTooLarge, got %v", err)
+	}
+}
+
+func TestTextCodecDatagrams(t *testing.T) {
+	server := NewUDPTransportWithCodec(transport.TextCodec{})
+	if err := server.Listen(context.Background(), "127.0.0.1:0"); err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	client := NewUDPTransportWithCodec(transport.TextCodec{})
+	if err := client.Connect(context.Background(), server.conn.LocalAddr().String()); err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer client.Close()
+
+	client.GetOutboundChannel() <- btree.Message{Content: "raw text datagram"}
+	if msg := receive(t, server); msg.Content != "raw text datagram" {
+		t.Errorf("Expected raw text datagram, got %q", msg.Content)
+	}
+}
+
+func TestListenAddrReportsAssignedPort(t *testing.T) {
+	server, client := startPair(t)
+
+	addr, ok := server.ListenAddr().(*net.UDPAddr)
+	if !ok || addr.Port == 0 {
+		t.Fatalf("Expected an assigned UDP port, got %v", server.ListenAddr())
+	}
+	if addr := client.ListenAddr(); addr != nil {
+		t.Errorf("Expected no listen address on a sending transport, got %v", addr)
+	}
+}